@@ -0,0 +1,3 @@
+module github.com/agnione/libs
+
+go 1.21
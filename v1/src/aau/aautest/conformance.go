@@ -0,0 +1,155 @@
+package aautest
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	iappfw "github.com/agnione/libs/v1/src/appfm/iappfw"
+
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// Conformance_Instance_ID instance id passed to Initialize by the conformance suite
+const Conformance_Instance_ID = 7
+
+// Check_Conformance drives a new instance of the given unit through
+// Initialize -> Start -> Status -> Stop -> Deinitialize using a FakeApp and checks the IAppUnit contract.
+//
+//	pUnit is used as the prototype, the checks run on the instance returned by New().
+//	Returns nil if the unit follows the contract. Unless returns all the violations joined into one error
+func Check_Conformance(pUnit iappunit.IAppUnit) error {
+	return Check_App_Conformance(New_FakeApp(), pUnit)
+}
+
+// Check_App_Conformance runs the checks of Check_Conformance with the given framework instead of a FakeApp,
+// e.g. to check a framework implementation hosting the unit.
+func Check_App_Conformance(pApp iappfw.IAgniApp, pUnit iappunit.IAppUnit) error {
+
+	if pApp == nil {
+		return errors.New("app is nil")
+	}
+	if pUnit == nil {
+		return errors.New("unit is nil")
+	}
+
+	var _errs []error
+	_fail := func(pFormat string, pArgs ...any) {
+		_errs = append(_errs, fmt.Errorf(pFormat, pArgs...))
+	}
+
+	var _unit iappunit.IAppUnit
+	if _err := guard("New", func() {
+		_new, _ok := pUnit.New().(iappunit.IAppUnit)
+		if !_ok || _new == nil {
+			_fail("New() must return a non nil iappunit.IAppUnit")
+			return
+		}
+		_unit = _new
+	}); _err != nil {
+		return _err
+	}
+	if _unit == nil {
+		return errors.Join(_errs...)
+	}
+
+	const _name, _path, _config = "aautest_unit", "aautest/unit.so", "aautest/unit.config"
+	_app := pApp
+
+	_steps := []struct {
+		name string
+		run  func()
+	}{
+		{"before Initialize", func() {
+			if _unit.IsInitialized() {
+				_fail("IsInitialized() must be false before Initialize")
+			}
+			if _unit.IsStarted() {
+				_fail("IsStarted() must be false before Initialize")
+			}
+		}},
+		{"Start before Initialize", func() {
+			if _ok, _err := _unit.Start(); _ok || _err == nil {
+				_fail("Start() before Initialize must return false and an error, got %v, %v", _ok, _err)
+			}
+		}},
+		{"Initialize", func() {
+			if _ok, _err := _unit.Initialize(_app, Conformance_Instance_ID, _name, _path, _config); !_ok || _err != nil {
+				_fail("Initialize() must return true and nil, got %v, %v", _ok, _err)
+			}
+			if !_unit.IsInitialized() {
+				_fail("IsInitialized() must be true after Initialize")
+			}
+			if _id := _unit.GetID(); _id != Conformance_Instance_ID {
+				_fail("GetID() must return the instance id given to Initialize (%d), got %d", Conformance_Instance_ID, _id)
+			}
+		}},
+		{"Start", func() {
+			if _ok, _err := _unit.Start(); !_ok || _err != nil {
+				_fail("Start() must return true and nil, got %v, %v", _ok, _err)
+			}
+			if !_unit.IsStarted() {
+				_fail("IsStarted() must be true after Start")
+			}
+		}},
+		{"Status", func() {
+			_status := _unit.Status()
+			if _status == nil {
+				_fail("Status() must not return nil after Start")
+				return
+			}
+			if _status.Info.Name != _name {
+				_fail("Status().Info.Name must be the unit name given to Initialize (%s), got %q", _name, _status.Info.Name)
+			}
+		}},
+		{"Info", func() {
+			_unit.Info()
+		}},
+		{"Stop", func() {
+			if _ok, _err := _unit.Stop(); !_ok || _err != nil {
+				_fail("Stop() must return true and nil, got %v, %v", _ok, _err)
+			}
+			if _unit.IsStarted() {
+				_fail("IsStarted() must be false after Stop")
+			}
+		}},
+		{"Deinitialize", func() {
+			_unit.Deinitialize()
+		}},
+	}
+
+	for _, _step := range _steps {
+		if _err := guard(_step.name, _step.run); _err != nil {
+			_errs = append(_errs, _err)
+			break
+		}
+	}
+
+	return errors.Join(_errs...)
+}
+
+// Run_Conformance runs Check_Conformance and reports the violations as test errors
+func Run_Conformance(t testing.TB, pUnit iappunit.IAppUnit) {
+	t.Helper()
+	if _err := Check_Conformance(pUnit); _err != nil {
+		t.Errorf("IAppUnit conformance failed:\n%v", _err)
+	}
+}
+
+// Run_App_Conformance runs Check_App_Conformance and reports the violations as test errors
+func Run_App_Conformance(t testing.TB, pApp iappfw.IAgniApp, pUnit iappunit.IAppUnit) {
+	t.Helper()
+	if _err := Check_App_Conformance(pApp, pUnit); _err != nil {
+		t.Errorf("IAppUnit conformance failed:\n%v", _err)
+	}
+}
+
+// guard runs the given step and converts a panic into an error
+func guard(pStep string, pRun func()) (err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("%s panicked: %v", pStep, _r)
+		}
+	}()
+	pRun()
+	return nil
+}
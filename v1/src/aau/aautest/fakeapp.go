@@ -0,0 +1,646 @@
+// aautest package provides the test harness for the AgniOne Application Units
+//
+// This package includes below types & functions:
+//   - FakeApp
+//   - New_FakeApp
+//   - Call
+//   - LogEntry
+//   - Plain
+//   - Check_Conformance
+//   - Run_Conformance
+//   - Check_App_Conformance
+//   - Run_App_Conformance
+//
+// ---------------------------------------------------------------------------------------------------------------------
+// Copyright		:	Open source MIT License
+// Class/module		:	aautest - AgniOne Application Framework
+// Objective		:	Provide a fake IAgniApp and the IAppUnit conformance suite for unit tests
+// ---------------------------------------------------------------------------------------------------------------------
+// FakeApp implements iappfw.IAgniApp and records every call, so the unit tests don't need to
+// implement the framework interface by hand. The conformance suite drives any iappunit.IAppUnit through
+// Initialize -> Start -> Status -> Stop -> Deinitialize and checks the contract.
+// ---------------------------------------------------------------------------------------------------------------------
+package aautest
+
+import (
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	iws "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	iappfw "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// make sure FakeApp satisfies the framework interface.
+// Adding a method to iappfw.IAgniApp breaks this package until the fake records it as well.
+var _ iappfw.IAgniApp = (*FakeApp)(nil)
+var _ iappfw.IAgniAppLogger = (*FakeApp)(nil)
+var _ iappfw.IAgniAppLogfiles = (*FakeApp)(nil)
+var _ iappfw.IAgniAppMetrics = (*FakeApp)(nil)
+var _ iappfw.IAgniAppTracer = (*FakeApp)(nil)
+var _ iappfw.IAgniAppBus = (*FakeApp)(nil)
+
+// plain_app framework with only the functions of iappfw.IAgniApp
+type plain_app struct {
+	iappfw.IAgniApp
+}
+
+// Plain returns the framework without its optional interfaces (iappfw.IAgniAppLogger, iappfw.IAgniAppLogfiles, ...),
+// to test the units with a framework which implements only iappfw.IAgniApp
+func Plain(pApp iappfw.IAgniApp) iappfw.IAgniApp {
+	return plain_app{IAgniApp: pApp}
+}
+
+// Call holds a recorded call of the framework interface
+type Call struct {
+	Method string // name of the IAgniApp method
+	Args   []any  // arguments passed to the method
+}
+
+// LogEntry holds a recorded Write2Log call or an entry written to the Logger
+type LogEntry struct {
+	Entry string
+	Level atypes.LogLevel
+	Attrs map[string]any // attributes of the Logger entry, group names are joined with "." (nil for Write2Log)
+}
+
+// FakeApp fake iappfw.IAgniApp which records every call.
+//
+// Exported fields are used as the return values of the matching methods and can be set by the test
+// before the unit is initialized.
+type FakeApp struct {
+	lock sync.Mutex
+	cond *sync.Cond
+
+	calls            []Call
+	logs             []LogEntry
+	monitor_messages [][]byte
+	log_level        atypes.LogLevel
+	logger           *slog.Logger
+	metrics          *ametrics.Registry
+	tracer           *atrace.Tracer
+	spans            *atrace.Memory_Exporter
+	bus              *abus.Bus
+	routines_added   int
+	routines_removed int
+	req_handled      uint64
+	req_failed       uint64
+
+	interrupt   chan bool
+	interrupted sync.Once
+	ctx         context.Context
+	cancel      context.CancelFunc
+
+	AppName     string
+	AppID       string
+	AppVersion  string
+	AppPath     string
+	StartedTime time.Time
+	Units       []atypes.Appunit
+	Files       map[string][]byte
+	RESTClients map[string]ihttp.IAHTTPClient
+	WSClients   map[string]iws.IAWSClient
+	LogFiles    []string // returned by Logfile_List
+
+	// Command_Result is called by Execute_Command. Returns an error when not set
+	Command_Result func(pCommand string) (string, error)
+
+	// Unit_Result is called by Unit_Start, Unit_Stop and Unit_Restart. Returns true when not set
+	Unit_Result func(pMethod string, pUnitName string, pForce bool) (bool, error)
+}
+
+// New_FakeApp creates a new fake framework instance with the default values
+func New_FakeApp() *FakeApp {
+	_app := &FakeApp{
+		interrupt:   make(chan bool),
+		log_level:   atypes.LOG_DEBUG,
+		AppName:     "aautest",
+		AppID:       "aautest",
+		AppVersion:  "0.0.0",
+		AppPath:     os.TempDir(),
+		StartedTime: time.Now(),
+		Files:       make(map[string][]byte),
+		RESTClients: make(map[string]ihttp.IAHTTPClient),
+		WSClients:   make(map[string]iws.IAWSClient),
+	}
+	_app.cond = sync.NewCond(&_app.lock)
+	_app.logger = slog.New(&log_handler{app: _app})
+	_app.metrics = ametrics.New_Registry()
+	_app.spans = atrace.New_Memory_Exporter()
+	_app.tracer = atrace.New_Tracer(atrace.Config{Service: _app.AppName, Sample_Ratio: 1}, _app.spans)
+	_app.bus = abus.New_Bus(abus.Config{})
+	_app.ctx, _app.cancel = context.WithCancel(context.Background())
+	return _app
+}
+
+// record stores the call and wakes up the waiting callers
+func (app *FakeApp) record(pMethod string, pArgs ...any) {
+	app.calls = append(app.calls, Call{Method: pMethod, Args: pArgs})
+	app.cond.Broadcast()
+}
+
+// Calls returns a copy of all the recorded calls in the order they were made
+func (app *FakeApp) Calls() []Call {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return append([]Call(nil), app.calls...)
+}
+
+// Call_Count returns the number of recorded calls of the given method
+func (app *FakeApp) Call_Count(pMethod string) int {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return app.call_count(pMethod)
+}
+
+func (app *FakeApp) call_count(pMethod string) int {
+	_count := 0
+	for _, _call := range app.calls {
+		if _call.Method == pMethod {
+			_count++
+		}
+	}
+	return _count
+}
+
+// Wait_Calls waits until the given method is called at least pCount times.
+// AUBase calls the framework from separate routines, so the tests should wait before checking the calls.
+//
+//	Returns false if the timeout is reached.
+func (app *FakeApp) Wait_Calls(pMethod string, pCount int, pTimeout time.Duration) bool {
+
+	_timer := time.AfterFunc(pTimeout, func() {
+		app.lock.Lock()
+		app.cond.Broadcast()
+		app.lock.Unlock()
+	})
+	defer _timer.Stop()
+
+	_deadline := time.Now().Add(pTimeout)
+
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	for app.call_count(pMethod) < pCount {
+		if !time.Now().Before(_deadline) {
+			return false
+		}
+		app.cond.Wait()
+	}
+	return true
+}
+
+// Logs returns a copy of the recorded Write2Log entries
+func (app *FakeApp) Logs() []LogEntry {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return append([]LogEntry(nil), app.logs...)
+}
+
+// Logs_Containing returns the recorded log entries which contain the given text
+func (app *FakeApp) Logs_Containing(pText string) []LogEntry {
+	var _found []LogEntry
+	for _, _entry := range app.Logs() {
+		if strings.Contains(_entry.Entry, pText) {
+			_found = append(_found, _entry)
+		}
+	}
+	return _found
+}
+
+// Monitor_Messages returns a copy of the recorded Send_Monitor_Message payloads
+func (app *FakeApp) Monitor_Messages() [][]byte {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return append([][]byte(nil), app.monitor_messages...)
+}
+
+// Routines_Added returns the number of Add_Routine calls
+func (app *FakeApp) Routines_Added() int {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return app.routines_added
+}
+
+// Routines_Removed returns the number of Remove_Routine calls
+func (app *FakeApp) Routines_Removed() int {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	return app.routines_removed
+}
+
+// Reset clears all the recorded calls
+func (app *FakeApp) Reset() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.calls = nil
+	app.logs = nil
+	app.monitor_messages = nil
+	app.routines_added = 0
+	app.routines_removed = 0
+	app.req_handled = 0
+	app.req_failed = 0
+	app.spans.Reset()
+}
+
+// Interrupt closes the interrupt channel and cancels the context
+func (app *FakeApp) Interrupt() {
+	app.interrupted.Do(func() {
+		close(app.interrupt)
+		app.cancel()
+	})
+}
+
+// ********* iappfw.IAgniApp *********
+
+func (app *FakeApp) Reload_Config() (bool, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Reload_Config")
+	return true, nil
+}
+
+func (app *FakeApp) Start_WSMonitor() (bool, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Start_WSMonitor")
+	return true, nil
+}
+
+func (app *FakeApp) Stop_WSMonitor() bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Stop_WSMonitor")
+	return true
+}
+
+func (app *FakeApp) Is_Interrupted() chan bool {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Is_Interrupted")
+	return app.interrupt
+}
+
+func (app *FakeApp) Write2Console(pMessage string) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Write2Console", pMessage)
+}
+
+func (app *FakeApp) Write2Log(pEntry string, pLog_Level atypes.LogLevel) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.logs = append(app.logs, LogEntry{Entry: pEntry, Level: pLog_Level})
+	app.record("Write2Log", pEntry, pLog_Level)
+}
+
+func (app *FakeApp) Logger() *slog.Logger {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Logger")
+	return app.logger
+}
+
+func (app *FakeApp) Set_LogLevel(pLogLevel atypes.LogLevel) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.log_level = pLogLevel
+	app.record("Set_LogLevel", pLogLevel)
+}
+
+func (app *FakeApp) Add_Routine() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.routines_added++
+	app.record("Add_Routine")
+}
+
+func (app *FakeApp) Remove_Routine() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.routines_removed++
+	app.record("Remove_Routine")
+}
+
+func (app *FakeApp) Version() string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Version")
+	return app.AppVersion
+}
+
+func (app *FakeApp) Name() string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Name")
+	return app.AppName
+}
+
+func (app *FakeApp) ID() string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("ID")
+	return app.AppID
+}
+
+func (app *FakeApp) PID() int {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("PID")
+	return os.Getpid()
+}
+
+func (app *FakeApp) Memory_Usage() string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Memory_Usage")
+	return ""
+}
+
+func (app *FakeApp) App_Path() *string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("App_Path")
+	_path := app.AppPath
+	return &_path
+}
+
+func (app *FakeApp) Routine_Count() int64 {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Routine_Count")
+	return int64(app.routines_added - app.routines_removed)
+}
+
+func (app *FakeApp) Execute_Command(pCommand *string) (string, error) {
+	app.lock.Lock()
+	_handler := app.Command_Result
+	app.record("Execute_Command", deref(pCommand))
+	app.lock.Unlock()
+
+	if _handler == nil {
+		return "", fmt.Errorf("aautest: Execute_Command %w", aerrors.ErrNotSupported)
+	}
+	return _handler(deref(pCommand))
+}
+
+func (app *FakeApp) Add_Request_HandleCount() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.req_handled++
+	app.record("Add_Request_HandleCount")
+}
+
+func (app *FakeApp) Save_App_Config(pAppConfigData *[]byte) (bool, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if pAppConfigData == nil {
+		app.record("Save_App_Config", nil)
+		return false, fmt.Errorf("config data %w", aerrors.ErrNilArgument)
+	}
+	app.record("Save_App_Config", append([]byte(nil), *pAppConfigData...))
+	return true, nil
+}
+
+func (app *FakeApp) Add_Request_Failed_Count() {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.req_failed++
+	app.record("Add_Request_Failed_Count")
+}
+
+func (app *FakeApp) Handled_Request_Count() uint64 {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Handled_Request_Count")
+	return app.req_handled
+}
+
+func (app *FakeApp) Failed_Request_Count() uint64 {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Failed_Request_Count")
+	return app.req_failed
+}
+
+func (app *FakeApp) Started() time.Time {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Started")
+	return app.StartedTime
+}
+
+func (app *FakeApp) Send_Monitor_Message(pMessage []byte) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	_message := append([]byte(nil), pMessage...)
+	app.monitor_messages = append(app.monitor_messages, _message)
+	app.record("Send_Monitor_Message", _message)
+}
+
+func (app *FakeApp) Get_App_Status() atypes.AppStatus {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_App_Status")
+	return atypes.AppStatus{
+		Req_Handled: app.req_handled,
+		Req_Failed:  app.req_failed,
+		Routines:    int64(app.routines_added - app.routines_removed),
+	}
+}
+
+func (app *FakeApp) Get_App_Info() atypes.AppInfo {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_App_Info")
+	return atypes.AppInfo{
+		Info:    atypes.Info{Name: app.AppName, Version: app.AppVersion},
+		Started: app.StartedTime.Format(time.RFC3339),
+		PID:     os.Getpid(),
+	}
+}
+
+func (app *FakeApp) Get_Context() *context.Context {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_Context")
+	return &app.ctx
+}
+
+func (app *FakeApp) Get_FileInfo(pFileName *string) (*atypes.FileInfo, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_FileInfo", deref(pFileName))
+
+	_data, _ok := app.Files[deref(pFileName)]
+	if !_ok {
+		return nil, os.ErrNotExist
+	}
+	return &atypes.FileInfo{Name: deref(pFileName), Size: int64(len(_data)), ModTime: app.StartedTime}, nil
+}
+
+func (app *FakeApp) Get_File_Content(pFileName *string) (*[]byte, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_File_Content", deref(pFileName))
+
+	_data, _ok := app.Files[deref(pFileName)]
+	if !_ok {
+		return nil, os.ErrNotExist
+	}
+	_copy := append([]byte(nil), _data...)
+	return &_copy, nil
+}
+
+func (app *FakeApp) Logfile_Basepath() *string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Logfile_Basepath")
+	_path := app.AppPath
+	return &_path
+}
+
+func (app *FakeApp) Logfile_Name() string {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Logfile_Name")
+	return app.AppID + ".log"
+}
+
+func (app *FakeApp) Logfile_List() ([]string, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Logfile_List")
+	return append([]string{}, app.LogFiles...), nil
+}
+
+func (app *FakeApp) Metrics() *ametrics.Registry {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Metrics")
+	return app.metrics
+}
+
+func (app *FakeApp) Tracer() *atrace.Tracer {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Tracer")
+	return app.tracer
+}
+
+func (app *FakeApp) Bus() *abus.Bus {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Bus")
+	return app.bus
+}
+
+// Spans returns the spans ended by the unit and exported by Tracer, after flushing the tracer
+func (app *FakeApp) Spans() []atrace.Span_Data {
+	app.tracer.Flush(context.Background())
+	return app.spans.Spans()
+}
+
+func (app *FakeApp) Get_FileContent_Lines(pFileName *string) (*[]string, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_FileContent_Lines", deref(pFileName))
+
+	_data, _ok := app.Files[deref(pFileName)]
+	if !_ok {
+		return nil, os.ErrNotExist
+	}
+	_lines := strings.Split(strings.TrimSuffix(string(_data), "\n"), "\n")
+	return &_lines, nil
+}
+
+func (app *FakeApp) Write_FileContent(pFileName *string, pData *[]byte) (bool, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	if pData == nil {
+		app.record("Write_FileContent", deref(pFileName), nil)
+		return false, fmt.Errorf("data %w", aerrors.ErrNilArgument)
+	}
+	_data := append([]byte(nil), *pData...)
+	app.Files[deref(pFileName)] = _data
+	app.record("Write_FileContent", deref(pFileName), _data)
+	return true, nil
+}
+
+func (app *FakeApp) Units_List() ([]atypes.Appunit, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Units_List")
+	return append([]atypes.Appunit(nil), app.Units...), nil
+}
+
+func (app *FakeApp) Unit_Stop(pUnitName *string, pForce bool) (bool, error) {
+	return app.unit_call("Unit_Stop", deref(pUnitName), pForce)
+}
+
+func (app *FakeApp) Unit_Start(pUnitName *string) (bool, error) {
+	return app.unit_call("Unit_Start", deref(pUnitName), false)
+}
+
+func (app *FakeApp) Unit_Restart(pUnitName *string, pForce bool) (bool, error) {
+	return app.unit_call("Unit_Restart", deref(pUnitName), pForce)
+}
+
+func (app *FakeApp) Unit_Status(pUnitName *string) (*atypes.AppUnitInfo, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Unit_Status", deref(pUnitName))
+	return &atypes.AppUnitInfo{Info: atypes.Info{Name: deref(pUnitName)}}, nil
+}
+
+func (app *FakeApp) Get_WSClient(pType *string) (iws.IAWSClient, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_WSClient", deref(pType))
+
+	if _client, _ok := app.WSClients[deref(pType)]; _ok {
+		return _client, nil
+	}
+	return nil, fmt.Errorf("aautest: web socket client %s. %w", deref(pType), aerrors.ErrPluginNotFound)
+}
+
+func (app *FakeApp) Get_RESTClient(pType *string) (ihttp.IAHTTPClient, error) {
+	app.lock.Lock()
+	defer app.lock.Unlock()
+	app.record("Get_RESTClient", deref(pType))
+
+	if _client, _ok := app.RESTClients[deref(pType)]; _ok {
+		return _client, nil
+	}
+	return nil, fmt.Errorf("aautest: http client %s. %w", deref(pType), aerrors.ErrPluginNotFound)
+}
+
+// unit_call records the unit control call and returns the result of Unit_Result
+func (app *FakeApp) unit_call(pMethod string, pUnitName string, pForce bool) (bool, error) {
+	app.lock.Lock()
+	_handler := app.Unit_Result
+	app.record(pMethod, pUnitName, pForce)
+	app.lock.Unlock()
+
+	if _handler == nil {
+		return true, nil
+	}
+	return _handler(pMethod, pUnitName, pForce)
+}
+
+// deref returns the value of the given string pointer, empty string for nil
+func deref(pValue *string) string {
+	if pValue == nil {
+		return ""
+	}
+	return *pValue
+}
@@ -0,0 +1,79 @@
+package aautest
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"context"
+	"log/slog"
+)
+
+// log_handler slog handler of FakeApp.Logger, records the entries as LogEntry.
+// Entries below the log level set by Set_LogLevel are not recorded.
+type log_handler struct {
+	app    *FakeApp
+	attrs  []slog.Attr
+	prefix string /// group names joined with "."
+}
+
+func (h *log_handler) Enabled(_ context.Context, pLevel slog.Level) bool {
+	h.app.lock.Lock()
+	defer h.app.lock.Unlock()
+	return pLevel >= h.app.log_level.Slog()
+}
+
+func (h *log_handler) Handle(_ context.Context, pRecord slog.Record) error {
+
+	_attrs := make(map[string]any, len(h.attrs)+pRecord.NumAttrs())
+	for _, _attr := range h.attrs {
+		add_attr(_attrs, "", _attr)
+	}
+	pRecord.Attrs(func(pAttr slog.Attr) bool {
+		add_attr(_attrs, h.prefix, pAttr)
+		return true
+	})
+
+	_level := atypes.From_SlogLevel(pRecord.Level)
+
+	h.app.lock.Lock()
+	defer h.app.lock.Unlock()
+	h.app.logs = append(h.app.logs, LogEntry{Entry: pRecord.Message, Level: _level, Attrs: _attrs})
+	h.app.record("Log", pRecord.Message, _level)
+	return nil
+}
+
+func (h *log_handler) WithAttrs(pAttrs []slog.Attr) slog.Handler {
+	_attrs := append([]slog.Attr(nil), h.attrs...)
+	for _, _attr := range pAttrs {
+		if h.prefix != "" {
+			_attr.Key = h.prefix + _attr.Key
+		}
+		_attrs = append(_attrs, _attr)
+	}
+	return &log_handler{app: h.app, attrs: _attrs, prefix: h.prefix}
+}
+
+func (h *log_handler) WithGroup(pName string) slog.Handler {
+	if pName == "" {
+		return h
+	}
+	return &log_handler{app: h.app, attrs: h.attrs, prefix: h.prefix + pName + "."}
+}
+
+// add_attr adds the attribute to the map, groups are flattened into "group.key"
+func add_attr(pAttrs map[string]any, pPrefix string, pAttr slog.Attr) {
+	_value := pAttr.Value.Resolve()
+	if _value.Kind() == slog.KindGroup {
+		_prefix := pPrefix
+		if pAttr.Key != "" {
+			_prefix += pAttr.Key + "."
+		}
+		for _, _attr := range _value.Group() {
+			add_attr(pAttrs, _prefix, _attr)
+		}
+		return
+	}
+	if pAttr.Key == "" {
+		return
+	}
+	pAttrs[pPrefix+pAttr.Key] = _value.Any()
+}
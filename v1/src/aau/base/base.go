@@ -7,9 +7,16 @@
 //   - IsInitialized
 //   - Start
 //   - Stop
+//   - StartContext
+//   - StopContext
+//   - Context
 //   - IsStarted
+//   - State
+//   - Set_Failed
 //   - Add_Routine
 //   - Remove_Routine
+//   - Go
+//   - Routines_List
 //   - Get_ID
 //   - Get_PID
 //   - Get_KAUUID
@@ -18,11 +25,33 @@
 //   - ConvertToFloat32
 //   - ConvertToInt32
 //   - Get_RESTClient
+//   - Register_HTTPInterceptor
+//   - Remove_HTTPInterceptor
 //   - Get_WSClient
 //   - Get_Mailer
 //   - ExecuteandFetch
 //   - Send_Monitor_Message
 //   - Write2Log
+//   - Logger
+//   - Flush
+//   - FlushContext
+//   - Dropped
+//   - Counter
+//   - Gauge
+//   - Histogram
+//   - Metric_Labels
+//   - Track
+//   - New_Pool
+//   - Add_Bytes_Sent
+//   - Add_Bytes_Received
+//   - Counters
+//   - Start_Span
+//   - With_Context
+//   - Check_Health
+//   - Publish
+//   - Subscribe
+//   - Request
+//   - Reply
 //     ---------------------------------------------------------------------------------------------------------------------
 //     Author        :   D. Ajith Nilantha de Silva  ajithdesilva@gmail.com | 02/01/2024
 //     Copyright     :   Open source MIT License
@@ -45,17 +74,27 @@
 package AUBase
 
 import (
-	autypes "agnione/v1/src/aau/types"
-	ihttp "agnione/v1/src/afplugins/http/iahttpclient" /// import the httplcient interface
-	"agnione/v1/src/afplugins/websocket/iawsclient"
-	iappfm "agnione/v1/src/appfm/iappfw"
-	atypes "agnione/v1/src/appfm/types"
+	autypes "github.com/agnione/libs/v1/src/aau/types"
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient" /// import the httplcient interface
+	"github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/aresource"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
-	"runtime"
+	"io"
+	"log/slog"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 // AUBase base struct to hold the propeties of the Application unit
@@ -69,10 +108,81 @@ type AUBase struct {
 	Unit_Path      string
 	App_UID string
 	Stopper        chan bool
+	// Deprecated: use IsStarted or State. Kept in sync with the state for the existing units under the lock
+	// of the state, reading it while the state changes is a data race.
 	Is_Started     bool
+	// Deprecated: use IsInitialized or State. Kept in sync with the state for the existing units under the lock
+	// of the state, reading it while the state changes is a data race.
 	Is_Initialized bool
+
+	state      atomic.Int32	/// atypes.UnitState
+	flags_lock sync.Mutex	/// guards the writes of Is_Started and Is_Initialized
+
+	logger *slog.Logger	/// child logger of the framework logger with the unit attributes
+	tracer *atrace.Tracer	/// tracer of the framework
+
+	/// Queue_Size and Drop_Policy of the log/monitoring delivery queue, set before Initialize.
+	/// DEFAULT_QUEUE_SIZE and DROP_OLDEST are used when not set
+	Queue_Size  int
+	Drop_Policy DropPolicy
+
+	outbox  atomic.Pointer[outbox]	/// delivery queue of Write2Log and Send_Monitor_Message
+	dropped atomic.Uint64
+
+	/// Stats_Window sliding window of the operation rates and percentiles reported by Status,
+	/// set before the first Track. DEFAULT_STATS_WINDOW is used when not set
+	Stats_Window time.Duration
+
+	ops_lock sync.Mutex
+	ops      map[string]*op_stats	/// operations tracked with Track
+
+	pools_lock     sync.Mutex
+	pools          map[string]*aresource.Pool	/// pools created with New_Pool
+	bytes_sent     atomic.Uint64	/// plugin traffic of the unit
+	bytes_received atomic.Uint64
+
+	req_handled atomic.Uint64	/// counters of the unit, see counters.go
+	req_failed  atomic.Uint64
+	routine_cnt atomic.Int64
+	active      atomic.Int64
+	fm_sums     bool	/// the framework sums the unit counters (iappfm.IAgniAppCounters)
+
+	bus           *abus.Bus	/// message bus of the framework, see bus.go
+	subs_lock     sync.Mutex
+	subs          []*abus.Subscription	/// subscriptions closed when the unit is stopped
+	bus_published atomic.Uint64	/// traffic of the unit on the message bus
+	bus_received  atomic.Uint64
+	bus_failed    atomic.Uint64
+	bus_dropped   atomic.Uint64
+	bus_requests  atomic.Uint64
+
+	breakers_lock sync.Mutex
+	breakers      map[string]breaker_reporter	/// latest http client of each type with a circuit breaker, see plugins.go
+
+	http_chain_lock sync.Mutex
+	http_chain      *aintercept.Chain	/// interceptors of the http clients of the unit, see plugins.go
+
+	ctx_lock   sync.RWMutex
+	ctx        context.Context    /// unit context, cancelled on Stop
+	ctx_cancel context.CancelFunc
+
+	routines_lock sync.Mutex
+	routines      map[uint64]*routine /// routines started with Go
+	routine_seq   uint64
+	routines_done chan struct{}       /// signalled when a tracked routine exits
+
+	/// On_Routine_Exit is called (if set) when a routine started with Go exits.
+	/// pErr is the returned error or the recovered panic.
+	On_Routine_Exit func(pName string, pErr error)
 }
 
+// stopped_ctx is returned by Context when the unit is not started
+var stopped_ctx = func() context.Context {
+	_ctx, _cancel := context.WithCancel(context.Background())
+	_cancel()
+	return _ctx
+}()
+
 // Initialize initializes the properties of the base struct.
 //
 // Reterns true and nil when success
@@ -81,149 +191,252 @@ func (appu *AUBase) Initialize(pFM_Instance iappfm.IAgniApp, pInstance_ID int,
 	 pUnit_Name string, pUnit_Path string,pConfig_File string,) (bool, error) {
 
 	if pFM_Instance == nil {
-		appu.Is_Initialized = false
-		appu.AppFramework.Send_Monitor_Message([]byte(fmt.Sprintf("%d fm_instance iappfm.IAgniApp is NIL", pInstance_ID)))
-		return false, fmt.Errorf("%d fm_instance iappfm.IKApp is NIL", pInstance_ID)
+		return false, fmt.Errorf("%d fm_instance iappfm.IKApp is NIL. %w", pInstance_ID, aerrors.ErrNilArgument)
+	}
+	if _state := appu.State(); !atypes.Can_Transition(_state, atypes.UNIT_INITIALIZED) {
+		return false, &StateError{Unit: pUnit_Name, From: _state, To: atypes.UNIT_INITIALIZED}
 	}
 	
-	appu.Unit_Info=new(atypes.AppUnitInfo)
+	/// the lock is kept by Deinitialize, Status may be called on a deinitialized unit
+	if appu.Info_Lock == nil {
+		appu.Info_Lock = &sync.Mutex{}
+	}
+	appu.Info_Lock.Lock()
+	appu.Unit_Info = &atypes.AppUnitInfo{Info: atypes.Info{Name: pUnit_Name}}
+	appu.Info_Lock.Unlock()
 	
 	appu.AppFramework = pFM_Instance
 	appu.ID = pInstance_ID
 	appu.Unit_Name = pUnit_Name
 	appu.Config_File = pConfig_File
 	appu.Unit_Path = pUnit_Path
-	appu.Info_Lock = &sync.Mutex{}
-	appu.Unit_Info.Info.Name = appu.Unit_Name
-	appu.Is_Initialized = true
 	appu.Stopper = nil
+	appu.reset_ops()
+	appu.bytes_sent.Store(0)
+	appu.bytes_received.Store(0)
+	appu.reset_counters(pFM_Instance)
+	appu.open_outbox()
+	appu.tracer = framework_tracer(pFM_Instance)
+	appu.reset_bus(framework_bus(pFM_Instance))
+	appu.reset_breakers()
+	appu.logger = nil
+	if _logger := framework_logger(pFM_Instance); _logger != nil {
+		appu.logger = _logger.With(
+			slog.String("unit", pUnit_Name),
+			slog.Int("instance", pInstance_ID),
+			slog.String("auid", strconv.Itoa(pInstance_ID)+"-"+strconv.Itoa(pFM_Instance.PID())))
+	}
 
 	appu.Read_Memory_Usage()
 	
+	if _err := appu.transition(atypes.UNIT_INITIALIZED, nil); _err != nil {
+		return false, _err
+	}
 	
 	return true, nil
 }
 
-// Deinitialize clear all the related objects.
+// Deinitialize clear all the related objects and resets the state to Created.
 func (appu *AUBase) Deinitialize() {
 
 	/// clear objects here
+	appu.cancel_context()
+	if appu.transition(atypes.UNIT_CREATED, nil) != nil {
+		/// Deinitialize always resets the unit, even from Running/Stopping
+		appu.publish_state(appu.State(), atypes.UNIT_CREATED, nil)
+		appu.state.Store(int32(atypes.UNIT_CREATED))
+		appu.sync_flags(atypes.UNIT_CREATED)
+	}
+	/// deliver the shutdown messages before the framework is released
+	appu.Flush()
+	if _box := appu.outbox.Swap(nil); _box != nil {
+		_box.close()
+	}
+	appu.unregister_metrics()
+	appu.reset_ops()
+	appu.reset_bus(nil)
+	appu.reset_breakers()
+	appu.reset_http_interceptors()
 	appu.AppFramework = nil
-	appu.Info_Lock = nil
-	appu.Unit_Info = nil
+	appu.logger = nil
+	appu.tracer = nil
+	if appu.Info_Lock != nil {
+		appu.Info_Lock.Lock()
+		appu.Unit_Info = nil
+		appu.Info_Lock.Unlock()
+	}
 	appu.Unit_Name = ""
 	appu.Config_File = ""
 	appu.Unit_Path = ""
 }
 
+// Start starts the application unit. Same as StartContext with context.Background()
 func (appu *AUBase) Start() (bool, error) {
-	if !appu.Is_Initialized {
-		return false, fmt.Errorf("%d Failed to Start. %s instance is not initialized", appu.ID, appu.Unit_Name)
+	return appu.StartContext(context.Background())
+}
+
+// StartContext starts the application unit and creates the unit context returned by Context.
+//
+// The unit context is derived from the framework context and cancelled on Stop.
+// pCtx only limits the start, returns false and error if it is already done.
+func (appu *AUBase) StartContext(pCtx context.Context) (bool, error) {
+	if appu.State() == atypes.UNIT_CREATED || appu.AppFramework == nil {
+		return false, fmt.Errorf("%d Failed to Start. %s %w", appu.ID, appu.Unit_Name, aerrors.ErrNotInitialized)
+	}
+	if _err := appu.transition(atypes.UNIT_STARTING, nil); _err != nil {
+		return false, _err
+	}
+	if pCtx != nil && pCtx.Err() != nil {
+		appu.transition(atypes.UNIT_FAILED, pCtx.Err())
+		return false, fmt.Errorf("%d Failed to Start. %s %w", appu.ID, appu.Unit_Name, pCtx.Err())
+	}
+
+	_parent := context.Background()
+	if _fm_ctx := appu.AppFramework.Get_Context(); _fm_ctx != nil && *_fm_ctx != nil {
+		_parent = *_fm_ctx
 	}
+
+	appu.ctx_lock.Lock()
+	if appu.ctx_cancel != nil {
+		appu.ctx_cancel()
+	}
+	appu.ctx, appu.ctx_cancel = context.WithCancel(_parent)
+	appu.ctx_lock.Unlock()
+
 	appu.Stopper = make(chan bool)
+	if _err := appu.transition(atypes.UNIT_RUNNING, nil); _err != nil {
+		return false, _err
+	}
 	return true, nil
 }
 
+// Context returns the unit context. It is cancelled when the unit is stopped or the framework is interrupted.
+//
+// Routines of the unit should select on Context().Done() to stop.
+// Returns an already cancelled context if the unit is not started.
+func (appu *AUBase) Context() context.Context {
+	appu.ctx_lock.RLock()
+	defer appu.ctx_lock.RUnlock()
+	if appu.ctx == nil {
+		return stopped_ctx
+	}
+	return appu.ctx
+}
+
+// cancel_context cancels the unit context
+func (appu *AUBase) cancel_context() {
+	appu.ctx_lock.Lock()
+	defer appu.ctx_lock.Unlock()
+	if appu.ctx_cancel != nil {
+		appu.ctx_cancel()
+		appu.ctx_cancel = nil
+	}
+}
+
+// Stop stops the application unit. Same as StopContext with a deadline of atypes.DEFAULT_STOP_TIMEOUT seconds
 func (appu *AUBase) Stop() (bool, error) {
+	_ctx, _cancel := context.WithTimeout(context.Background(), atypes.DEFAULT_STOP_TIMEOUT*time.Second)
+	defer _cancel()
+	return appu.StopContext(_ctx)
+}
+
+// StopContext stops the application unit by cancelling the unit context and closing the Stopper channel,
+// then waits for the routines started with Go to exit.
+//
+// pCtx is the deadline for the stop, when it is done the unit is stopped without waiting (forced stop):
+// the unit moves into the Failed state and the queued log entries and monitoring messages get
+// FORCED_FLUSH_TIMEOUT more to be delivered. The ones still queued after it are delivered in the background and
+// are lost if the process exits first.
+// Returns false and error with the names of the routines which did not exit before the deadline.
+func (appu *AUBase) StopContext(pCtx context.Context) (bool, error) {
 	if appu.AppFramework == nil {
-		return false, fmt.Errorf("instance is not initialized")
+		return false, aerrors.ErrNotInitialized
 	}
+	defer appu.flush_stopped(pCtx)
 	//fmt.Printf("%d In the  BASE STOP %s..... 2 \n", appu.ID, appu.Unit_name)
 	appu.AppFramework.Write2Log(appu.App_UID + " - Stopping the AUBase.....", atypes.LOG_INFO)
 	appu.AppFramework.Send_Monitor_Message([]byte(appu.App_UID + " - Stopping the AUBase....."))
 
-	if !appu.Is_Started {		
+	/// units which set Is_Started by themselves without calling Start
+	if appu.legacy_started() {
+		appu.state.Store(int32(atypes.UNIT_RUNNING))
+	}
+
+	if _err := appu.transition(atypes.UNIT_STOPPING, nil); _err != nil {		
 		appu.AppFramework.Write2Log(appu.App_UID + " - Failed to Stopping the AUBase. Instance process is not started", atypes.LOG_INFO)
 		appu.AppFramework.Send_Monitor_Message([]byte(appu.App_UID + " - Stopping the AUBase. Instance process is not started"))
-		return false, fmt.Errorf("instance process is not started")
+		return false, fmt.Errorf("%w. %w", aerrors.ErrNotStarted, _err)
 	}
 
+	appu.cancel_context()
+	appu.close_subscriptions()
+
 	if appu.Stopper != nil {
 		appu.Write2Log(appu.App_UID + " - Closing the AUBase Stopper chan .....", atypes.LOG_INFO)
 		close(appu.Stopper)
 		appu.Write2Log(appu.App_UID + " - Closing the AUBase Stopper chan ........DONE", atypes.LOG_INFO)
 	}
 
+	if _pending := appu.wait_routines(pCtx); len(_pending) > 0 {
+		_err := fmt.Errorf("routines did not exit : %s. %w", strings.Join(_pending, ", "), aerrors.ErrStopTimeout)
+		appu.AppFramework.Write2Log(appu.App_UID + " - Stopping the AUBase. Routines did not exit : " + strings.Join(_pending, ", "), atypes.LOG_WARN)
+		appu.transition(atypes.UNIT_FAILED, _err)
+		return false, _err
+	}
+
 	appu.AppFramework.Write2Log(appu.App_UID + " - Stopping the AUBase..... DONE", atypes.LOG_INFO)
 	appu.AppFramework.Send_Monitor_Message([]byte(appu.App_UID + " - Stopping the AUBase.....DONE"))
-	appu.Is_Started = false
+	appu.transition(atypes.UNIT_STOPPED, nil)
 	
 	return true, nil
 }
 
 // Add_Routine increment the routine total routine count of the AppFramework
 // and the current application unit
+//
+// Deprecated: use Go, which tracks the routine and keeps the counts paired.
 func (appu *AUBase) Add_Routine() {
-	
-
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
-	appu.Unit_Info.Routines++
-	
-	go func ()  {
-		defer recover()
-		appu.AppFramework.Add_Routine()
-		///appu.AppFramework.Set_Unitinfo(&appu.ID,appu.Unit_Info)
-	
-	}()
-	
+	appu.routine_cnt.Add(1)
+	appu.forward(iappfm.IAgniApp.Add_Routine)
 }
 
 // Remove_Routine decrement the total routine count of the AppFramework
-// and the current application unit
+// and the current application unit.
+// Unpaired calls are ignored so the count does not underflow.
+//
+// Deprecated: use Go, which tracks the routine and keeps the counts paired.
 func (appu *AUBase) Remove_Routine() {
-	
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
-	appu.Unit_Info.Routines--
-	
-	go func(){
-		defer recover()
-		appu.AppFramework.Remove_Routine()
-	}()
-	
-
+	if decrement(&appu.routine_cnt) {
+		appu.forward(iappfm.IAgniApp.Remove_Routine)
+	}
 }
 
 // Add_Request_Handled_Count increment the total requests handled of the AppFramework
 // and the current application unit
 func (appu *AUBase) Add_Request_Handled_Count() {
-	
-	
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
-	appu.Unit_Info.Req_Handled++
-	
-	go func(){
-		defer recover()
-		appu.AppFramework.Add_Request_HandleCount()
-	}()
-
+	appu.req_handled.Add(1)
+	if !appu.fm_sums {
+		appu.forward(iappfm.IAgniApp.Add_Request_HandleCount)
+	}
 }
 
 // Add_Request_Failed_Count increment the total failed requests of the AppFramework
 // and the current application unit
 func (appu *AUBase) Add_Request_Failed_Count() {
-	
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
-	appu.Unit_Info.Req_Failed++
-	
-	
-	go func ()  {
-		defer recover()
-		appu.AppFramework.Add_Request_Failed_Count()
-	}()
+	appu.req_failed.Add(1)
+	if !appu.fm_sums {
+		appu.forward(iappfm.IAgniApp.Add_Request_Failed_Count)
+	}
 }
 
 // IsInitialized returns the initialize status of the application unit
 func (appu *AUBase) IsInitialized() bool {
-	return appu.Is_Initialized
+	return appu.State() != atypes.UNIT_CREATED
 }
 
 /// Returns the ZAU instance ID
 func (appu *AUBase) Get_ID() (instance_ID int) {
-	if !appu.Is_Initialized {
+	if !appu.IsInitialized() {
 		fmt.Println("Not Initialized.")
 		return 0
 	}
@@ -233,7 +446,7 @@ func (appu *AUBase) Get_ID() (instance_ID int) {
 
 /// Returns the Application PID
 func (appu *AUBase) Get_PID() ( int) {
-	if !appu.Is_Initialized {
+	if !appu.IsInitialized() {
 		fmt.Println("Not Initialized.")
 		return 0
 	}
@@ -243,7 +456,7 @@ func (appu *AUBase) Get_PID() ( int) {
 
 /// Returns the Application PID
 func (appu *AUBase) Get_AUID() ( string) {
-	if !appu.Is_Initialized {
+	if !appu.IsInitialized() {
 		fmt.Println("Not Initialized.")
 		return ""
 	}
@@ -254,31 +467,46 @@ func (appu *AUBase) Get_AUID() ( string) {
 
 // IsStarted returns the start status of the application unit
 func (appu *AUBase) IsStarted() bool {
-	return appu.Is_Started
+	return appu.State() == atypes.UNIT_RUNNING
 }
 
+// Status returns a copy of the status of the application unit, nil if the unit is not initialized
 func (appu *AUBase) Status() *atypes.AppUnitInfo {
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
+	_lock := appu.Info_Lock
+	if _lock == nil {
+		return nil
+	}
+	_lock.Lock()
+	defer _lock.Unlock()
+	if appu.Unit_Info == nil {
+		return nil
+	}
 	appu.Read_Memory_Usage()
-	return appu.Unit_Info
+	appu.Unit_Info.State = appu.State()
+	appu.fill_counters(appu.Unit_Info)
+	appu.Unit_Info.Msg_Dropped = appu.Dropped()
+	appu.Unit_Info.Operations = appu.operations()
+	appu.Unit_Info.Resources = appu.resources()
+	appu.Unit_Info.Bus = appu.bus_stats()
+	appu.Unit_Info.Breakers = appu.breaker_status()
+	_info := *appu.Unit_Info
+	return &_info
 }
 
 
 // Increase_Active_Count +1 total active processing message count of 
+//
+// Deprecated: use Track, which maintains the active count.
 func (appu *AUBase) Increase_Active_Count() {
-	
-		appu.Info_Lock.Lock()
-		defer appu.Info_Lock.Unlock()
-		appu.Unit_Info.Active++
+	appu.active.Add(1)
 }
 
-// Decrease_Active_Count -1 total active processing message count of 
+// Decrease_Active_Count -1 total active processing message count of.
+// Unpaired calls are ignored so the count does not underflow.
+//
+// Deprecated: use Track, which maintains the active count.
 func (appu *AUBase) Decrease_Active_Count() {
-	
-	appu.Info_Lock.Lock()
-	defer appu.Info_Lock.Unlock()
-	appu.Unit_Info.Active--
+	decrement(&appu.active)
 }
 
 
@@ -335,22 +563,43 @@ func (appu *AUBase) ConvertToInt32(pValue string) int32 {
 
 
 // ******** PLUGIN functions *********************/
-// Get_RESTClient returns the REST client plugin instance
+// Get_RESTClient returns the REST client plugin instance.
+// The returned client counts the request/response bodies as the unit traffic (Status().Resources) and calls the
+// interceptors of the unit (Register_HTTPInterceptor), Unwrap() returns the plugin instance of the framework.
+//
+//	Returns aerrors.ErrNilArgument if pType is nil
 func (appu *AUBase) Get_RESTClient(pType *string) (ihttp.IAHTTPClient, error) {
+	if pType == nil {
+		return nil, fmt.Errorf("client type %w", aerrors.ErrNilArgument)
+	}
 	if appu.AppFramework == nil {
-		return nil, errors.New("app instance is not initialized")
-	} else {
-		return appu.AppFramework.Get_RESTClient(pType)
+		return nil, fmt.Errorf("app %w", aerrors.ErrNotInitialized)
 	}
+	_client, _err := appu.AppFramework.Get_RESTClient(pType)
+	if _client == nil {
+		return _client, _err
+	}
+	appu.track_breaker(*pType, _client)
+	return &http_client{IAHTTPClient: _client, intercepted: aintercept.Wrap_HTTPClient(*pType, _client, appu.http_interceptors()), appu: appu}, _err
 }
 
-// Get_RESTClient returns the Web Socket client plugin instance
+// Get_WSClient returns the Web Socket client plugin instance.
+// The returned client counts the messages as the unit traffic (Status().Resources),
+// Unwrap() returns the plugin instance of the framework.
+//
+//	Returns aerrors.ErrNilArgument if pType is nil
 func (appu *AUBase) Get_WSClient(pType *string) (iawsclient.IAWSClient, error) {
+	if pType == nil {
+		return nil, fmt.Errorf("client type %w", aerrors.ErrNilArgument)
+	}
 	if appu.AppFramework == nil {
-		return nil, errors.New("app instance is not initialized")
-	} else {
-		return appu.AppFramework.Get_WSClient(pType)
+		return nil, fmt.Errorf("app %w", aerrors.ErrNotInitialized)
+	}
+	_client, _err := appu.AppFramework.Get_WSClient(pType)
+	if _client == nil {
+		return _client, _err
 	}
+	return &ws_client{IAWSClient: _client, appu: appu}, _err
 }
 
 
@@ -358,34 +607,63 @@ func (appu *AUBase) Get_WSClient(pType *string) (iawsclient.IAWSClient, error) {
 // Get_RESTClient returns the Logger plugin instance
 func (appu *AUBase) ExecuteandFetch(os_command *string) (string, error) {
 	if appu.AppFramework == nil {
-		return "", errors.New("app instance is not initialized")
+		return "", fmt.Errorf("app %w", aerrors.ErrNotInitialized)
 	} else {
 		return appu.AppFramework.Execute_Command(os_command)
 	}
 }
 
+// Send_Monitor_Message queues the given message for the framework monitoring.
+// Messages are delivered in the order they are queued, see Drop_Policy when the queue is full.
 func (appu *AUBase) Send_Monitor_Message(pMessage []byte) {
 	
-	go func ()  {
-		defer recover()
-		appu.AppFramework.Send_Monitor_Message(pMessage)
-	}()
+	/// take the framework when queued, Deinitialize clears it
+	_fm := appu.AppFramework
+	if _fm == nil {
+		return
+	}
+	appu.enqueue(delivery{fm: _fm, monitor: true, message: pMessage})
 }
 
+// discard_logger is returned by Logger when the unit is not initialized
+var discard_logger = slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 100}))
+
+// framework_logger returns the logger of the framework (iappfm.IAgniAppLogger),
+// a logger writing with Write2Log if the framework has none
+func framework_logger(pFM_Instance iappfm.IAgniApp) *slog.Logger {
+	if _fm, _ok := pFM_Instance.(iappfm.IAgniAppLogger); _ok {
+		return _fm.Logger()
+	}
+	return slog.New(iappfm.Write2Log_Handler(pFM_Instance))
+}
+
+// Logger returns the structured logger of the unit. Every entry carries the unit name (unit),
+// the instance ID (instance) and the AUID (auid) attributes and is filtered by the framework log level.
+//
+//	appu.Logger().Info("order received", "order_id", _id, "amount", _amount)
+//
+// Returns a logger which discards the entries if the unit is not initialized.
+func (appu *AUBase) Logger() *slog.Logger {
+	if appu.logger == nil {
+		return discard_logger
+	}
+	return appu.logger
+}
+
+// Write2Log queues the given entry for the framework log.
+// Entries are delivered in the order they are queued, see Drop_Policy when the queue is full.
 func (appu *AUBase) Write2Log(log_entry string, log_level atypes.LogLevel) {
-	go func ()  {
-		defer recover()
-		
-		appu.AppFramework.Write2Log(log_entry, log_level)
-	}()
+	_fm := appu.AppFramework
+	if _fm == nil {
+		return
+	}
+	appu.enqueue(delivery{fm: _fm, entry: log_entry, level: log_level})
 }
 
 
+// Read_Memory_Usage reads the memory usage into Unit_Info.Mem_Usage.
+// Units share the Go heap, so the values are the process memory usage (see aresource.Mem_Usage).
+// The resources attributed to the unit are in Unit_Info.Resources.
 func (appu *AUBase) Read_Memory_Usage() {
-	var _currentMem runtime.MemStats
-	runtime.ReadMemStats(&_currentMem)
-	
-	appu.Unit_Info.Mem_Usage.Heap=_currentMem.Alloc-appu.Unit_Info.Mem_Usage.Heap
-	appu.Unit_Info.Mem_Usage.HeapAlloc=_currentMem.HeapAlloc-appu.Unit_Info.Mem_Usage.HeapAlloc
-	appu.Unit_Info.Mem_Usage.Total=_currentMem.TotalAlloc-appu.Unit_Info.Mem_Usage.Total
+	appu.Unit_Info.Mem_Usage = aresource.Mem_Usage()
 }
@@ -0,0 +1,80 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slow_app framework which takes 5ms to write a log entry
+type slow_app struct {
+	*aautest.FakeApp
+}
+
+func (app *slow_app) Write2Log(pEntry string, pLog_Level atypes.LogLevel) {
+	time.Sleep(5 * time.Millisecond)
+	app.FakeApp.Write2Log(pEntry, pLog_Level)
+}
+
+// TestStopDeadline stops a unit whose routine does not exit, the stop is forced at the deadline
+func TestStopDeadline(t *testing.T) {
+	_tests := []struct {
+		name    string
+		timeout time.Duration
+	}{
+		{name: "deadline", timeout: 50 * time.Millisecond},
+		{name: "done", timeout: 0},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_app := &slow_app{FakeApp: aautest.New_FakeApp()}
+			_unit := &AUBase.AUBase{}
+			if _, _err := _unit.Initialize(_app, 1, "unit", "", ""); _err != nil {
+				t.Fatal(_err)
+			}
+			if _, _err := _unit.Start(); _err != nil {
+				t.Fatal(_err)
+			}
+			_release := make(chan struct{})
+			t.Cleanup(func() {
+				close(_release)
+				_unit.Deinitialize()
+			})
+			_unit.Go("stuck", func(ctx context.Context) error {
+				<-_release
+				return nil
+			})
+
+			_ctx, _cancel := context.WithTimeout(context.Background(), _test.timeout)
+			defer _cancel()
+			_started := time.Now()
+			_ok, _err := _unit.StopContext(_ctx)
+			_elapsed := time.Since(_started)
+
+			if _ok || !errors.Is(_err, aerrors.ErrStopTimeout) || !strings.Contains(_err.Error(), "stuck") {
+				t.Errorf("StopContext() = %v, %v, want aerrors.ErrStopTimeout naming the routine stuck", _ok, _err)
+			}
+			if _elapsed < _test.timeout || _elapsed > _test.timeout+AUBase.FORCED_FLUSH_TIMEOUT+time.Second {
+				t.Errorf("StopContext() returned after %v, want the deadline %v", _elapsed, _test.timeout)
+			}
+			if _unit.State() != atypes.UNIT_FAILED || _unit.IsStarted() {
+				t.Errorf("state %v, started %v after the forced stop, want Failed", _unit.State(), _unit.IsStarted())
+			}
+			if _names := _unit.Routines_List(); len(_names) != 1 || _names[0] != "stuck" {
+				t.Errorf("Routines_List() = %v, want [stuck]", _names)
+			}
+
+			/// the queued state change messages are delivered before StopContext returns
+			if len(_app.Logs_Containing("Stopping -> Failed")) != 1 || len(_app.Logs_Containing("Running -> Stopping")) != 1 {
+				t.Errorf("logs after the forced stop %v, want the state changes", _app.Logs())
+			}
+		})
+	}
+}
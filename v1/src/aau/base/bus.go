@@ -0,0 +1,214 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// message_bus returns the message bus of the framework.
+//
+//	Returns aerrors.ErrNotInitialized if the unit is not initialized,
+//	aerrors.ErrNotSupported if the framework has no message bus (iappfm.IAgniAppBus)
+func (appu *AUBase) message_bus() (*abus.Bus, error) {
+	if appu.bus == nil {
+		if appu.AppFramework != nil {
+			return nil, fmt.Errorf("%s message bus of the framework %w", appu.Unit_Name, aerrors.ErrNotSupported)
+		}
+		return nil, fmt.Errorf("%s message bus. %w", appu.Unit_Name, aerrors.ErrNotInitialized)
+	}
+	return appu.bus, nil
+}
+
+// framework_bus returns the message bus of the framework (iappfm.IAgniAppBus), nil if it has none
+func framework_bus(pFM_Instance iappfm.IAgniApp) *abus.Bus {
+	if _fm, _ok := pFM_Instance.(iappfm.IAgniAppBus); _ok {
+		return _fm.Bus()
+	}
+	return nil
+}
+
+// bus_source returns the source of the envelopes of the unit, unit#instance
+func (appu *AUBase) bus_source() string {
+	return appu.Unit_Name + "#" + strconv.Itoa(appu.ID)
+}
+
+// new_envelope returns the envelope of the payload with the traceparent of the span of pCtx
+func (appu *AUBase) new_envelope(pCtx context.Context, pTopic string, pPayload any) *abus.Envelope {
+	_msg := &abus.Envelope{
+		Topic:   pTopic,
+		Source:  appu.bus_source(),
+		Headers: make(map[string]string),
+		Payload: pPayload,
+	}
+	atrace.Inject(pCtx, _msg.Headers)
+	return _msg
+}
+
+// Publish publishes the payload on the topic of the message bus of the framework (an event).
+// The publish span is the child of the span of pCtx (the unit context if nil), its traceparent
+// is sent with the envelope so the spans of the subscribers continue the trace.
+//
+//	Returns aerrors.ErrInvalidArgument if the topic is not valid, aerrors.ErrBusClosed if the bus is closed
+func (appu *AUBase) Publish(pCtx context.Context, pTopic string, pPayload any) error {
+
+	_bus, _err := appu.message_bus()
+	if _err != nil {
+		return _err
+	}
+	_ctx, _span := appu.start_span(pCtx, "publish "+pTopic, atrace.KIND_PRODUCER)
+	defer _span.End()
+
+	_msg := appu.new_envelope(_ctx, pTopic, pPayload)
+	_span.Set_Attribute("bus.topic", pTopic)
+	_err = _bus.Publish(_msg)
+	_span.Set_Attribute("bus.message_id", _msg.ID)
+	_span.Record_Error(_err)
+	if _err == nil {
+		appu.bus_published.Add(1)
+	}
+	return _err
+}
+
+// Subscribe subscribes the handler to the topics matching the pattern ("orders.*", "orders.>").
+// The handler is called with a consumer span which continues the trace of the publisher, an error fails the envelope.
+// The subscriptions of the unit are closed when the unit is stopped.
+//
+//	Returns aerrors.ErrInvalidArgument if the pattern is not valid, aerrors.ErrBusClosed if the bus is closed
+func (appu *AUBase) Subscribe(pPattern string, pOptions abus.Sub_Options, pHandler abus.Handler) (*abus.Subscription, error) {
+
+	_bus, _err := appu.message_bus()
+	if _err != nil {
+		return nil, _err
+	}
+	if pHandler == nil {
+		return nil, fmt.Errorf("handler %w", aerrors.ErrNilArgument)
+	}
+
+	_on_drop := pOptions.On_Drop
+	pOptions.On_Drop = func(pMsg *abus.Envelope) {
+		appu.bus_dropped.Add(1)
+		if _on_drop != nil {
+			_on_drop(pMsg)
+		}
+	}
+
+	_sub, _err := _bus.Subscribe(pPattern, pOptions, func(pCtx context.Context, pMsg *abus.Envelope) error {
+		_ctx, _span := appu.start_span(atrace.Extract(pCtx, pMsg.Headers), "receive "+pMsg.Topic, atrace.KIND_CONSUMER)
+		defer _span.End()
+		_span.Set_Attribute("bus.topic", pMsg.Topic)
+		_span.Set_Attribute("bus.message_id", pMsg.ID)
+		_span.Set_Attribute("bus.source", pMsg.Source)
+
+		_err := pHandler(_ctx, pMsg)
+		_span.Record_Error(_err)
+		if _err != nil {
+			appu.bus_failed.Add(1)
+		} else {
+			appu.bus_received.Add(1)
+		}
+		return _err
+	})
+	if _err != nil {
+		return nil, _err
+	}
+
+	appu.subs_lock.Lock()
+	appu.subs = append(appu.subs, _sub)
+	appu.subs_lock.Unlock()
+	return _sub, nil
+}
+
+// Request publishes the payload on the topic and waits for the first reply, abus.DEFAULT_REQUEST_TIMEOUT
+// if pCtx (the unit context if nil) has no deadline.
+//
+//	Returns the reply. Unless returns aerrors.ErrNoResponders if no subscription matches the topic,
+//	or the error of pCtx when no reply is received in time
+func (appu *AUBase) Request(pCtx context.Context, pTopic string, pPayload any) (*abus.Envelope, error) {
+
+	_bus, _err := appu.message_bus()
+	if _err != nil {
+		return nil, _err
+	}
+	_ctx, _span := appu.start_span(pCtx, "request "+pTopic, atrace.KIND_CLIENT)
+	defer _span.End()
+
+	_msg := appu.new_envelope(_ctx, pTopic, pPayload)
+	_span.Set_Attribute("bus.topic", pTopic)
+	appu.bus_requests.Add(1)
+	_reply, _err := _bus.Request(_ctx, _msg)
+	_span.Set_Attribute("bus.message_id", _msg.ID)
+	_span.Record_Error(_err)
+	if _msg.Reply_To != "" {
+		/// the request was published
+		appu.bus_published.Add(1)
+	}
+	return _reply, _err
+}
+
+// Reply publishes the payload as the reply of the request received by a subscription of the unit.
+//
+//	Returns aerrors.ErrInvalidArgument if the envelope is not a request
+func (appu *AUBase) Reply(pCtx context.Context, pRequest *abus.Envelope, pPayload any) error {
+
+	_bus, _err := appu.message_bus()
+	if _err != nil {
+		return _err
+	}
+	if pRequest == nil {
+		return fmt.Errorf("request %w", aerrors.ErrNilArgument)
+	}
+	if pCtx == nil {
+		pCtx = appu.Context()
+	}
+
+	_err = _bus.Reply(pRequest, appu.new_envelope(pCtx, pRequest.Reply_To, pPayload))
+	if _err == nil {
+		appu.bus_published.Add(1)
+	}
+	return _err
+}
+
+// close_subscriptions closes the subscriptions of the unit
+func (appu *AUBase) close_subscriptions() {
+	appu.subs_lock.Lock()
+	_subs := appu.subs
+	appu.subs = nil
+	appu.subs_lock.Unlock()
+
+	for _, _sub := range _subs {
+		_sub.Unsubscribe()
+	}
+}
+
+// reset_bus sets the message bus of the unit and clears its traffic counts
+func (appu *AUBase) reset_bus(pBus *abus.Bus) {
+	appu.close_subscriptions()
+	appu.bus = pBus
+	appu.bus_published.Store(0)
+	appu.bus_received.Store(0)
+	appu.bus_failed.Store(0)
+	appu.bus_dropped.Store(0)
+	appu.bus_requests.Store(0)
+}
+
+// bus_stats returns the traffic of the unit on the message bus
+func (appu *AUBase) bus_stats() atypes.BusStats {
+	appu.subs_lock.Lock()
+	_subs := len(appu.subs)
+	appu.subs_lock.Unlock()
+	return atypes.BusStats{
+		Published:     appu.bus_published.Load(),
+		Received:      appu.bus_received.Load(),
+		Failed:        appu.bus_failed.Load(),
+		Dropped:       appu.bus_dropped.Load(),
+		Requests:      appu.bus_requests.Load(),
+		Subscriptions: _subs,
+	}
+}
@@ -0,0 +1,30 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	build "github.com/agnione/libs/v1/src/lib"
+
+	"testing"
+)
+
+// conformance_unit the smallest unit built on AUBase
+type conformance_unit struct {
+	AUBase.AUBase
+}
+
+func (u *conformance_unit) New() interface{} {
+	return &conformance_unit{}
+}
+
+func (u *conformance_unit) GetID() int {
+	return u.ID
+}
+
+func (u *conformance_unit) Info() build.BuildInfo {
+	return build.BuildInfo{Version: "test"}
+}
+
+func TestConformance(t *testing.T) {
+	aautest.Run_Conformance(t, &conformance_unit{})
+}
@@ -0,0 +1,63 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"sync/atomic"
+)
+
+// Counters returns the request, routine and active counts of the unit without locking (iappunit.IAppUnitCounters)
+func (appu *AUBase) Counters() atypes.UnitCounters {
+	return atypes.UnitCounters{
+		Req_Handled: appu.req_handled.Load(),
+		Req_Failed:  appu.req_failed.Load(),
+		Routines:    appu.routine_cnt.Load(),
+		Active:      appu.active.Load(),
+	}
+}
+
+// reset_counters clears the counters and checks whether the given framework sums them at read time
+func (appu *AUBase) reset_counters(pFM iappfm.IAgniApp) {
+	appu.req_handled.Store(0)
+	appu.req_failed.Store(0)
+	appu.routine_cnt.Store(0)
+	appu.active.Store(0)
+
+	_sums, _ok := pFM.(iappfm.IAgniAppCounters)
+	appu.fm_sums = _ok && _sums.Sums_Unit_Counters()
+}
+
+// fill_counters copies the counters into the given unit information
+func (appu *AUBase) fill_counters(pInfo *atypes.AppUnitInfo) {
+	_counters := appu.Counters()
+	pInfo.Req_Handled = _counters.Req_Handled
+	pInfo.Req_Failed = _counters.Req_Failed
+	pInfo.Routines = _counters.Routines
+	pInfo.Active = _counters.Active
+}
+
+// forward calls the given framework function in the calling routine. A panic of the framework is ignored.
+func (appu *AUBase) forward(pCall func(iappfm.IAgniApp)) {
+	_fm := appu.AppFramework
+	if _fm == nil {
+		return
+	}
+	defer func() { recover() }()
+	pCall(_fm)
+}
+
+// decrement subtracts 1 from the given counter unless it is 0.
+//
+//	Returns false if the counter was 0
+func decrement(pCounter *atomic.Int64) bool {
+	for {
+		_current := pCounter.Load()
+		if _current <= 0 {
+			return false
+		}
+		if pCounter.CompareAndSwap(_current, _current-1) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// BenchmarkCounters measures the request counters of AUBase under concurrent load (b.RunParallel),
+// compare the sub benchmarks with go test -bench Counters -cpu 1,4,16.
+//
+// "mutex+routine" is the previous design (Info_Lock and a routine per increment forwarding the count),
+// "atomic+forward" the atomic counters with a framework which counts every forwarded request and
+// "atomic" the atomic counters with a framework which sums the unit counters at read time (agniapp).
+func BenchmarkCounters(b *testing.B) {
+
+	b.Run("mutex+routine", func(b *testing.B) {
+		_app := &bench_app{FakeApp: aautest.New_FakeApp()}
+		_counters := &locked_counters{}
+		b.ReportAllocs()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				_counters.add(_app)
+			}
+		})
+		b.StopTimer()
+		_counters.wg.Wait()
+	})
+
+	b.Run("atomic+forward", func(b *testing.B) {
+		bench_unit(b, &bench_app{FakeApp: aautest.New_FakeApp()})
+	})
+
+	b.Run("atomic", func(b *testing.B) {
+		bench_unit(b, &bench_app{FakeApp: aautest.New_FakeApp(), sums: true})
+	})
+}
+
+// bench_unit runs Add_Request_Handled_Count of an AUBase initialized with the given framework
+func bench_unit(b *testing.B, pApp *bench_app) {
+
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(pApp, 1, "bench", pApp.AppPath, ""); _err != nil {
+		b.Fatal(_err)
+	}
+	defer _unit.Deinitialize()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_unit.Add_Request_Handled_Count()
+		}
+	})
+	b.StopTimer()
+
+	if _count := _unit.Counters().Req_Handled + pApp.handled.Load(); _count < uint64(b.N) {
+		b.Fatalf("counted %d requests of %d", _count, b.N)
+	}
+}
+
+// bench_app framework of the benchmarks, counts the forwarded requests without recording the calls
+type bench_app struct {
+	*aautest.FakeApp
+	sums    bool
+	handled atomic.Uint64
+}
+
+func (app *bench_app) Add_Request_HandleCount() {
+	app.handled.Add(1)
+}
+
+func (app *bench_app) Sums_Unit_Counters() bool {
+	return app.sums
+}
+
+// locked_counters the counters of AUBase before the atomic counters, kept for the comparison
+type locked_counters struct {
+	lock    sync.Mutex
+	handled uint32
+	wg      sync.WaitGroup
+}
+
+func (c *locked_counters) add(pApp *bench_app) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handled++
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() { recover() }()
+		pApp.Add_Request_HandleCount()
+	}()
+}
@@ -0,0 +1,37 @@
+package AUBase
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/ahealth"
+
+	"context"
+)
+
+// Check_Health reports the health of the unit from its state (ahealth.HealthChecker): the unit is live
+// unless it failed and ready while it is running. Units override it to check their dependencies:
+//
+//	func (u *Unit) Check_Health(ctx context.Context) ahealth.Result {
+//		_result := u.AUBase.Check_Health(ctx)
+//		if _ok, _err := u.ws.IsConnected(); !_ok {
+//			_result.Ready, _result.Error = false, _err.Error()
+//		}
+//		return _result
+//	}
+func (appu *AUBase) Check_Health(pCtx context.Context) ahealth.Result {
+
+	_state := appu.State()
+	_result := ahealth.Result{
+		Live:  _state != atypes.UNIT_FAILED,
+		Ready: _state == atypes.UNIT_RUNNING,
+		Details: map[string]any{
+			"state":    _state.String(),
+			"active":   appu.active.Load(),
+			"routines": appu.routine_cnt.Load(),
+			"dropped":  appu.Dropped(),
+		},
+	}
+	if !_result.Ready {
+		_result.Error = "unit is " + _state.String()
+	}
+	return _result
+}
@@ -0,0 +1,94 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+
+	"fmt"
+)
+
+// Metric_Labels returns the labels added to the metrics of the unit (app_id, unit and instance)
+func (appu *AUBase) Metric_Labels() ametrics.Labels {
+	_app_id := ""
+	if _fm := appu.AppFramework; _fm != nil {
+		_app_id = _fm.ID()
+	}
+	return ametrics.Unit_Labels(_app_id, appu.Unit_Name, appu.ID)
+}
+
+// Counter returns the counter of the unit with the given name, registered in the framework metrics registry.
+// The unit labels (Metric_Labels) are added to the given labels.
+//
+//	Returns error if the unit is not initialized or the metric is not valid,
+//	aerrors.ErrNotSupported if the framework has no metrics registry (iappfm.IAgniAppMetrics)
+func (appu *AUBase) Counter(pName string, pHelp string, pLabels ametrics.Labels) (*ametrics.Counter, error) {
+	_registry, _labels, _err := appu.metric_registry(pLabels)
+	if _err != nil {
+		return nil, _err
+	}
+	return _registry.Counter(pName, pHelp, _labels)
+}
+
+// Gauge returns the gauge of the unit with the given name, registered in the framework metrics registry.
+// The unit labels (Metric_Labels) are added to the given labels.
+//
+//	Returns error if the unit is not initialized or the metric is not valid,
+//	aerrors.ErrNotSupported if the framework has no metrics registry (iappfm.IAgniAppMetrics)
+func (appu *AUBase) Gauge(pName string, pHelp string, pLabels ametrics.Labels) (*ametrics.Gauge, error) {
+	_registry, _labels, _err := appu.metric_registry(pLabels)
+	if _err != nil {
+		return nil, _err
+	}
+	return _registry.Gauge(pName, pHelp, _labels)
+}
+
+// Histogram returns the histogram of the unit with the given name, registered in the framework metrics registry.
+// The unit labels (Metric_Labels) are added to the given labels, ametrics.DEFAULT_BUCKETS are used when pBuckets is empty.
+//
+//	Returns error if the unit is not initialized or the metric is not valid,
+//	aerrors.ErrNotSupported if the framework has no metrics registry (iappfm.IAgniAppMetrics)
+func (appu *AUBase) Histogram(pName string, pHelp string, pBuckets []float64, pLabels ametrics.Labels) (*ametrics.Histogram, error) {
+	_registry, _labels, _err := appu.metric_registry(pLabels)
+	if _err != nil {
+		return nil, _err
+	}
+	return _registry.Histogram(pName, pHelp, pBuckets, _labels)
+}
+
+// metric_registry returns the framework metrics registry and the given labels with the unit labels
+func (appu *AUBase) metric_registry(pLabels ametrics.Labels) (*ametrics.Registry, ametrics.Labels, error) {
+
+	_fm := appu.AppFramework
+	if _fm == nil {
+		return nil, nil, fmt.Errorf("%d Failed to register the metric. %s %w", appu.ID, appu.Unit_Name, aerrors.ErrNotInitialized)
+	}
+	_registry := framework_metrics(_fm)
+	if _registry == nil {
+		return nil, nil, fmt.Errorf("metrics registry of the framework %w", aerrors.ErrNotSupported)
+	}
+
+	_labels := make(ametrics.Labels, len(pLabels)+3)
+	for _name, _value := range pLabels {
+		_labels[_name] = _value
+	}
+	for _name, _value := range appu.Metric_Labels() {
+		_labels[_name] = _value
+	}
+	return _registry, _labels, nil
+}
+
+// unregister_metrics removes the metrics of the unit from the framework metrics registry
+func (appu *AUBase) unregister_metrics() {
+	if _registry := framework_metrics(appu.AppFramework); _registry != nil {
+		_registry.Unregister(appu.Metric_Labels())
+	}
+}
+
+// framework_metrics returns the metrics registry of the framework (iappfm.IAgniAppMetrics), nil if it has none
+func framework_metrics(pFM_Instance iappfm.IAgniApp) *ametrics.Registry {
+	if _fm, _ok := pFM_Instance.(iappfm.IAgniAppMetrics); _ok {
+		return _fm.Metrics()
+	}
+	return nil
+}
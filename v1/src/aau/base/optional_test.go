@@ -0,0 +1,48 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestPlainFramework runs a unit with a framework which implements none of the optional interfaces
+func TestPlainFramework(t *testing.T) {
+
+	_app := aautest.New_FakeApp()
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(aautest.Plain(_app), 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+	defer _unit.Deinitialize()
+
+	_unit.Logger().Warn("order received", "order_id", 42)
+	_logs := _app.Logs_Containing("order received")
+	if len(_logs) != 1 {
+		t.Fatalf("Logger() entries written with Write2Log = %v, want 1", _logs)
+	}
+	if _logs[0].Level != atypes.LOG_WARN || !strings.Contains(_logs[0].Entry, "order_id=42") ||
+		!strings.Contains(_logs[0].Entry, "unit=unit") {
+		t.Errorf("Logger() entry = %+v", _logs[0])
+	}
+
+	if _, _err := _unit.Counter("orders_total", "orders", nil); !errors.Is(_err, aerrors.ErrNotSupported) {
+		t.Errorf("Counter() without the metrics of the framework = %v, want aerrors.ErrNotSupported", _err)
+	}
+
+	_, _span := _unit.Start_Span(context.Background(), "create_order")
+	if _span != nil {
+		t.Errorf("Start_Span() without the tracer of the framework = %v, want nil", _span)
+	}
+	_span.End()
+
+	if _err := _unit.Publish(context.Background(), "orders.created", 42); !errors.Is(_err, aerrors.ErrNotSupported) {
+		t.Errorf("Publish() without the bus of the framework = %v, want aerrors.ErrNotSupported", _err)
+	}
+}
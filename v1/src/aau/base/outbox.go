@@ -0,0 +1,222 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DropPolicy decides what Write2Log and Send_Monitor_Message do when the delivery queue of the unit is full
+type DropPolicy int32
+
+// drop policies of the delivery queue
+const (
+	DROP_OLDEST DropPolicy = 0 // default, the oldest queued entry is dropped to make room
+	DROP_NEWEST DropPolicy = 1 // the new entry is dropped
+	DROP_BLOCK  DropPolicy = 2 // the caller waits until there is room
+)
+
+// DEFAULT_QUEUE_SIZE number of log entries and monitoring messages queued by a unit when Queue_Size is not set
+const DEFAULT_QUEUE_SIZE = 1024
+
+// FORCED_FLUSH_TIMEOUT time the queued entries get to be delivered when a stop is forced (its deadline is done)
+const FORCED_FLUSH_TIMEOUT = 100 * time.Millisecond
+
+// String returns the name of the drop policy
+func (p DropPolicy) String() string {
+	switch p {
+	case DROP_OLDEST:
+		return "drop-oldest"
+	case DROP_NEWEST:
+		return "drop-newest"
+	case DROP_BLOCK:
+		return "block"
+	}
+	return "unknown"
+}
+
+// delivery holds a queued log entry or monitoring message
+type delivery struct {
+	fm      iappfm.IAgniApp
+	monitor bool
+	entry   string
+	level   atypes.LogLevel
+	message []byte
+}
+
+// outbox bounded queue of the unit. One routine delivers the entries to the framework in the queued order.
+type outbox struct {
+	lock   sync.Mutex
+	cond   *sync.Cond
+	items  []delivery /// ring buffer
+	head   int
+	count  int
+	policy DropPolicy
+	closed bool
+	busy   bool /// an entry is being delivered
+
+	dropped *atomic.Uint64 /// drop counter of the unit
+}
+
+// new_outbox creates the queue and starts the delivery routine
+func new_outbox(pSize int, pPolicy DropPolicy, pDropped *atomic.Uint64) *outbox {
+	if pSize <= 0 {
+		pSize = DEFAULT_QUEUE_SIZE
+	}
+	_box := &outbox{items: make([]delivery, pSize), policy: pPolicy, dropped: pDropped}
+	_box.cond = sync.NewCond(&_box.lock)
+	go _box.drain()
+	return _box
+}
+
+// push queues the entry according to the drop policy
+func (b *outbox) push(pItem delivery) {
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for !b.closed && b.count == len(b.items) {
+		switch b.policy {
+		case DROP_NEWEST:
+			b.dropped.Add(1)
+			return
+		case DROP_BLOCK:
+			b.cond.Wait()
+		default:
+			b.items[b.head] = delivery{}
+			b.head = (b.head + 1) % len(b.items)
+			b.count--
+			b.dropped.Add(1)
+		}
+	}
+	if b.closed {
+		b.dropped.Add(1)
+		return
+	}
+
+	b.items[(b.head+b.count)%len(b.items)] = pItem
+	b.count++
+	b.cond.Broadcast()
+}
+
+// drain delivers the queued entries until the queue is closed and empty
+func (b *outbox) drain() {
+	for {
+		b.lock.Lock()
+		for b.count == 0 && !b.closed {
+			b.cond.Wait()
+		}
+		if b.count == 0 {
+			b.lock.Unlock()
+			return
+		}
+		_item := b.items[b.head]
+		b.items[b.head] = delivery{}
+		b.head = (b.head + 1) % len(b.items)
+		b.count--
+		b.busy = true
+		b.cond.Broadcast()
+		b.lock.Unlock()
+
+		_item.deliver()
+
+		b.lock.Lock()
+		b.busy = false
+		b.cond.Broadcast()
+		b.lock.Unlock()
+	}
+}
+
+// deliver passes the entry to the framework
+func (d *delivery) deliver() {
+	defer func() { recover() }()
+	if d.monitor {
+		d.fm.Send_Monitor_Message(d.message)
+	} else {
+		d.fm.Write2Log(d.entry, d.level)
+	}
+}
+
+// flush waits until the queued entries are delivered or the given context is done
+func (b *outbox) flush(pCtx context.Context) error {
+
+	_stop := context.AfterFunc(pCtx, func() {
+		b.lock.Lock()
+		b.cond.Broadcast()
+		b.lock.Unlock()
+	})
+	defer _stop()
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for b.count > 0 || b.busy {
+		if _err := pCtx.Err(); _err != nil {
+			return _err
+		}
+		b.cond.Wait()
+	}
+	return nil
+}
+
+// close stops the delivery routine once the queued entries are delivered. Entries pushed later are dropped.
+func (b *outbox) close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	b.cond.Broadcast()
+}
+
+// open_outbox creates the delivery queue of the unit, the previous queue (if any) is closed
+func (appu *AUBase) open_outbox() {
+	if _old := appu.outbox.Swap(new_outbox(appu.Queue_Size, appu.Drop_Policy, &appu.dropped)); _old != nil {
+		_old.close()
+	}
+}
+
+// enqueue queues the entry for the delivery, entries are dropped if the unit is not initialized
+func (appu *AUBase) enqueue(pItem delivery) {
+	if _box := appu.outbox.Load(); _box != nil {
+		_box.push(pItem)
+	}
+}
+
+// Flush waits until the queued log entries and monitoring messages are delivered to the framework.
+// Same as FlushContext with a deadline of atypes.DEFAULT_STOP_TIMEOUT seconds
+func (appu *AUBase) Flush() error {
+	_ctx, _cancel := context.WithTimeout(context.Background(), atypes.DEFAULT_STOP_TIMEOUT*time.Second)
+	defer _cancel()
+	return appu.FlushContext(_ctx)
+}
+
+// FlushContext waits until the queued log entries and monitoring messages are delivered to the framework.
+//
+//	Returns the context error if it is done before the queue is empty.
+func (appu *AUBase) FlushContext(pCtx context.Context) error {
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	if _box := appu.outbox.Load(); _box != nil {
+		return _box.flush(pCtx)
+	}
+	return nil
+}
+
+// flush_stopped delivers the queued entries at the end of StopContext. The entries of a forced stop get
+// FORCED_FLUSH_TIMEOUT once the deadline is done, so the shutdown messages are not lost with the stop.
+func (appu *AUBase) flush_stopped(pCtx context.Context) {
+	if pCtx != nil && pCtx.Err() == nil && appu.FlushContext(pCtx) == nil {
+		return
+	}
+	_ctx, _cancel := context.WithTimeout(context.Background(), FORCED_FLUSH_TIMEOUT)
+	defer _cancel()
+	appu.FlushContext(_ctx)
+}
+
+// Dropped returns the number of log entries and monitoring messages dropped because the queue was full
+func (appu *AUBase) Dropped() uint64 {
+	return appu.dropped.Load()
+}
@@ -0,0 +1,144 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// gate_app framework whose delivery of the "1" log entry waits until it is released
+type gate_app struct {
+	*aautest.FakeApp
+	started chan struct{}
+	release chan struct{}
+}
+
+func (app *gate_app) Write2Log(pEntry string, pLog_Level atypes.LogLevel) {
+	if pEntry == "1" {
+		close(app.started)
+		<-app.release
+	}
+	app.FakeApp.Write2Log(pEntry, pLog_Level)
+}
+
+// new_gate_unit initializes a unit with an empty delivery queue of the given size and policy on a gate_app
+func new_gate_unit(t *testing.T, pSize int, pPolicy AUBase.DropPolicy) (*AUBase.AUBase, *gate_app) {
+	_app := &gate_app{FakeApp: aautest.New_FakeApp(), started: make(chan struct{}), release: make(chan struct{})}
+	_unit := &AUBase.AUBase{Queue_Size: pSize, Drop_Policy: pPolicy}
+	if _, _err := _unit.Initialize(_app, 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+	t.Cleanup(_unit.Deinitialize)
+
+	/// the state change messages of Initialize are delivered before the test fills the queue
+	if _err := _unit.Flush(); _err != nil {
+		t.Fatal(_err)
+	}
+	return _unit, _app
+}
+
+// delivered returns the log entries and the monitoring messages of the test in the delivered order
+func delivered(pApp *aautest.FakeApp) []string {
+	var _delivered []string
+	for _, _call := range pApp.Calls() {
+		switch _call.Method {
+		case "Write2Log":
+			if _entry := _call.Args[0].(string); !strings.Contains(_entry, " ") {
+				_delivered = append(_delivered, _entry)
+			}
+		case "Send_Monitor_Message":
+			if _message := string(_call.Args[0].([]byte)); !strings.HasPrefix(_message, "{") {
+				_delivered = append(_delivered, "monitor "+_message)
+			}
+		}
+	}
+	return _delivered
+}
+
+func TestOutboxOrder(t *testing.T) {
+	_unit, _app := new_gate_unit(t, 0, AUBase.DROP_OLDEST)
+	close(_app.release)
+
+	_want := []string{"1", "monitor a", "2", "3", "monitor b", "4"}
+	for _, _item := range _want {
+		if _message, _ok := strings.CutPrefix(_item, "monitor "); _ok {
+			_unit.Send_Monitor_Message([]byte(_message))
+		} else {
+			_unit.Write2Log(_item, atypes.LOG_INFO)
+		}
+	}
+	if _err := _unit.Flush(); _err != nil {
+		t.Fatal(_err)
+	}
+	if _got := delivered(_app.FakeApp); !slices.Equal(_got, _want) {
+		t.Errorf("delivered %v, want %v", _got, _want)
+	}
+	if _unit.Dropped() != 0 {
+		t.Errorf("Dropped() = %d, want 0", _unit.Dropped())
+	}
+}
+
+func TestOutboxDropPolicies(t *testing.T) {
+	_tests := []struct {
+		policy    AUBase.DropPolicy
+		delivered []string
+		dropped   uint64
+	}{
+		{policy: AUBase.DROP_OLDEST, delivered: []string{"1", "3", "4"}, dropped: 1},
+		{policy: AUBase.DROP_NEWEST, delivered: []string{"1", "2", "3"}, dropped: 1},
+		{policy: AUBase.DROP_BLOCK, delivered: []string{"1", "2", "3", "4"}, dropped: 0},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.policy.String(), func(t *testing.T) {
+			_unit, _app := new_gate_unit(t, 2, _test.policy)
+
+			/// 1 is being delivered, 2 and 3 fill the queue, 4 does not fit
+			_unit.Write2Log("1", atypes.LOG_INFO)
+			<-_app.started
+			_unit.Write2Log("2", atypes.LOG_INFO)
+			_unit.Write2Log("3", atypes.LOG_INFO)
+
+			_queued := make(chan struct{})
+			go func() {
+				_unit.Write2Log("4", atypes.LOG_INFO)
+				close(_queued)
+			}()
+			select {
+			case <-_queued:
+				if _test.policy == AUBase.DROP_BLOCK {
+					t.Error("Write2Log() with a full queue returned, want it to wait for room")
+				}
+			case <-time.After(20 * time.Millisecond):
+				if _test.policy != AUBase.DROP_BLOCK {
+					t.Error("Write2Log() with a full queue waits for room, want the entry dropped")
+				}
+			}
+
+			/// the queue stays full until the delivery is released
+			_ctx, _cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer _cancel()
+			if _err := _unit.FlushContext(_ctx); !errors.Is(_err, context.DeadlineExceeded) {
+				t.Errorf("FlushContext() of a blocked delivery = %v, want context.DeadlineExceeded", _err)
+			}
+
+			close(_app.release)
+			<-_queued
+			if _err := _unit.Flush(); _err != nil {
+				t.Fatal(_err)
+			}
+			if _got := delivered(_app.FakeApp); !slices.Equal(_got, _test.delivered) {
+				t.Errorf("delivered %v, want %v", _got, _test.delivered)
+			}
+			if _unit.Dropped() != _test.dropped {
+				t.Errorf("Dropped() = %d, want %d", _unit.Dropped(), _test.dropped)
+			}
+		})
+	}
+}
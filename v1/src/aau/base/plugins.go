@@ -0,0 +1,331 @@
+package AUBase
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// http_client counts the request and response bodies of the http client plugin as the unit traffic,
+// records a client span of every request, injects the traceparent header and calls the interceptors of the unit
+type http_client struct {
+	ihttp.IAHTTPClient
+	intercepted ihttp.IAHTTPClient /// the plugin of the framework wrapped with the interceptors of the unit
+	appu        *AUBase
+	ctx         context.Context /// parent of the spans, the unit context if nil
+}
+
+// make sure http_client performs the requests of all the methods for every plugin
+var _ ihttp.IAHTTPClientDo = (*http_client)(nil)
+
+// Unwrap returns the http client plugin returned by the framework
+func (c *http_client) Unwrap() ihttp.IAHTTPClient {
+	return c.IAHTTPClient
+}
+
+func (c *http_client) with_context(pCtx context.Context) any {
+	return ihttp.IAHTTPClient(&http_client{IAHTTPClient: c.IAHTTPClient, intercepted: c.intercepted, appu: c.appu, ctx: pCtx})
+}
+
+// count counts the request and response bodies, the streamed bodies are counted as they are read
+func (c *http_client) count(pRequest *htypes.AHTTPRequest, pResponse *htypes.AHTTPResponse) {
+	if pRequest != nil && pRequest.Body_Reader == nil {
+		c.appu.Add_Bytes_Sent(len(pRequest.Body))
+	}
+	if pResponse == nil {
+		return
+	}
+	c.appu.Add_Bytes_Received(len(pResponse.Body))
+	if pResponse.Body_Stream != nil {
+		pResponse.Body_Stream = &count_reader{Reader: pResponse.Body_Stream, add: c.appu.Add_Bytes_Received}
+	}
+}
+
+// do performs the request with the given plugin function inside a client span, the child of the span of pCtx
+func (c *http_client) do(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest,
+	pCall func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) (*htypes.AHTTPResponse, error) {
+
+	_ctx, _span := c.appu.start_span(pCtx, "HTTP "+pMethod, atrace.KIND_CLIENT)
+	_span.Set_Attribute("http.request.method", pMethod)
+	if pRequest != nil {
+		_span.Set_Attribute("url.full", span_url(pRequest.URL))
+		if _span != nil {
+			if pRequest.Headers == nil {
+				pRequest.Headers = make(map[string]string)
+			}
+			atrace.Inject(_ctx, pRequest.Headers)
+		}
+		if _seeker, _ok := pRequest.Body_Reader.(io.ReadSeeker); _ok {
+			/// the body can still be rewound by the retries of the plugin
+			pRequest.Body_Reader = &count_read_seeker{count_reader: count_reader{Reader: _seeker, add: c.appu.Add_Bytes_Sent}, seeker: _seeker}
+		} else if pRequest.Body_Reader != nil {
+			pRequest.Body_Reader = &count_reader{Reader: pRequest.Body_Reader, add: c.appu.Add_Bytes_Sent}
+		}
+	}
+
+	_response, _err := pCall(_ctx, pRequest)
+	c.count(pRequest, _response)
+
+	if _response != nil {
+		_span.Set_Attribute("http.response.status_code", _response.StatusCode)
+	}
+	if _err != nil {
+		_span.Record_Error(_err)
+	} else if _response != nil && _response.StatusCode >= http.StatusBadRequest {
+		_span.Set_Status(atrace.STATUS_ERROR, "HTTP "+strconv.Itoa(_response.StatusCode))
+	}
+	_span.End()
+	return _response, _err
+}
+
+// request_context returns the context of the requests: the context bound with With_Context, the unit context
+// while the unit is running, unless a background context (the unit context is cancelled before the start)
+func (c *http_client) request_context() context.Context {
+	if c.ctx != nil {
+		return c.ctx
+	}
+	if c.appu.State() == atypes.UNIT_RUNNING {
+		return c.appu.Context()
+	}
+	return context.Background()
+}
+
+// method_call returns the call of do performing the request of the method with Do of the interceptors,
+// so the request and the retries of the framework (aresilience) are cancelled with the context of do
+func (c *http_client) method_call(pMethod string) func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return func(pCtx context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		if pRequest == nil {
+			return ihttp.Method_Func(c.intercepted, pMethod)(pRequest)
+		}
+		if pRequest.Method != pMethod {
+			_request := *pRequest
+			_request.Method = pMethod
+			pRequest = &_request
+		}
+		return ihttp.Do(pCtx, c.intercepted, pRequest)
+	}
+}
+
+func (c *http_client) Get(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodGet, pHTTP_Request, c.method_call(http.MethodGet))
+}
+
+func (c *http_client) Post(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodPost, pHTTP_Request, c.method_call(http.MethodPost))
+}
+
+func (c *http_client) Put(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodPut, pHTTP_Request, c.method_call(http.MethodPut))
+}
+
+func (c *http_client) Delete(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodDelete, pHTTP_Request, c.method_call(http.MethodDelete))
+}
+
+func (c *http_client) Patch(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodPatch, pHTTP_Request, c.method_call(http.MethodPatch))
+}
+
+func (c *http_client) Head(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodHead, pHTTP_Request, c.method_call(http.MethodHead))
+}
+
+func (c *http_client) Options(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.do(c.request_context(), http.MethodOptions, pHTTP_Request, c.method_call(http.MethodOptions))
+}
+
+// Do performs the request, the span is the child of the span of pCtx (the context of the client if nil)
+func (c *http_client) Do(pCtx context.Context, pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	if pCtx == nil {
+		pCtx = c.request_context()
+	}
+	_method := http.MethodGet
+	if pHTTP_Request != nil && pHTTP_Request.Method != "" {
+		_method = pHTTP_Request.Method
+	}
+	return c.do(pCtx, _method, pHTTP_Request, func(pCtx context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return ihttp.Do(pCtx, c.intercepted, pRequest)
+	})
+}
+
+// count_reader counts the bytes read from the body, Close closes the body if it is an io.Closer
+type count_reader struct {
+	io.Reader
+	add func(pBytes int)
+}
+
+func (r *count_reader) Read(pBuffer []byte) (int, error) {
+	_n, _err := r.Reader.Read(pBuffer)
+	r.add(_n)
+	return _n, _err
+}
+
+func (r *count_reader) Close() error {
+	if _closer, _ok := r.Reader.(io.Closer); _ok {
+		return _closer.Close()
+	}
+	return nil
+}
+
+// count_read_seeker count_reader of a body which can be rewound
+type count_read_seeker struct {
+	count_reader
+	seeker io.Seeker
+}
+
+func (r *count_read_seeker) Seek(pOffset int64, pWhence int) (int64, error) {
+	return r.seeker.Seek(pOffset, pWhence)
+}
+
+// breaker_reporter implemented by the http clients with a circuit breaker (aresilience.HTTP_Client)
+type breaker_reporter interface {
+	Breaker_Status() atypes.BreakerStatus
+}
+
+// track_breaker keeps the client of the type for the status of the unit if it, or a client it wraps, reports a
+// circuit breaker. Only the latest client of a type is reported.
+func (appu *AUBase) track_breaker(pType string, pClient ihttp.IAHTTPClient) {
+	_reporter, _ok := pClient.(breaker_reporter)
+	for !_ok {
+		_wrapper, _is_wrapper := pClient.(interface{ Unwrap() ihttp.IAHTTPClient })
+		if !_is_wrapper {
+			return
+		}
+		pClient = _wrapper.Unwrap()
+		_reporter, _ok = pClient.(breaker_reporter)
+	}
+	appu.breakers_lock.Lock()
+	defer appu.breakers_lock.Unlock()
+	if appu.breakers == nil {
+		appu.breakers = make(map[string]breaker_reporter)
+	}
+	appu.breakers[pType] = _reporter
+}
+
+// reset_breakers forgets the clients of the unit
+func (appu *AUBase) reset_breakers() {
+	appu.breakers_lock.Lock()
+	defer appu.breakers_lock.Unlock()
+	appu.breakers = nil
+}
+
+// breaker_status returns the status of the circuit breakers of the http clients of the unit, ordered by the type
+func (appu *AUBase) breaker_status() []atypes.BreakerStatus {
+	appu.breakers_lock.Lock()
+	defer appu.breakers_lock.Unlock()
+	if len(appu.breakers) == 0 {
+		return nil
+	}
+	_status := make([]atypes.BreakerStatus, 0, len(appu.breakers))
+	for _, _reporter := range appu.breakers {
+		_status = append(_status, _reporter.Breaker_Status())
+	}
+	sort.Slice(_status, func(i, j int) bool { return _status[i].Client < _status[j].Client })
+	return _status
+}
+
+// ws_client counts the messages of the web socket client plugin as the unit traffic,
+// records a client span of Connect and Write and injects the traceparent header into the handshake
+type ws_client struct {
+	iawsclient.IAWSClient
+	appu *AUBase
+	ctx  context.Context /// parent of the spans, the unit context if nil
+}
+
+// Unwrap returns the web socket client plugin returned by the framework
+func (c *ws_client) Unwrap() iawsclient.IAWSClient {
+	return c.IAWSClient
+}
+
+func (c *ws_client) with_context(pCtx context.Context) any {
+	return iawsclient.IAWSClient(&ws_client{IAWSClient: c.IAWSClient, appu: c.appu, ctx: pCtx})
+}
+
+func (c *ws_client) Connect(pWS_URL string, pRequest_Headers *map[string][]string, pSub_protocols *[]string, pCompression bool) (bool, int, error) {
+
+	_ctx, _span := c.appu.start_span(c.ctx, "WS CONNECT", atrace.KIND_CLIENT)
+	_span.Set_Attribute("url.full", span_url(pWS_URL))
+	if _span != nil {
+		if pRequest_Headers == nil {
+			pRequest_Headers = &map[string][]string{}
+		}
+		if *pRequest_Headers == nil {
+			*pRequest_Headers = make(map[string][]string)
+		}
+		atrace.Inject_Values(_ctx, *pRequest_Headers)
+	}
+
+	_ok, _status, _err := c.IAWSClient.Connect(pWS_URL, pRequest_Headers, pSub_protocols, pCompression)
+	_span.Set_Attribute("http.response.status_code", _status)
+	if _err != nil {
+		_span.Record_Error(_err)
+	}
+	_span.End()
+	return _ok, _status, _err
+}
+
+func (c *ws_client) Read() (int, *[]byte, error) {
+	_type, _message, _err := c.IAWSClient.Read()
+	if _message != nil {
+		c.appu.Add_Bytes_Received(len(*_message))
+	}
+	return _type, _message, _err
+}
+
+func (c *ws_client) Write(pMessage_Type int, pMessage *[]byte) (bool, error) {
+
+	_, _span := c.appu.start_span(c.ctx, "WS WRITE", atrace.KIND_CLIENT)
+	_ok, _err := c.IAWSClient.Write(pMessage_Type, pMessage)
+	if _ok && pMessage != nil {
+		c.appu.Add_Bytes_Sent(len(*pMessage))
+		_span.Set_Attribute("messaging.message.body.size", len(*pMessage))
+	}
+	if _err != nil {
+		_span.Record_Error(_err)
+	}
+	_span.End()
+	return _ok, _err
+}
+
+// Register_HTTPInterceptor adds the interceptor to the requests of the http clients of the unit returned by
+// Get_RESTClient, also the clients created before. The interceptors are called by their order (lower first), the
+// interceptors with the same order by their registration, before the interceptors of the framework.
+//
+//	Returns aerrors.ErrNilArgument if the interceptor is nil, aerrors.ErrInvalidArgument if the name is empty or registered
+func (appu *AUBase) Register_HTTPInterceptor(pName string, pOrder int, pInterceptor aintercept.Interceptor) error {
+	return appu.http_interceptors().Add(pName, pOrder, pInterceptor)
+}
+
+// Remove_HTTPInterceptor removes the interceptor of the unit registered with the given name.
+//
+//	Returns false if the name is not registered
+func (appu *AUBase) Remove_HTTPInterceptor(pName string) bool {
+	return appu.http_interceptors().Remove(pName)
+}
+
+// http_interceptors returns the interceptor chain of the unit
+func (appu *AUBase) http_interceptors() *aintercept.Chain {
+	appu.http_chain_lock.Lock()
+	defer appu.http_chain_lock.Unlock()
+	if appu.http_chain == nil {
+		appu.http_chain = aintercept.New_Chain()
+	}
+	return appu.http_chain
+}
+
+// reset_http_interceptors forgets the interceptors of the unit, the unit registers them again when it is initialized.
+// The clients created before keep the interceptors.
+func (appu *AUBase) reset_http_interceptors() {
+	appu.http_chain_lock.Lock()
+	defer appu.http_chain_lock.Unlock()
+	appu.http_chain = nil
+}
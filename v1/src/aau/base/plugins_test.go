@@ -0,0 +1,104 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	"github.com/agnione/libs/v1/src/afplugins/http/aresilience"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// unavailable_client plugin which answers every request with 503
+type unavailable_client struct {
+	calls atomic.Int32
+}
+
+func (c *unavailable_client) New() interface{}                 { return &unavailable_client{} }
+func (c *unavailable_client) GetID() int                       { return 1 }
+func (c *unavailable_client) Initialize(pInstance_ID int) bool { return true }
+func (c *unavailable_client) Info() build.BuildInfo            { return build.BuildInfo{Version: "test"} }
+
+func (c *unavailable_client) Get(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	c.calls.Add(1)
+	return &htypes.AHTTPResponse{StatusCode: http.StatusServiceUnavailable}, &aerrors.HTTPStatusError{Method: http.MethodGet, StatusCode: http.StatusServiceUnavailable}
+}
+
+func (c *unavailable_client) Post(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *unavailable_client) Put(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *unavailable_client) Delete(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+// TestStopCancelsRetries stops a unit while the framework waits to retry a Get of the unit
+func TestStopCancelsRetries(t *testing.T) {
+
+	_plugin := &unavailable_client{}
+	_app := aautest.New_FakeApp()
+	_app.RESTClients["rest"] = aresilience.Wrap_HTTPClient("rest", _plugin, aresilience.Policy{Max_Attempts: 3, Backoff: time.Minute})
+
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(_app, 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+	defer _unit.Deinitialize()
+	if _ok, _err := _unit.Start(); !_ok {
+		t.Fatal(_err)
+	}
+
+	_type := "rest"
+	_client, _err := _unit.Get_RESTClient(&_type)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_done := make(chan *htypes.AHTTPResponse)
+	go func() {
+		_response, _ := _client.Get(&htypes.AHTTPRequest{URL: "http://localhost/orders"})
+		_done <- _response
+	}()
+
+	for _plugin.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_unit.Stop()
+
+	select {
+	case _response := <-_done:
+		if _response == nil || _response.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("Get() = %v, want the response 503 of the first attempt", _response)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Get() still waits to retry after the unit is stopped")
+	}
+}
+
+func TestGetClientNilType(t *testing.T) {
+	_app := aautest.New_FakeApp()
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(_app, 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+	defer _unit.Deinitialize()
+
+	if _client, _err := _unit.Get_RESTClient(nil); _client != nil || !errors.Is(_err, aerrors.ErrNilArgument) {
+		t.Errorf("Get_RESTClient(nil) = %v, %v, want aerrors.ErrNilArgument", _client, _err)
+	}
+	if _client, _err := _unit.Get_WSClient(nil); _client != nil || !errors.Is(_err, aerrors.ErrNilArgument) {
+		t.Errorf("Get_WSClient(nil) = %v, %v, want aerrors.ErrNilArgument", _client, _err)
+	}
+	if _calls := _app.Call_Count("Get_RESTClient") + _app.Call_Count("Get_WSClient"); _calls != 0 {
+		t.Errorf("%d calls of the framework, want none", _calls)
+	}
+}
@@ -0,0 +1,76 @@
+package AUBase
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aresource"
+
+	"sort"
+)
+
+// New_Pool creates a pool of the unit with the given name. The gets, new allocations and puts of the unit pools
+// are reported by Status (Resources). A pool with the same name is replaced.
+func (appu *AUBase) New_Pool(pName string, pNew func() any) *aresource.Pool {
+
+	_pool := aresource.New_Pool(pNew)
+
+	appu.pools_lock.Lock()
+	defer appu.pools_lock.Unlock()
+	if appu.pools == nil {
+		appu.pools = make(map[string]*aresource.Pool)
+	}
+	appu.pools[pName] = _pool
+	return _pool
+}
+
+// Pools_List returns the names of the unit pools, sorted by name
+func (appu *AUBase) Pools_List() []string {
+	appu.pools_lock.Lock()
+	defer appu.pools_lock.Unlock()
+
+	_names := make([]string, 0, len(appu.pools))
+	for _name := range appu.pools {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+	return _names
+}
+
+// Add_Bytes_Sent adds the given number of bytes to the bytes sent by the unit.
+// The plugins returned by Get_RESTClient and Get_WSClient count the bytes automatically.
+func (appu *AUBase) Add_Bytes_Sent(pBytes int) {
+	if pBytes > 0 {
+		appu.bytes_sent.Add(uint64(pBytes))
+	}
+}
+
+// Add_Bytes_Received adds the given number of bytes to the bytes received by the unit.
+// The plugins returned by Get_RESTClient and Get_WSClient count the bytes automatically.
+func (appu *AUBase) Add_Bytes_Received(pBytes int) {
+	if pBytes > 0 {
+		appu.bytes_received.Add(uint64(pBytes))
+	}
+}
+
+// resources returns the resources attributed to the unit
+func (appu *AUBase) resources() atypes.UnitResources {
+
+	_resources := atypes.UnitResources{
+		Bytes_Sent:     appu.bytes_sent.Load(),
+		Bytes_Received: appu.bytes_received.Load(),
+	}
+
+	appu.routines_lock.Lock()
+	_resources.Routines = len(appu.routines)
+	appu.routines_lock.Unlock()
+
+	appu.pools_lock.Lock()
+	for _, _pool := range appu.pools {
+		_gets, _news, _puts := _pool.Stats()
+		_resources.Pool_Gets += _gets
+		_resources.Pool_News += _news
+		_resources.Pool_Puts += _puts
+	}
+	appu.pools_lock.Unlock()
+
+	return _resources
+}
@@ -0,0 +1,139 @@
+package AUBase
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"time"
+)
+
+// routine holds a routine started with Go
+type routine struct {
+	name    string
+	started time.Time
+}
+
+// Go runs the given function in a new routine tracked by the unit.
+//
+// The function gets the unit context (cancelled on Stop) and should return when it is done.
+// The routine is added to the routine count of the unit and the framework while it is running.
+// A panic is recovered and written to the log with the stack trace, and moves the running unit into the
+// Failed state so the framework can restart it. The returned error (or the panic)
+// is written to the log and passed to On_Routine_Exit.
+// Stop waits for the tracked routines and reports the ones which did not exit.
+//
+//	Returns error if the unit is not started.
+func (appu *AUBase) Go(pName string, pRun func(ctx context.Context) error) error {
+
+	if pRun == nil {
+		return fmt.Errorf("routine function %w", aerrors.ErrNilArgument)
+	}
+	if !appu.IsInitialized() || appu.AppFramework == nil {
+		return fmt.Errorf("%d Failed to start the routine %s. %s %w", appu.ID, pName, appu.Unit_Name, aerrors.ErrNotInitialized)
+	}
+
+	_ctx := appu.Context()
+	if _ctx.Err() != nil {
+		return fmt.Errorf("%d Failed to start the routine %s. %s %w", appu.ID, pName, appu.Unit_Name, aerrors.ErrNotStarted)
+	}
+
+	appu.routines_lock.Lock()
+	if appu.routines == nil {
+		appu.routines = make(map[uint64]*routine)
+		appu.routines_done = make(chan struct{}, 1)
+	}
+	appu.routine_seq++
+	_id := appu.routine_seq
+	appu.routines[_id] = &routine{name: pName, started: time.Now()}
+	appu.routines_lock.Unlock()
+
+	appu.Add_Routine()
+
+	go func() {
+		var _err error
+
+		defer func() {
+			if _r := recover(); _r != nil {
+				_err = fmt.Errorf("routine %s panicked : %v", pName, _r)
+				appu.Write2Log(fmt.Sprintf("%s - %v\n%s", appu.App_UID, _err, debug.Stack()), atypes.LOG_ERROR)
+				if appu.State() == atypes.UNIT_RUNNING {
+					appu.Set_Failed(_err)
+				}
+			} else if _err != nil {
+				appu.Write2Log(appu.App_UID+" - Routine "+pName+" exited with error : "+_err.Error(), atypes.LOG_ERROR)
+			} else {
+				appu.Write2Log(appu.App_UID+" - Routine "+pName+" exited", atypes.LOG_DEBUG)
+			}
+
+			if _handler := appu.On_Routine_Exit; _handler != nil {
+				func() {
+					defer func() { recover() }()
+					_handler(pName, _err)
+				}()
+			}
+
+			appu.routines_lock.Lock()
+			delete(appu.routines, _id)
+			select {
+			case appu.routines_done <- struct{}{}:
+			default:
+			}
+			appu.routines_lock.Unlock()
+
+			appu.Remove_Routine()
+		}()
+
+		_err = pRun(_ctx)
+	}()
+
+	return nil
+}
+
+// Routines_List returns the names of the running routines started with Go, sorted by name
+func (appu *AUBase) Routines_List() []string {
+	appu.routines_lock.Lock()
+	defer appu.routines_lock.Unlock()
+	return appu.routine_names()
+}
+
+// routine_names returns the names of the tracked routines. Caller must hold routines_lock
+func (appu *AUBase) routine_names() []string {
+	_names := make([]string, 0, len(appu.routines))
+	for _, _routine := range appu.routines {
+		_names = append(_names, _routine.name)
+	}
+	sort.Strings(_names)
+	return _names
+}
+
+// wait_routines waits until all the tracked routines exit or the given context is done.
+//
+//	Returns the names of the routines which are still running.
+func (appu *AUBase) wait_routines(pCtx context.Context) []string {
+
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+
+	for {
+		appu.routines_lock.Lock()
+		if len(appu.routines) == 0 {
+			appu.routines_lock.Unlock()
+			return nil
+		}
+		_done := appu.routines_done
+		appu.routines_lock.Unlock()
+
+		select {
+		case <-_done:
+		case <-pCtx.Done():
+			appu.routines_lock.Lock()
+			defer appu.routines_lock.Unlock()
+			return appu.routine_names()
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// start_unit initializes and starts a unit on a FakeApp
+func start_unit(t *testing.T) (*AUBase.AUBase, *aautest.FakeApp) {
+	_app := aautest.New_FakeApp()
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(_app, 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+	if _, _err := _unit.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+	t.Cleanup(_unit.Deinitialize)
+	return _unit, _app
+}
+
+func TestGoPanic(t *testing.T) {
+	_unit, _app := start_unit(t)
+	_exited := make(chan error, 1)
+	_unit.On_Routine_Exit = func(pName string, pErr error) {
+		_exited <- pErr
+	}
+
+	if _err := _unit.Go("worker", func(ctx context.Context) error { panic("invalid order") }); _err != nil {
+		t.Fatal(_err)
+	}
+	select {
+	case _err := <-_exited:
+		if _err == nil || !strings.Contains(_err.Error(), "routine worker panicked : invalid order") {
+			t.Errorf("exit error = %v, want the panic", _err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the panicking routine did not exit")
+	}
+
+	/// On_Routine_Exit is called before the routine is removed from the counts
+	if !_app.Wait_Calls("Remove_Routine", 1, 5*time.Second) {
+		t.Fatal("the panicking routine is not removed from the routine count")
+	}
+	if _added, _removed := _app.Routines_Added(), _app.Routines_Removed(); _added != 1 || _removed != 1 {
+		t.Errorf("routines added %d, removed %d, want 1 and 1", _added, _removed)
+	}
+	if _status := _unit.Status(); _status.Routines != 0 || len(_unit.Routines_List()) != 0 {
+		t.Errorf("routines %d %v after the panic, want none", _status.Routines, _unit.Routines_List())
+	}
+	if _unit.State() != atypes.UNIT_FAILED {
+		t.Errorf("state %v after the panic, want Failed", _unit.State())
+	}
+
+	_unit.Flush()
+	if _logs := _app.Logs_Containing("routine worker panicked"); len(_logs) != 1 || _logs[0].Level != atypes.LOG_ERROR || !strings.Contains(_logs[0].Entry, "goroutine") {
+		t.Errorf("logs of the panic %v, want one error entry with the stack trace", _logs)
+	}
+}
+
+func TestStopWaitsForRoutines(t *testing.T) {
+	_unit, _app := start_unit(t)
+
+	var _finished atomic.Int32
+	for _, _name := range []string{"reader", "writer"} {
+		if _err := _unit.Go(_name, func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			_finished.Add(1)
+			return nil
+		}); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+	if _names := _unit.Routines_List(); len(_names) != 2 || _names[0] != "reader" || _names[1] != "writer" {
+		t.Errorf("Routines_List() = %v, want [reader writer]", _names)
+	}
+
+	if _ok, _err := _unit.Stop(); !_ok || _err != nil {
+		t.Fatalf("Stop() = %v, %v", _ok, _err)
+	}
+	if _finished.Load() != 2 || len(_unit.Routines_List()) != 0 {
+		t.Errorf("Stop() returned with %d routines finished, running %v, want all finished", _finished.Load(), _unit.Routines_List())
+	}
+	if _added, _removed := _app.Routines_Added(), _app.Routines_Removed(); _added != 2 || _removed != 2 {
+		t.Errorf("routines added %d, removed %d, want 2 and 2", _added, _removed)
+	}
+
+	if _err := _unit.Go("late", func(ctx context.Context) error { return nil }); !errors.Is(_err, aerrors.ErrNotStarted) {
+		t.Errorf("Go() on a stopped unit = %v, want aerrors.ErrNotStarted", _err)
+	}
+	if _err := _unit.Go("nil", nil); !errors.Is(_err, aerrors.ErrNilArgument) {
+		t.Errorf("Go() without a function = %v, want aerrors.ErrNilArgument", _err)
+	}
+	if _err := (&AUBase.AUBase{}).Go("early", func(ctx context.Context) error { return nil }); !errors.Is(_err, aerrors.ErrNotInitialized) {
+		t.Errorf("Go() on a unit which is not initialized = %v, want aerrors.ErrNotInitialized", _err)
+	}
+}
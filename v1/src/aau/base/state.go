@@ -0,0 +1,104 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"strconv"
+)
+
+// ErrInvalidTransition is matched (errors.Is) by every StateError
+var ErrInvalidTransition = aerrors.ErrInvalidTransition
+
+// StateError returned when the unit is asked to move into a state which is not allowed from the current state
+type StateError = aerrors.StateError
+
+// State returns the current lifecycle state of the unit
+func (appu *AUBase) State() atypes.UnitState {
+	return atypes.UnitState(appu.state.Load())
+}
+
+// Set_Failed moves the unit into the Failed state. Units call it when the process can not continue.
+//
+//	Returns StateError if the unit is already failed or not initialized
+func (appu *AUBase) Set_Failed(pReason error) error {
+	return appu.transition(atypes.UNIT_FAILED, pReason)
+}
+
+// transition moves the unit atomically from the current state into the given state and
+// publishes the change via Send_Monitor_Message.
+//
+//	Returns StateError if the transition is not allowed by atypes.Can_Transition
+func (appu *AUBase) transition(pTo atypes.UnitState, pReason error) error {
+
+	for {
+		_from := appu.State()
+		if !atypes.Can_Transition(_from, pTo) {
+			return &StateError{Unit: appu.Unit_Name, From: _from, To: pTo}
+		}
+		if appu.state.CompareAndSwap(int32(_from), int32(pTo)) {
+			appu.sync_flags(pTo)
+			appu.publish_state(_from, pTo, pReason)
+			if pTo == atypes.UNIT_FAILED {
+				appu.report_failure(pReason)
+			}
+			return nil
+		}
+	}
+}
+
+// sync_flags sets the deprecated Is_Initialized and Is_Started of the given state
+func (appu *AUBase) sync_flags(pState atypes.UnitState) {
+	appu.flags_lock.Lock()
+	defer appu.flags_lock.Unlock()
+	appu.Is_Initialized = pState != atypes.UNIT_CREATED
+	appu.Is_Started = pState == atypes.UNIT_RUNNING
+}
+
+// legacy_started returns true if the unit set Is_Started by itself without calling Start
+func (appu *AUBase) legacy_started() bool {
+	appu.flags_lock.Lock()
+	defer appu.flags_lock.Unlock()
+	return appu.Is_Started && appu.State() == atypes.UNIT_INITIALIZED
+}
+
+// publish_state sends the state change as a monitoring message and writes it to the log
+func (appu *AUBase) publish_state(pFrom atypes.UnitState, pTo atypes.UnitState, pReason error) {
+
+	_fm := appu.AppFramework
+	if _fm == nil {
+		return
+	}
+
+	_info := map[string]string{
+		"unit": appu.Unit_Name,
+		"from": pFrom.String(),
+		"to":   pTo.String(),
+	}
+	_level := atypes.LOG_DEBUG
+	if pReason != nil {
+		_info["reason"] = pReason.Error()
+		_level = atypes.LOG_ERROR
+	}
+
+	appu.Write2Log(appu.App_UID+" - "+appu.Unit_Name+" state "+pFrom.String()+" -> "+pTo.String(), _level)
+	if _msg := appu.Generate_Monitoring_Message(_fm.ID(), strconv.Itoa(appu.ID), pTo.String(), _info); _msg != nil {
+		appu.Send_Monitor_Message(_msg)
+	}
+}
+
+// report_failure passes the failure to the framework if it supervises the units (iappfm.IAgniAppSupervisor)
+func (appu *AUBase) report_failure(pReason error) {
+
+	_fm, _ok := appu.AppFramework.(iappfm.IAgniAppSupervisor)
+	if !_ok {
+		return
+	}
+	if pReason == nil {
+		pReason = errors.New("unit failed")
+	}
+	defer func() { recover() }()
+	_fm.Unit_Failed(appu.Unit_Name, appu.ID, pReason)
+}
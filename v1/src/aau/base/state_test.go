@@ -0,0 +1,61 @@
+package AUBase_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+
+	"context"
+	"sync"
+	"testing"
+)
+
+// TestStateRace reads the start status while the unit is started and stopped, run with go test -race
+func TestStateRace(t *testing.T) {
+
+	_unit := &AUBase.AUBase{}
+	if _, _err := _unit.Initialize(aautest.New_FakeApp(), 1, "unit", "", ""); _err != nil {
+		t.Fatal(_err)
+	}
+
+	_done := make(chan struct{})
+	var _readers sync.WaitGroup
+	for _i := 0; _i < 4; _i++ {
+		_readers.Add(1)
+		go func() {
+			defer _readers.Done()
+			for {
+				select {
+				case <-_done:
+					return
+				default:
+				}
+				_unit.IsStarted()
+				_unit.IsInitialized()
+				_unit.Get_ID()
+				_unit.Status()
+				_unit.Go("reader", func(context.Context) error { return nil })
+			}
+		}()
+	}
+
+	for _i := 0; _i < 50; _i++ {
+		if _ok, _err := _unit.Start(); !_ok {
+			t.Fatalf("Start() = %v, %v", _ok, _err)
+		}
+		if !_unit.IsStarted() {
+			t.Fatal("IsStarted() must be true after Start")
+		}
+		if _ok, _err := _unit.Stop(); !_ok {
+			t.Fatalf("Stop() = %v, %v", _ok, _err)
+		}
+		if _unit.IsStarted() {
+			t.Fatal("IsStarted() must be false after Stop")
+		}
+	}
+	close(_done)
+	_readers.Wait()
+	_unit.Deinitialize()
+	if _unit.IsInitialized() {
+		t.Error("IsInitialized() must be false after Deinitialize")
+	}
+}
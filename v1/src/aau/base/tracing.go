@@ -0,0 +1,76 @@
+package AUBase
+
+import (
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"net/url"
+)
+
+// Start_Span starts a span of the unit which is the child of the span of pCtx (the unit context if nil).
+// The span carries the unit and instance attributes and must be ended with End:
+//
+//	_ctx, _span := appu.Start_Span(ctx, "create_order")
+//	defer _span.End()
+//	_response, _err := AUBase.With_Context(_client, _ctx).Post(_request)
+//	_span.Record_Error(_err)
+//
+// Returns the context carrying the span and the span. The span is nil (and records nothing)
+// if the unit is not initialized or the framework has no tracer (iappfm.IAgniAppTracer).
+func (appu *AUBase) Start_Span(pCtx context.Context, pName string) (context.Context, *atrace.Span) {
+	return appu.start_span(pCtx, pName, atrace.KIND_INTERNAL)
+}
+
+// framework_tracer returns the tracer of the framework (iappfm.IAgniAppTracer), nil if it has none.
+// The units of a framework without a tracer do not record the spans.
+func framework_tracer(pFM_Instance iappfm.IAgniApp) *atrace.Tracer {
+	if _fm, _ok := pFM_Instance.(iappfm.IAgniAppTracer); _ok {
+		return _fm.Tracer()
+	}
+	return nil
+}
+
+// start_span starts a span of the given kind with the unit attributes
+func (appu *AUBase) start_span(pCtx context.Context, pName string, pKind atrace.SpanKind) (context.Context, *atrace.Span) {
+
+	if pCtx == nil {
+		pCtx = appu.Context()
+	}
+	_tracer := appu.tracer
+	if _tracer == nil {
+		return pCtx, nil
+	}
+
+	_ctx, _span := _tracer.Start(pCtx, pName, pKind)
+	_span.Set_Attribute("unit", appu.Unit_Name)
+	_span.Set_Attribute("instance", appu.ID)
+	return _ctx, _span
+}
+
+// context_client implemented by the clients returned by Get_RESTClient and Get_WSClient
+type context_client interface {
+	with_context(pCtx context.Context) any
+}
+
+// With_Context returns the client returned by Get_RESTClient or Get_WSClient bound to the given context.
+// The spans of the client calls are the children of the span of pCtx and the traceparent header
+// sent downstream carries it. Other clients are returned as they are.
+func With_Context[T any](pClient T, pCtx context.Context) T {
+	if _client, _ok := any(pClient).(context_client); _ok {
+		if _bound, _ok := _client.with_context(pCtx).(T); _ok {
+			return _bound
+		}
+	}
+	return pClient
+}
+
+// span_url returns the url without the user information for the span attributes
+func span_url(pURL string) string {
+	_url, _err := url.Parse(pURL)
+	if _err != nil || _url.User == nil {
+		return pURL
+	}
+	_url.User = nil
+	return _url.String()
+}
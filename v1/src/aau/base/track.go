@@ -0,0 +1,260 @@
+package AUBase
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DEFAULT_STATS_WINDOW sliding window of the operation rates and percentiles when Stats_Window is not set
+const DEFAULT_STATS_WINDOW = time.Minute
+
+// number of buckets of the sliding window and the max durations sampled per bucket.
+// The percentiles weight the samples of a bucket by its executions.
+const (
+	stats_buckets             = 60
+	stats_bucket_samples      = 256
+	operation_duration_metric = "agnione_unit_operation_duration_seconds"
+	operations_metric         = "agnione_unit_operations_total"
+)
+
+// Tracker tracks one execution of an operation, returned by Track
+type Tracker struct {
+	appu    *AUBase
+	stats   *op_stats
+	started time.Time
+	done    atomic.Bool
+}
+
+// Track starts tracking an execution of the given operation and increments the active count of the unit.
+// Done must be called when the execution completes:
+//
+//	_track := appu.Track("create_order")
+//	_err := create_order(...)
+//	_track.Done(_err)
+//
+// Done records the duration, counts the execution as handled (nil error) or failed and decrements
+// the active count. The per operation rates and percentiles are reported by Status (Operations).
+func (appu *AUBase) Track(pOp string) *Tracker {
+	appu.Increase_Active_Count()
+	return &Tracker{appu: appu, stats: appu.op_stats(pOp), started: time.Now()}
+}
+
+// Done completes the execution. pErr nil counts it as handled, unless as failed.
+// Calls after the first one are ignored.
+//
+//	Returns the duration of the execution
+func (t *Tracker) Done(pErr error) time.Duration {
+
+	_duration := time.Since(t.started)
+	if !t.done.CompareAndSwap(false, true) {
+		return _duration
+	}
+
+	t.appu.Decrease_Active_Count()
+	if pErr == nil {
+		t.appu.Add_Request_Handled_Count()
+	} else {
+		t.appu.Add_Request_Failed_Count()
+	}
+	t.stats.record(time.Now(), _duration, pErr != nil)
+	return _duration
+}
+
+// ********* per operation statistics *********
+
+// stats_bucket executions of a time slot of the sliding window
+type stats_bucket struct {
+	slot    int64 /// start of the slot (unix nano / slot size)
+	count   uint64
+	failed  uint64
+	samples []float64 /// sampled durations (ms)
+}
+
+// op_stats statistics of an operation
+type op_stats struct {
+	lock    sync.Mutex
+	slot    time.Duration
+	buckets [stats_buckets]stats_bucket
+	handled uint64
+	failed  uint64
+	first   time.Time /// first execution, the rate is calculated from it until the window is filled
+
+	duration  *ametrics.Histogram /// nil if the metrics are not available
+	ok_total  *ametrics.Counter
+	err_total *ametrics.Counter
+}
+
+// op_stats returns the statistics of the given operation, created on the first use
+func (appu *AUBase) op_stats(pOp string) *op_stats {
+
+	appu.ops_lock.Lock()
+	defer appu.ops_lock.Unlock()
+
+	if _stats, _ok := appu.ops[pOp]; _ok {
+		return _stats
+	}
+	if appu.ops == nil {
+		appu.ops = make(map[string]*op_stats)
+	}
+
+	_window := appu.Stats_Window
+	if _window <= 0 {
+		_window = DEFAULT_STATS_WINDOW
+	}
+	_slot := _window / stats_buckets
+	if _slot <= 0 {
+		_slot = 1
+	}
+
+	_stats := &op_stats{slot: _slot}
+	_labels := ametrics.Labels{"op": pOp}
+	_stats.duration, _ = appu.Histogram(operation_duration_metric, "Duration of the unit operations.", nil, _labels)
+	_stats.ok_total, _ = appu.Counter(operations_metric, "Executions of the unit operations by result.", ametrics.Labels{"op": pOp, "result": "ok"})
+	_stats.err_total, _ = appu.Counter(operations_metric, "Executions of the unit operations by result.", ametrics.Labels{"op": pOp, "result": "error"})
+
+	appu.ops[pOp] = _stats
+	return _stats
+}
+
+// reset_ops clears the statistics of the operations
+func (appu *AUBase) reset_ops() {
+	appu.ops_lock.Lock()
+	defer appu.ops_lock.Unlock()
+	appu.ops = nil
+}
+
+// record adds the execution to the statistics and the metrics
+func (s *op_stats) record(pNow time.Time, pDuration time.Duration, pFailed bool) {
+
+	if s.duration != nil {
+		s.duration.Observe(pDuration.Seconds())
+	}
+	if pFailed && s.err_total != nil {
+		s.err_total.Inc()
+	} else if !pFailed && s.ok_total != nil {
+		s.ok_total.Inc()
+	}
+
+	_slot := pNow.UnixNano() / int64(s.slot)
+	_ms := float64(pDuration) / float64(time.Millisecond)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	_bucket := &s.buckets[_slot%stats_buckets]
+	if _bucket.slot != _slot {
+		*_bucket = stats_bucket{slot: _slot, samples: _bucket.samples[:0]}
+	}
+
+	if s.first.IsZero() {
+		s.first = pNow
+	}
+	_bucket.count++
+	if pFailed {
+		_bucket.failed++
+		s.failed++
+	} else {
+		s.handled++
+	}
+
+	/// reservoir sampling keeps the bucket bounded
+	if len(_bucket.samples) < stats_bucket_samples {
+		_bucket.samples = append(_bucket.samples, _ms)
+	} else if _index := rand.Int63n(int64(_bucket.count)); _index < stats_bucket_samples {
+		_bucket.samples[_index] = _ms
+	}
+}
+
+// snapshot returns the statistics of the sliding window ending at the given time
+func (s *op_stats) snapshot(pNow time.Time) atypes.OperationStats {
+
+	_current := pNow.UnixNano() / int64(s.slot)
+
+	s.lock.Lock()
+	_stats := atypes.OperationStats{Handled: s.handled, Failed: s.failed}
+	var _count, _failed uint64
+	var _samples []weighted_sample
+	_first := s.first
+	for _i := range s.buckets {
+		_bucket := &s.buckets[_i]
+		if _bucket.count == 0 || _current-_bucket.slot >= stats_buckets || _bucket.slot > _current {
+			continue
+		}
+		_count += _bucket.count
+		_failed += _bucket.failed
+		/// a sample of a busy bucket stands for more executions than a sample of a quiet one
+		_weight := float64(_bucket.count) / float64(len(_bucket.samples))
+		for _, _ms := range _bucket.samples {
+			_samples = append(_samples, weighted_sample{ms: _ms, weight: _weight})
+		}
+	}
+	s.lock.Unlock()
+
+	_window := (s.slot * stats_buckets).Seconds()
+	_stats.Window_Seconds = _window
+	if _count == 0 {
+		return _stats
+	}
+
+	_elapsed := pNow.Sub(_first).Seconds()
+	if _elapsed > _window {
+		_elapsed = _window
+	} else if _elapsed < 1 {
+		_elapsed = 1
+	}
+	_stats.Rate = float64(_count) / _elapsed
+	_stats.Error_Rate = float64(_failed) / float64(_count)
+
+	sort.Slice(_samples, func(i, j int) bool { return _samples[i].ms < _samples[j].ms })
+	_stats.P50_Ms = percentile(_samples, float64(_count), 0.50)
+	_stats.P95_Ms = percentile(_samples, float64(_count), 0.95)
+	_stats.P99_Ms = percentile(_samples, float64(_count), 0.99)
+	return _stats
+}
+
+// weighted_sample sampled duration and the number of executions it stands for
+type weighted_sample struct {
+	ms     float64
+	weight float64
+}
+
+// percentile returns the nearest rank percentile of the sorted samples, pTotal is the sum of their weights
+func percentile(pSorted []weighted_sample, pTotal float64, pRank float64) float64 {
+	if len(pSorted) == 0 {
+		return 0
+	}
+	_rank := math.Ceil(pRank * pTotal)
+	var _cumulative float64
+	for _, _sample := range pSorted {
+		_cumulative += _sample.weight
+		/// the weights are fractions, allow the rounding error of the sum
+		if _cumulative >= _rank-1e-9 {
+			return _sample.ms
+		}
+	}
+	return pSorted[len(pSorted)-1].ms
+}
+
+// operations returns the statistics of all the tracked operations, nil if none
+func (appu *AUBase) operations() map[string]atypes.OperationStats {
+
+	appu.ops_lock.Lock()
+	defer appu.ops_lock.Unlock()
+
+	if len(appu.ops) == 0 {
+		return nil
+	}
+	_now := time.Now()
+	_operations := make(map[string]atypes.OperationStats, len(appu.ops))
+	for _op, _stats := range appu.ops {
+		_operations[_op] = _stats.snapshot(_now)
+	}
+	return _operations
+}
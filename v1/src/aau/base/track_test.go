@@ -0,0 +1,47 @@
+package AUBase
+
+import (
+	"testing"
+	"time"
+)
+
+// TestPercentilesSkewedSlots a busy slot of fast executions and a quiet slot of slow ones,
+// the samples of the quiet slot must not count as much as the samples of the busy slot
+func TestPercentilesSkewedSlots(t *testing.T) {
+
+	_stats := &op_stats{slot: time.Second}
+	_start := time.Unix(1_800_000_000, 0)
+
+	for _i := 0; _i < 10_000; _i++ {
+		_stats.record(_start, time.Millisecond, false)
+	}
+	for _i := 0; _i < 200; _i++ {
+		_stats.record(_start.Add(time.Second), 100*time.Millisecond, false)
+	}
+
+	_snapshot := _stats.snapshot(_start.Add(2 * time.Second))
+	/// 200 of 10200 executions (2%) are slow
+	if _snapshot.P50_Ms != 1 || _snapshot.P95_Ms != 1 {
+		t.Errorf("P50/P95 = %v/%v ms, want 1/1", _snapshot.P50_Ms, _snapshot.P95_Ms)
+	}
+	if _snapshot.P99_Ms != 100 {
+		t.Errorf("P99 = %v ms, want 100", _snapshot.P99_Ms)
+	}
+	if _snapshot.Handled != 10_200 {
+		t.Errorf("Handled = %d, want 10200", _snapshot.Handled)
+	}
+}
+
+func TestPercentilesEvenSlots(t *testing.T) {
+
+	_stats := &op_stats{slot: time.Second}
+	_start := time.Unix(1_800_000_000, 0)
+	for _i := 1; _i <= 100; _i++ {
+		_stats.record(_start, time.Duration(_i)*time.Millisecond, false)
+	}
+
+	_snapshot := _stats.snapshot(_start)
+	if _snapshot.P50_Ms != 50 || _snapshot.P95_Ms != 95 || _snapshot.P99_Ms != 99 {
+		t.Errorf("P50/P95/P99 = %v/%v/%v ms, want 50/95/99", _snapshot.P50_Ms, _snapshot.P95_Ms, _snapshot.P99_Ms)
+	}
+}
@@ -11,6 +11,8 @@
 //   - Stop
 //   - Status
 //   - Info
+//   - IAppUnitContext (StartContext, StopContext)
+//   - IAppUnitCounters (Counters)
 //     ---------------------------------------------------------------------------------------------------------------------
 //     Author        : D. Ajith Nilantha de Silva ajithdesilva@gmail.com | 26/01/2024
 //     Copyright     :	Open source MIT License
@@ -29,17 +31,48 @@
 package iappunit
 
 import (
-	iappfm "agnione/v1/src/appfm/iappfw"
-	atypes "agnione/v1/src/appfm/types"
-	build "agnione/v1/src/lib"
+	iappfm "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	build "github.com/agnione/libs/v1/src/lib"
+
+	"context"
 )
 
-// IAppUnit the interface for the AgniOne Application Unit
-type IAppUnit interface {
+// IAppUnitCore functions of the application unit which are shared by the v1 and v2 unit interfaces
+type IAppUnitCore interface {
 
 	//New creates a new instance and return the IZAppUnit interface
+	//
+	// The framework calls New on the registered unit once for every instance of the unit pool (Appunit.PoolSize)
+	// and initializes each instance with a distinct instance id. The instances must not share mutable state
+	// unless it is safe for concurrent use, the work is dispatched to them concurrently.
 	New() interface{}
 
+	// IsInitialized returns the initialize status of the application unit
+	IsInitialized() bool
+
+	// GetID retuns the pre-set id of the current instance
+	GetID() (instance_id int)
+
+	// Deinitialize clear all the related objects
+	Deinitialize()
+
+	// IsStarted returns the start status of the application unit
+	IsStarted() bool
+
+	// Status return the status of the application unit
+	Status() *atypes.AppUnitInfo
+
+	// Info returns the information of the library
+	Info() build.BuildInfo
+}
+
+// IAppUnit the interface for the AgniOne Application Unit
+//
+// Errors returned by the units built on AUBase wrap the aerrors sentinel errors (aerrors.ErrNotInitialized, aerrors.ErrNotStarted, ...).
+type IAppUnit interface {
+	IAppUnitCore
+
 	// Initialize initializes the application unit
 	//
 	// Parameter frm_instance - instance of application framework (ztypes.IZApp)
@@ -51,27 +84,36 @@ type IAppUnit interface {
 	Initialize(frm_instance iappfm.IAgniApp, instance_id int,
 		appunit_name string, appunit_path string,config_file string) (bool, error)
 
-	// IsInitialized returns the initialize status of the application unit
-	IsInitialized() bool
-
-	// GetID retuns the pre-set id of the current instance
-	GetID() (instance_id int)
-
-	// Deinitialize clear all the related objects
-	Deinitialize()
-
 	// Start starts the process of the application unit
 	Start() (bool, error)
 
-	// IsStarted returns the start status of the application unit
-	IsStarted() bool
-
 	// Stop stops the process of the application unit
 	Stop() (bool, error)
+}
 
-	// Status return the status of the application unit
-	Status() *atypes.AppUnitInfo
+// IAppUnitContext optional interface for the application units which support the context aware lifecycle.
+//
+// The framework checks whether the unit implements this interface and calls StartContext/StopContext instead of Start/Stop.
+type IAppUnitContext interface {
 
-	// Info returns the information of the library
-	Info() build.BuildInfo
+	// StartContext starts the process of the application unit.
+	//	Parameter ctx - limits the time allowed for the start. The unit must not keep it after returning.
+	// Returns true if suceess, unless false and error
+	StartContext(ctx context.Context) (bool, error)
+
+	// StopContext stops the process of the application unit.
+	//	Parameter ctx - deadline for the stop. The unit should finish the current executions before the deadline
+	//	and must return without waiting when ctx is done (forced stop).
+	// Returns true if suceess, unless false and error
+	StopContext(ctx context.Context) (bool, error)
+}
+
+// IAppUnitCounters optional interface for the application units which keep the request counters themselves.
+//
+// A framework implementing iappfw.IAgniAppCounters sums the counters of these units when its totals are read,
+// so the units must not forward the counts to the framework (Add_Request_HandleCount/Add_Request_Failed_Count).
+type IAppUnitCounters interface {
+
+	// Counters returns the current counters of the unit. It is called concurrently and must not block.
+	Counters() atypes.UnitCounters
 }
@@ -0,0 +1,167 @@
+// aauth package provides the authentication providers of the http client plugins of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Provider, New_Provider
+//
+//   - Basic, Bearer, OAuth2_Client_Credentials, HMAC_Signer
+//
+//   - HTTP_Client, Wrap_HTTPClient
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   aauth - AgniOne Application Framework
+//     Objective	:   Keep the credentials of the upstreams out of the units
+//     ---------------------------------------------------------------------------------------------------------------------
+//     HTTP_Client wraps a http client plugin with a Provider which authorizes every request before it is sent:
+//     static basic and bearer credentials, OAuth2 client credentials with the token cached and refreshed before
+//     its expiry, and the AWS Signature Version 4 signing of the canonical request. The providers are configured
+//     by the auth section of the plugin in plugins.http of the FMConfig, the credentials "env:NAME" are read from
+//     the environment.
+//     ---------------------------------------------------------------------------------------------------------------------
+package aauth
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// types of the providers of the Authconfig
+const (
+	AUTH_BASIC  = "basic"
+	AUTH_BEARER = "bearer"
+	AUTH_OAUTH2 = "oauth2"
+	AUTH_HMAC   = "hmac"
+)
+
+// ENV_PREFIX prefix of the credentials read from the environment
+const ENV_PREFIX = "env:"
+
+// AUTHORIZATION_HEADER header set by the providers
+const AUTHORIZATION_HEADER = "Authorization"
+
+// Provider authorizes the requests of a http client
+type Provider interface {
+
+	// Authorize sets the credentials of the request (headers) before it is sent with the given method.
+	//
+	//	Returns an error matching aerrors.ErrAuthentication if the credentials can not be obtained
+	Authorize(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) error
+}
+
+// invalidator implemented by the providers with cached credentials which the upstream can reject (401)
+type invalidator interface {
+	Invalidate()
+}
+
+// New_Provider returns the provider of the auth section of a http plugin config, nil if the type is empty.
+//
+//	Returns aerrors.ErrInvalidArgument if the type is unknown, a credential is missing or its environment variable is not set
+func New_Provider(pConfig atypes.Authconfig) (Provider, error) {
+
+	_env := env_reader{}
+	switch strings.ToLower(pConfig.Type) {
+	case "":
+		return nil, nil
+	case AUTH_BASIC:
+		_provider := &Basic{Username: _env.required("username", pConfig.Username), Password: _env.read(pConfig.Password)}
+		return _provider, _env.err
+	case AUTH_BEARER:
+		_provider := &Bearer{Token: _env.required("token", pConfig.Token)}
+		return _provider, _env.err
+	case AUTH_OAUTH2:
+		_provider := &OAuth2_Client_Credentials{
+			Token_URL:      _env.required("token_url", pConfig.TokenURL),
+			Client_ID:      _env.required("client_id", pConfig.ClientID),
+			Client_Secret:  _env.required("client_secret", pConfig.ClientSecret),
+			Scopes:         pConfig.Scopes,
+			Refresh_Before: time.Duration(pConfig.RefreshBefore) * time.Second,
+		}
+		return _provider, _env.err
+	case AUTH_HMAC:
+		_provider := &HMAC_Signer{
+			Access_Key:    _env.required("access_key", pConfig.AccessKey),
+			Secret_Key:    _env.required("secret_key", pConfig.SecretKey),
+			Session_Token: _env.read(pConfig.SessionToken),
+			Region:        _env.required("region", pConfig.Region),
+			Service:       _env.required("service", pConfig.Service),
+			Single_Encode: pConfig.SingleEncode == 1,
+		}
+		return _provider, _env.err
+	}
+	return nil, fmt.Errorf("auth type %q. %w", pConfig.Type, aerrors.ErrInvalidArgument)
+}
+
+// env_reader reads the credentials of a config, err is the first missing credential
+type env_reader struct {
+	err error
+}
+
+// read returns the value, the environment variable of a "env:NAME" value
+func (r *env_reader) read(pValue string) string {
+	_name, _ok := strings.CutPrefix(pValue, ENV_PREFIX)
+	if !_ok {
+		return pValue
+	}
+	_value, _ok := os.LookupEnv(_name)
+	if !_ok && r.err == nil {
+		r.err = fmt.Errorf("environment variable %s is not set. %w", _name, aerrors.ErrInvalidArgument)
+	}
+	return _value
+}
+
+// required returns the value of the credential, which must not be empty
+func (r *env_reader) required(pName string, pValue string) string {
+	_value := r.read(pValue)
+	if _value == "" && r.err == nil {
+		r.err = fmt.Errorf("auth %s is not set. %w", pName, aerrors.ErrInvalidArgument)
+	}
+	return _value
+}
+
+// set_header sets the header of the request, replacing the values of the same header in any case
+func set_header(pRequest *htypes.AHTTPRequest, pName string, pValue string) {
+	for _name := range pRequest.Headers {
+		if strings.EqualFold(_name, pName) {
+			delete(pRequest.Headers, _name)
+		}
+	}
+	for _name := range pRequest.Header_Values {
+		if strings.EqualFold(_name, pName) {
+			delete(pRequest.Header_Values, _name)
+		}
+	}
+	if pRequest.Headers == nil {
+		pRequest.Headers = make(map[string]string)
+	}
+	pRequest.Headers[pName] = pValue
+}
+
+// Basic static basic authentication (RFC 7617)
+type Basic struct {
+	Username string
+	Password string
+}
+
+func (p *Basic) Authorize(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) error {
+	set_header(pRequest, AUTHORIZATION_HEADER, "Basic "+base64.StdEncoding.EncodeToString([]byte(p.Username+":"+p.Password)))
+	return nil
+}
+
+// Bearer static bearer token (RFC 6750)
+type Bearer struct {
+	Token string
+}
+
+func (p *Bearer) Authorize(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) error {
+	set_header(pRequest, AUTHORIZATION_HEADER, "Bearer "+p.Token)
+	return nil
+}
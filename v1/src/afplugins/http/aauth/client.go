@@ -0,0 +1,127 @@
+package aauth
+
+import (
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// HTTP_Client http client plugin which authorizes every request with its provider before it is sent
+type HTTP_Client struct {
+	ihttp.IAHTTPClient
+	provider Provider
+}
+
+// make sure HTTP_Client satisfies the plugin interface
+var _ ihttp.IAHTTPClient = (*HTTP_Client)(nil)
+var _ ihttp.IAHTTPClientDo = (*HTTP_Client)(nil)
+
+// Wrap_HTTPClient returns the client performing the requests of the http client plugin authorized by the provider.
+// When the upstream answers 401 the cached credentials of the provider (OAuth2 token) are dropped and
+// the request is sent once more if its body can be sent again.
+func Wrap_HTTPClient(pClient ihttp.IAHTTPClient, pProvider Provider) *HTTP_Client {
+	if pClient == nil {
+		return nil
+	}
+	return &HTTP_Client{IAHTTPClient: pClient, provider: pProvider}
+}
+
+// New creates a new instance of the plugin with the provider of the client
+func (c *HTTP_Client) New() interface{} {
+	_client, _ok := c.IAHTTPClient.New().(ihttp.IAHTTPClient)
+	if !_ok {
+		return nil
+	}
+	return Wrap_HTTPClient(_client, c.provider)
+}
+
+// Unwrap returns the http client plugin
+func (c *HTTP_Client) Unwrap() ihttp.IAHTTPClient {
+	return c.IAHTTPClient
+}
+
+func (c *HTTP_Client) Get(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodGet, pHTTP_Request, call(c.IAHTTPClient.Get))
+}
+
+func (c *HTTP_Client) Post(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPost, pHTTP_Request, call(c.IAHTTPClient.Post))
+}
+
+func (c *HTTP_Client) Put(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPut, pHTTP_Request, call(c.IAHTTPClient.Put))
+}
+
+func (c *HTTP_Client) Delete(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodDelete, pHTTP_Request, call(c.IAHTTPClient.Delete))
+}
+
+func (c *HTTP_Client) Patch(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPatch, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodPatch)))
+}
+
+func (c *HTTP_Client) Head(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodHead, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodHead)))
+}
+
+func (c *HTTP_Client) Options(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodOptions, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodOptions)))
+}
+
+func (c *HTTP_Client) Do(pCtx context.Context, pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	_method := http.MethodGet
+	if pHTTP_Request != nil && pHTTP_Request.Method != "" {
+		_method = pHTTP_Request.Method
+	}
+	return c.execute(pCtx, _method, pHTTP_Request, func(pCtx context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return ihttp.Do(pCtx, c.IAHTTPClient, pRequest)
+	})
+}
+
+// call returns the plugin function as the call of execute
+func call(pFunc func(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return func(_ context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return pFunc(pRequest)
+	}
+}
+
+// execute authorizes the request and performs it with the given plugin function
+func (c *HTTP_Client) execute(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest,
+	pCall func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) (*htypes.AHTTPResponse, error) {
+
+	if pRequest == nil || c.provider == nil {
+		return pCall(pCtx, pRequest)
+	}
+	if _err := c.provider.Authorize(pCtx, pMethod, pRequest); _err != nil {
+		return nil, fmt.Errorf("%s %s. %w", pMethod, pRequest.URL, _err)
+	}
+	_response, _err := pCall(pCtx, pRequest)
+
+	_invalidator, _ok := c.provider.(invalidator)
+	var _status_err *aerrors.HTTPStatusError
+	if !_ok || !errors.As(_err, &_status_err) || _status_err.StatusCode != http.StatusUnauthorized {
+		return _response, _err
+	}
+
+	/// the cached credentials may be revoked before their expiry
+	if pRequest.Body_Reader != nil {
+		_seeker, _ok := pRequest.Body_Reader.(io.Seeker)
+		if !_ok {
+			return _response, _err
+		}
+		if _, _seek_err := _seeker.Seek(0, io.SeekStart); _seek_err != nil {
+			return _response, _err
+		}
+	}
+	_invalidator.Invalidate()
+	if _auth_err := c.provider.Authorize(pCtx, pMethod, pRequest); _auth_err != nil {
+		return _response, _err
+	}
+	return pCall(pCtx, pRequest)
+}
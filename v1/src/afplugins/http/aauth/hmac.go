@@ -0,0 +1,221 @@
+package aauth
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// headers and values of the signature version 4
+const (
+	SIGV4_ALGORITHM        = "AWS4-HMAC-SHA256"
+	SIGV4_DATE_HEADER      = "X-Amz-Date"
+	SIGV4_CONTENT_HEADER   = "X-Amz-Content-Sha256"
+	SIGV4_TOKEN_HEADER     = "X-Amz-Security-Token"
+	SIGV4_UNSIGNED_PAYLOAD = "UNSIGNED-PAYLOAD"
+	SIGV4_S3_SERVICE       = "s3"
+	sigv4_time_format      = "20060102T150405Z"
+	sigv4_date_format      = "20060102"
+)
+
+// HMAC_Signer signs the requests with the AWS Signature Version 4: the HMAC-SHA256 signature of the canonical request
+// (method, path, query, the host, content-type and x-amz-* headers and the hash of the body) with a key derived
+// from the secret key, the date, the region and the service.
+//
+// The path of the canonical request is normalized and every segment of the sent (escaped) path is URI encoded
+// again, except for S3 (and Single_Encode) where the path is not normalized and encoded once. The S3 requests also
+// carry the hash of the body in X-Amz-Content-Sha256, a Body_Reader which can not be rewound is sent as UNSIGNED-PAYLOAD.
+type HMAC_Signer struct {
+	Access_Key    string
+	Secret_Key    string
+	Session_Token string // temporary credentials, sent in X-Amz-Security-Token
+	Region        string // e.g. eu-west-1
+	Service       string // e.g. execute-api
+	Single_Encode bool   // encode the path once like S3 for a service with another name (always for SIGV4_S3_SERVICE)
+}
+
+func (p *HMAC_Signer) Authorize(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) error {
+	return p.sign(time.Now().UTC(), pMethod, pRequest)
+}
+
+// sign signs the request at the given time
+func (p *HMAC_Signer) sign(pNow time.Time, pMethod string, pRequest *htypes.AHTTPRequest) error {
+
+	_full_url, _err := pRequest.Full_URL()
+	if _err != nil {
+		return _err
+	}
+	_url, _err := url.Parse(_full_url)
+	if _err != nil || _url.Host == "" {
+		return fmt.Errorf("url %q. %w", pRequest.URL, aerrors.ErrInvalidArgument)
+	}
+	if pRequest.Method != "" {
+		pMethod = pRequest.Method
+	}
+
+	_payload_hash, _err := payload_hash(pMethod, pRequest)
+	if _err != nil {
+		return fmt.Errorf("hash of the body. %w. %w", aerrors.ErrAuthentication, _err)
+	}
+
+	_s3 := p.Service == SIGV4_S3_SERVICE
+	set_header(pRequest, SIGV4_DATE_HEADER, pNow.Format(sigv4_time_format))
+	if _s3 {
+		set_header(pRequest, SIGV4_CONTENT_HEADER, _payload_hash)
+	}
+	if p.Session_Token != "" {
+		set_header(pRequest, SIGV4_TOKEN_HEADER, p.Session_Token)
+	}
+
+	_headers, _signed := canonical_headers(_url.Host, pRequest)
+	_canonical := strings.Join([]string{
+		pMethod,
+		canonical_path(_url, !_s3 && !p.Single_Encode),
+		canonical_query(_url.Query()),
+		_headers,
+		_signed,
+		_payload_hash,
+	}, "\n")
+
+	_scope := pNow.Format(sigv4_date_format) + "/" + p.Region + "/" + p.Service + "/aws4_request"
+	_string_to_sign := SIGV4_ALGORITHM + "\n" + pNow.Format(sigv4_time_format) + "\n" + _scope + "\n" + sha256_hex([]byte(_canonical))
+
+	_key := hmac_sha256([]byte("AWS4"+p.Secret_Key), pNow.Format(sigv4_date_format))
+	_key = hmac_sha256(_key, p.Region)
+	_key = hmac_sha256(_key, p.Service)
+	_key = hmac_sha256(_key, "aws4_request")
+	_signature := hex.EncodeToString(hmac_sha256(_key, _string_to_sign))
+
+	set_header(pRequest, AUTHORIZATION_HEADER, SIGV4_ALGORITHM+" Credential="+p.Access_Key+"/"+_scope+
+		", SignedHeaders="+_signed+", Signature="+_signature)
+	return nil
+}
+
+// payload_hash returns the hex SHA-256 of the body, a Body_Reader is read and rewound
+func payload_hash(pMethod string, pRequest *htypes.AHTTPRequest) (string, error) {
+
+	if pRequest.Body_Reader == nil {
+		if pMethod == "GET" || pMethod == "HEAD" {
+			/// the body is not sent, see htypes.New_HTTP_Request
+			return sha256_hex(nil), nil
+		}
+		return sha256_hex(pRequest.Body), nil
+	}
+
+	_seeker, _ok := pRequest.Body_Reader.(io.ReadSeeker)
+	if !_ok {
+		return SIGV4_UNSIGNED_PAYLOAD, nil
+	}
+	_hash := sha256.New()
+	if _, _err := io.Copy(_hash, _seeker); _err != nil {
+		return "", _err
+	}
+	if _, _err := _seeker.Seek(0, io.SeekStart); _err != nil {
+		return "", _err
+	}
+	return hex.EncodeToString(_hash.Sum(nil)), nil
+}
+
+// canonical_headers returns the canonical headers (host, content-type and x-amz-*) and the signed header names
+func canonical_headers(pHost string, pRequest *htypes.AHTTPRequest) (string, string) {
+
+	_values := map[string][]string{"host": {pHost}}
+	for _name, _header_values := range pRequest.Header() {
+		_lower := strings.ToLower(_name)
+		if _lower == "content-type" || strings.HasPrefix(_lower, "x-amz-") {
+			_values[_lower] = _header_values
+		}
+	}
+
+	_names := make([]string, 0, len(_values))
+	for _name := range _values {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+
+	var _buffer bytes.Buffer
+	for _, _name := range _names {
+		_trimmed := make([]string, len(_values[_name]))
+		for _i, _value := range _values[_name] {
+			_trimmed[_i] = strings.Join(strings.Fields(_value), " ")
+		}
+		_buffer.WriteString(_name + ":" + strings.Join(_trimmed, ",") + "\n")
+	}
+	return _buffer.String(), strings.Join(_names, ";")
+}
+
+// canonical_path returns the URI encoded path, / if empty. pDouble normalizes the path (removes the empty, . and ..
+// segments) and encodes the escaped segments sent in the request, otherwise the unescaped segments are encoded
+func canonical_path(pURL *url.URL, pDouble bool) string {
+	_path := pURL.EscapedPath()
+	if _path == "" {
+		return "/"
+	}
+	if pDouble {
+		_cleaned := path.Clean(_path)
+		if strings.HasSuffix(_path, "/") && _cleaned != "/" {
+			_cleaned += "/"
+		}
+		_path = _cleaned
+	}
+	_segments := strings.Split(_path, "/")
+	for _i, _segment := range _segments {
+		if !pDouble {
+			if _unescaped, _err := url.PathUnescape(_segment); _err == nil {
+				_segment = _unescaped
+			}
+		}
+		_segments[_i] = uri_encode(_segment)
+	}
+	return strings.Join(_segments, "/")
+}
+
+// canonical_query returns the query parameters URI encoded and sorted by name and value
+func canonical_query(pQuery url.Values) string {
+	_pairs := make([]string, 0, len(pQuery))
+	for _name, _values := range pQuery {
+		for _, _value := range _values {
+			_pairs = append(_pairs, uri_encode(_name)+"="+uri_encode(_value))
+		}
+	}
+	sort.Strings(_pairs)
+	return strings.Join(_pairs, "&")
+}
+
+// uri_encode encodes all the characters except the unreserved characters of RFC 3986
+func uri_encode(pValue string) string {
+	var _buffer strings.Builder
+	for _, _byte := range []byte(pValue) {
+		switch {
+		case 'A' <= _byte && _byte <= 'Z', 'a' <= _byte && _byte <= 'z', '0' <= _byte && _byte <= '9',
+			_byte == '-', _byte == '_', _byte == '.', _byte == '~':
+			_buffer.WriteByte(_byte)
+		default:
+			fmt.Fprintf(&_buffer, "%%%02X", _byte)
+		}
+	}
+	return _buffer.String()
+}
+
+func sha256_hex(pData []byte) string {
+	_sum := sha256.Sum256(pData)
+	return hex.EncodeToString(_sum[:])
+}
+
+func hmac_sha256(pKey []byte, pData string) []byte {
+	_mac := hmac.New(sha256.New, pKey)
+	_mac.Write([]byte(pData))
+	return _mac.Sum(nil)
+}
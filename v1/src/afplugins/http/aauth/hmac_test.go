@@ -0,0 +1,132 @@
+package aauth
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// credentials and time of the AWS Signature Version 4 test suite
+var (
+	sigv4_test_time   = time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	sigv4_test_signer = HMAC_Signer{
+		Access_Key: "AKIDEXAMPLE",
+		Secret_Key: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		Region:     "us-east-1",
+		Service:    "service",
+	}
+)
+
+// TestSigV4Suite signs the requests of the AWS Signature Version 4 test suite (aws-sig-v4-test-suite).
+// The requests of get-space and get-utf8 send the path unescaped, which is the single encoding of the path.
+func TestSigV4Suite(t *testing.T) {
+
+	_unreserved := "-._~0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+	_tests := []struct {
+		name      string
+		method    string
+		path      string
+		headers   map[string]string
+		body      string
+		single    bool
+		signed    string
+		signature string
+	}{
+		{name: "get-vanilla", method: http.MethodGet, path: "/",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "get-vanilla-query-order-key-case", method: http.MethodGet, path: "/?Param2=value2&Param1=value1",
+			signature: "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{name: "get-vanilla-query-unreserved", method: http.MethodGet, path: "/?" + _unreserved + "=" + _unreserved,
+			signature: "9c3e54bfcdf0b19771a7f523ee5669cdf59bc7cc0884027167c21bb143a40197"},
+		{name: "get-unreserved", method: http.MethodGet, path: "/" + _unreserved,
+			signature: "07ef7494c76fa4850883e2b006601f940f8a34d404d0cfa977f52a65bbf5f24f"},
+		{name: "normalize-path/get-relative", method: http.MethodGet, path: "/example/..",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "normalize-path/get-slash-dot-slash", method: http.MethodGet, path: "/./",
+			signature: "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{name: "normalize-path/get-slash-pointless-dot", method: http.MethodGet, path: "/./example",
+			signature: "ef75d96142cf21edca26f06005da7988e4f8dc83a165a80865db7089db637ec5"},
+		{name: "normalize-path/get-slashes", method: http.MethodGet, path: "//example//",
+			signature: "9a624bd73a37c9a373b5312afbebe7a714a789de108f0bdfe846570885f57e84"},
+		{name: "normalize-path/get-space", method: http.MethodGet, path: "/example space/", single: true,
+			signature: "652487583200325589f1fba4c7e578f72c47cb61beeca81406b39ddec1366741"},
+		{name: "get-utf8", method: http.MethodGet, path: "/ሴ", single: true,
+			signature: "8318018e0b0f223aa2bbf98705b62bb787dc9c0e678f255a891fd03141be5d85"},
+		{name: "post-vanilla", method: http.MethodPost, path: "/",
+			signature: "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{name: "post-x-www-form-urlencoded", method: http.MethodPost, path: "/",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded"}, body: "Param1=value1",
+			signed: "content-type;host;x-amz-date", signature: "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_signer := sigv4_test_signer
+			_signer.Single_Encode = _test.single
+			_request := &htypes.AHTTPRequest{URL: "https://example.amazonaws.com" + _test.path, Headers: _test.headers, Body: []byte(_test.body)}
+			if _err := _signer.sign(sigv4_test_time, _test.method, _request); _err != nil {
+				t.Fatal(_err)
+			}
+
+			_signed := _test.signed
+			if _signed == "" {
+				_signed = "host;x-amz-date"
+			}
+			_want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+				_signed + ", Signature=" + _test.signature
+			if _got := _request.Headers[AUTHORIZATION_HEADER]; _got != _want {
+				t.Errorf("Authorization\n got %s\nwant %s", _got, _want)
+			}
+			if _got := _request.Headers[SIGV4_DATE_HEADER]; _got != "20150830T123600Z" {
+				t.Errorf("%s = %q, want 20150830T123600Z", SIGV4_DATE_HEADER, _got)
+			}
+		})
+	}
+}
+
+// TestCanonicalPath encodes the path twice for the services other than S3, as the example of the AWS documentation
+// (/documents and settings/ is /documents%2520and%2520settings/ in the canonical request)
+func TestCanonicalPath(t *testing.T) {
+
+	_tests := []struct {
+		url    string
+		double bool
+		want   string
+	}{
+		{url: "https://example.amazonaws.com/documents and settings/", double: true, want: "/documents%2520and%2520settings/"},
+		{url: "https://example.amazonaws.com/documents and settings/", double: false, want: "/documents%20and%20settings/"},
+		{url: "https://example.amazonaws.com/a%2Fb/c", double: true, want: "/a%252Fb/c"},
+		{url: "https://example.amazonaws.com/a%2Fb/c", double: false, want: "/a%2Fb/c"},
+		{url: "https://examplebucket.s3.amazonaws.com//photos/../a.jpg", double: false, want: "//photos/../a.jpg"},
+		{url: "https://example.amazonaws.com", double: true, want: "/"},
+	}
+	for _, _test := range _tests {
+		_url, _err := url.Parse(_test.url)
+		if _err != nil {
+			t.Fatal(_err)
+		}
+		if _got := canonical_path(_url, _test.double); _got != _test.want {
+			t.Errorf("canonical_path(%s, %v) = %s, want %s", _test.url, _test.double, _got, _test.want)
+		}
+	}
+}
+
+func TestSigV4S3(t *testing.T) {
+
+	_signer := sigv4_test_signer
+	_signer.Service = SIGV4_S3_SERVICE
+	_request := &htypes.AHTTPRequest{URL: "https://examplebucket.s3.amazonaws.com/photos/my photo.jpg"}
+	if _err := _signer.sign(sigv4_test_time, http.MethodGet, _request); _err != nil {
+		t.Fatal(_err)
+	}
+	if _got := _request.Headers[SIGV4_CONTENT_HEADER]; _got != sha256_hex(nil) {
+		t.Errorf("%s = %q, want the hash of the empty body", SIGV4_CONTENT_HEADER, _got)
+	}
+	if _got := _request.Headers[AUTHORIZATION_HEADER]; !strings.Contains(_got, "/s3/aws4_request, SignedHeaders=host;x-amz-content-sha256;x-amz-date,") {
+		t.Errorf("Authorization = %s, want the s3 scope and the signed x-amz-content-sha256", _got)
+	}
+}
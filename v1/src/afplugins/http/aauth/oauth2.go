@@ -0,0 +1,141 @@
+package aauth
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_REFRESH_BEFORE time before the expiry of the OAuth2 token when it is refreshed
+const DEFAULT_REFRESH_BEFORE = 60 * time.Second
+
+// DEFAULT_TOKEN_TIMEOUT time to wait for the token endpoint when the context has no deadline
+const DEFAULT_TOKEN_TIMEOUT = 30 * time.Second
+
+// OAuth2_Client_Credentials OAuth2 client credentials grant (RFC 6749 section 4.4).
+// The token is requested on the first request, cached and requested again Refresh_Before its expiry.
+type OAuth2_Client_Credentials struct {
+	Token_URL      string        // token endpoint
+	Client_ID      string        // client id, sent with the client secret as basic authentication
+	Client_Secret  string        // client secret
+	Scopes         []string      // scopes of the token, none if empty
+	Params         url.Values    // other parameters of the token request (e.g. audience)
+	Refresh_Before time.Duration // 0 = DEFAULT_REFRESH_BEFORE
+	Client         *http.Client  // client of the token requests, http.DefaultClient if nil
+
+	lock   sync.Mutex
+	token  string
+	expiry time.Time /// zero if the token does not expire
+}
+
+// token_response response of the token endpoint
+type token_response struct {
+	Access_Token string `json:"access_token"`
+	Token_Type   string `json:"token_type"`
+	Expires_In   int64  `json:"expires_in"`
+}
+
+func (p *OAuth2_Client_Credentials) Authorize(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) error {
+	_token, _err := p.Token(pCtx)
+	if _err != nil {
+		return _err
+	}
+	set_header(pRequest, AUTHORIZATION_HEADER, "Bearer "+_token)
+	return nil
+}
+
+// Token returns the cached token, a new token if there is none or it expires within Refresh_Before.
+// The concurrent requests wait for the same token request.
+//
+//	Returns an error matching aerrors.ErrAuthentication if the token endpoint fails
+func (p *OAuth2_Client_Credentials) Token(pCtx context.Context) (string, error) {
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	_refresh_before := p.Refresh_Before
+	if _refresh_before <= 0 {
+		_refresh_before = DEFAULT_REFRESH_BEFORE
+	}
+	if p.token != "" && (p.expiry.IsZero() || time.Until(p.expiry) > _refresh_before) {
+		return p.token, nil
+	}
+
+	_token, _err := p.request_token(pCtx)
+	if _err != nil {
+		return "", fmt.Errorf("oauth2 token of %s. %w. %w", p.Token_URL, aerrors.ErrAuthentication, _err)
+	}
+	p.token = _token.Access_Token
+	p.expiry = time.Time{}
+	if _token.Expires_In > 0 {
+		p.expiry = time.Now().Add(time.Duration(_token.Expires_In) * time.Second)
+	}
+	return p.token, nil
+}
+
+// Invalidate drops the cached token, the next request gets a new token
+func (p *OAuth2_Client_Credentials) Invalidate() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.token = ""
+	p.expiry = time.Time{}
+}
+
+// request_token requests a token from the token endpoint
+func (p *OAuth2_Client_Credentials) request_token(pCtx context.Context) (*token_response, error) {
+
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	if _, _ok := pCtx.Deadline(); !_ok {
+		var _cancel context.CancelFunc
+		pCtx, _cancel = context.WithTimeout(pCtx, DEFAULT_TOKEN_TIMEOUT)
+		defer _cancel()
+	}
+
+	_form := url.Values{"grant_type": {"client_credentials"}}
+	if len(p.Scopes) > 0 {
+		_form.Set("scope", strings.Join(p.Scopes, " "))
+	}
+	for _name, _values := range p.Params {
+		_form[_name] = _values
+	}
+
+	_request := &htypes.AHTTPRequest{
+		Method: http.MethodPost,
+		URL:    p.Token_URL,
+		Body:   []byte(_form.Encode()),
+		Headers: map[string]string{
+			"Content-Type": "application/x-www-form-urlencoded",
+			"Accept":       "application/json",
+		},
+	}
+	/// RFC 6749 section 2.3.1, the client id and secret are form encoded in the basic credentials
+	_basic := &Basic{Username: url.QueryEscape(p.Client_ID), Password: url.QueryEscape(p.Client_Secret)}
+	_basic.Authorize(pCtx, http.MethodPost, _request)
+
+	_response, _err := htypes.Do_HTTP(pCtx, p.Client, http.MethodPost, _request)
+	if _err != nil {
+		return nil, _err
+	}
+
+	_token := new(token_response)
+	if _err := json.Unmarshal(_response.Body, _token); _err != nil {
+		return nil, fmt.Errorf("invalid token response. %w", _err)
+	}
+	if _token.Access_Token == "" {
+		return nil, fmt.Errorf("token response has no access_token")
+	}
+	if _token.Token_Type != "" && !strings.EqualFold(_token.Token_Type, "bearer") {
+		return nil, fmt.Errorf("token type %s is not supported", _token.Token_Type)
+	}
+	return _token, nil
+}
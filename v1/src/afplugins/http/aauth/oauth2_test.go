@@ -0,0 +1,148 @@
+package aauth_test
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aauth"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// token_server token endpoint which issues token-1, token-2, ... valid for expires_in seconds
+func token_server(t *testing.T, pExpires_In int) (*httptest.Server, *atomic.Int32) {
+	_issued := new(atomic.Int32)
+	_server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_id, _secret, _ok := r.BasicAuth()
+		if !_ok || _id != "agnione" || _secret != "secret" {
+			http.Error(w, "invalid client", http.StatusUnauthorized)
+			return
+		}
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "orders.read orders.write" {
+			http.Error(w, "invalid request", http.StatusBadRequest)
+			return
+		}
+		_token := "token-" + strconv.Itoa(int(_issued.Add(1)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"access_token": _token, "token_type": "Bearer", "expires_in": pExpires_In})
+	}))
+	t.Cleanup(_server.Close)
+	return _server, _issued
+}
+
+func new_oauth2(pToken_URL string) *aauth.OAuth2_Client_Credentials {
+	return &aauth.OAuth2_Client_Credentials{
+		Token_URL:     pToken_URL,
+		Client_ID:     "agnione",
+		Client_Secret: "secret",
+		Scopes:        []string{"orders.read", "orders.write"},
+	}
+}
+
+func TestOAuth2Cached(t *testing.T) {
+	_server, _issued := token_server(t, 3600)
+	_provider := new_oauth2(_server.URL)
+
+	for _i := 0; _i < 3; _i++ {
+		_request := &htypes.AHTTPRequest{URL: "https://api.example.com/orders"}
+		if _err := _provider.Authorize(context.Background(), http.MethodGet, _request); _err != nil {
+			t.Fatal(_err)
+		}
+		if _got := _request.Headers[aauth.AUTHORIZATION_HEADER]; _got != "Bearer token-1" {
+			t.Fatalf("Authorization = %q, want Bearer token-1", _got)
+		}
+	}
+	if _issued.Load() != 1 {
+		t.Errorf("token requests = %d, want 1", _issued.Load())
+	}
+}
+
+func TestOAuth2RefreshBeforeExpiry(t *testing.T) {
+	/// the token expires within the default refresh time, every request gets a new token
+	_server, _issued := token_server(t, 30)
+	_provider := new_oauth2(_server.URL)
+
+	for _i := 1; _i <= 2; _i++ {
+		_token, _err := _provider.Token(context.Background())
+		if _err != nil {
+			t.Fatal(_err)
+		}
+		if _want := "token-" + strconv.Itoa(_i); _token != _want {
+			t.Errorf("Token() = %q, want %q", _token, _want)
+		}
+	}
+	if _issued.Load() != 2 {
+		t.Errorf("token requests = %d, want 2", _issued.Load())
+	}
+}
+
+func TestOAuth2Error(t *testing.T) {
+	_server, _ := token_server(t, 3600)
+	_provider := new_oauth2(_server.URL)
+	_provider.Client_Secret = "wrong"
+
+	if _, _err := _provider.Token(context.Background()); !errors.Is(_err, aerrors.ErrAuthentication) {
+		t.Errorf("Token() with a wrong secret = %v, want aerrors.ErrAuthentication", _err)
+	}
+}
+
+// token_client plugin which accepts only the given bearer token, 401 otherwise
+type token_client struct {
+	accepted string
+	sent     []string
+}
+
+func (c *token_client) New() interface{}                 { return &token_client{accepted: c.accepted} }
+func (c *token_client) GetID() int                       { return 1 }
+func (c *token_client) Initialize(pInstance_ID int) bool { return true }
+func (c *token_client) Info() build.BuildInfo            { return build.BuildInfo{Version: "test"} }
+
+func (c *token_client) Get(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	_authorization := pRequest.Headers[aauth.AUTHORIZATION_HEADER]
+	c.sent = append(c.sent, _authorization)
+	if _authorization != "Bearer "+c.accepted {
+		return &htypes.AHTTPResponse{StatusCode: http.StatusUnauthorized},
+			&aerrors.HTTPStatusError{Method: http.MethodGet, URL: pRequest.URL, StatusCode: http.StatusUnauthorized}
+	}
+	return &htypes.AHTTPResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (c *token_client) Post(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *token_client) Put(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *token_client) Delete(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func TestOAuth2RevokedToken(t *testing.T) {
+	_server, _issued := token_server(t, 3600)
+	_provider := new_oauth2(_server.URL)
+	if _, _err := _provider.Token(context.Background()); _err != nil {
+		t.Fatal(_err)
+	}
+
+	/// token-1 is revoked by the upstream before its expiry
+	_plugin := &token_client{accepted: "token-2"}
+	_response, _err := aauth.Wrap_HTTPClient(_plugin, _provider).Get(&htypes.AHTTPRequest{URL: "https://api.example.com/orders"})
+	if _err != nil || _response.StatusCode != http.StatusOK {
+		t.Fatalf("Get() = %v, %v, want 200 with a new token", _response, _err)
+	}
+	if len(_plugin.sent) != 2 || _plugin.sent[0] != "Bearer token-1" || _plugin.sent[1] != "Bearer token-2" {
+		t.Errorf("sent authorizations = %v, want [Bearer token-1 Bearer token-2]", _plugin.sent)
+	}
+	if _issued.Load() != 2 {
+		t.Errorf("token requests = %d, want 2", _issued.Load())
+	}
+}
@@ -0,0 +1,203 @@
+// aintercept package provides the interceptor chains around the requests of the http client plugins of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Interceptor, Handler, Chain, New_Chain, Client_Name
+//
+//   - Logging, Redactor, Metrics, Headers, Header_Func, Cache, New_Cache
+//
+//   - HTTP_Client, Wrap_HTTPClient
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   aintercept - AgniOne Application Framework
+//     Objective	:   Apply the cross-cutting behaviour of the http requests once instead of in every unit
+//     ---------------------------------------------------------------------------------------------------------------------
+//     A Chain keeps the interceptors by name, ordered by their order and then by their registration. HTTP_Client wraps
+//     a http client plugin with a chain: every request calls the first interceptor, which calls the next one with
+//     pNext, the last one calls the plugin. An interceptor can change the request, the response and the error, or
+//     return without calling pNext (a cached response). The interceptors added to the chain after the client was
+//     created are used by its next requests. The framework chain is applied by Get_RESTClient of the framework, the
+//     chain of a unit by Get_RESTClient of AUBase, which calls the interceptors of the unit before the ones of
+//     the framework.
+//     ---------------------------------------------------------------------------------------------------------------------
+package aintercept
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// orders of the built-in interceptors, the lower orders are called first
+const (
+	ORDER_LOGGING = 100 // sees the cached responses and the injected headers of the unit
+	ORDER_METRICS = 200
+	ORDER_CACHE   = 300
+	ORDER_HEADERS = 400 // the injected headers are not a part of the cache key
+)
+
+// Handler performs the request with the given method, the next interceptor of the chain or the plugin
+type Handler func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)
+
+// Interceptor is called around the requests of the clients of a chain, it calls pNext to continue the request.
+// pCtx is the context of Do or a background context, carrying the Client_Name. pRequest can be nil.
+type Interceptor func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pNext Handler) (*htypes.AHTTPResponse, error)
+
+// entry interceptor of a chain
+type entry struct {
+	name        string
+	order       int
+	seq         uint64 /// registration sequence
+	interceptor Interceptor
+}
+
+// Chain ordered interceptors shared by the clients wrapped with it
+type Chain struct {
+	lock    sync.Mutex
+	seq     uint64
+	entries atomic.Pointer[[]entry] /// sorted, replaced on every change so the requests read it without a lock
+}
+
+// New_Chain creates an empty chain
+func New_Chain() *Chain {
+	_chain := &Chain{}
+	_chain.entries.Store(&[]entry{})
+	return _chain
+}
+
+// Add adds the interceptor with the given name. The interceptors are called by their order (lower first),
+// the interceptors with the same order by their registration.
+//
+//	Returns aerrors.ErrNilArgument if the interceptor is nil, aerrors.ErrInvalidArgument if the name is empty or added
+func (c *Chain) Add(pName string, pOrder int, pInterceptor Interceptor) error {
+
+	if pInterceptor == nil {
+		return fmt.Errorf("http interceptor %s %w", pName, aerrors.ErrNilArgument)
+	}
+	if pName == "" {
+		return fmt.Errorf("http interceptor name. %w", aerrors.ErrInvalidArgument)
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_current := *c.entries.Load()
+	for _, _entry := range _current {
+		if _entry.name == pName {
+			return fmt.Errorf("http interceptor %s is already added. %w", pName, aerrors.ErrInvalidArgument)
+		}
+	}
+
+	c.seq++
+	_entries := make([]entry, 0, len(_current)+1)
+	_entries = append(_entries, _current...)
+	_entries = append(_entries, entry{name: pName, order: pOrder, seq: c.seq, interceptor: pInterceptor})
+	sort.Slice(_entries, func(i, j int) bool {
+		if _entries[i].order != _entries[j].order {
+			return _entries[i].order < _entries[j].order
+		}
+		return _entries[i].seq < _entries[j].seq
+	})
+	c.entries.Store(&_entries)
+	return nil
+}
+
+// Remove removes the interceptor with the given name.
+//
+//	Returns false if the name is not added
+func (c *Chain) Remove(pName string) bool {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_current := *c.entries.Load()
+	_entries := make([]entry, 0, len(_current))
+	for _, _entry := range _current {
+		if _entry.name != pName {
+			_entries = append(_entries, _entry)
+		}
+	}
+	if len(_entries) == len(_current) {
+		return false
+	}
+	c.entries.Store(&_entries)
+	return true
+}
+
+// Names returns the names of the interceptors in the order they are called
+func (c *Chain) Names() []string {
+	_entries := *c.entries.Load()
+	_names := make([]string, len(_entries))
+	for _i, _entry := range _entries {
+		_names[_i] = _entry.name
+	}
+	return _names
+}
+
+// Handler returns the handler calling the current interceptors of the chain and then pLast
+func (c *Chain) Handler(pLast Handler) Handler {
+	_handler := pLast
+	_entries := *c.entries.Load()
+	for _i := len(_entries) - 1; _i >= 0; _i-- {
+		_interceptor, _next := _entries[_i].interceptor, _handler
+		_handler = func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+			return _interceptor(pCtx, pMethod, pRequest, _next)
+		}
+	}
+	return _handler
+}
+
+// client_key context key of the name of the client
+type client_key struct{}
+
+// Client_Name returns the name (plugin type) of the client performing the request of the interceptor
+func Client_Name(pCtx context.Context) string {
+	if pCtx == nil {
+		return ""
+	}
+	_name, _ := pCtx.Value(client_key{}).(string)
+	return _name
+}
+
+// Headers returns the interceptor which sets the given headers of the requests which do not have them
+func Headers(pHeaders map[string]string) Interceptor {
+	return Header_Func(func(context.Context, *htypes.AHTTPRequest) map[string]string {
+		return pHeaders
+	})
+}
+
+// Header_Func returns the interceptor which sets the headers returned by pHeaders for every request
+// (e.g. a request id) when the request does not have them. The request of the caller is not changed.
+func Header_Func(pHeaders func(pCtx context.Context, pRequest *htypes.AHTTPRequest) map[string]string) Interceptor {
+	return func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pNext Handler) (*htypes.AHTTPResponse, error) {
+		if pRequest == nil {
+			return pNext(pCtx, pMethod, pRequest)
+		}
+		_current := pRequest.Header()
+		var _headers map[string]string
+		for _name, _value := range pHeaders(pCtx, pRequest) {
+			if _current.Get(_name) != "" {
+				continue
+			}
+			if _headers == nil {
+				_headers = make(map[string]string, len(pRequest.Headers)+1)
+				for _existing, _existing_value := range pRequest.Headers {
+					_headers[_existing] = _existing_value
+				}
+			}
+			_headers[_name] = _value
+		}
+		if _headers == nil {
+			return pNext(pCtx, pMethod, pRequest)
+		}
+		_request := *pRequest
+		_request.Headers = _headers
+		return pNext(pCtx, pMethod, &_request)
+	}
+}
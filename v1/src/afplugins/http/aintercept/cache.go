@@ -0,0 +1,223 @@
+package aintercept
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// default settings of the cache
+const (
+	DEFAULT_CACHE_TTL     = time.Minute
+	DEFAULT_CACHE_ENTRIES = 1000
+)
+
+// DEFAULT_CACHE_VARY request headers of the cache key when the cache has no Vary
+var DEFAULT_CACHE_VARY = []string{"Accept", "Accept-Encoding", "Authorization"}
+
+// Cache in memory cache of the responses of the GET and HEAD requests, shared by the clients of its interceptor.
+//
+// The responses are stored by the client (the plugin type) of the request, so a client does not get the responses
+// of the credentials of another client: the interceptors run before the auth provider of the client, which adds
+// its credentials after the cache key is computed. The Authorization set by the caller is in DEFAULT_CACHE_VARY.
+//
+// Only the 200 responses which are not streamed are stored, for the max-age of their Cache-Control or TTL, and
+// not with no-store, no-cache or private. A request with Cache-Control no-cache or no-store is sent to the upstream.
+// The least recently used response is dropped when the cache has Max_Entries responses.
+type Cache struct {
+	TTL         time.Duration // time a response without max-age is used (0 = DEFAULT_CACHE_TTL)
+	Max_Entries int           // stored responses (0 = DEFAULT_CACHE_ENTRIES)
+	Vary        []string      // request headers of the cache key besides the method and the url, DEFAULT_CACHE_VARY if nil
+
+	lock    sync.Mutex
+	entries map[[sha256.Size]byte]*list.Element
+	lru     list.List /// of *cache_entry, most recently used first
+	hits    atomic.Uint64
+	misses  atomic.Uint64
+}
+
+// cache_entry stored response
+type cache_entry struct {
+	key      [sha256.Size]byte
+	response *htypes.AHTTPResponse
+	expires  time.Time
+}
+
+// New_Cache creates a cache with the given TTL and max entries (0 = defaults)
+func New_Cache(pTTL time.Duration, pMax_Entries int) *Cache {
+	return &Cache{TTL: pTTL, Max_Entries: pMax_Entries}
+}
+
+// Interceptor returns the interceptor which answers the requests from the cache
+func (c *Cache) Interceptor() Interceptor {
+	return func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pNext Handler) (*htypes.AHTTPResponse, error) {
+
+		if pRequest == nil || (pMethod != http.MethodGet && pMethod != http.MethodHead) || pRequest.Stream || pRequest.Body_Reader != nil {
+			return pNext(pCtx, pMethod, pRequest)
+		}
+		_key, _ok := c.key(pCtx, pMethod, pRequest)
+		if !_ok {
+			return pNext(pCtx, pMethod, pRequest)
+		}
+
+		_directives := pRequest.Header().Get("Cache-Control")
+		if !has_directive(_directives, "no-cache") && !has_directive(_directives, "no-store") {
+			if _response := c.get(_key); _response != nil {
+				c.hits.Add(1)
+				return _response, nil
+			}
+		}
+		c.misses.Add(1)
+
+		_response, _err := pNext(pCtx, pMethod, pRequest)
+		if _err == nil && !has_directive(_directives, "no-store") {
+			c.put(_key, _response)
+		}
+		return _response, _err
+	}
+}
+
+// Stats returns the hits and the misses of the cache
+func (c *Cache) Stats() (uint64, uint64) {
+	return c.hits.Load(), c.misses.Load()
+}
+
+// Purge drops the stored responses
+func (c *Cache) Purge() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.entries = nil
+	c.lru.Init()
+}
+
+// key returns the cache key of the request of the client of pCtx, false if its url is not valid
+func (c *Cache) key(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest) ([sha256.Size]byte, bool) {
+
+	_url, _err := pRequest.Full_URL()
+	if _err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	_vary := c.Vary
+	if _vary == nil {
+		_vary = DEFAULT_CACHE_VARY
+	}
+
+	/// the key is hashed, so the credentials of the vary headers are not kept
+	_header := pRequest.Header()
+	var _buffer strings.Builder
+	_buffer.WriteString(Client_Name(pCtx) + "\n" + pMethod + " " + _url)
+	for _, _name := range _vary {
+		_buffer.WriteString("\n" + _name + ": " + strings.Join(_header.Values(_name), ", "))
+	}
+	return sha256.Sum256([]byte(_buffer.String())), true
+}
+
+// get returns a copy of the stored response, nil if none or expired
+func (c *Cache) get(pKey [sha256.Size]byte) *htypes.AHTTPResponse {
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	_element, _ok := c.entries[pKey]
+	if !_ok {
+		return nil
+	}
+	_entry := _element.Value.(*cache_entry)
+	if time.Now().After(_entry.expires) {
+		c.lru.Remove(_element)
+		delete(c.entries, pKey)
+		return nil
+	}
+	c.lru.MoveToFront(_element)
+	return copy_response(_entry.response)
+}
+
+// put stores a copy of the response if it can be cached
+func (c *Cache) put(pKey [sha256.Size]byte, pResponse *htypes.AHTTPResponse) {
+
+	if pResponse == nil || pResponse.StatusCode != http.StatusOK || pResponse.Body_Stream != nil {
+		return
+	}
+	_ttl := c.TTL
+	if _ttl <= 0 {
+		_ttl = DEFAULT_CACHE_TTL
+	}
+	_directives := http.Header(pResponse.Header_Values).Get("Cache-Control")
+	if _directives == "" {
+		_directives = pResponse.Headers["Cache-Control"]
+	}
+	/// private responses are for the identity of the request, the cache is shared by the callers of the client
+	if has_directive(_directives, "no-store") || has_directive(_directives, "no-cache") || has_directive(_directives, "private") {
+		return
+	}
+	if _max_age, _ok := directive_value(_directives, "max-age"); _ok {
+		_seconds, _err := strconv.Atoi(_max_age)
+		if _err != nil || _seconds <= 0 {
+			return
+		}
+		_ttl = time.Duration(_seconds) * time.Second
+	}
+
+	_max := c.Max_Entries
+	if _max <= 0 {
+		_max = DEFAULT_CACHE_ENTRIES
+	}
+	_entry := &cache_entry{key: pKey, response: copy_response(pResponse), expires: time.Now().Add(_ttl)}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[[sha256.Size]byte]*list.Element)
+	}
+	if _element, _ok := c.entries[pKey]; _ok {
+		c.lru.Remove(_element)
+	}
+	c.entries[pKey] = c.lru.PushFront(_entry)
+	for c.lru.Len() > _max {
+		_oldest := c.lru.Back()
+		c.lru.Remove(_oldest)
+		delete(c.entries, _oldest.Value.(*cache_entry).key)
+	}
+}
+
+// copy_response returns a copy of the response, the caller can change it
+func copy_response(pResponse *htypes.AHTTPResponse) *htypes.AHTTPResponse {
+	_copy := *pResponse
+	_copy.Body = append([]byte(nil), pResponse.Body...)
+	_copy.Headers = make(map[string]string, len(pResponse.Headers))
+	for _name, _value := range pResponse.Headers {
+		_copy.Headers[_name] = _value
+	}
+	if pResponse.Header_Values != nil {
+		_copy.Header_Values = make(map[string][]string, len(pResponse.Header_Values))
+		for _name, _values := range pResponse.Header_Values {
+			_copy.Header_Values[_name] = append([]string(nil), _values...)
+		}
+	}
+	return &_copy
+}
+
+// has_directive returns true if the Cache-Control has the directive
+func has_directive(pDirectives string, pName string) bool {
+	_, _ok := directive_value(pDirectives, pName)
+	return _ok
+}
+
+// directive_value returns the value of the directive of the Cache-Control, false if it has not the directive
+func directive_value(pDirectives string, pName string) (string, bool) {
+	for _, _directive := range strings.Split(pDirectives, ",") {
+		_name, _value, _ := strings.Cut(strings.TrimSpace(_directive), "=")
+		if strings.EqualFold(_name, pName) {
+			return strings.Trim(_value, `"`), true
+		}
+	}
+	return "", false
+}
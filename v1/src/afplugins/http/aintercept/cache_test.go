@@ -0,0 +1,98 @@
+package aintercept_test
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+
+	"net/http"
+	"testing"
+)
+
+// response_client plugin which answers every request with 200 and its body, the given response headers
+type response_client struct {
+	body    string
+	headers map[string]string
+	calls   int
+}
+
+func (c *response_client) New() interface{} {
+	return &response_client{body: c.body, headers: c.headers}
+}
+func (c *response_client) GetID() int                       { return 1 }
+func (c *response_client) Initialize(pInstance_ID int) bool { return true }
+func (c *response_client) Info() build.BuildInfo            { return build.BuildInfo{Version: "test"} }
+
+func (c *response_client) Get(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	c.calls++
+	return &htypes.AHTTPResponse{StatusCode: http.StatusOK, Headers: c.headers, Body: []byte(c.body)}, nil
+}
+
+func (c *response_client) Post(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *response_client) Put(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *response_client) Delete(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func new_cache_chain(t *testing.T) *aintercept.Chain {
+	_chain := aintercept.New_Chain()
+	if _err := _chain.Add("cache", aintercept.ORDER_CACHE, aintercept.New_Cache(0, 0).Interceptor()); _err != nil {
+		t.Fatal(_err)
+	}
+	return _chain
+}
+
+func TestCacheHit(t *testing.T) {
+	_plugin := &response_client{body: "orders"}
+	_client := aintercept.Wrap_HTTPClient("rest", _plugin, new_cache_chain(t))
+
+	for _i := 0; _i < 2; _i++ {
+		_response, _err := _client.Get(&htypes.AHTTPRequest{URL: "http://localhost/orders"})
+		if _err != nil || string(_response.Body) != "orders" {
+			t.Fatalf("Get() = %v, %v, want the body orders", _response, _err)
+		}
+	}
+	if _plugin.calls != 1 {
+		t.Errorf("calls of the plugin = %d, want 1", _plugin.calls)
+	}
+}
+
+// TestCacheClients the clients of a chain (the plugin types with their own credentials) do not share the responses
+func TestCacheClients(t *testing.T) {
+	_chain := new_cache_chain(t)
+	_tenant_a := &response_client{body: "orders of a"}
+	_tenant_b := &response_client{body: "orders of b"}
+	_client_a := aintercept.Wrap_HTTPClient("tenant-a", _tenant_a, _chain)
+	_client_b := aintercept.Wrap_HTTPClient("tenant-b", _tenant_b, _chain)
+
+	if _, _err := _client_a.Get(&htypes.AHTTPRequest{URL: "http://localhost/orders"}); _err != nil {
+		t.Fatal(_err)
+	}
+	_response, _err := _client_b.Get(&htypes.AHTTPRequest{URL: "http://localhost/orders"})
+	if _err != nil || string(_response.Body) != "orders of b" {
+		t.Fatalf("Get() of tenant-b = %v, %v, want the body orders of b", _response, _err)
+	}
+	if _tenant_a.calls != 1 || _tenant_b.calls != 1 {
+		t.Errorf("calls of the plugins = %d, %d, want 1, 1", _tenant_a.calls, _tenant_b.calls)
+	}
+}
+
+func TestCachePrivate(t *testing.T) {
+	_plugin := &response_client{body: "profile", headers: map[string]string{"Cache-Control": "private, max-age=60"}}
+	_client := aintercept.Wrap_HTTPClient("rest", _plugin, new_cache_chain(t))
+
+	for _i := 0; _i < 2; _i++ {
+		if _, _err := _client.Get(&htypes.AHTTPRequest{URL: "http://localhost/profile"}); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+	if _plugin.calls != 2 {
+		t.Errorf("calls of the plugin = %d, want 2, a private response is not stored", _plugin.calls)
+	}
+}
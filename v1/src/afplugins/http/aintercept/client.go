@@ -0,0 +1,104 @@
+package aintercept
+
+import (
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+
+	"context"
+	"net/http"
+)
+
+// HTTP_Client http client plugin which performs the requests through the interceptors of its chain
+type HTTP_Client struct {
+	ihttp.IAHTTPClient
+	name  string
+	chain *Chain
+}
+
+// make sure HTTP_Client satisfies the plugin interface
+var _ ihttp.IAHTTPClient = (*HTTP_Client)(nil)
+var _ ihttp.IAHTTPClientDo = (*HTTP_Client)(nil)
+
+// Wrap_HTTPClient returns the client performing the requests of the http client plugin through the interceptors
+// of the chain. pName (the plugin type) is given to the interceptors with Client_Name.
+func Wrap_HTTPClient(pName string, pClient ihttp.IAHTTPClient, pChain *Chain) *HTTP_Client {
+	if pClient == nil {
+		return nil
+	}
+	if pChain == nil {
+		pChain = New_Chain()
+	}
+	return &HTTP_Client{IAHTTPClient: pClient, name: pName, chain: pChain}
+}
+
+// New creates a new instance of the plugin with the chain of the client
+func (c *HTTP_Client) New() interface{} {
+	_client, _ok := c.IAHTTPClient.New().(ihttp.IAHTTPClient)
+	if !_ok {
+		return nil
+	}
+	return Wrap_HTTPClient(c.name, _client, c.chain)
+}
+
+// Unwrap returns the http client plugin
+func (c *HTTP_Client) Unwrap() ihttp.IAHTTPClient {
+	return c.IAHTTPClient
+}
+
+// Chain returns the chain of the client
+func (c *HTTP_Client) Chain() *Chain {
+	return c.chain
+}
+
+func (c *HTTP_Client) Get(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodGet, pHTTP_Request, call(c.IAHTTPClient.Get))
+}
+
+func (c *HTTP_Client) Post(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPost, pHTTP_Request, call(c.IAHTTPClient.Post))
+}
+
+func (c *HTTP_Client) Put(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPut, pHTTP_Request, call(c.IAHTTPClient.Put))
+}
+
+func (c *HTTP_Client) Delete(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodDelete, pHTTP_Request, call(c.IAHTTPClient.Delete))
+}
+
+func (c *HTTP_Client) Patch(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPatch, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodPatch)))
+}
+
+func (c *HTTP_Client) Head(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodHead, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodHead)))
+}
+
+func (c *HTTP_Client) Options(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodOptions, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodOptions)))
+}
+
+func (c *HTTP_Client) Do(pCtx context.Context, pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	_method := http.MethodGet
+	if pHTTP_Request != nil && pHTTP_Request.Method != "" {
+		_method = pHTTP_Request.Method
+	}
+	return c.execute(pCtx, _method, pHTTP_Request, func(pCtx context.Context, _ string, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return ihttp.Do(pCtx, c.IAHTTPClient, pRequest)
+	})
+}
+
+// call returns the plugin function as the last handler of the chain
+func call(pFunc func(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) Handler {
+	return func(_ context.Context, _ string, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return pFunc(pRequest)
+	}
+}
+
+// execute performs the request through the interceptors of the chain and then the given plugin function
+func (c *HTTP_Client) execute(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pCall Handler) (*htypes.AHTTPResponse, error) {
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	return c.chain.Handler(pCall)(context.WithValue(pCtx, client_key{}, c.name), pMethod, pRequest)
+}
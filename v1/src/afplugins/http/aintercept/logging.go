@@ -0,0 +1,222 @@
+package aintercept
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// REDACTED value of the redacted headers and fields
+const REDACTED = "***"
+
+// DEFAULT_MAX_LOG_BODY bytes of a body written to the log when the Redactor has no Max_Body
+const DEFAULT_MAX_LOG_BODY = 1024
+
+// DEFAULT_REDACT_HEADERS headers redacted when the Redactor has no Headers
+var DEFAULT_REDACT_HEADERS = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	"X-Api-Key", "X-Amz-Security-Token"}
+
+// DEFAULT_REDACT_FIELDS json and form fields redacted when the Redactor has no Fields
+var DEFAULT_REDACT_FIELDS = []string{"password", "secret", "token", "access_token", "refresh_token", "id_token",
+	"client_secret", "api_key", "authorization"}
+
+// Redactor removes the secrets of the headers and the bodies written to the log
+type Redactor struct {
+	Headers  []string // redacted headers (any case), DEFAULT_REDACT_HEADERS if nil
+	Fields   []string // redacted fields (any case) of the json (any depth) and form bodies, DEFAULT_REDACT_FIELDS if nil
+	Max_Body int      // logged bytes of a body (0 = DEFAULT_MAX_LOG_BODY, negative = the bodies are not logged)
+	Raw_Body bool     // log the bodies which can not be redacted (not json or form), only their size is logged if false
+}
+
+// Redact_Headers returns the headers as name: value lines ordered by name, with the values of the redacted headers replaced
+func (r *Redactor) Redact_Headers(pHeader http.Header) string {
+
+	_names := make([]string, 0, len(pHeader))
+	for _name := range pHeader {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+
+	_redacted := r.Headers
+	if _redacted == nil {
+		_redacted = DEFAULT_REDACT_HEADERS
+	}
+	var _buffer strings.Builder
+	for _, _name := range _names {
+		_value := strings.Join(pHeader[_name], ", ")
+		if contains_fold(_redacted, _name) {
+			_value = REDACTED
+		}
+		fmt.Fprintf(&_buffer, "\n  %s: %s", _name, _value)
+	}
+	return _buffer.String()
+}
+
+// Redact_Body returns the body with the values of the redacted fields replaced, cut at Max_Body.
+// The json and form bodies are redacted by their content type. The other bodies (and the json and form bodies
+// which can not be parsed) are not redacted, only their size and content type are returned unless Raw_Body is set.
+func (r *Redactor) Redact_Body(pContent_Type string, pBody []byte) string {
+
+	_max := r.Max_Body
+	if _max == 0 {
+		_max = DEFAULT_MAX_LOG_BODY
+	}
+	if _max < 0 || len(pBody) == 0 {
+		return ""
+	}
+
+	_fields := r.Fields
+	if _fields == nil {
+		_fields = DEFAULT_REDACT_FIELDS
+	}
+	_media, _, _ := mime.ParseMediaType(pContent_Type)
+	_redacted := false
+	switch {
+	case _media == "application/json" || strings.HasSuffix(_media, "+json"):
+		_decoder := json.NewDecoder(bytes.NewReader(pBody))
+		_decoder.UseNumber()
+		var _value any
+		if _decoder.Decode(&_value) == nil {
+			if _body, _err := json.Marshal(redact_value(_value, _fields)); _err == nil {
+				pBody, _redacted = _body, true
+			}
+		}
+	case _media == "application/x-www-form-urlencoded":
+		if _form, _err := url.ParseQuery(string(pBody)); _err == nil {
+			for _name := range _form {
+				if contains_fold(_fields, _name) {
+					_form[_name] = []string{REDACTED}
+				}
+			}
+			pBody, _redacted = []byte(_form.Encode()), true
+		}
+	}
+	if !_redacted && !r.Raw_Body {
+		return "(" + strings.TrimSpace(fmt.Sprintf("%d bytes %s", len(pBody), pContent_Type)) + ")"
+	}
+
+	if len(pBody) > _max {
+		return string(pBody[:_max]) + fmt.Sprintf("...(%d bytes)", len(pBody))
+	}
+	return string(pBody)
+}
+
+// redact_value replaces the values of the redacted fields of the decoded json
+func redact_value(pValue any, pFields []string) any {
+	switch _value := pValue.(type) {
+	case map[string]any:
+		for _name, _field := range _value {
+			if contains_fold(pFields, _name) {
+				_value[_name] = REDACTED
+			} else {
+				_value[_name] = redact_value(_field, pFields)
+			}
+		}
+	case []any:
+		for _i, _item := range _value {
+			_value[_i] = redact_value(_item, pFields)
+		}
+	}
+	return pValue
+}
+
+func contains_fold(pValues []string, pValue string) bool {
+	for _, _value := range pValues {
+		if strings.EqualFold(_value, pValue) {
+			return true
+		}
+	}
+	return false
+}
+
+// Logging returns the interceptor which writes a line of every request to pLog (Write2Log of the framework or
+// the unit): the client, the method, the url without the query, the status and the time. The failed requests
+// (error or status >= 500) are written with LOG_ERROR, the others with pLevel. When pRedactor is not nil the
+// headers and the bodies (not the streams) are written too, with the secrets redacted.
+func Logging(pLog func(pEntry string, pLog_Level atypes.LogLevel), pLevel atypes.LogLevel, pRedactor *Redactor) Interceptor {
+	return func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pNext Handler) (*htypes.AHTTPResponse, error) {
+
+		_started := time.Now()
+		var _request_details string
+		if pRedactor != nil && pRequest != nil {
+			/// the request is logged as it is sent by this interceptor, the interceptors after it may change it
+			_header := pRequest.Header()
+			_request_details = pRedactor.Redact_Headers(_header)
+			if pRequest.Body_Reader == nil {
+				if _body := pRedactor.Redact_Body(_header.Get("Content-Type"), pRequest.Body); _body != "" {
+					_request_details += "\n  " + _body
+				}
+			}
+		}
+
+		_response, _err := pNext(pCtx, pMethod, pRequest)
+
+		_url := ""
+		if pRequest != nil {
+			_url = log_url(pRequest.URL)
+		}
+		_entry := fmt.Sprintf("http client %s - %s %s", Client_Name(pCtx), pMethod, _url)
+		_level := pLevel
+		if _response != nil {
+			_entry += fmt.Sprintf(" -> %d", _response.StatusCode)
+			if _response.StatusCode >= http.StatusInternalServerError {
+				_level = atypes.LOG_ERROR
+			}
+		}
+		_entry += fmt.Sprintf(" (%s)", time.Since(_started).Round(time.Microsecond))
+		if _err != nil {
+			_entry += ". " + log_error(_err)
+			_level = atypes.LOG_ERROR
+		}
+
+		if pRedactor != nil {
+			_entry += _request_details
+			if _response != nil {
+				_header := http.Header(_response.Header_Values)
+				if len(_header) == 0 {
+					_header = make(http.Header, len(_response.Headers))
+					for _name, _value := range _response.Headers {
+						_header.Set(_name, _value)
+					}
+				}
+				_entry += "\n  --" + pRedactor.Redact_Headers(_header)
+				if _body := pRedactor.Redact_Body(_header.Get("Content-Type"), _response.Body); _body != "" {
+					_entry += "\n  " + _body
+				}
+			}
+		}
+		pLog(_entry, _level)
+		return _response, _err
+	}
+}
+
+// error_url urls of the error texts, the errors of the plugins and the wrappers have the url of the request
+var error_url = regexp.MustCompile(`(?i)\bhttps?://[^\s"]+`)
+
+// log_error returns the text of the error with the urls without the query and the user info
+func log_error(pErr error) string {
+	return error_url.ReplaceAllStringFunc(pErr.Error(), log_url)
+}
+
+// log_url returns the url without the query and the user info
+func log_url(pURL string) string {
+	_url, _err := url.Parse(pURL)
+	if _err != nil {
+		return "(invalid url)"
+	}
+	_url.User = nil
+	_url.RawQuery = ""
+	_url.Fragment = ""
+	return _url.String()
+}
@@ -0,0 +1,49 @@
+package aintercept
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+
+	"context"
+	"strconv"
+	"time"
+)
+
+// metrics of the Metrics interceptor
+const (
+	METRIC_REQUESTS = "agnione_http_client_requests_total"
+	METRIC_DURATION = "agnione_http_client_request_duration_seconds"
+)
+
+// Metrics returns the interceptor which counts the requests in the registry (Metrics() of the framework):
+// METRIC_REQUESTS by client, method and code (the status or "error" without a response) and
+// METRIC_DURATION by client and method. pLabels are added to the labels (e.g. ametrics.Unit_Labels of a unit).
+func Metrics(pRegistry *ametrics.Registry, pLabels ametrics.Labels) Interceptor {
+	return func(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest, pNext Handler) (*htypes.AHTTPResponse, error) {
+
+		_started := time.Now()
+		_response, _err := pNext(pCtx, pMethod, pRequest)
+		if pRegistry == nil {
+			return _response, _err
+		}
+
+		_labels := make(ametrics.Labels, len(pLabels)+3)
+		for _name, _value := range pLabels {
+			_labels[_name] = _value
+		}
+		_labels["client"] = Client_Name(pCtx)
+		_labels["method"] = pMethod
+		if _histogram, _hist_err := pRegistry.Histogram(METRIC_DURATION, "Duration of the http client requests", nil, _labels); _hist_err == nil {
+			_histogram.Observe(time.Since(_started).Seconds())
+		}
+
+		_labels["code"] = "error"
+		if _response != nil {
+			_labels["code"] = strconv.Itoa(_response.StatusCode)
+		}
+		if _counter, _count_err := pRegistry.Counter(METRIC_REQUESTS, "Requests of the http clients", _labels); _count_err == nil {
+			_counter.Inc()
+		}
+		return _response, _err
+	}
+}
@@ -0,0 +1,230 @@
+// aresilience package provides the retry and circuit breaker policies of the http client plugins of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Policy, Policy_From_Config, With_Policy, Policy_From
+//
+//   - Breaker, New_Breaker, Breaker_Config, BreakerState
+//
+//   - HTTP_Client, Wrap_HTTPClient
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   aresilience - AgniOne Application Framework
+//     Objective	:   Retry the failed requests of the http clients and stop calling the failing upstreams
+//     ---------------------------------------------------------------------------------------------------------------------
+//     HTTP_Client wraps a http client plugin with a Policy, configured by the resilience section of the plugin in
+//     plugins.http of the FMConfig. A failed request is retried up to Max_Attempts with an exponential backoff with
+//     jitter when the failure matches Retry_On and the request is idempotent. The circuit breaker of the client opens
+//     after Threshold consecutive failures, rejects the requests with aerrors.ErrCircuitOpen for Open_Time, then lets
+//     half-open probes through and closes after Probes successful probes.
+//     ---------------------------------------------------------------------------------------------------------------------
+package aresilience
+
+import (
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default settings of the policies
+const (
+	DEFAULT_BACKOFF     = 100 * time.Millisecond
+	DEFAULT_MAX_BACKOFF = 10 * time.Second
+	DEFAULT_MULTIPLIER  = 2.0
+	DEFAULT_JITTER      = 0.5
+	DEFAULT_OPEN_TIME   = 30 * time.Second
+)
+
+// failures of the Retry_On of a policy, besides the status codes (503) and classes (5xx)
+const (
+	RETRY_NETWORK = "network" // the request failed without a response (connection refused, reset, timeout)
+)
+
+// IDEMPOTENCY_KEY_HEADER header which makes a POST or PATCH request safe to retry
+const IDEMPOTENCY_KEY_HEADER = "Idempotency-Key"
+
+// default_retry_on failures retried when Retry_On is empty
+var default_retry_on = []string{RETRY_NETWORK, "408", "429", "502", "503", "504"}
+
+// Policy retry and circuit breaker policy of a http client
+type Policy struct {
+	Max_Attempts         int            // attempts of a request including the first, 1 = no retry
+	Backoff              time.Duration  // wait before the first retry (0 = DEFAULT_BACKOFF)
+	Max_Backoff          time.Duration  // max wait between the attempts (0 = DEFAULT_MAX_BACKOFF)
+	Multiplier           float64        // growth of the backoff for every retry (0 = DEFAULT_MULTIPLIER)
+	Jitter               float64        // random part of the backoff 0..1 (0 = DEFAULT_JITTER, negative = none)
+	Retry_On             []string       // RETRY_NETWORK, status codes or classes (5xx), default_retry_on if empty
+	Retry_Non_Idempotent bool           // retry POST and PATCH without the IDEMPOTENCY_KEY_HEADER
+	Breaker              Breaker_Config // circuit breaker of the client
+}
+
+// Policy_From_Config returns the policy of the resilience section of a http plugin config
+func Policy_From_Config(pConfig atypes.Resilienceconfig) Policy {
+	return Policy{
+		Max_Attempts:         pConfig.MaxAttempts,
+		Backoff:              time.Duration(pConfig.Backoff) * time.Millisecond,
+		Max_Backoff:          time.Duration(pConfig.MaxBackoff) * time.Millisecond,
+		Multiplier:           pConfig.BackoffMultiplier,
+		Jitter:               pConfig.Jitter,
+		Retry_On:             pConfig.RetryOn,
+		Retry_Non_Idempotent: pConfig.RetryNonIdempotent == 1,
+		Breaker: Breaker_Config{
+			Threshold: pConfig.BreakerThreshold,
+			Open_Time: time.Duration(pConfig.BreakerOpenTime) * time.Millisecond,
+			Probes:    pConfig.BreakerProbes,
+		},
+	}
+}
+
+// Is_Enabled returns true if the policy retries the requests or has a circuit breaker
+func (p Policy) Is_Enabled() bool {
+	return p.Max_Attempts > 1 || p.Breaker.Threshold > 0
+}
+
+// with_defaults returns the policy with the defaults of the settings which are not set
+func (p Policy) with_defaults() Policy {
+	if p.Max_Attempts < 1 {
+		p.Max_Attempts = 1
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = DEFAULT_BACKOFF
+	}
+	if p.Max_Backoff <= 0 {
+		p.Max_Backoff = DEFAULT_MAX_BACKOFF
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = DEFAULT_MULTIPLIER
+	}
+	if p.Jitter == 0 {
+		p.Jitter = DEFAULT_JITTER
+	}
+	p.Jitter = min(p.Jitter, 1)
+	if len(p.Retry_On) == 0 {
+		p.Retry_On = default_retry_on
+	}
+	return p
+}
+
+// backoff returns the wait before the retry after the given attempt. The Retry-After seconds of the response
+// are used when they are longer, up to Max_Backoff
+func (p Policy) backoff(pAttempt int, pResponse *htypes.AHTTPResponse) time.Duration {
+
+	_wait := float64(p.Backoff) * math.Pow(p.Multiplier, float64(pAttempt-1))
+	_wait = min(_wait, float64(p.Max_Backoff))
+	if p.Jitter > 0 {
+		/// the jitter part of the wait is random, so the clients retrying together spread out
+		_wait = _wait*(1-p.Jitter) + _wait*p.Jitter*rand.Float64()
+	}
+
+	if pResponse != nil {
+		if _seconds, _err := strconv.Atoi(pResponse.Headers[http.CanonicalHeaderKey("Retry-After")]); _err == nil {
+			_wait = max(_wait, float64(time.Duration(_seconds)*time.Second))
+		}
+	}
+	return time.Duration(min(_wait, float64(p.Max_Backoff)))
+}
+
+// should_retry returns true if the failure of the attempt matches Retry_On. The status of a response returned
+// without an error is checked as well (plugins which do not return *aerrors.HTTPStatusError)
+func (p Policy) should_retry(pResponse *htypes.AHTTPResponse, pErr error) bool {
+
+	_status := 0
+	var _status_err *aerrors.HTTPStatusError
+	if pErr == nil {
+		if pResponse == nil || pResponse.StatusCode < http.StatusMultipleChoices {
+			return false
+		}
+		_status = pResponse.StatusCode
+	} else if errors.As(pErr, &_status_err) {
+		_status = _status_err.StatusCode
+	} else if errors.Is(pErr, aerrors.ErrCircuitOpen) || errors.Is(pErr, context.Canceled) ||
+		errors.Is(pErr, aerrors.ErrNotSupported) {
+		return false
+	}
+
+	_code := strconv.Itoa(_status)
+	for _, _on := range p.Retry_On {
+		_on = strings.ToLower(strings.TrimSpace(_on))
+		switch {
+		case _on == RETRY_NETWORK:
+			if _status == 0 {
+				return true
+			}
+		case _status == 0:
+		case _on == _code:
+			return true
+		case len(_on) == 3 && strings.HasSuffix(_on, "xx") && _on[0] == _code[0]:
+			return true
+		}
+	}
+	return false
+}
+
+// can_retry returns true if the request can be sent again: the method is idempotent (or the policy retries
+// the other methods, or the request has an idempotency key) and the body can be sent again
+func (p Policy) can_retry(pMethod string, pRequest *htypes.AHTTPRequest) bool {
+
+	if pRequest == nil {
+		return false
+	}
+	if pRequest.Body_Reader != nil {
+		if _, _ok := pRequest.Body_Reader.(io.Seeker); !_ok {
+			return false
+		}
+	}
+
+	switch pMethod {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete, http.MethodTrace:
+		return true
+	}
+	return p.Retry_Non_Idempotent || pRequest.Header().Get(IDEMPOTENCY_KEY_HEADER) != ""
+}
+
+// is_failure returns true if the result counts as a failure of the upstream for the circuit breaker:
+// a network error or a retryable status (408, 429, 5xx except 501), also of a response returned without an error.
+// A method the plugin does not support is not
+func is_failure(pResponse *htypes.AHTTPResponse, pErr error) bool {
+	if pErr == nil {
+		return pResponse != nil && (&aerrors.HTTPStatusError{StatusCode: pResponse.StatusCode}).Retryable()
+	}
+	if errors.Is(pErr, context.Canceled) || errors.Is(pErr, aerrors.ErrNotSupported) {
+		return false
+	}
+	var _status_err *aerrors.HTTPStatusError
+	if errors.As(pErr, &_status_err) {
+		return _status_err.Retryable()
+	}
+	return true
+}
+
+// policy_key context key of the policy of a request
+type policy_key struct{}
+
+// With_Policy returns a copy of the context carrying the policy of the requests performed with Do,
+// instead of the policy of the client. The circuit breaker of the client is kept.
+func With_Policy(pCtx context.Context, pPolicy Policy) context.Context {
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	return context.WithValue(pCtx, policy_key{}, pPolicy)
+}
+
+// Policy_From returns the policy carried by the context, false if none
+func Policy_From(pCtx context.Context) (Policy, bool) {
+	if pCtx == nil {
+		return Policy{}, false
+	}
+	_policy, _ok := pCtx.Value(policy_key{}).(Policy)
+	return _policy, _ok
+}
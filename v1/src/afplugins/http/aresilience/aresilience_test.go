@@ -0,0 +1,92 @@
+package aresilience_test
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aresilience"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// status_client plugin which answers the statuses in order without an error, the last one repeatedly
+type status_client struct {
+	statuses []int
+	calls    atomic.Int32
+}
+
+func (c *status_client) New() interface{}                 { return &status_client{statuses: c.statuses} }
+func (c *status_client) GetID() int                       { return 1 }
+func (c *status_client) Initialize(pInstance_ID int) bool { return true }
+func (c *status_client) Info() build.BuildInfo            { return build.BuildInfo{Version: "test"} }
+
+func (c *status_client) Get(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	_call := int(c.calls.Add(1)) - 1
+	return &htypes.AHTTPResponse{StatusCode: c.statuses[min(_call, len(c.statuses)-1)]}, nil
+}
+
+func (c *status_client) Post(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *status_client) Put(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func (c *status_client) Delete(pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.Get(pRequest)
+}
+
+func TestRetryStatusWithoutError(t *testing.T) {
+	_plugin := &status_client{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}}
+	_client := aresilience.Wrap_HTTPClient("rest", _plugin, aresilience.Policy{Max_Attempts: 3, Backoff: time.Millisecond, Jitter: -1})
+
+	_response, _err := _client.Get(&htypes.AHTTPRequest{URL: "http://localhost/orders"})
+	if _err != nil || _response.StatusCode != http.StatusOK {
+		t.Fatalf("Get() = %v, %v, want 200", _response, _err)
+	}
+	if _plugin.calls.Load() != 2 || _client.Breaker_Status().Retries != 1 {
+		t.Errorf("calls %d, retries %d, want 2 calls and 1 retry", _plugin.calls.Load(), _client.Breaker_Status().Retries)
+	}
+}
+
+func TestBreakerStatusWithoutError(t *testing.T) {
+	_plugin := &status_client{statuses: []int{http.StatusBadGateway}}
+	_client := aresilience.Wrap_HTTPClient("rest", _plugin, aresilience.Policy{
+		Max_Attempts: 1,
+		Breaker:      aresilience.Breaker_Config{Threshold: 2, Open_Time: time.Minute},
+	})
+
+	for _i := 0; _i < 2; _i++ {
+		if _, _err := _client.Get(&htypes.AHTTPRequest{}); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+	if _, _err := _client.Get(&htypes.AHTTPRequest{}); !errors.Is(_err, aerrors.ErrCircuitOpen) {
+		t.Errorf("Get() after 2 responses 502 = %v, want aerrors.ErrCircuitOpen", _err)
+	}
+	if _plugin.calls.Load() != 2 {
+		t.Errorf("calls of the plugin = %d, want 2", _plugin.calls.Load())
+	}
+}
+
+func TestWaitCancelled(t *testing.T) {
+	_plugin := &status_client{statuses: []int{http.StatusServiceUnavailable}}
+	_client := aresilience.Wrap_HTTPClient("rest", _plugin, aresilience.Policy{Max_Attempts: 3, Backoff: time.Minute})
+
+	_ctx, _cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer _cancel()
+	_started := time.Now()
+	_response, _ := _client.Do(_ctx, &htypes.AHTTPRequest{})
+	if _elapsed := time.Since(_started); _elapsed > 5*time.Second {
+		t.Fatalf("Do() returned after %v, want when the context is done", _elapsed)
+	}
+	if _response == nil || _response.StatusCode != http.StatusServiceUnavailable || _plugin.calls.Load() != 1 {
+		t.Errorf("Do() = %v after %d calls, want the response 503 of the first call", _response, _plugin.calls.Load())
+	}
+}
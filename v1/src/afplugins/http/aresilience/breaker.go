@@ -0,0 +1,166 @@
+package aresilience
+
+import (
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"sync"
+	"time"
+)
+
+// BreakerState state of a circuit breaker
+type BreakerState int32
+
+// states of the circuit breaker
+const (
+	BREAKER_CLOSED    BreakerState = 0 // the requests are sent
+	BREAKER_OPEN      BreakerState = 1 // the requests are rejected until the open time is over
+	BREAKER_HALF_OPEN BreakerState = 2 // the probes are sent, the other requests are rejected
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BREAKER_CLOSED:
+		return "closed"
+	case BREAKER_OPEN:
+		return "open"
+	case BREAKER_HALF_OPEN:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// Breaker_Config settings of a circuit breaker
+type Breaker_Config struct {
+	Threshold int           // consecutive failures which open the circuit, 0 = no circuit breaker
+	Open_Time time.Duration // time the circuit stays open before the half-open probes (0 = DEFAULT_OPEN_TIME)
+	Probes    int           // successful half-open probes which close the circuit (0 = 1), sent one at a time
+}
+
+// Breaker circuit breaker of a http client
+type Breaker struct {
+	config Breaker_Config
+
+	lock       sync.Mutex
+	state      BreakerState
+	generation uint64 /// changed with the state, the results of the requests allowed in a previous state are ignored
+	failures   int    /// consecutive failures while closed
+	successes  int    /// successful probes while half-open
+	probing    bool   /// a probe is in progress
+	opened     time.Time
+	rejected   uint64
+}
+
+// New_Breaker returns a closed circuit breaker, nil if the threshold is not set
+func New_Breaker(pConfig Breaker_Config) *Breaker {
+	if pConfig.Threshold <= 0 {
+		return nil
+	}
+	if pConfig.Open_Time <= 0 {
+		pConfig.Open_Time = DEFAULT_OPEN_TIME
+	}
+	if pConfig.Probes <= 0 {
+		pConfig.Probes = 1
+	}
+	return &Breaker{config: pConfig}
+}
+
+// Allow returns the generation of the breaker to give to Record with the result of the request.
+//
+//	Returns aerrors.ErrCircuitOpen if the request is rejected
+func (b *Breaker) Allow() (uint64, error) {
+	if b == nil {
+		return 0, nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if b.state == BREAKER_OPEN && time.Since(b.opened) >= b.config.Open_Time {
+		b.set_state(BREAKER_HALF_OPEN)
+	}
+	switch b.state {
+	case BREAKER_OPEN:
+		b.rejected++
+		return 0, aerrors.ErrCircuitOpen
+	case BREAKER_HALF_OPEN:
+		if b.probing {
+			b.rejected++
+			return 0, aerrors.ErrCircuitOpen
+		}
+		b.probing = true
+	}
+	return b.generation, nil
+}
+
+// Record counts the result of a request allowed with the given generation
+func (b *Breaker) Record(pGeneration uint64, pFailure bool) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if pGeneration != b.generation {
+		return
+	}
+	switch b.state {
+	case BREAKER_CLOSED:
+		if !pFailure {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.config.Threshold {
+			b.set_state(BREAKER_OPEN)
+		}
+	case BREAKER_HALF_OPEN:
+		b.probing = false
+		if pFailure {
+			b.set_state(BREAKER_OPEN)
+			return
+		}
+		b.successes++
+		if b.successes >= b.config.Probes {
+			b.set_state(BREAKER_CLOSED)
+		}
+	}
+}
+
+// set_state moves the breaker into the state, locked by the caller
+func (b *Breaker) set_state(pState BreakerState) {
+	b.state = pState
+	b.generation++
+	b.successes = 0
+	b.probing = false
+	switch pState {
+	case BREAKER_OPEN:
+		b.opened = time.Now()
+	case BREAKER_CLOSED:
+		b.failures = 0
+		b.opened = time.Time{}
+	}
+}
+
+// State returns the state of the breaker, BREAKER_CLOSED for nil
+func (b *Breaker) State() BreakerState {
+	if b == nil {
+		return BREAKER_CLOSED
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.state == BREAKER_OPEN && time.Since(b.opened) >= b.config.Open_Time {
+		return BREAKER_HALF_OPEN
+	}
+	return b.state
+}
+
+// snapshot returns the state, failures, open time and rejected requests of the breaker
+func (b *Breaker) snapshot() (BreakerState, int, time.Time, uint64) {
+	if b == nil {
+		return BREAKER_CLOSED, 0, time.Time{}, 0
+	}
+	_state := b.State()
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return _state, b.failures, b.opened, b.rejected
+}
@@ -0,0 +1,186 @@
+package aresilience
+
+import (
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HTTP_Client http client plugin which retries the requests and stops calling a failing upstream by its policy
+type HTTP_Client struct {
+	ihttp.IAHTTPClient
+	name    string
+	policy  Policy
+	breaker *Breaker
+	retries *atomic.Uint64
+}
+
+// make sure HTTP_Client satisfies the plugin interface
+var _ ihttp.IAHTTPClient = (*HTTP_Client)(nil)
+var _ ihttp.IAHTTPClientDo = (*HTTP_Client)(nil)
+
+// Wrap_HTTPClient returns the client performing the requests of the http client plugin with the policy.
+// pName is the type of the plugin reported in the status of the breaker.
+func Wrap_HTTPClient(pName string, pClient ihttp.IAHTTPClient, pPolicy Policy) *HTTP_Client {
+	if pClient == nil {
+		return nil
+	}
+	return &HTTP_Client{
+		IAHTTPClient: pClient,
+		name:         pName,
+		policy:       pPolicy.with_defaults(),
+		breaker:      New_Breaker(pPolicy.Breaker),
+		retries:      new(atomic.Uint64),
+	}
+}
+
+// New creates a new instance of the plugin with the policy of the client and its own circuit breaker
+func (c *HTTP_Client) New() interface{} {
+	_client, _ok := c.IAHTTPClient.New().(ihttp.IAHTTPClient)
+	if !_ok {
+		return nil
+	}
+	return Wrap_HTTPClient(c.name, _client, c.policy)
+}
+
+// Unwrap returns the http client plugin
+func (c *HTTP_Client) Unwrap() ihttp.IAHTTPClient {
+	return c.IAHTTPClient
+}
+
+// Breaker returns the circuit breaker of the client, nil if the policy has none
+func (c *HTTP_Client) Breaker() *Breaker {
+	return c.breaker
+}
+
+// Breaker_Status returns the state of the circuit breaker and the retries of the client
+func (c *HTTP_Client) Breaker_Status() atypes.BreakerStatus {
+	_state, _failures, _opened, _rejected := c.breaker.snapshot()
+	_status := atypes.BreakerStatus{
+		Client:   c.name,
+		State:    _state.String(),
+		Failures: _failures,
+		Rejected: _rejected,
+		Retries:  c.retries.Load(),
+	}
+	if !_opened.IsZero() {
+		_status.Opened = _opened.Format(time.RFC3339)
+	}
+	return _status
+}
+
+func (c *HTTP_Client) Get(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodGet, pHTTP_Request, call(c.IAHTTPClient.Get))
+}
+
+func (c *HTTP_Client) Post(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPost, pHTTP_Request, call(c.IAHTTPClient.Post))
+}
+
+func (c *HTTP_Client) Put(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPut, pHTTP_Request, call(c.IAHTTPClient.Put))
+}
+
+func (c *HTTP_Client) Delete(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodDelete, pHTTP_Request, call(c.IAHTTPClient.Delete))
+}
+
+func (c *HTTP_Client) Patch(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodPatch, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodPatch)))
+}
+
+func (c *HTTP_Client) Head(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodHead, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodHead)))
+}
+
+func (c *HTTP_Client) Options(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.execute(nil, http.MethodOptions, pHTTP_Request, call(ihttp.Method_Func(c.IAHTTPClient, http.MethodOptions)))
+}
+
+// Do performs the request with the policy carried by pCtx (With_Policy), the policy of the client if none
+func (c *HTTP_Client) Do(pCtx context.Context, pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	_method := http.MethodGet
+	if pHTTP_Request != nil && pHTTP_Request.Method != "" {
+		_method = pHTTP_Request.Method
+	}
+	return c.execute(pCtx, _method, pHTTP_Request, func(pCtx context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return ihttp.Do(pCtx, c.IAHTTPClient, pRequest)
+	})
+}
+
+// call returns the plugin function as the call of execute
+func call(pFunc func(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return func(_ context.Context, pRequest *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+		return pFunc(pRequest)
+	}
+}
+
+// execute performs the request with the given plugin function, checked by the circuit breaker and retried by the policy.
+//
+//	Returns the response and the error of the last attempt. Unless returns aerrors.ErrCircuitOpen if the circuit is open
+func (c *HTTP_Client) execute(pCtx context.Context, pMethod string, pRequest *htypes.AHTTPRequest,
+	pCall func(context.Context, *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error)) (*htypes.AHTTPResponse, error) {
+
+	_policy := c.policy
+	if _ctx_policy, _ok := Policy_From(pCtx); _ok {
+		_policy = _ctx_policy.with_defaults()
+	}
+	_can_retry := _policy.Max_Attempts > 1 && _policy.can_retry(pMethod, pRequest)
+
+	for _attempt := 1; ; _attempt++ {
+
+		_generation, _err := c.breaker.Allow()
+		if _err != nil {
+			_url := ""
+			if pRequest != nil {
+				_url = pRequest.URL
+			}
+			return nil, fmt.Errorf("%s %s (%s). %w", pMethod, _url, c.name, _err)
+		}
+
+		_response, _err := pCall(pCtx, pRequest)
+		c.breaker.Record(_generation, is_failure(_response, _err))
+
+		if !_can_retry || _attempt >= _policy.Max_Attempts || !_policy.should_retry(_response, _err) {
+			return _response, _err
+		}
+
+		/// the failed response is not returned, its stream is released
+		if _response != nil && _response.Body_Stream != nil {
+			_response.Body_Stream.Close()
+		}
+		if !wait(pCtx, _policy.backoff(_attempt, _response)) {
+			return _response, _err
+		}
+		if _seeker, _ok := pRequest.Body_Reader.(io.Seeker); _ok {
+			if _, _seek_err := _seeker.Seek(0, io.SeekStart); _seek_err != nil {
+				return _response, _err
+			}
+		}
+		c.retries.Add(1)
+	}
+}
+
+// wait waits for the given time, false if the context is done before. The requests of the clients of the units
+// (AUBase.Get_RESTClient) carry the unit context, a nil context (Get, Post, ... of a plugin client) is not cancelled
+func wait(pCtx context.Context, pWait time.Duration) bool {
+	if pCtx == nil {
+		time.Sleep(pWait)
+		return true
+	}
+	_timer := time.NewTimer(pWait)
+	defer _timer.Stop()
+	select {
+	case <-_timer.C:
+		return true
+	case <-pCtx.Done():
+		return false
+	}
+}
@@ -16,6 +16,12 @@
 //
 //   - Delete
 //
+//   - Initialize_TLS (optional IAHTTPClientTLS)
+//
+//   - Patch, Head, Options, Do (optional IAHTTPClientDo)
+//
+//   - Do, Method_Func
+//
 //     ---------------------------------------------------------------------------------------------------------------------
 //     Author        :  D. Ajith Nilantha de Silva  | 02/01/2024
 //     Copyright     :  Open source MIT License
@@ -34,21 +40,29 @@
 package iahttpclient
 
 import (
-	atypes "agnione/v1/src/afplugins/http/types"
-	build "agnione/v1/src/lib"
+	atypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/atls"
+
+	"context"
+	"fmt"
+	"net/http"
 )
 
-// IHTTPClient interface expose the functions relates to HTTP protocol
-type IAHTTPClient interface {
+// IAHTTPClientCore functions of the http client plugin which are shared by the v1 and v2 plugin interfaces.
+//
+// When the response status code is not 2xx, the request functions return the response together with
+// *aerrors.HTTPStatusError (matches aerrors.ErrHTTPStatus), so callers can use errors.As to read the status code.
+//
+// The request functions honour the Query, Header_Values, Body_Reader, Stream and Timeout_Duration of the request.
+// The plugins built on net/http implement them with atypes.Do_HTTP. The other methods and the context of the
+// request are supported by the optional IAHTTPClientDo.
+type IAHTTPClientCore interface {
 
 	//Cretes a new isntance of IZHTTPClient
 	New() interface{}
 
-	//Initialize the instance.
-	//
-	// Returns the ture if initialized scussessfully. Unless false
-	Initialize(pInstance_ID int) bool
-
 	// GetID retuns the pre-set id of the current instance
 	GetID() (pInstance_ID int)
 
@@ -83,3 +97,106 @@ type IAHTTPClient interface {
 	// Info returns the build information of the library
 	Info() build.BuildInfo
 }
+
+// IHTTPClient interface expose the functions relates to HTTP protocol
+type IAHTTPClient interface {
+	IAHTTPClientCore
+
+	//Initialize the instance.
+	//
+	// Returns the ture if initialized scussessfully. Unless false
+	Initialize(pInstance_ID int) bool
+}
+
+// IAHTTPClientTLS optional interface for the http client plugins which support the tls settings of the plugin config.
+//
+// When the plugin has a tls section the framework calls Initialize_TLS instead of Initialize, and fails if the
+// plugin does not implement this interface. The plugin sends the requests with pTLS.Transport() or a transport
+// with pTLS.Client_Config(), which use the certificates reloaded from the disk without a new instance.
+type IAHTTPClientTLS interface {
+
+	// Initialize_TLS initializes the instance with the given id and tls settings.
+	//
+	// Returns the ture if initialized scussessfully. Unless false
+	Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) bool
+}
+
+// IAHTTPClientDo optional interface for the http client plugins which perform the requests of all the methods
+// and cancel them with the context.
+//
+// The clients returned by the framework implement it for every plugin, Do and Method_Func perform the requests
+// of a plugin which does not implement it with its Get, Post, Put and Delete functions.
+type IAHTTPClientDo interface {
+
+	// Patch perfoms a HTTP PATCH request based on the given AHTTPRequest.
+	//
+	// If success returns AHTTPResponse with result (status code, headers and body in []bytes)
+	// If failed then returns AHTTPResponse with valid status code and error with menaningful error
+	Patch(pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error)
+
+	// Head perfoms a HTTP HEAD request based on the given AHTTPRequest. The response has no body.
+	//
+	// If success returns AHTTPResponse with result (status code and headers)
+	// If failed then returns AHTTPResponse with valid status code and error with menaningful error
+	Head(pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error)
+
+	// Options perfoms a HTTP OPTIONS request based on the given AHTTPRequest.
+	//
+	// If success returns AHTTPResponse with result (status code, headers and body in []bytes)
+	// If failed then returns AHTTPResponse with valid status code and error with menaningful error
+	Options(pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error)
+
+	// Do perfoms the HTTP request with the Method of the given AHTTPRequest (GET if empty).
+	// The request is cancelled when pCtx is done.
+	//
+	// If success returns AHTTPResponse with result (status code, headers and body in []bytes)
+	// If failed then returns AHTTPResponse with valid status code and error with menaningful error
+	Do(pCtx context.Context, pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error)
+}
+
+// Do performs the request with the Method of the request (GET if empty) by the given plugin: with its Do function
+// if it implements IAHTTPClientDo, unless with the function of the method after checking pCtx (the request of
+// such a plugin is not cancelled when pCtx is done later).
+//
+//	Returns aerrors.ErrNotSupported for PATCH, HEAD, OPTIONS and the other methods of a plugin without IAHTTPClientDo
+func Do(pCtx context.Context, pClient IAHTTPClientCore, pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error) {
+	if _client, _ok := pClient.(IAHTTPClientDo); _ok {
+		return _client.Do(pCtx, pHTTP_Request)
+	}
+	if pCtx != nil && pCtx.Err() != nil {
+		return nil, pCtx.Err()
+	}
+	_method := http.MethodGet
+	if pHTTP_Request != nil && pHTTP_Request.Method != "" {
+		_method = pHTTP_Request.Method
+	}
+	return Method_Func(pClient, _method)(pHTTP_Request)
+}
+
+// Method_Func returns the request function of the given method of the plugin (Get for GET, Patch of IAHTTPClientDo
+// for PATCH, ...). The function of a method the plugin does not support returns aerrors.ErrNotSupported.
+func Method_Func(pClient IAHTTPClientCore, pMethod string) func(pHTTP_Request *atypes.AHTTPRequest) (*atypes.AHTTPResponse, error) {
+	switch pMethod {
+	case http.MethodGet:
+		return pClient.Get
+	case http.MethodPost:
+		return pClient.Post
+	case http.MethodPut:
+		return pClient.Put
+	case http.MethodDelete:
+		return pClient.Delete
+	}
+	if _client, _ok := pClient.(IAHTTPClientDo); _ok {
+		switch pMethod {
+		case http.MethodPatch:
+			return _client.Patch
+		case http.MethodHead:
+			return _client.Head
+		case http.MethodOptions:
+			return _client.Options
+		}
+	}
+	return func(*atypes.AHTTPRequest) (*atypes.AHTTPResponse, error) {
+		return nil, fmt.Errorf("http %s of the client plugin %w", pMethod, aerrors.ErrNotSupported)
+	}
+}
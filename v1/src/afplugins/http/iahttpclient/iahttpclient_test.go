@@ -0,0 +1,113 @@
+package iahttpclient_test
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aauth"
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	"github.com/agnione/libs/v1/src/afplugins/http/aresilience"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// legacy_client plugin which implements only the functions of the published v1 interface
+type legacy_client struct {
+	calls []string
+}
+
+func (c *legacy_client) New() interface{}                 { return &legacy_client{} }
+func (c *legacy_client) GetID() int                       { return 1 }
+func (c *legacy_client) Initialize(pInstance_ID int) bool { return true }
+func (c *legacy_client) Info() build.BuildInfo            { return build.BuildInfo{Version: "test"} }
+
+func (c *legacy_client) request(pMethod string) (*htypes.AHTTPResponse, error) {
+	c.calls = append(c.calls, pMethod)
+	return &htypes.AHTTPResponse{StatusCode: http.StatusOK}, nil
+}
+
+func (c *legacy_client) Get(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.request(http.MethodGet)
+}
+
+func (c *legacy_client) Post(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.request(http.MethodPost)
+}
+
+func (c *legacy_client) Put(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.request(http.MethodPut)
+}
+
+func (c *legacy_client) Delete(*htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return c.request(http.MethodDelete)
+}
+
+func TestDoLegacyPlugin(t *testing.T) {
+
+	_client := &legacy_client{}
+	if _, _ok := any(_client).(ihttp.IAHTTPClientDo); _ok {
+		t.Fatal("legacy plugin implements IAHTTPClientDo")
+	}
+
+	if _, _err := ihttp.Do(context.Background(), _client, &htypes.AHTTPRequest{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _, _err := ihttp.Do(context.Background(), _client, &htypes.AHTTPRequest{Method: http.MethodPut}); _err != nil {
+		t.Fatal(_err)
+	}
+	if len(_client.calls) != 2 || _client.calls[0] != http.MethodGet || _client.calls[1] != http.MethodPut {
+		t.Errorf("calls of the plugin = %v, want [GET PUT]", _client.calls)
+	}
+
+	if _, _err := ihttp.Do(context.Background(), _client, &htypes.AHTTPRequest{Method: http.MethodPatch}); !errors.Is(_err, aerrors.ErrNotSupported) {
+		t.Errorf("Do(PATCH) = %v, want aerrors.ErrNotSupported", _err)
+	}
+
+	_ctx, _cancel := context.WithCancel(context.Background())
+	_cancel()
+	if _, _err := ihttp.Do(_ctx, _client, &htypes.AHTTPRequest{}); !errors.Is(_err, context.Canceled) {
+		t.Errorf("Do() with a cancelled context = %v, want context.Canceled", _err)
+	}
+	if len(_client.calls) != 2 {
+		t.Errorf("calls of the plugin = %v, want no call after the cancelled context", _client.calls)
+	}
+}
+
+func TestWrappersLegacyPlugin(t *testing.T) {
+
+	_wrappers := map[string]func(ihttp.IAHTTPClient) ihttp.IAHTTPClient{
+		"aintercept": func(pClient ihttp.IAHTTPClient) ihttp.IAHTTPClient {
+			return aintercept.Wrap_HTTPClient("rest", pClient, nil)
+		},
+		"aresilience": func(pClient ihttp.IAHTTPClient) ihttp.IAHTTPClient {
+			return aresilience.Wrap_HTTPClient("rest", pClient, aresilience.Policy{Max_Attempts: 3})
+		},
+		"aauth": func(pClient ihttp.IAHTTPClient) ihttp.IAHTTPClient {
+			return aauth.Wrap_HTTPClient(pClient, nil)
+		},
+	}
+
+	for _name, _wrap := range _wrappers {
+		t.Run(_name, func(t *testing.T) {
+			_plugin := &legacy_client{}
+			_client, _ok := _wrap(_plugin).(ihttp.IAHTTPClientDo)
+			if !_ok {
+				t.Fatal("wrapper does not implement IAHTTPClientDo")
+			}
+
+			if _, _err := _client.Do(context.Background(), &htypes.AHTTPRequest{Method: http.MethodDelete}); _err != nil {
+				t.Fatal(_err)
+			}
+			if _, _err := _client.Patch(&htypes.AHTTPRequest{}); !errors.Is(_err, aerrors.ErrNotSupported) {
+				t.Errorf("Patch() = %v, want aerrors.ErrNotSupported", _err)
+			}
+			if len(_plugin.calls) != 1 || _plugin.calls[0] != http.MethodDelete {
+				t.Errorf("calls of the plugin = %v, want [DELETE]", _plugin.calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package types
+
+import (
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// MAX_ERROR_BODY bytes of the response body kept in aerrors.HTTPStatusError of a streamed response
+const MAX_ERROR_BODY = 4096
+
+// Add_Query adds a value of the query parameter and returns the request
+func (r *AHTTPRequest) Add_Query(pName string, pValue string) *AHTTPRequest {
+	if r.Query == nil {
+		r.Query = make(url.Values)
+	}
+	r.Query.Add(pName, pValue)
+	return r
+}
+
+// Add_Header adds a value of the header and returns the request. The values of Headers are kept
+func (r *AHTTPRequest) Add_Header(pName string, pValue string) *AHTTPRequest {
+	if r.Header_Values == nil {
+		r.Header_Values = make(map[string][]string)
+	}
+	_name := http.CanonicalHeaderKey(pName)
+	r.Header_Values[_name] = append(r.Header_Values[_name], pValue)
+	return r
+}
+
+// Get_Timeout returns the time to wait for result, Timeout_Duration or Timeout seconds. 0 if not set
+func (r *AHTTPRequest) Get_Timeout() time.Duration {
+	if r.Timeout_Duration > 0 {
+		return r.Timeout_Duration
+	}
+	if r.Timeout > 0 {
+		return time.Duration(r.Timeout) * time.Second
+	}
+	return 0
+}
+
+// Full_URL returns the URL with the Query parameters added to its query
+func (r *AHTTPRequest) Full_URL() (string, error) {
+	if len(r.Query) == 0 {
+		return r.URL, nil
+	}
+	_url, _err := url.Parse(r.URL)
+	if _err != nil {
+		return "", fmt.Errorf("url %q. %w", r.URL, aerrors.ErrInvalidArgument)
+	}
+	_query := _url.Query()
+	for _name, _values := range r.Query {
+		for _, _value := range _values {
+			_query.Add(_name, _value)
+		}
+	}
+	_url.RawQuery = _query.Encode()
+	return _url.String(), nil
+}
+
+// Header returns the headers of Headers and Header_Values
+func (r *AHTTPRequest) Header() http.Header {
+	_header := make(http.Header, len(r.Headers)+len(r.Header_Values))
+	for _name, _value := range r.Headers {
+		_header.Set(_name, _value)
+	}
+	for _name, _values := range r.Header_Values {
+		for _, _value := range _values {
+			_header.Add(_name, _value)
+		}
+	}
+	return _header
+}
+
+// New_HTTP_Request returns the net/http request of the given request bound to pCtx.
+// pMethod is used when the Method of the request is empty. Body is not sent with GET and HEAD.
+func New_HTTP_Request(pCtx context.Context, pMethod string, pRequest *AHTTPRequest) (*http.Request, error) {
+
+	if pRequest == nil {
+		return nil, fmt.Errorf("http request %w", aerrors.ErrNilArgument)
+	}
+	if pRequest.Method != "" {
+		pMethod = pRequest.Method
+	}
+	if pMethod == "" {
+		pMethod = http.MethodGet
+	}
+
+	_url, _err := pRequest.Full_URL()
+	if _err != nil {
+		return nil, _err
+	}
+
+	var _body io.Reader
+	switch {
+	case pRequest.Body_Reader != nil:
+		_body = pRequest.Body_Reader
+	case len(pRequest.Body) > 0 && pMethod != http.MethodGet && pMethod != http.MethodHead:
+		_body = bytes.NewReader(pRequest.Body)
+	}
+
+	_request, _err := http.NewRequestWithContext(pCtx, pMethod, _url, _body)
+	if _err != nil {
+		return nil, fmt.Errorf("%s %s. %w", pMethod, pRequest.URL, _err)
+	}
+	_request.Header = pRequest.Header()
+	return _request, nil
+}
+
+// New_AHTTPResponse returns the response of the net/http response. The body is read and closed,
+// unless pStream is set and the body is returned in Body_Stream.
+func New_AHTTPResponse(pResponse *http.Response, pStream bool) (*AHTTPResponse, error) {
+
+	if pResponse == nil {
+		return nil, fmt.Errorf("http response %w", aerrors.ErrNilArgument)
+	}
+
+	_response := &AHTTPResponse{
+		StatusCode:     pResponse.StatusCode,
+		Headers:        make(map[string]string, len(pResponse.Header)),
+		Header_Values:  make(map[string][]string, len(pResponse.Header)),
+		Content_Length: pResponse.ContentLength,
+	}
+	for _name, _values := range pResponse.Header {
+		if len(_values) > 0 {
+			_response.Headers[_name] = _values[0]
+		}
+		_response.Header_Values[_name] = append([]string(nil), _values...)
+	}
+
+	if pStream {
+		_response.Body_Stream = pResponse.Body
+		return _response, nil
+	}
+
+	defer pResponse.Body.Close()
+	_body, _err := io.ReadAll(pResponse.Body)
+	_response.Body = _body
+	return _response, _err
+}
+
+// cancel_body cancels the context of the streamed request when its body is closed
+type cancel_body struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancel_body) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// Do_HTTP performs the request with the given net/http client (http.DefaultClient if nil).
+// It is the reference implementation of the request functions of IAHTTPClientCore for the plugins built on net/http:
+// the timeout of the request limits the call and the read of the body (also the read of Body_Stream),
+// pMethod is used when the Method of the request is empty.
+//
+//	Returns the response together with *aerrors.HTTPStatusError when the status code is not 2xx
+func Do_HTTP(pCtx context.Context, pClient *http.Client, pMethod string, pRequest *AHTTPRequest) (*AHTTPResponse, error) {
+
+	if pRequest == nil {
+		return nil, fmt.Errorf("http request %w", aerrors.ErrNilArgument)
+	}
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	if pClient == nil {
+		pClient = http.DefaultClient
+	}
+
+	_cancel := context.CancelFunc(func() {})
+	if _timeout := pRequest.Get_Timeout(); _timeout > 0 {
+		pCtx, _cancel = context.WithTimeout(pCtx, _timeout)
+	}
+
+	_request, _err := New_HTTP_Request(pCtx, pMethod, pRequest)
+	if _err != nil {
+		_cancel()
+		return nil, _err
+	}
+	_http_response, _err := pClient.Do(_request)
+	if _err != nil {
+		_cancel()
+		return nil, _err
+	}
+
+	_stream := pRequest.Stream && _request.Method != http.MethodHead
+	_response, _err := New_AHTTPResponse(_http_response, _stream)
+	if _stream {
+		_response.Body_Stream = &cancel_body{ReadCloser: _response.Body_Stream, cancel: _cancel}
+	} else {
+		_cancel()
+	}
+	if _err != nil {
+		return _response, fmt.Errorf("%s %s. %w", _request.Method, pRequest.URL, _err)
+	}
+
+	if _response.StatusCode < 200 || _response.StatusCode > 299 {
+		_body := _response.Body
+		if _stream {
+			/// the body of an error is not streamed
+			_body, _ = io.ReadAll(io.LimitReader(_response.Body_Stream, MAX_ERROR_BODY))
+			_response.Body_Stream.Close()
+			_response.Body_Stream = nil
+			_response.Body = _body
+		}
+		return _response, &aerrors.HTTPStatusError{Method: _request.Method, URL: pRequest.URL, StatusCode: _response.StatusCode, Body: _body}
+	}
+	return _response, nil
+}
@@ -6,6 +6,12 @@
 //
 //   - AHTTPResponse
 //
+//   - Do_HTTP
+//
+//   - New_HTTP_Request
+//
+//   - New_AHTTPResponse
+//
 //     ---------------------------------------------------------------------------------------------------------------------
 //     Author        :		D. Ajith Nilantha de Silva  | 02/01/2024
 //     Copyright     :		Open source MIT License
@@ -21,6 +27,12 @@
 //     ---------------------------------------------------------------------------------------------------------------------
 package types
 
+import (
+	"io"
+	"net/url"
+	"time"
+)
+
 // HTTPRequest contains the request parameters.
 type AHTTPRequest struct {
 
@@ -35,6 +47,24 @@ type AHTTPRequest struct {
 
 	// Timeout time to wait for result in seconds
 	Timeout int
+
+	// Method HTTP method of the request performed by Do (GET if empty). Get, Post, ... use their own method
+	Method string
+
+	// Query query parameters added to the query of the URL, a parameter can have several values
+	Query url.Values
+
+	// Header_Values headers with several values (e.g. Cookie, Accept), added to Headers
+	Header_Values map[string][]string
+
+	// Body_Reader body streamed to the server, used instead of Body when set. Closed by the plugin if it is an io.Closer
+	Body_Reader io.Reader
+
+	// Stream if true the response body is not read, it is returned in AHTTPResponse.Body_Stream
+	Stream bool
+
+	// Timeout_Duration time to wait for result, takes precedence over Timeout (e.g. 250 * time.Millisecond)
+	Timeout_Duration time.Duration
 }
 
 // HTTPResponse contains the response/result of the performed HTTP request.
@@ -48,5 +78,14 @@ type AHTTPResponse struct {
 	
 	// StatusCode HTTP status code after performing the request
 	StatusCode int
+
+	// Header_Values all the values of the response headers (e.g. several Set-Cookie), Headers holds the first value
+	Header_Values map[string][]string
+
+	// Body_Stream response body of a Stream request, Body is nil. The caller must close it
+	Body_Stream io.ReadCloser
+
+	// Content_Length length of the response body, -1 if unknown
+	Content_Length int64
 	
 }
@@ -26,6 +26,8 @@
 //
 //   - Info
 //
+//   - Initialize_TLS (optional IAWSClientTLS)
+//
 //     ---------------------------------------------------------------------------------------------------------------------
 //     Author        :   D. Ajith Nilantha de Silva ajithdesilva@gmail.com | 02/02/2024
 //     Copyright     :   Open source MIT License
@@ -41,24 +43,46 @@
 //     ---------------------------------------------------------------------------------------------------------------------
 package iawsclient
 
-import build "agnione/v1/src/lib"
+import (
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/atls"
+)
 
-type IAWSClient interface {
+// IAWSClientCore functions of the web socket client plugin which are shared by the v1 and v2 plugin interfaces.
+//
+// When the connection is closed, the functions return *aerrors.WSClosedError (matches aerrors.ErrWSClosed)
+// with the close code and reason sent by the peer.
+type IAWSClientCore interface {
 
 	// New creates a new instance of IWSClient and return the interface
 	New() interface{}
 
-	// Initialize initializes the given id to the instance. Used to identify the instance
-	//
-	// Returns true if success. Unless false
-	Initialize(pInstance_ID int) bool
-
 	/// GetID returns the pre-set id of the current instance
 	GetID() (pInstance_ID int)
 
 	// DeInitialize removes the matching web socket connection of the pool.
 	DeInitialize()
 
+	// Read reads the data from the connection.
+	//
+	// If success returns message 1|0 (1=Text Message ,2=Binary Message), message bytes and nil for error
+	//
+	// Unless returns 0 as message type, nil and error
+	Read() (pMessage_Type int, pMessage *[]byte, err error)
+
+	// Info returns the build information of the library
+	Info() build.BuildInfo
+}
+
+// IAWSClient interface of the web socket client plugin
+type IAWSClient interface {
+	IAWSClientCore
+
+	// Initialize initializes the given id to the instance. Used to identify the instance
+	//
+	// Returns true if success. Unless false
+	Initialize(pInstance_ID int) bool
+
 	// IsConnected checks the fetched web socket connection is connected by writing PING message.
 	//
 	// Returns true if the connection is live. unless false with error message
@@ -80,20 +104,23 @@ type IAWSClient interface {
 	// Unless returns false and error message
 	Disconnect() (bool, error)
 
-	// Read reads the data from the connection.
-	//
-	// If success returns message 1|0 (1=Text Message ,2=Binary Message), message bytes and nil for error
-	//
-	// Unless returns 0 as message type, nil and error
-	Read() (pMessage_Type int, pMessage *[]byte, err error)
-
 	// Write writes the binary message to the fetched web socket connection.
 	//
 	// Returns true and nil if write is success.
 	//
 	// Unless returns false and error message
 	Write(pMessage_Type int, pMessage *[]byte) (bool, error)
+}
 
-	// Info returns the build information of the library
-	Info() build.BuildInfo
+// IAWSClientTLS optional interface for the web socket client plugins which support the tls settings of the plugin config.
+//
+// When the plugin has a tls section the framework calls Initialize_TLS instead of Initialize, and fails if the
+// plugin does not implement this interface. Connect dials the wss urls with pTLS.Client_Config(), which uses the
+// certificates reloaded from the disk without a new instance.
+type IAWSClientTLS interface {
+
+	// Initialize_TLS initializes the given id and tls settings to the instance.
+	//
+	// Returns true if success. Unless false
+	Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) bool
 }
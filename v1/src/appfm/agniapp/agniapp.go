@@ -0,0 +1,609 @@
+// agniapp package provides the reference in-process implementation of the IAgniApp interface
+//
+// This package includes below type & functions:
+//   - AgniApp
+//   - New
+//   - Load_Config
+//   - Register_Unit
+//   - Register_RESTClient
+//   - Register_WSClient
+//   - Configure_Plugins
+//   - Register_HTTPInterceptor
+//   - Remove_HTTPInterceptor
+//   - Set_Monitor
+//   - Set_Log_Output
+//   - Start
+//   - Stop
+//   - Interrupt
+//   - Wait
+//   - Run
+//   - Rotate_Log
+//   - Start_HTTPMonitor
+//   - Stop_HTTPMonitor
+//   - HTTPMonitor_Address
+//   - HTTP_Handler
+//   - Health_Report
+//   - Register_Health_Check
+//   - Unregister_Health_Check
+//   - Escalation
+//   - Dispatch
+//   - Resize_Unit_Pool
+//   - Unit_Start_Cascade
+//   - Unit_Stop_Cascade
+//   - Logger
+//   - Logfile_List
+//   - Sums_Unit_Counters
+//   - Unit_Failed
+//   - Metrics
+//   - Tracer
+//   - Bus
+//
+// The functions of iappfw.IAgniApp are not listed. Logger, Logfile_List, Sums_Unit_Counters, Unit_Failed,
+// Metrics, Tracer and Bus implement the optional interfaces of iappfw.
+//
+// ---------------------------------------------------------------------------------------------------------------------
+// Copyright		:	Open source MIT License
+// Class/module		:	agniapp - AgniOne Application Framework
+// Objective		:	Implement the IAgniApp interface to host application units without the full framework
+// ---------------------------------------------------------------------------------------------------------------------
+// AgniApp hosts IAppUnit instances inside a plain Go program or an integration test.
+// Application units and client plugins are registered in-process (no plugin loading) and
+// the units are started/stopped according to the given atypes.AppConfig.
+// The web socket monitor is not available, monitoring messages are passed to the handler set by Set_Monitor.
+// ---------------------------------------------------------------------------------------------------------------------
+package agniapp
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	"github.com/agnione/libs/v1/src/afplugins/http/aauth"
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	iws "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	iappfw "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ahealth"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+	"github.com/agnione/libs/v1/src/lib/aresource"
+	"github.com/agnione/libs/v1/src/lib/arotate"
+	"github.com/agnione/libs/v1/src/lib/atls"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// make sure AgniApp satisfies the framework interface
+var _ iappfw.IAgniApp = (*AgniApp)(nil)
+var _ iappfw.IAgniAppCounters = (*AgniApp)(nil)
+var _ iappfw.IAgniAppSupervisor = (*AgniApp)(nil)
+var _ iappfw.IAgniAppLogger = (*AgniApp)(nil)
+var _ iappfw.IAgniAppLogfiles = (*AgniApp)(nil)
+var _ iappfw.IAgniAppMetrics = (*AgniApp)(nil)
+var _ iappfw.IAgniAppTracer = (*AgniApp)(nil)
+var _ iappfw.IAgniAppBus = (*AgniApp)(nil)
+
+// AgniApp reference implementation of iappfw.IAgniApp
+type AgniApp struct {
+	config_lock sync.RWMutex
+	config      *atypes.AppConfig
+	config_file string
+	app_path    string
+
+	started     time.Time
+	ctx         context.Context
+	cancel      context.CancelFunc
+	interrupt   chan bool
+	interrupted sync.Once
+	routine_wg  sync.WaitGroup
+
+	log_lock  sync.Mutex
+	log_file  *arotate.Writer
+	log_out   io.Writer
+	log_name  string
+	log_base  string
+	log_level atomic.Int32
+	log_json  bool
+	logger    *slog.Logger
+
+	routines    atomic.Int64
+	req_handled atomic.Uint64
+	req_failed  atomic.Uint64 /// counts forwarded by the units without their own counters
+
+	metrics *ametrics.Registry
+	tracer  *atrace.Tracer
+	bus     *abus.Bus
+
+	ready         atomic.Bool /// Start completed and Stop not called
+	health        *ahealth.Checker
+	health_lock   sync.RWMutex
+	health_checks map[string]ahealth.HealthChecker /// checks added with Register_Health_Check
+	http_lock     sync.Mutex
+	http_server   *http.Server
+	http_address  string
+
+	monitor_lock sync.RWMutex
+	monitor      func(pMessage []byte)
+
+	units_lock   sync.RWMutex
+	prototypes   map[string]iappunit.IAppUnit
+	units        map[string]*unit_pool
+	start_order  []string
+	pending      map[string]bool
+	waiting      map[string]bool /// units stopped with their dependency, started again when it is running
+	last_unit_id int
+
+	stopping        map[*unit_entry]struct{} /// units removed from units until they are deinitialized
+	retired_handled uint64                   /// final request counts of the stopped units
+	retired_failed  uint64
+
+	sup_lock    sync.Mutex
+	sup_wake    chan struct{}             /// wakes the supervision routine when a unit fails
+	failures    map[int]unit_failure      /// failures reported by Unit_Failed by instance id
+	restarts    map[string]*restart_state /// supervision of the units with a restart policy
+	supervising bool                      /// restarts are allowed (Start called, Stop not called)
+	escalation  error                     /// failure of the unit which stopped the application
+
+	plugins_lock sync.RWMutex
+	rest_clients map[string]ihttp.IAHTTPClient
+	rest_configs map[string]atypes.PlugIn  /// configs of the http client plugins by type, set by Configure_Plugins
+	rest_auth    map[string]aauth.Provider /// auth providers of the http client plugins by type, shared by their clients
+	rest_tls     map[string]*atls.TLS      /// tls settings of the http client plugins by type, shared by their clients
+	http_chain   *aintercept.Chain         /// interceptors of the requests of all the http clients
+	ws_clients   map[string]iws.IAWSClient
+	ws_configs   map[string]atypes.PlugIn /// configs of the web socket client plugins by type, set by Configure_Plugins
+	ws_tls       map[string]*atls.TLS     /// tls settings of the web socket client plugins by type
+	last_plug_id int
+}
+
+// New creates a new application instance with the given configuration.
+//
+//	pApp_Path is the base path of the application (log and config files are resolved relative to it).
+//	pConfig_File is the app.config file used by Reload_Config and Save_App_Config. It can be empty.
+func New(pApp_Path string, pConfig *atypes.AppConfig, pConfig_File string) *AgniApp {
+
+	if pConfig == nil {
+		pConfig = &atypes.AppConfig{}
+	}
+	if pApp_Path == "" {
+		pApp_Path, _ = os.Getwd()
+	}
+
+	_app := &AgniApp{
+		config:      pConfig,
+		config_file: pConfig_File,
+		app_path:    pApp_Path,
+		interrupt:   make(chan bool),
+		log_out:     os.Stdout,
+		prototypes:  make(map[string]iappunit.IAppUnit),
+		units:       make(map[string]*unit_pool),
+		pending:     make(map[string]bool),
+		waiting:     make(map[string]bool),
+		stopping:    make(map[*unit_entry]struct{}),
+		sup_wake:    make(chan struct{}, 1),
+		failures:    make(map[int]unit_failure),
+		restarts:    make(map[string]*restart_state),
+
+		health_checks: make(map[string]ahealth.HealthChecker),
+		rest_clients:  make(map[string]ihttp.IAHTTPClient),
+		http_chain:    aintercept.New_Chain(),
+		ws_clients:    make(map[string]iws.IAWSClient),
+	}
+	_app.ctx, _app.cancel = context.WithCancel(context.Background())
+	_app.log_level.Store(int32(atypes.Parse_LogLevel(pConfig.Log.LogLevel)))
+	_app.log_base = pConfig.Log.LogFileBasePath
+	_app.logger = _app.new_logger(pConfig.Log.LogFormat)
+	_app.started = time.Now()
+	_app.metrics = ametrics.New_Registry()
+	_app.metrics.Register_Collector(_app.collect_status)
+	_app.tracer = _app.new_tracer(pConfig.Tracing)
+	_app.health = _app.new_health_checker()
+	_app.bus = _app.new_bus(pConfig.Bus)
+	return _app
+}
+
+// Load_Config reads and parses the given app.config file.
+//
+//	Returns the configuration and nil if successful. Unless returns nil and error
+func Load_Config(pConfig_File string) (*atypes.AppConfig, error) {
+
+	_data, _err := os.ReadFile(pConfig_File)
+	if _err != nil {
+		return nil, fmt.Errorf("failed to read the config file %s. %w", pConfig_File, _err)
+	}
+
+	_config := new(atypes.AppConfig)
+	if _err = json.Unmarshal(_data, _config); _err != nil {
+		return nil, fmt.Errorf("invalid config file %s. %w", pConfig_File, _err)
+	}
+	return _config, nil
+}
+
+// Set_Monitor sets the handler which receives the monitoring messages sent by Send_Monitor_Message.
+// Messages are discarded when no handler is set.
+func (app *AgniApp) Set_Monitor(pHandler func(pMessage []byte)) {
+	app.monitor_lock.Lock()
+	defer app.monitor_lock.Unlock()
+	app.monitor = pHandler
+}
+
+// Start opens the log file, starts the supervision of the units and starts all the enabled units
+// after the units they depend on (depends_on), in the order of the configuration otherwise.
+//
+//	Returns nil when all the enabled units are started. Unless returns the error of the first failed unit,
+//	or aerrors.ErrDependency / aerrors.ErrDependencyCycle if the dependencies of the enabled units can not be met.
+//	A unit with a restart policy which fails to start does not fail the application, it is restarted by the policy
+//	and the units depending on it are started when it is running.
+func (app *AgniApp) Start() error {
+
+	if _err := app.open_log(); _err != nil {
+		return _err
+	}
+
+	app.started = time.Now()
+	app.Write2Log(app.Name()+" - Starting the application", atypes.LOG_INFO)
+
+	_units, _err := app.start_order_configs()
+	if _err != nil {
+		app.Write2Log(app.Name()+" - "+_err.Error(), atypes.LOG_ERROR)
+		return _err
+	}
+
+	app.sup_lock.Lock()
+	app.supervising = true
+	app.sup_lock.Unlock()
+	go app.supervise()
+
+	for _, _unit := range _units {
+		_name := _unit.Uname
+		if _, _err := app.Unit_Start(&_name); _err != nil {
+			if errors.Is(_err, aerrors.ErrDependency) {
+				app.Write2Log(app.Name()+" - Unit "+_name+" waits for its dependencies. "+_err.Error(), atypes.LOG_WARN)
+				app.set_waiting(_name, true)
+				continue
+			}
+			app.Write2Log(app.Name()+" - Failed to start the unit "+_name+". "+_err.Error(), atypes.LOG_ERROR)
+			if restart_policy(_unit) == atypes.RESTART_NEVER {
+				return _err
+			}
+		}
+	}
+
+	if _address := app.http_monitor_address(); _address != "" {
+		if _err := app.Start_HTTPMonitor(_address); _err != nil {
+			app.Write2Log(app.Name()+" - "+_err.Error(), atypes.LOG_ERROR)
+			return _err
+		}
+	}
+
+	app.ready.Store(true)
+	app.Write2Log(app.Name()+" - Starting the application.....DONE", atypes.LOG_INFO)
+	return nil
+}
+
+// Stop stops the supervision and all the running units, a unit before the units it depends on and in the reverse
+// order of start otherwise, signals the interrupt channel, cancels the application context and closes the log file.
+func (app *AgniApp) Stop() error {
+
+	app.ready.Store(false)
+	app.stop_supervision()
+	app.Write2Log(app.Name()+" - Stopping the application", atypes.LOG_INFO)
+
+	var _errs []error
+	for _, _name := range app.running_units_reverse() {
+		_unit := _name
+		if _, _err := app.stop_pool(_unit, false); _err != nil {
+			_errs = append(_errs, _err)
+		}
+	}
+
+	/// the units closed their subscriptions, the requests still waiting fail with aerrors.ErrBusClosed
+	app.bus.Close()
+
+	app.plugins_lock.Lock()
+	close_plugins_tls(app.rest_tls)
+	close_plugins_tls(app.ws_tls)
+	app.plugins_lock.Unlock()
+
+	if _err := app.Stop_HTTPMonitor(); _err != nil {
+		_errs = append(_errs, _err)
+	}
+
+	if _err := app.shutdown_tracer(); _err != nil {
+		_errs = append(_errs, _err)
+	}
+
+	app.Interrupt()
+	app.Write2Log(app.Name()+" - Stopping the application.....DONE", atypes.LOG_INFO)
+	app.close_log()
+
+	return errors.Join(_errs...)
+}
+
+// Interrupt closes the interrupt channel returned by Is_Interrupted and cancels the application context.
+// It is safe to call more than once.
+func (app *AgniApp) Interrupt() {
+	app.interrupted.Do(func() {
+		close(app.interrupt)
+		app.cancel()
+	})
+}
+
+// Wait blocks until all the routines added with Add_Routine are removed.
+func (app *AgniApp) Wait() {
+	app.routine_wg.Wait()
+}
+
+// Run starts the application and blocks until the interrupt channel is closed or
+// the process receives SIGINT/SIGTERM, then stops the application. SIGHUP rotates the log file.
+//
+//	Returns the error of the unit which escalated its failure (aerrors.ErrRestartLimit) with the errors of Stop
+func (app *AgniApp) Run() error {
+
+	if _err := app.Start(); _err != nil {
+		app.Stop()
+		return _err
+	}
+
+	_signals := make(chan os.Signal, 1)
+	signal.Notify(_signals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(_signals)
+
+	for {
+		select {
+		case _signal := <-_signals:
+			if _signal == syscall.SIGHUP {
+				if _err := app.Rotate_Log(); _err != nil {
+					app.Write2Log("failed to rotate the log file. "+_err.Error(), atypes.LOG_ERROR)
+				}
+				continue
+			}
+		case <-app.interrupt:
+		}
+		_err := app.Stop()
+		return errors.Join(app.Escalation(), _err)
+	}
+}
+
+// Reload_Config reloads the configuration from the config file given to New.
+func (app *AgniApp) Reload_Config() (bool, error) {
+
+	if app.config_file == "" {
+		return false, fmt.Errorf("config file is not set. %w", aerrors.ErrNotSupported)
+	}
+
+	_config, _err := Load_Config(app.config_file)
+	if _err != nil {
+		return false, _err
+	}
+
+	app.config_lock.Lock()
+	app.config = _config
+	app.config_lock.Unlock()
+
+	app.Set_LogLevel(atypes.Parse_LogLevel(_config.Log.LogLevel))
+	return true, nil
+}
+
+// Start_WSMonitor is not supported by the reference implementation.
+func (app *AgniApp) Start_WSMonitor() (bool, error) {
+	return false, fmt.Errorf("web socket monitor %w", aerrors.ErrNotSupported)
+}
+
+// Stop_WSMonitor is not supported by the reference implementation.
+func (app *AgniApp) Stop_WSMonitor() bool {
+	return false
+}
+
+// Is_Interrupted returns the application interrupt channel. The channel is closed by Interrupt/Stop.
+func (app *AgniApp) Is_Interrupted() chan bool {
+	return app.interrupt
+}
+
+// Add_Routine increments the routine count and adds 1 to the application waitgroup
+func (app *AgniApp) Add_Routine() {
+	app.routine_wg.Add(1)
+	app.routines.Add(1)
+}
+
+// Remove_Routine decrements the routine count and removes 1 from the application waitgroup
+func (app *AgniApp) Remove_Routine() {
+	if app.routines.Add(-1) < 0 {
+		app.routines.Add(1)
+		return
+	}
+	app.routine_wg.Done()
+}
+
+// Version returns the application version given in the configuration
+func (app *AgniApp) Version() string {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+	return app.config.App.Version
+}
+
+// Name returns the application name given in the configuration
+func (app *AgniApp) Name() string {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+	return app.config.App.Name
+}
+
+// ID returns the application ID given in the configuration
+func (app *AgniApp) ID() string {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+	return app.config.App.ID
+}
+
+// PID returns the OS process ID
+func (app *AgniApp) PID() int {
+	return os.Getpid()
+}
+
+// Memory_Usage returns the current memory usage of the process as a string
+func (app *AgniApp) Memory_Usage() string {
+	_mem := aresource.Mem_Usage()
+	return fmt.Sprintf("Heap = %d KB, HeapAlloc = %d KB, TotalAlloc = %d KB", _mem.Heap/1024, _mem.HeapAlloc/1024, _mem.Total/1024)
+}
+
+// App_Path returns the application base path
+func (app *AgniApp) App_Path() *string {
+	_path := app.app_path
+	return &_path
+}
+
+// Routine_Count returns the number of routines added with Add_Routine
+func (app *AgniApp) Routine_Count() int64 {
+	return app.routine_count()
+}
+
+// routine_count returns the number of routines added with Add_Routine, 0 if more were removed
+func (app *AgniApp) routine_count() int64 {
+	return max(app.routines.Load(), 0)
+}
+
+// Execute_Command executes the given command with the shell and returns the combined output
+func (app *AgniApp) Execute_Command(pCommand *string) (string, error) {
+
+	if pCommand == nil || *pCommand == "" {
+		return "", fmt.Errorf("command %w", aerrors.ErrNilArgument)
+	}
+
+	_out, _err := exec.CommandContext(app.ctx, "sh", "-c", *pCommand).CombinedOutput()
+	return string(_out), _err
+}
+
+// Add_Request_HandleCount adds 1 to the request handle count
+func (app *AgniApp) Add_Request_HandleCount() {
+	app.req_handled.Add(1)
+}
+
+// Add_Request_Failed_Count adds 1 to the request failed count
+func (app *AgniApp) Add_Request_Failed_Count() {
+	app.req_failed.Add(1)
+}
+
+// Handled_Request_Count returns the number of handled requests, including the counters of the units
+func (app *AgniApp) Handled_Request_Count() uint64 {
+	_handled, _ := app.unit_totals()
+	return app.req_handled.Load() + _handled
+}
+
+// Failed_Request_Count returns the number of failed requests, including the counters of the units
+func (app *AgniApp) Failed_Request_Count() uint64 {
+	_, _failed := app.unit_totals()
+	return app.req_failed.Load() + _failed
+}
+
+// Started returns the application started time
+func (app *AgniApp) Started() time.Time {
+	return app.started
+}
+
+// Send_Monitor_Message passes the given message to the monitor handler (if set)
+func (app *AgniApp) Send_Monitor_Message(pMessage []byte) {
+	app.monitor_lock.RLock()
+	_handler := app.monitor
+	app.monitor_lock.RUnlock()
+
+	if _handler != nil {
+		_handler(pMessage)
+	}
+}
+
+// Get_App_Status returns the current application status
+func (app *AgniApp) Get_App_Status() atypes.AppStatus {
+	_handled, _failed := app.unit_totals()
+	return atypes.AppStatus{
+		Mem_Usage:   aresource.Mem_Usage(),
+		Req_Handled: app.req_handled.Load() + _handled,
+		Req_Failed:  app.req_failed.Load() + _failed,
+		Routines:    app.routine_count(),
+		Process:     aresource.Process_Stats(),
+	}
+}
+
+// Get_App_Info returns the current application information with the status of the running units
+// and the supervised units which are waiting for a restart or given up
+func (app *AgniApp) Get_App_Info() atypes.AppInfo {
+
+	_info := atypes.AppInfo{
+		Info:    atypes.Info{Name: app.Name(), Version: app.Version()},
+		Started: app.started.Format(time.RFC3339),
+		UpTime:  time.Since(app.started).Round(time.Second).String(),
+		PID:     app.PID(),
+	}
+
+	_running := make(map[string]bool)
+	for _, _name := range app.running_units() {
+		_running[_name] = true
+		if _status, _err := app.Unit_Status(&_name); _err == nil && _status != nil {
+			_info.AppUnits = append(_info.AppUnits, *_status)
+		}
+	}
+
+	for _, _name := range app.supervised_units() {
+		if _running[_name] {
+			continue
+		}
+		_restart, _ok := app.restart_status(_name)
+		if !_ok {
+			continue
+		}
+		_state := atypes.UNIT_STOPPED
+		if _restart.Gave_Up || _restart.Last_Reason != "" {
+			_state = atypes.UNIT_FAILED
+		}
+		_info.AppUnits = append(_info.AppUnits, atypes.AppUnitInfo{Info: atypes.Info{Name: _name}, State: _state, Restart: _restart})
+	}
+	return _info
+}
+
+// Get_Context returns the application context. It is cancelled by Interrupt/Stop.
+func (app *AgniApp) Get_Context() *context.Context {
+	return &app.ctx
+}
+
+// Save_App_Config validates the given content and writes it into the config file given to New.
+func (app *AgniApp) Save_App_Config(pAppConfigData *[]byte) (bool, error) {
+
+	if pAppConfigData == nil {
+		return false, fmt.Errorf("config data %w", aerrors.ErrNilArgument)
+	}
+	if app.config_file == "" {
+		return false, fmt.Errorf("config file is not set. %w", aerrors.ErrNotSupported)
+	}
+
+	var _config atypes.AppConfig
+	if _err := json.Unmarshal(*pAppConfigData, &_config); _err != nil {
+		return false, fmt.Errorf("invalid app config content. %w", _err)
+	}
+
+	if _err := os.WriteFile(app.config_file, *pAppConfigData, 0o644); _err != nil {
+		return false, _err
+	}
+	return true, nil
+}
+
+// resolve_path returns the given file name relative to the application path (if it is not absolute)
+func (app *AgniApp) resolve_path(pFileName string) string {
+	if filepath.IsAbs(pFileName) {
+		return pFileName
+	}
+	return filepath.Join(app.app_path, pFileName)
+}
@@ -0,0 +1,27 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+
+	"time"
+)
+
+// Bus returns the message bus of the units of the application
+func (app *AgniApp) Bus() *abus.Bus {
+	return app.bus
+}
+
+// new_bus creates the message bus of the bus configuration. An unknown policy takes the default policy of the bus.
+func (app *AgniApp) new_bus(pConfig atypes.Busconfig) *abus.Bus {
+
+	_policy := abus.Parse_Policy(pConfig.Policy)
+	if pConfig.Policy != "" && _policy == abus.DROP_DEFAULT {
+		app.Write2Log(app.Name()+" - Unknown bus policy "+pConfig.Policy+", drop-oldest is used", atypes.LOG_ERROR)
+	}
+	return abus.New_Bus(abus.Config{
+		Queue_Size:    pConfig.QueueSize,
+		Policy:        _policy,
+		Block_Timeout: time.Duration(pConfig.BlockTimeout) * time.Millisecond,
+	})
+}
@@ -0,0 +1,60 @@
+package agniapp_test
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	"github.com/agnione/libs/v1/src/appfm/agniapp"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	build "github.com/agnione/libs/v1/src/lib"
+
+	"testing"
+)
+
+// conformance_unit the smallest unit built on AUBase
+type conformance_unit struct {
+	AUBase.AUBase
+}
+
+func (u *conformance_unit) New() interface{} {
+	return &conformance_unit{}
+}
+
+func (u *conformance_unit) GetID() int {
+	return u.ID
+}
+
+func (u *conformance_unit) Info() build.BuildInfo {
+	return build.BuildInfo{Version: "test"}
+}
+
+func TestConformance(t *testing.T) {
+	_app := agniapp.New(t.TempDir(), nil, "")
+	defer _app.Stop()
+	aautest.Run_App_Conformance(t, _app, &conformance_unit{})
+}
+
+func TestHostedUnit(t *testing.T) {
+	_config := &atypes.AppConfig{Appunits: []atypes.Appunit{{Uname: "unit", Enable: 1}}}
+	_app := agniapp.New(t.TempDir(), _config, "")
+	if _err := _app.Register_Unit("unit", &conformance_unit{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _err := _app.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+
+	_name := "unit"
+	_status, _err := _app.Unit_Status(&_name)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	if _status.Info.Name != _name {
+		t.Errorf("Unit_Status().Info.Name = %q, want %q", _status.Info.Name, _name)
+	}
+	if _ok, _err := _app.Unit_Stop(&_name, false); !_ok || _err != nil {
+		t.Errorf("Unit_Stop() = %v, %v", _ok, _err)
+	}
+	if _err := _app.Stop(); _err != nil {
+		t.Error(_err)
+	}
+}
@@ -0,0 +1,65 @@
+package agniapp
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+)
+
+// Sums_Unit_Counters returns true, the request totals include the counters of the units
+// implementing iappunit.IAppUnitCounters, read when the totals are requested (iappfw.IAgniAppCounters)
+func (app *AgniApp) Sums_Unit_Counters() bool {
+	return true
+}
+
+// unit_totals returns the requests handled and failed by the units which keep their own counters:
+// the running and stopping units plus the final counts of the units already stopped
+func (app *AgniApp) unit_totals() (uint64, uint64) {
+
+	app.units_lock.RLock()
+	defer app.units_lock.RUnlock()
+
+	_handled, _failed := app.retired_handled, app.retired_failed
+	for _, _pool := range app.units {
+		for _, _entry := range _pool.instances {
+			_h, _f := entry_counters(_entry)
+			_handled += _h
+			_failed += _f
+		}
+	}
+	for _entry := range app.stopping {
+		_h, _f := entry_counters(_entry)
+		_handled += _h
+		_failed += _f
+	}
+	return _handled, _failed
+}
+
+// retire_unit adds the final counters of the given stopping unit to the totals of the stopped units.
+// Called before the unit is deinitialized, so the totals do not go back when a unit stops.
+func (app *AgniApp) retire_unit(pEntry *unit_entry) {
+
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+
+	if _, _ok := app.stopping[pEntry]; !_ok {
+		return
+	}
+	delete(app.stopping, pEntry)
+	_h, _f := entry_counters(pEntry)
+	app.retired_handled += _h
+	app.retired_failed += _f
+}
+
+// entry_counters returns the request counters of the unit instance, 0 if the unit forwards its counts or panics
+func entry_counters(pEntry *unit_entry) (handled uint64, failed uint64) {
+	_unit, _ok := pEntry.instance.(iappunit.IAppUnitCounters)
+	if !_ok {
+		return 0, 0
+	}
+	defer func() {
+		if recover() != nil {
+			handled, failed = 0, 0
+		}
+	}()
+	_counters := _unit.Counters()
+	return _counters.Req_Handled, _counters.Req_Failed
+}
@@ -0,0 +1,299 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// dependency_order returns the given units ordered so every unit follows the units it depends on,
+// in the given order otherwise. Dependencies on units which are not given are ignored.
+//
+//	Returns aerrors.ErrDependencyCycle with the units of a cycle if the units depend on each other
+func dependency_order(pConfigs []atypes.Appunit) ([]atypes.Appunit, error) {
+
+	_index := make(map[string]int, len(pConfigs))
+	for _i, _config := range pConfigs {
+		_index[_config.Uname] = _i
+	}
+
+	/// Kahn's algorithm, the ready unit first in the given order is taken next
+	_waits := make([]int, len(pConfigs))
+	_dependents := make([][]int, len(pConfigs))
+	for _i, _config := range pConfigs {
+		for _, _dep := range _config.DependsOn {
+			if _j, _ok := _index[_dep]; _ok && _j != _i {
+				_waits[_i]++
+				_dependents[_j] = append(_dependents[_j], _i)
+			} else if _ok {
+				return nil, fmt.Errorf("%s depends on itself. %w", _dep, aerrors.ErrDependencyCycle)
+			}
+		}
+	}
+
+	_ordered := make([]atypes.Appunit, 0, len(pConfigs))
+	_done := make([]bool, len(pConfigs))
+	for len(_ordered) < len(pConfigs) {
+		_next := -1
+		for _i := range pConfigs {
+			if !_done[_i] && _waits[_i] == 0 {
+				_next = _i
+				break
+			}
+		}
+		if _next < 0 {
+			return nil, fmt.Errorf("%s. %w", dependency_cycle(pConfigs, _index, _done), aerrors.ErrDependencyCycle)
+		}
+		_done[_next] = true
+		_ordered = append(_ordered, pConfigs[_next])
+		for _, _j := range _dependents[_next] {
+			_waits[_j]--
+		}
+	}
+	return _ordered, nil
+}
+
+// dependency_cycle returns a cycle of the units not ordered by dependency_order as "a -> b -> a"
+func dependency_cycle(pConfigs []atypes.Appunit, pIndex map[string]int, pDone []bool) string {
+
+	_start := 0
+	for _start < len(pDone) && pDone[_start] {
+		_start++
+	}
+
+	/// every unit left depends on another unit left, follow the dependencies until a unit repeats
+	_seen := make(map[int]int)
+	var _path []string
+	for _i := _start; ; {
+		if _at, _ok := _seen[_i]; _ok {
+			return strings.Join(append(_path[_at:], pConfigs[_i].Uname), " -> ")
+		}
+		_seen[_i] = len(_path)
+		_path = append(_path, pConfigs[_i].Uname)
+		for _, _dep := range pConfigs[_i].DependsOn {
+			if _j, _ok := pIndex[_dep]; _ok && !pDone[_j] {
+				_i = _j
+				break
+			}
+		}
+	}
+}
+
+// check_dependencies checks that the given units depend only on the given units
+func check_dependencies(pConfigs []atypes.Appunit) error {
+
+	_names := make(map[string]bool, len(pConfigs))
+	for _, _config := range pConfigs {
+		_names[_config.Uname] = true
+	}
+	for _, _config := range pConfigs {
+		for _, _dep := range _config.DependsOn {
+			if !_names[_dep] {
+				return &aerrors.UnitError{Unit: _config.Uname, Op: "start",
+					Err: fmt.Errorf("depends on %s which is not configured or not enabled. %w", _dep, aerrors.ErrDependency)}
+			}
+		}
+	}
+	return nil
+}
+
+// start_order_configs returns the enabled units in the order of start
+func (app *AgniApp) start_order_configs() ([]atypes.Appunit, error) {
+
+	var _enabled []atypes.Appunit
+	for _, _config := range app.unit_configs() {
+		if _config.Enable == 1 {
+			_enabled = append(_enabled, _config)
+		}
+	}
+	if _err := check_dependencies(_enabled); _err != nil {
+		return nil, _err
+	}
+	return dependency_order(_enabled)
+}
+
+// is_running returns true if an instance of the unit is running
+func (app *AgniApp) is_running(pUnit_Name string) bool {
+	app.units_lock.RLock()
+	defer app.units_lock.RUnlock()
+	_, _ok := app.units[pUnit_Name]
+	return _ok
+}
+
+// missing_dependencies returns the dependencies of the unit which are not running
+func (app *AgniApp) missing_dependencies(pConfig atypes.Appunit) []string {
+	var _missing []string
+	for _, _dep := range pConfig.DependsOn {
+		if !app.is_running(_dep) {
+			_missing = append(_missing, _dep)
+		}
+	}
+	return _missing
+}
+
+// running_dependents returns the running units which depend on the unit directly or through other units,
+// in the order of stop (a unit before its dependencies)
+func (app *AgniApp) running_dependents(pUnit_Name string) []string {
+
+	_configs := app.unit_configs()
+	_found := map[string]bool{pUnit_Name: true}
+	for _changed := true; _changed; {
+		_changed = false
+		for _, _config := range _configs {
+			if _found[_config.Uname] {
+				continue
+			}
+			for _, _dep := range _config.DependsOn {
+				if _found[_dep] {
+					_found[_config.Uname] = true
+					_changed = true
+					break
+				}
+			}
+		}
+	}
+
+	var _running []atypes.Appunit
+	for _, _config := range _configs {
+		if _config.Uname != pUnit_Name && _found[_config.Uname] && app.is_running(_config.Uname) {
+			_running = append(_running, _config)
+		}
+	}
+	return reverse_names(_running)
+}
+
+// reverse_names returns the names of the units in the reverse dependency order, in the reverse given order on a cycle
+func reverse_names(pConfigs []atypes.Appunit) []string {
+
+	if _ordered, _err := dependency_order(pConfigs); _err == nil {
+		pConfigs = _ordered
+	}
+	_names := make([]string, len(pConfigs))
+	for _i, _config := range pConfigs {
+		_names[len(pConfigs)-1-_i] = _config.Uname
+	}
+	return _names
+}
+
+// Unit_Start_Cascade starts the dependencies of the unit which are not running, in the dependency order, then the unit.
+//
+//	Returns UnitError wrapping aerrors.ErrDependencyCycle if the unit depends on itself through other units
+func (app *AgniApp) Unit_Start_Cascade(pUnit_Name string) (bool, error) {
+
+	if _, _err := app.unit_config(pUnit_Name); _err != nil {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: _err}
+	}
+
+	_configs := app.unit_configs()
+	_needed := map[string]bool{pUnit_Name: true}
+	for _changed := true; _changed; {
+		_changed = false
+		for _, _config := range _configs {
+			if !_needed[_config.Uname] {
+				continue
+			}
+			for _, _dep := range _config.DependsOn {
+				if !_needed[_dep] {
+					_needed[_dep] = true
+					_changed = true
+				}
+			}
+		}
+	}
+
+	var _units []atypes.Appunit
+	for _, _config := range _configs {
+		if _needed[_config.Uname] {
+			_units = append(_units, _config)
+		}
+	}
+	_ordered, _err := dependency_order(_units)
+	if _err != nil {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: _err}
+	}
+
+	/// the unit is started below, not by its dependencies when it waits for them
+	app.set_waiting(pUnit_Name, false)
+
+	for _, _config := range _ordered {
+		if _config.Uname == pUnit_Name || app.is_running(_config.Uname) {
+			continue
+		}
+		if _err := app.start_unit(_config.Uname); _err != nil && !errors.Is(_err, aerrors.ErrAlreadyStarted) {
+			return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: fmt.Errorf("dependency %s. %w", _config.Uname, _err)}
+		}
+	}
+	return app.Unit_Start(&pUnit_Name)
+}
+
+// Unit_Stop_Cascade stops the running units which depend on the unit, in the reverse dependency order, then the unit.
+// The dependents with on_dependency_stop restart are started again when the unit is started.
+func (app *AgniApp) Unit_Stop_Cascade(pUnit_Name string, pForce bool) (bool, error) {
+
+	app.cancel_restart(pUnit_Name)
+	if _err := app.stop_dependents(pUnit_Name, pForce, false); _err != nil {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "stop", Err: _err}
+	}
+	return app.stop_pool(pUnit_Name, pForce)
+}
+
+// stop_dependents stops the running units which depend on the unit. The dependents with on_dependency_stop restart,
+// or all of them if pRestart_All is set, wait for the unit to start again.
+func (app *AgniApp) stop_dependents(pUnit_Name string, pForce bool, pRestart_All bool) error {
+
+	var _errs []error
+	for _, _name := range app.running_dependents(pUnit_Name) {
+		_config, _ := app.unit_config(_name)
+		if pRestart_All || strings.ToLower(_config.OnDependencyStop) == atypes.DEPENDENCY_RESTART {
+			app.set_waiting(_name, true)
+		}
+		app.Write2Log(app.Name()+" - Stopping the unit "+_name+" which depends on "+pUnit_Name, atypes.LOG_INFO)
+		if _, _err := app.stop_pool(_name, pForce); _err != nil && !errors.Is(_err, aerrors.ErrNotStarted) {
+			_errs = append(_errs, _err)
+		}
+	}
+	return errors.Join(_errs...)
+}
+
+// set_waiting marks the unit as waiting for its dependencies to start again
+func (app *AgniApp) set_waiting(pUnit_Name string, pWaiting bool) {
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+	if pWaiting {
+		app.waiting[pUnit_Name] = true
+	} else {
+		delete(app.waiting, pUnit_Name)
+	}
+}
+
+// start_waiting starts the units waiting for the started unit whose dependencies are all running
+func (app *AgniApp) start_waiting(pUnit_Name string) {
+
+	app.units_lock.RLock()
+	_waiting := make([]string, 0, len(app.waiting))
+	for _name := range app.waiting {
+		_waiting = append(_waiting, _name)
+	}
+	app.units_lock.RUnlock()
+	if len(_waiting) == 0 {
+		return
+	}
+
+	for _, _config := range app.unit_configs() {
+		if !slices.Contains(_waiting, _config.Uname) || !slices.Contains(_config.DependsOn, pUnit_Name) {
+			continue
+		}
+		if len(app.missing_dependencies(_config)) > 0 {
+			continue
+		}
+		app.set_waiting(_config.Uname, false)
+		app.Write2Log(app.Name()+" - Starting the unit "+_config.Uname+", "+pUnit_Name+" is running again", atypes.LOG_INFO)
+		if _err := app.start_unit(_config.Uname); _err != nil && !errors.Is(_err, aerrors.ErrAlreadyStarted) {
+			app.Write2Log(app.Name()+" - Failed to start the unit "+_config.Uname+". "+_err.Error(), atypes.LOG_ERROR)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unit_names returns the names of the units
+func unit_names(pConfigs []atypes.Appunit) []string {
+	_names := make([]string, len(pConfigs))
+	for _i, _config := range pConfigs {
+		_names[_i] = _config.Uname
+	}
+	return _names
+}
+
+func TestDependencyOrder(t *testing.T) {
+	_tests := []struct {
+		name  string
+		units []atypes.Appunit
+		want  []string
+		cycle string /// text of the cycle error, no error if empty
+	}{
+		{name: "no dependencies",
+			units: []atypes.Appunit{{Uname: "a"}, {Uname: "b"}, {Uname: "c"}},
+			want:  []string{"a", "b", "c"}},
+		{name: "dependencies first",
+			units: []atypes.Appunit{{Uname: "c", DependsOn: []string{"b"}}, {Uname: "b", DependsOn: []string{"a"}}, {Uname: "a"}, {Uname: "d"}},
+			want:  []string{"a", "b", "c", "d"}},
+		{name: "given order among the ready units",
+			units: []atypes.Appunit{{Uname: "x", DependsOn: []string{"a", "b"}}, {Uname: "b"}, {Uname: "a"}},
+			want:  []string{"b", "a", "x"}},
+		{name: "unknown dependency ignored",
+			units: []atypes.Appunit{{Uname: "a", DependsOn: []string{"missing"}}},
+			want:  []string{"a"}},
+		{name: "self dependency",
+			units: []atypes.Appunit{{Uname: "a", DependsOn: []string{"a"}}},
+			cycle: "a depends on itself"},
+		{name: "cycle",
+			units: []atypes.Appunit{{Uname: "a", DependsOn: []string{"c"}}, {Uname: "b", DependsOn: []string{"a"}}, {Uname: "c", DependsOn: []string{"b"}}, {Uname: "d"}},
+			cycle: "a -> c -> b -> a"},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_ordered, _err := dependency_order(_test.units)
+			if _test.cycle != "" {
+				if !errors.Is(_err, aerrors.ErrDependencyCycle) || !strings.HasPrefix(_err.Error(), _test.cycle+".") {
+					t.Errorf("dependency_order() error = %v, want aerrors.ErrDependencyCycle with %q", _err, _test.cycle)
+				}
+				return
+			}
+			if _err != nil {
+				t.Fatal(_err)
+			}
+			if _got := unit_names(_ordered); !slices.Equal(_got, _test.want) {
+				t.Errorf("dependency_order() = %v, want %v", _got, _test.want)
+			}
+		})
+	}
+}
+
+func TestCheckDependencies(t *testing.T) {
+	if _err := check_dependencies([]atypes.Appunit{{Uname: "a"}, {Uname: "b", DependsOn: []string{"a"}}}); _err != nil {
+		t.Errorf("check_dependencies() = %v, want nil", _err)
+	}
+	_err := check_dependencies([]atypes.Appunit{{Uname: "a"}, {Uname: "b", DependsOn: []string{"a", "c"}}})
+	var _unit_err *aerrors.UnitError
+	if !errors.Is(_err, aerrors.ErrDependency) || !errors.As(_err, &_unit_err) || _unit_err.Unit != "b" || !strings.Contains(_err.Error(), "depends on c") {
+		t.Errorf("check_dependencies() = %v, want UnitError of b wrapping aerrors.ErrDependency", _err)
+	}
+}
+
+// start_units starts an application running the given units built on AUBase
+func start_units(t *testing.T, pUnits []atypes.Appunit) *AgniApp {
+	_app := New(t.TempDir(), &atypes.AppConfig{Appunits: pUnits}, "")
+	for _, _unit := range pUnits {
+		if _err := _app.Register_Unit(_unit.Uname, &pool_unit{}); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+	if _err := _app.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+	t.Cleanup(func() { _app.Stop() })
+	return _app
+}
+
+// running_names returns the given units which are running
+func running_names(pApp *AgniApp, pUnits ...string) []string {
+	var _running []string
+	for _, _name := range pUnits {
+		if pApp.is_running(_name) {
+			_running = append(_running, _name)
+		}
+	}
+	return _running
+}
+
+// TestCascade stops and starts the chain db <- cache <- api (api waits for cache to start again) and report
+func TestCascade(t *testing.T) {
+	_app := start_units(t, []atypes.Appunit{
+		{Uname: "api", Enable: 1, DependsOn: []string{"cache"}, OnDependencyStop: atypes.DEPENDENCY_RESTART},
+		{Uname: "cache", Enable: 1, DependsOn: []string{"db"}},
+		{Uname: "db", Enable: 1},
+		{Uname: "report", Enable: 1, DependsOn: []string{"db"}},
+	})
+	if _running := running_names(_app, "api", "cache", "db", "report"); len(_running) != 4 {
+		t.Fatalf("running units = %v, want all", _running)
+	}
+
+	if _got := _app.running_dependents("db"); !slices.Equal(_got, []string{"report", "api", "cache"}) {
+		t.Errorf("running_dependents(db) = %v, want [report api cache]", _got)
+	}
+	_name := "db"
+	if _, _err := _app.Unit_Stop(&_name, false); !errors.Is(_err, aerrors.ErrDependency) {
+		t.Errorf("Unit_Stop(db) = %v, want aerrors.ErrDependency", _err)
+	}
+
+	if _, _err := _app.Unit_Stop_Cascade("db", false); _err != nil {
+		t.Fatal(_err)
+	}
+	if _running := running_names(_app, "api", "cache", "db", "report"); len(_running) != 0 {
+		t.Fatalf("running units after Unit_Stop_Cascade(db) = %v, want none", _running)
+	}
+
+	_name = "cache"
+	if _, _err := _app.Unit_Start(&_name); !errors.Is(_err, aerrors.ErrDependency) {
+		t.Errorf("Unit_Start(cache) = %v, want aerrors.ErrDependency", _err)
+	}
+
+	/// api waits for cache (on_dependency_stop restart) and is started by start_waiting, report is not
+	if _, _err := _app.Unit_Start_Cascade("cache"); _err != nil {
+		t.Fatal(_err)
+	}
+	if _running := running_names(_app, "api", "cache", "db", "report"); !slices.Equal(_running, []string{"api", "cache", "db"}) {
+		t.Errorf("running units after Unit_Start_Cascade(cache) = %v, want [api cache db]", _running)
+	}
+}
+
+// TestFailureStopsDependents fails a unit which is not restarted, its dependents are stopped
+func TestFailureStopsDependents(t *testing.T) {
+	_app := start_units(t, []atypes.Appunit{
+		{Uname: "db", Enable: 1, Restart: atypes.RESTART_NEVER},
+		{Uname: "api", Enable: 1, DependsOn: []string{"db"}},
+	})
+
+	running_instance(_app, "db").Set_Failed(errors.New("disk full"))
+	if !wait_until(5*time.Second, func() bool { return len(running_names(_app, "api", "db")) == 0 }) {
+		t.Errorf("running units = %v after the failure of db, want none", running_names(_app, "api", "db"))
+	}
+}
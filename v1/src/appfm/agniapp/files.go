@@ -0,0 +1,73 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// Get_FileInfo returns the information of the given file
+func (app *AgniApp) Get_FileInfo(pFileName *string) (*atypes.FileInfo, error) {
+
+	if pFileName == nil {
+		return nil, fmt.Errorf("file name %w", aerrors.ErrNilArgument)
+	}
+
+	_stat, _err := os.Stat(app.resolve_path(*pFileName))
+	if _err != nil {
+		return nil, _err
+	}
+
+	return &atypes.FileInfo{Name: _stat.Name(), Size: _stat.Size(), ModTime: _stat.ModTime(), IsDir: _stat.IsDir()}, nil
+}
+
+// Get_File_Content returns the content of the given file
+func (app *AgniApp) Get_File_Content(pFileName *string) (*[]byte, error) {
+
+	if pFileName == nil {
+		return nil, fmt.Errorf("file name %w", aerrors.ErrNilArgument)
+	}
+
+	_data, _err := os.ReadFile(app.resolve_path(*pFileName))
+	if _err != nil {
+		return nil, _err
+	}
+	return &_data, nil
+}
+
+// Get_FileContent_Lines returns the lines of the given file
+func (app *AgniApp) Get_FileContent_Lines(pFileName *string) (*[]string, error) {
+
+	_data, _err := app.Get_File_Content(pFileName)
+	if _err != nil {
+		return nil, _err
+	}
+
+	_lines := make([]string, 0)
+	_scanner := bufio.NewScanner(bytes.NewReader(*_data))
+	_scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for _scanner.Scan() {
+		_lines = append(_lines, _scanner.Text())
+	}
+	if _err = _scanner.Err(); _err != nil {
+		return nil, _err
+	}
+	return &_lines, nil
+}
+
+// Write_FileContent writes the given content to the given file
+func (app *AgniApp) Write_FileContent(pFileName *string, pData *[]byte) (bool, error) {
+
+	if pFileName == nil || pData == nil {
+		return false, fmt.Errorf("file name or data %w", aerrors.ErrNilArgument)
+	}
+
+	if _err := os.WriteFile(app.resolve_path(*pFileName), *pData, 0o644); _err != nil {
+		return false, _err
+	}
+	return true, nil
+}
@@ -0,0 +1,118 @@
+package agniapp
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ahealth"
+
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// prefixes of the check names of the health report
+const (
+	health_app    = "app"
+	health_unit   = "unit/"
+	health_plugin = "plugin/"
+)
+
+// Register_Health_Check adds the given check to the health report of the application.
+// A check registered with the same name is replaced.
+func (app *AgniApp) Register_Health_Check(pName string, pCheck ahealth.HealthChecker) error {
+
+	if pName == "" || pCheck == nil {
+		return fmt.Errorf("health check %w", aerrors.ErrNilArgument)
+	}
+
+	app.health_lock.Lock()
+	defer app.health_lock.Unlock()
+	app.health_checks[pName] = pCheck
+	return nil
+}
+
+// Unregister_Health_Check removes the check registered with the given name
+func (app *AgniApp) Unregister_Health_Check(pName string) {
+	app.health_lock.Lock()
+	defer app.health_lock.Unlock()
+	delete(app.health_checks, pName)
+}
+
+// Health_Report runs the health checks of the application, the running units, the registered plugins
+// implementing ahealth.HealthChecker and the registered checks.
+//
+//	Units which do not implement ahealth.HealthChecker are ready while they are started.
+func (app *AgniApp) Health_Report(pCtx context.Context) ahealth.Report {
+
+	_checks := map[string]ahealth.HealthChecker{health_app: app_health{app}}
+
+	app.units_lock.RLock()
+	for _name, _pool := range app.units {
+		for _, _entry := range _pool.instances {
+			_key := health_unit + _name + "#" + strconv.Itoa(_entry.id)
+			if _check, _ok := _entry.instance.(ahealth.HealthChecker); _ok {
+				_checks[_key] = _check
+			} else {
+				_checks[_key] = unit_health{_entry.instance}
+			}
+		}
+	}
+	app.units_lock.RUnlock()
+
+	app.plugins_lock.RLock()
+	for _type, _client := range app.rest_clients {
+		if _check, _ok := _client.(ahealth.HealthChecker); _ok {
+			_checks[health_plugin+"rest/"+_type] = _check
+		}
+	}
+	for _type, _client := range app.ws_clients {
+		if _check, _ok := _client.(ahealth.HealthChecker); _ok {
+			_checks[health_plugin+"ws/"+_type] = _check
+		}
+	}
+	app.plugins_lock.RUnlock()
+
+	app.health_lock.RLock()
+	for _name, _check := range app.health_checks {
+		_checks[_name] = _check
+	}
+	app.health_lock.RUnlock()
+
+	return app.health.Check(pCtx, _checks)
+}
+
+// new_health_checker creates the checker with the health settings of the HTTP monitor
+func (app *AgniApp) new_health_checker() *ahealth.Checker {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+	return ahealth.New_Checker(
+		time.Duration(app.config.HTTPMonitor.HealthTimeout)*time.Millisecond,
+		time.Duration(app.config.HTTPMonitor.HealthCache)*time.Millisecond)
+}
+
+// app_health health of the application, ready after Start until Stop
+type app_health struct {
+	app *AgniApp
+}
+
+func (h app_health) Check_Health(pCtx context.Context) ahealth.Result {
+	_result := ahealth.Result{Live: true, Ready: h.app.ready.Load()}
+	if !_result.Ready {
+		_result.Error = "application is not started"
+	}
+	return _result
+}
+
+// unit_health health of a unit which does not implement ahealth.HealthChecker
+type unit_health struct {
+	unit iappunit.IAppUnit
+}
+
+func (h unit_health) Check_Health(pCtx context.Context) ahealth.Result {
+	_result := ahealth.Result{Live: true, Ready: h.unit.IsStarted()}
+	if !_result.Ready {
+		_result.Error = "unit is not started"
+	}
+	return _result
+}
@@ -0,0 +1,205 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/arotate"
+
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// log_writer writes the output of the slog handler to the log file or the log output
+type log_writer struct {
+	app *AgniApp
+}
+
+func (w log_writer) Write(pData []byte) (int, error) {
+	w.app.log_lock.Lock()
+	defer w.app.log_lock.Unlock()
+
+	if w.app.log_file != nil {
+		return w.app.log_file.Write(pData)
+	} else if w.app.log_out != nil {
+		return w.app.log_out.Write(pData)
+	}
+	return len(pData), nil
+}
+
+// log_leveler reports the current runtime log level to the slog handler, so Set_LogLevel applies to Logger
+type log_leveler struct {
+	app *AgniApp
+}
+
+func (l log_leveler) Level() slog.Level {
+	return atypes.LogLevel(l.app.log_level.Load()).Slog()
+}
+
+// new_logger creates the structured logger of the application for the given log format (text or json)
+func (app *AgniApp) new_logger(pFormat string) *slog.Logger {
+
+	_opts := &slog.HandlerOptions{
+		Level: log_leveler{app: app},
+		ReplaceAttr: func(pGroups []string, pAttr slog.Attr) slog.Attr {
+			/// use the framework level names (FATAL, PANIC, ...)
+			if len(pGroups) == 0 && pAttr.Key == slog.LevelKey {
+				if _level, _ok := pAttr.Value.Any().(slog.Level); _ok {
+					return slog.String(slog.LevelKey, atypes.From_SlogLevel(_level).String())
+				}
+			}
+			return pAttr
+		},
+	}
+
+	if strings.EqualFold(strings.TrimSpace(pFormat), atypes.LOG_FORMAT_JSON) {
+		app.log_json = true
+		return slog.New(slog.NewJSONHandler(log_writer{app: app}, _opts))
+	}
+	return slog.New(slog.NewTextHandler(log_writer{app: app}, _opts))
+}
+
+// Logger returns the structured logger of the application
+func (app *AgniApp) Logger() *slog.Logger {
+	return app.logger
+}
+
+// Set_Log_Output sets the writer used when the log file base path is not configured (default os.Stdout)
+func (app *AgniApp) Set_Log_Output(pOut io.Writer) {
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+	app.log_out = pOut
+}
+
+// open_log opens the log file under the configured log base path.
+// The log file is rotated according to the log_file_* settings of the log config.
+// Log entries are written to the log output (os.Stdout) when the base path is not configured.
+func (app *AgniApp) open_log() error {
+
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+
+	if app.log_file != nil || app.log_base == "" {
+		return nil
+	}
+
+	_base := app.resolve_path(app.log_base)
+	if _err := os.MkdirAll(_base, 0o755); _err != nil {
+		return fmt.Errorf("failed to create the log base path %s. %w", _base, _err)
+	}
+
+	_name := app.ID()
+	if _name == "" {
+		_name = "agniapp"
+	}
+	app.log_name = _name + ".log"
+
+	app.config_lock.RLock()
+	_config, _err := arotate.Config_From(_base, app.log_name, app.config.Log)
+	app.config_lock.RUnlock()
+	if _err != nil {
+		return _err
+	}
+
+	_file, _err := arotate.New(_config)
+	if _err != nil {
+		return _err
+	}
+	app.log_file = _file
+	return nil
+}
+
+// Rotate_Log rotates the log file (called on SIGHUP by Run).
+//
+//	Returns error if the log file is not opened or failed to rotate
+func (app *AgniApp) Rotate_Log() error {
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+
+	if app.log_file == nil {
+		return fmt.Errorf("log file %w", aerrors.ErrNotInitialized)
+	}
+	return app.log_file.Rotate()
+}
+
+// Logfile_List returns the names of the rotated log files (newest first)
+func (app *AgniApp) Logfile_List() ([]string, error) {
+	app.log_lock.Lock()
+	_file := app.log_file
+	app.log_lock.Unlock()
+
+	if _file == nil {
+		return []string{}, nil
+	}
+	_names, _err := _file.Backups()
+	if _err != nil {
+		return nil, _err
+	}
+	if _names == nil {
+		_names = []string{}
+	}
+	return _names, nil
+}
+
+// close_log closes the log file (if opened)
+func (app *AgniApp) close_log() {
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+
+	if app.log_file != nil {
+		app.log_file.Close()
+		app.log_file = nil
+	}
+}
+
+// Write2Console writes the given message to the console
+func (app *AgniApp) Write2Console(pMessage string) {
+	fmt.Println(pMessage)
+}
+
+// Write2Log writes the given entry to the log file if the level is enabled by the current log level.
+// The entry is written as the message of a JSON line when the log format is json.
+func (app *AgniApp) Write2Log(pEntry string, pLog_Level atypes.LogLevel) {
+
+	if int32(pLog_Level) > app.log_level.Load() {
+		return
+	}
+
+	if app.log_json {
+		app.logger.Log(context.Background(), pLog_Level.Slog(), pEntry)
+		return
+	}
+
+	_line := time.Now().Format("2006-01-02 15:04:05.000") + " " + pLog_Level.String() + " " + pEntry + "\n"
+
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+
+	if app.log_file != nil {
+		io.WriteString(app.log_file, _line)
+	} else if app.log_out != nil {
+		io.WriteString(app.log_out, _line)
+	}
+}
+
+// Set_LogLevel sets the current runtime log level
+func (app *AgniApp) Set_LogLevel(pLogLevel atypes.LogLevel) {
+	app.log_level.Store(int32(pLogLevel))
+}
+
+// Logfile_Basepath returns the log base path
+func (app *AgniApp) Logfile_Basepath() *string {
+	_path := app.resolve_path(app.log_base)
+	return &_path
+}
+
+// Logfile_Name returns the name of the application log file
+func (app *AgniApp) Logfile_Name() string {
+	app.log_lock.Lock()
+	defer app.log_lock.Unlock()
+	return app.log_name
+}
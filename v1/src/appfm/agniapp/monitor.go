@@ -0,0 +1,123 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ahealth"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Metrics returns the metrics registry of the application
+func (app *AgniApp) Metrics() *ametrics.Registry {
+	return app.metrics
+}
+
+// collect_status returns the samples of the application status and the running units
+func (app *AgniApp) collect_status() []ametrics.Sample {
+
+	_samples := ametrics.App_Samples(app.ID(), app.Get_App_Status(), time.Since(app.started).Seconds())
+
+	app.units_lock.RLock()
+	_entries := make([]*unit_entry, 0, len(app.start_order))
+	for _, _name := range app.start_order {
+		if _pool, _ok := app.units[_name]; _ok {
+			_entries = append(_entries, _pool.instances...)
+		}
+	}
+	app.units_lock.RUnlock()
+
+	for _, _entry := range _entries {
+		_info := _entry.status()
+		if _info == nil {
+			continue
+		}
+		_unit := *_info
+		_unit.Info.Name = _entry.config.Uname
+		_samples = append(_samples, ametrics.Unit_Samples(app.ID(), _entry.id, &_unit)...)
+	}
+	return _samples
+}
+
+// HTTP_Handler returns the handler of the HTTP monitor: /metrics, /healthz (liveness) and /readyz (readiness)
+func (app *AgniApp) HTTP_Handler() http.Handler {
+	_mux := http.NewServeMux()
+	_mux.Handle("/metrics", app.metrics.Handler())
+	_mux.Handle("/healthz", ahealth.Handler(app.Health_Report, false))
+	_mux.Handle("/readyz", ahealth.Handler(app.Health_Report, true))
+	return _mux
+}
+
+// Start_HTTPMonitor starts the HTTP monitor on the given address (host:port).
+//
+//	Returns error if the monitor is already started or failed to listen
+func (app *AgniApp) Start_HTTPMonitor(pAddress string) error {
+
+	app.http_lock.Lock()
+	defer app.http_lock.Unlock()
+
+	if app.http_server != nil {
+		return fmt.Errorf("http monitor %w", aerrors.ErrAlreadyStarted)
+	}
+
+	_listener, _err := net.Listen("tcp", pAddress)
+	if _err != nil {
+		return fmt.Errorf("failed to start the http monitor on %s. %w", pAddress, _err)
+	}
+
+	_server := &http.Server{Handler: app.HTTP_Handler(), ReadHeaderTimeout: 10 * time.Second}
+	app.http_server = _server
+	app.http_address = _listener.Addr().String()
+
+	go func() {
+		if _err := _server.Serve(_listener); _err != nil && !errors.Is(_err, http.ErrServerClosed) {
+			app.Write2Log(app.Name()+" - HTTP monitor stopped. "+_err.Error(), atypes.LOG_ERROR)
+		}
+	}()
+
+	app.Write2Log(app.Name()+" - HTTP monitor started on "+app.http_address, atypes.LOG_INFO)
+	return nil
+}
+
+// HTTPMonitor_Address returns the listening address of the HTTP monitor, empty if not started
+func (app *AgniApp) HTTPMonitor_Address() string {
+	app.http_lock.Lock()
+	defer app.http_lock.Unlock()
+	return app.http_address
+}
+
+// Stop_HTTPMonitor stops the HTTP monitor (if started)
+func (app *AgniApp) Stop_HTTPMonitor() error {
+
+	app.http_lock.Lock()
+	_server := app.http_server
+	app.http_server = nil
+	app.http_address = ""
+	app.http_lock.Unlock()
+
+	if _server == nil {
+		return nil
+	}
+
+	_ctx, _cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer _cancel()
+	return _server.Shutdown(_ctx)
+}
+
+// http_monitor_address returns the address of the configured HTTP monitor, empty if not enabled
+func (app *AgniApp) http_monitor_address() string {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+
+	if app.config.HTTPMonitor.Enable != 1 {
+		return ""
+	}
+	return net.JoinHostPort(app.config.HTTPMonitor.Host, strconv.Itoa(app.config.HTTPMonitor.Port))
+}
@@ -0,0 +1,44 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"strings"
+	"testing"
+)
+
+// panic_unit unit whose status and counters panic
+type panic_unit struct {
+	pool_unit
+}
+
+func (u *panic_unit) New() interface{}              { return &panic_unit{} }
+func (u *panic_unit) Status() *atypes.AppUnitInfo   { panic("status failed") }
+func (u *panic_unit) Counters() atypes.UnitCounters { panic("counters failed") }
+
+// TestScrapePanickingUnit scrapes the metrics while the status of a unit panics, the others are still exported
+func TestScrapePanickingUnit(t *testing.T) {
+	_app := New(t.TempDir(), &atypes.AppConfig{Appunits: []atypes.Appunit{{Uname: "good", Enable: 1}, {Uname: "bad", Enable: 1}}}, "")
+	if _err := _app.Register_Unit("good", &pool_unit{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _err := _app.Register_Unit("bad", &panic_unit{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _err := _app.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+	defer _app.Stop()
+
+	var _out strings.Builder
+	if _err := _app.Metrics().Write(&_out); _err != nil {
+		t.Fatal(_err)
+	}
+	_scrape := _out.String()
+	if !strings.Contains(_scrape, "agnione_app_requests_handled_total{") || !strings.Contains(_scrape, `unit="good"} 1`) {
+		t.Errorf("scrape without the application or the good unit:\n%s", _scrape)
+	}
+	if strings.Contains(_scrape, `unit="bad"`) {
+		t.Errorf("scrape with the panicking unit:\n%s", _scrape)
+	}
+}
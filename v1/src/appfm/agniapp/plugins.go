@@ -0,0 +1,247 @@
+package agniapp
+
+import (
+	"github.com/agnione/libs/v1/src/afplugins/http/aauth"
+	"github.com/agnione/libs/v1/src/afplugins/http/aintercept"
+	"github.com/agnione/libs/v1/src/afplugins/http/aresilience"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	iws "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/atls"
+
+	"errors"
+	"fmt"
+)
+
+// Register_RESTClient registers the HTTP client plugin with the given type name.
+// Get_RESTClient creates a new instance of the plugin using New() for every call.
+func (app *AgniApp) Register_RESTClient(pType string, pClient ihttp.IAHTTPClient) error {
+
+	if pClient == nil {
+		return fmt.Errorf("http client plugin %w", aerrors.ErrNilArgument)
+	}
+
+	app.plugins_lock.Lock()
+	defer app.plugins_lock.Unlock()
+	app.rest_clients[pType] = pClient
+	return nil
+}
+
+// Configure_Plugins sets the configs of the http and web socket client plugins (plugins.http and plugins.websocket
+// of the FMConfig) by their type. The clients returned by Get_RESTClient authorize the requests by the auth section,
+// the clients of a type share the credentials (OAuth2 token), and retry the requests and have a circuit breaker by
+// the resilience section. The clients of both kinds are initialized with the tls section, whose files are reloaded
+// when they change on the disk until Stop.
+//
+//	Returns aerrors.ErrInvalidArgument if an auth or tls section is not valid, Get_RESTClient/Get_WSClient of the
+//	plugin then fails (aerrors.ErrAuthentication for the auth), the other plugins are configured
+func (app *AgniApp) Configure_Plugins(pHTTP []atypes.PlugIn, pWebsocket []atypes.PlugIn) error {
+
+	var _errs []error
+	_configs := make(map[string]atypes.PlugIn, len(pHTTP))
+	_auth := make(map[string]aauth.Provider)
+	_rest_tls := app.new_plugins_tls("http client", pHTTP, &_errs)
+	for _, _config := range pHTTP {
+		_configs[_config.Type] = _config
+		_provider, _err := aauth.New_Provider(_config.Auth)
+		if _err != nil {
+			_errs = append(_errs, fmt.Errorf("http client plugin %s. %w", _config.Type, _err))
+			continue
+		}
+		if _provider != nil {
+			_auth[_config.Type] = _provider
+		}
+	}
+
+	_ws_configs := make(map[string]atypes.PlugIn, len(pWebsocket))
+	for _, _config := range pWebsocket {
+		_ws_configs[_config.Type] = _config
+	}
+	_ws_tls := app.new_plugins_tls("web socket client", pWebsocket, &_errs)
+
+	app.plugins_lock.Lock()
+	defer app.plugins_lock.Unlock()
+	/// the clients created before keep the certificates loaded last
+	close_plugins_tls(app.rest_tls)
+	close_plugins_tls(app.ws_tls)
+	app.rest_configs = _configs
+	app.rest_auth = _auth
+	app.rest_tls = _rest_tls
+	app.ws_configs = _ws_configs
+	app.ws_tls = _ws_tls
+	return errors.Join(_errs...)
+}
+
+// new_plugins_tls returns the tls settings of the plugins by type, watching their files.
+// The errors of the invalid tls sections are appended to pErrs.
+func (app *AgniApp) new_plugins_tls(pKind string, pPlugins []atypes.PlugIn, pErrs *[]error) map[string]*atls.TLS {
+
+	_tls := make(map[string]*atls.TLS)
+	for _, _config := range pPlugins {
+		_settings, _err := atls.New_TLS(_config.TLS)
+		if _err != nil {
+			*pErrs = append(*pErrs, fmt.Errorf("%s plugin %s. %w", pKind, _config.Type, _err))
+			continue
+		}
+		if _settings == nil {
+			continue
+		}
+
+		_name := pKind + " plugin " + _config.Type
+		_settings.Watch(func(pErr error) {
+			if pErr != nil {
+				app.Write2Log(_name+" - tls reload failed, the certificates loaded before are used. "+pErr.Error(), atypes.LOG_ERROR)
+				return
+			}
+			app.Write2Log(_name+" - tls certificates reloaded", atypes.LOG_INFO)
+		})
+		_tls[_config.Type] = _settings
+	}
+	return _tls
+}
+
+// close_plugins_tls stops watching the files of the tls settings
+func close_plugins_tls(pTLS map[string]*atls.TLS) {
+	for _, _settings := range pTLS {
+		_settings.Close()
+	}
+}
+
+// plugin_tls returns the tls settings of the plugin type, nil if none.
+//
+//	Returns aerrors.ErrInvalidArgument if the tls section of the plugin is not valid
+func plugin_tls(pKind string, pType string, pConfigs map[string]atypes.PlugIn, pTLS map[string]*atls.TLS) (*atls.TLS, error) {
+
+	if _settings, _ok := pTLS[pType]; _ok {
+		return _settings, nil
+	}
+	if _config, _ok := pConfigs[pType]; _ok && atls.Is_Enabled(_config.TLS) {
+		/// the requests are not sent without the tls settings of the config
+		return nil, fmt.Errorf("%s plugin %s has an invalid tls config. %w", pKind, pType, aerrors.ErrInvalidArgument)
+	}
+	return nil, nil
+}
+
+// Register_WSClient registers the web socket client plugin with the given type name.
+// Get_WSClient creates a new instance of the plugin using New() for every call.
+func (app *AgniApp) Register_WSClient(pType string, pClient iws.IAWSClient) error {
+
+	if pClient == nil {
+		return fmt.Errorf("web socket client plugin %w", aerrors.ErrNilArgument)
+	}
+
+	app.plugins_lock.Lock()
+	defer app.plugins_lock.Unlock()
+	app.ws_clients[pType] = pClient
+	return nil
+}
+
+// Get_RESTClient returns a new initialized instance of the registered HTTP client plugin
+func (app *AgniApp) Get_RESTClient(pType *string) (ihttp.IAHTTPClient, error) {
+
+	if pType == nil {
+		return nil, fmt.Errorf("http client type %w", aerrors.ErrNilArgument)
+	}
+
+	app.plugins_lock.Lock()
+	defer app.plugins_lock.Unlock()
+
+	_proto, _ok := app.rest_clients[*pType]
+	if !_ok {
+		return nil, fmt.Errorf("http client plugin %s. %w", *pType, aerrors.ErrPluginNotFound)
+	}
+	_tls, _err := plugin_tls("http client", *pType, app.rest_configs, app.rest_tls)
+	if _err != nil {
+		return nil, _err
+	}
+
+	_client, _ok := _proto.New().(ihttp.IAHTTPClient)
+	if !_ok || _client == nil {
+		return nil, fmt.Errorf("http client plugin %s failed to create a new instance", *pType)
+	}
+
+	app.last_plug_id++
+	if _tls != nil {
+		_tls_client, _ok := _client.(ihttp.IAHTTPClientTLS)
+		if !_ok {
+			return nil, fmt.Errorf("http client plugin %s does not support tls. %w", *pType, aerrors.ErrNotSupported)
+		}
+		if !_tls_client.Initialize_TLS(app.last_plug_id, _tls) {
+			return nil, fmt.Errorf("http client plugin %s failed to initialize. %w", *pType, aerrors.ErrNotInitialized)
+		}
+	} else if !_client.Initialize(app.last_plug_id) {
+		return nil, fmt.Errorf("http client plugin %s failed to initialize. %w", *pType, aerrors.ErrNotInitialized)
+	}
+
+	/// the retries authorize every attempt, every instance has its own circuit breaker
+	_config, _configured := app.rest_configs[*pType]
+	if _provider, _ok := app.rest_auth[*pType]; _ok {
+		_client = aauth.Wrap_HTTPClient(_client, _provider)
+	} else if _configured && _config.Auth.Type != "" {
+		/// the requests are not sent without the credentials of an invalid auth config
+		return nil, fmt.Errorf("http client plugin %s has an invalid auth config. %w", *pType, aerrors.ErrAuthentication)
+	}
+	if _configured {
+		if _policy := aresilience.Policy_From_Config(_config.Resilience); _policy.Is_Enabled() {
+			_client = aresilience.Wrap_HTTPClient(*pType, _client, _policy)
+		}
+	}
+	/// the interceptors see a request once, not every retry
+	return aintercept.Wrap_HTTPClient(*pType, _client, app.http_chain), nil
+}
+
+// Register_HTTPInterceptor adds the interceptor to the requests of all the http clients returned by Get_RESTClient,
+// also the clients created before. The interceptors are called by their order (lower first), the interceptors with
+// the same order by their registration, after the interceptors of the unit (AUBase.Register_HTTPInterceptor).
+//
+//	Returns aerrors.ErrNilArgument if the interceptor is nil, aerrors.ErrInvalidArgument if the name is empty or registered
+func (app *AgniApp) Register_HTTPInterceptor(pName string, pOrder int, pInterceptor aintercept.Interceptor) error {
+	return app.http_chain.Add(pName, pOrder, pInterceptor)
+}
+
+// Remove_HTTPInterceptor removes the interceptor registered with the given name.
+//
+//	Returns false if the name is not registered
+func (app *AgniApp) Remove_HTTPInterceptor(pName string) bool {
+	return app.http_chain.Remove(pName)
+}
+
+// Get_WSClient returns a new initialized instance of the registered web socket client plugin
+func (app *AgniApp) Get_WSClient(pType *string) (iws.IAWSClient, error) {
+
+	if pType == nil {
+		return nil, fmt.Errorf("web socket client type %w", aerrors.ErrNilArgument)
+	}
+
+	app.plugins_lock.Lock()
+	defer app.plugins_lock.Unlock()
+
+	_proto, _ok := app.ws_clients[*pType]
+	if !_ok {
+		return nil, fmt.Errorf("web socket client plugin %s. %w", *pType, aerrors.ErrPluginNotFound)
+	}
+	_tls, _err := plugin_tls("web socket client", *pType, app.ws_configs, app.ws_tls)
+	if _err != nil {
+		return nil, _err
+	}
+
+	_client, _ok := _proto.New().(iws.IAWSClient)
+	if !_ok || _client == nil {
+		return nil, fmt.Errorf("web socket client plugin %s failed to create a new instance", *pType)
+	}
+
+	app.last_plug_id++
+	if _tls != nil {
+		_tls_client, _ok := _client.(iws.IAWSClientTLS)
+		if !_ok {
+			return nil, fmt.Errorf("web socket client plugin %s does not support tls. %w", *pType, aerrors.ErrNotSupported)
+		}
+		if !_tls_client.Initialize_TLS(app.last_plug_id, _tls) {
+			return nil, fmt.Errorf("web socket client plugin %s failed to initialize. %w", *pType, aerrors.ErrNotInitialized)
+		}
+	} else if !_client.Initialize(app.last_plug_id) {
+		return nil, fmt.Errorf("web socket client plugin %s failed to initialize. %w", *pType, aerrors.ErrNotInitialized)
+	}
+	return _client, nil
+}
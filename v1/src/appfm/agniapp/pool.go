@@ -0,0 +1,269 @@
+package agniapp
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MAX_POOL_SIZE max number of instances of a unit
+const MAX_POOL_SIZE = 1024
+
+// unit_pool holds the running instances of an application unit. The instances are guarded by units_lock.
+type unit_pool struct {
+	config    atypes.Appunit
+	size      int           /// number of instances to keep running, PoolSize of the config or set by Resize_Unit_Pool
+	instances []*unit_entry /// running instances in the start order
+	next      atomic.Uint64 /// position of the round robin dispatch
+}
+
+// pool_size returns the number of instances of the unit config, 1 if not set
+func pool_size(pConfig atypes.Appunit) int {
+	if pConfig.PoolSize < 1 {
+		return 1
+	}
+	return int(pConfig.PoolSize)
+}
+
+// pool_strategy returns the dispatch strategy of the unit config, POOL_ROUND_ROBIN if not set or not valid
+func pool_strategy(pConfig atypes.Appunit) string {
+	if strings.ToLower(pConfig.PoolStrategy) == atypes.POOL_LEAST_ACTIVE {
+		return atypes.POOL_LEAST_ACTIVE
+	}
+	return atypes.POOL_ROUND_ROBIN
+}
+
+// Dispatch runs the given work with a running instance of the unit picked by the pool strategy of the unit:
+// round-robin (default) or least-active, the instance with the least active executions (AppUnitInfo.Active),
+// or the least calls in progress if the unit does not count them.
+//
+//	Returns the error of the work. Unless returns UnitError wrapping aerrors.ErrNotStarted if no instance is running
+func (app *AgniApp) Dispatch(pUnit_Name string, pWork func(pUnit iappunit.IAppUnit) error) error {
+
+	if pWork == nil {
+		return fmt.Errorf("work %w", aerrors.ErrNilArgument)
+	}
+
+	_entry := app.pick_instance(pUnit_Name)
+	if _entry == nil {
+		return &aerrors.UnitError{Unit: pUnit_Name, Op: "dispatch", Err: aerrors.ErrNotStarted}
+	}
+
+	defer _entry.inflight.Add(-1)
+	return pWork(_entry.instance)
+}
+
+// pick_instance returns the running instance of the unit picked by the pool strategy, nil if none.
+// The call is counted in progress on the picked instance before units_lock is released, so the instance
+// is not stopped before the call completes.
+func (app *AgniApp) pick_instance(pUnit_Name string) *unit_entry {
+
+	app.units_lock.RLock()
+	defer app.units_lock.RUnlock()
+
+	_entry := app.pick_entry(pUnit_Name)
+	if _entry != nil {
+		_entry.dispatched.Add(1)
+		_entry.inflight.Add(1)
+	}
+	return _entry
+}
+
+// pick_entry returns the running instance of the unit picked by the pool strategy, nil if none. Called holding units_lock
+func (app *AgniApp) pick_entry(pUnit_Name string) *unit_entry {
+
+	_pool, _ok := app.units[pUnit_Name]
+	if !_ok || len(_pool.instances) == 0 {
+		return nil
+	}
+
+	_count := len(_pool.instances)
+	_start := int((_pool.next.Add(1) - 1) % uint64(_count))
+	_least_active := pool_strategy(_pool.config) == atypes.POOL_LEAST_ACTIVE
+
+	var _picked *unit_entry
+	var _picked_load int64
+	for _i := 0; _i < _count; _i++ {
+		_entry := _pool.instances[(_start+_i)%_count]
+		if unit_state(_entry) != atypes.UNIT_RUNNING {
+			continue
+		}
+		if !_least_active {
+			return _entry
+		}
+		/// ties are broken by the round robin position
+		if _load := _entry.load(); _picked == nil || _load < _picked_load {
+			_picked, _picked_load = _entry, _load
+		}
+	}
+	return _picked
+}
+
+// load returns the active executions of the instance, the calls in progress if the unit does not count them
+func (e *unit_entry) load() int64 {
+	_load := e.inflight.Load()
+	var _active int64
+	if _unit, _ok := e.instance.(iappunit.IAppUnitCounters); _ok {
+		_active = _unit.Counters().Active
+	} else if _info := e.status(); _info != nil {
+		_active = int64(_info.Active)
+	}
+	return max(_load, _active)
+}
+
+// Resize_Unit_Pool changes the number of running instances of the unit.
+// New instances are started, the newest instances are stopped when the pool shrinks.
+// The calls in progress on a stopped instance are given the stop timeout of the unit to complete.
+//
+//	Returns aerrors.ErrInvalidArgument if the size is not between 1 and MAX_POOL_SIZE, aerrors.ErrNotStarted if the unit is not running
+func (app *AgniApp) Resize_Unit_Pool(pUnit_Name string, pSize int) (bool, error) {
+
+	if pSize < 1 || pSize > MAX_POOL_SIZE {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "resize",
+			Err: fmt.Errorf("pool size %d is not between 1 and %d. %w", pSize, MAX_POOL_SIZE, aerrors.ErrInvalidArgument)}
+	}
+
+	app.units_lock.Lock()
+	_pool, _ok := app.units[pUnit_Name]
+	if !_ok {
+		app.units_lock.Unlock()
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "resize", Err: aerrors.ErrNotStarted}
+	}
+	_pool.size = pSize
+	var _removed []*unit_entry
+	for len(_pool.instances) > pSize {
+		_last := len(_pool.instances) - 1
+		_removed = append(_removed, _pool.instances[_last])
+		app.set_stopping(_pool.instances[_last])
+		_pool.instances = _pool.instances[:_last]
+	}
+	_missing := pSize - len(_pool.instances)
+	app.units_lock.Unlock()
+
+	app.Write2Log(app.Name()+" - Resizing the pool of the unit "+pUnit_Name+" to "+strconv.Itoa(pSize), atypes.LOG_INFO)
+
+	if _err := app.stop_instances(_removed, false); _err != nil {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "resize", Err: _err}
+	}
+	if _missing > 0 {
+		if _err := app.start_unit(pUnit_Name); _err != nil {
+			return false, _err
+		}
+	}
+	return true, nil
+}
+
+// stop_instances stops the given instances concurrently
+func (app *AgniApp) stop_instances(pEntries []*unit_entry, pForce bool) error {
+
+	_errs := make([]error, len(pEntries))
+	var _wg sync.WaitGroup
+	for _i, _entry := range pEntries {
+		_wg.Add(1)
+		go func(pIndex int, pEntry *unit_entry) {
+			defer _wg.Done()
+			if _err := app.stop_unit(pEntry, pForce); _err != nil {
+				_errs[pIndex] = fmt.Errorf("instance %d. %w", pEntry.id, _err)
+			}
+		}(_i, _entry)
+	}
+	_wg.Wait()
+	return errors.Join(_errs...)
+}
+
+// pool_status returns the status of the pool with the sums of the counters and the operations of the instances.
+// The memory usage is the process memory usage, the same for all the instances.
+func (app *AgniApp) pool_status(pPool *unit_pool, pEntries []*unit_entry, pSize int) *atypes.AppUnitInfo {
+
+	var _info *atypes.AppUnitInfo
+	_pool := atypes.PoolStatus{Size: pSize, Strategy: pool_strategy(pPool.config)}
+
+	for _, _entry := range pEntries {
+		_status := _entry.status()
+		if _status == nil {
+			continue
+		}
+		_pool.Instances = append(_pool.Instances, atypes.InstanceStatus{
+			ID:          _entry.id,
+			State:       _status.State,
+			Active:      _status.Active,
+			Req_Handled: _status.Req_Handled,
+			Req_Failed:  _status.Req_Failed,
+			Dispatched:  _entry.dispatched.Load(),
+		})
+
+		if _info == nil {
+			_copy := *_status
+			_copy.Operations = merge_operations(nil, _status.Operations)
+			_info = &_copy
+			continue
+		}
+		_info.Operations = merge_operations(_info.Operations, _status.Operations)
+		_info.Req_Handled += _status.Req_Handled
+		_info.Req_Failed += _status.Req_Failed
+		_info.Routines += _status.Routines
+		_info.Active += _status.Active
+		_info.Msg_Dropped += _status.Msg_Dropped
+		_info.Resources.Routines += _status.Resources.Routines
+		_info.Resources.Pool_Gets += _status.Resources.Pool_Gets
+		_info.Resources.Pool_News += _status.Resources.Pool_News
+		_info.Resources.Pool_Puts += _status.Resources.Pool_Puts
+		_info.Resources.Bytes_Sent += _status.Resources.Bytes_Sent
+		_info.Resources.Bytes_Received += _status.Resources.Bytes_Received
+		_info.Bus.Published += _status.Bus.Published
+		_info.Bus.Received += _status.Bus.Received
+		_info.Bus.Failed += _status.Bus.Failed
+		_info.Bus.Dropped += _status.Bus.Dropped
+		_info.Bus.Requests += _status.Bus.Requests
+		_info.Bus.Subscriptions += _status.Bus.Subscriptions
+		if _status.State == atypes.UNIT_RUNNING {
+			_info.State = atypes.UNIT_RUNNING
+		}
+	}
+
+	if _info == nil {
+		return nil
+	}
+	_info.Pool = _pool
+	return _info
+}
+
+// merge_operations adds the operations of an instance to the operations of the pool. The totals and the rates are
+// summed, the error rate is weighted by the rates. The percentiles can not be merged, the highest of the instances is kept.
+func merge_operations(pPool map[string]atypes.OperationStats, pInstance map[string]atypes.OperationStats) map[string]atypes.OperationStats {
+
+	if len(pInstance) == 0 {
+		return pPool
+	}
+	if pPool == nil {
+		pPool = make(map[string]atypes.OperationStats, len(pInstance))
+	}
+
+	for _op, _stats := range pInstance {
+		_merged, _ok := pPool[_op]
+		if !_ok {
+			pPool[_op] = _stats
+			continue
+		}
+		_rate := _merged.Rate + _stats.Rate
+		if _rate > 0 {
+			_merged.Error_Rate = (_merged.Error_Rate*_merged.Rate + _stats.Error_Rate*_stats.Rate) / _rate
+		}
+		_merged.Rate = _rate
+		_merged.Handled += _stats.Handled
+		_merged.Failed += _stats.Failed
+		_merged.P50_Ms = max(_merged.P50_Ms, _stats.P50_Ms)
+		_merged.P95_Ms = max(_merged.P95_Ms, _stats.P95_Ms)
+		_merged.P99_Ms = max(_merged.P99_Ms, _stats.P99_Ms)
+		_merged.Window_Seconds = max(_merged.Window_Seconds, _stats.Window_Seconds)
+		pPool[_op] = _merged
+	}
+	return pPool
+}
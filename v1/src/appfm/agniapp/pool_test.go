@@ -0,0 +1,116 @@
+package agniapp
+
+import (
+	AUBase "github.com/agnione/libs/v1/src/aau/base"
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	build "github.com/agnione/libs/v1/src/lib"
+
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// pool_unit unit built on AUBase for the pool tests
+type pool_unit struct {
+	AUBase.AUBase
+}
+
+func (u *pool_unit) New() interface{}      { return &pool_unit{} }
+func (u *pool_unit) GetID() int            { return u.ID }
+func (u *pool_unit) Info() build.BuildInfo { return build.BuildInfo{Version: "test"} }
+
+// TestResizeWhileDispatching resizes the pool while calls are dispatched and the status is read,
+// a call never runs on a stopped or deinitialized instance (run with -race)
+func TestResizeWhileDispatching(t *testing.T) {
+	_name := "pool"
+	_config := &atypes.AppConfig{Appunits: []atypes.Appunit{{Uname: _name, Enable: 1, PoolSize: 2}}}
+	_app := New(t.TempDir(), _config, "")
+	if _err := _app.Register_Unit(_name, &pool_unit{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _err := _app.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+	defer _app.Stop()
+
+	var _stop atomic.Bool
+	var _calls atomic.Int64
+	var _wg sync.WaitGroup
+	_errs := make(chan error, 1)
+	for _i := 0; _i < 8; _i++ {
+		_wg.Add(1)
+		go func() {
+			defer _wg.Done()
+			for !_stop.Load() {
+				_err := _app.Dispatch(_name, func(pUnit iappunit.IAppUnit) error {
+					_calls.Add(1)
+					if _state := pUnit.(*pool_unit).State(); _state != atypes.UNIT_RUNNING {
+						return fmt.Errorf("dispatched to an instance in state %v", _state)
+					}
+					return nil
+				})
+				if _err != nil {
+					select {
+					case _errs <- _err:
+					default:
+					}
+					return
+				}
+			}
+		}()
+	}
+	for _i := 0; _i < 4; _i++ {
+		_wg.Add(1)
+		go func() {
+			defer _wg.Done()
+			for !_stop.Load() {
+				_app.Unit_Status(&_name)
+				_app.Get_App_Info()
+			}
+		}()
+	}
+
+	for _i := 0; _i < 40; _i++ {
+		if _, _err := _app.Resize_Unit_Pool(_name, 1+_i%4); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+	_stop.Store(true)
+	_wg.Wait()
+
+	select {
+	case _err := <-_errs:
+		t.Fatal(_err)
+	default:
+	}
+	if _calls.Load() == 0 {
+		t.Error("no call was dispatched")
+	}
+}
+
+func TestMergeOperations(t *testing.T) {
+	_first := map[string]atypes.OperationStats{
+		"get": {Handled: 90, Failed: 10, Rate: 10, Error_Rate: 0.1, P50_Ms: 5, P95_Ms: 20, P99_Ms: 40, Window_Seconds: 60},
+	}
+	_second := map[string]atypes.OperationStats{
+		"get": {Handled: 30, Failed: 0, Rate: 30, Error_Rate: 0, P50_Ms: 8, P95_Ms: 10, P99_Ms: 50, Window_Seconds: 60},
+		"put": {Handled: 1, Rate: 1},
+	}
+
+	_pool := merge_operations(nil, _first)
+	_pool = merge_operations(_pool, _second)
+
+	_get := _pool["get"]
+	_want := atypes.OperationStats{Handled: 120, Failed: 10, Rate: 40, Error_Rate: 0.025, P50_Ms: 8, P95_Ms: 20, P99_Ms: 50, Window_Seconds: 60}
+	if _get != _want {
+		t.Errorf("merged get = %+v, want %+v", _get, _want)
+	}
+	if _pool["put"] != _second["put"] {
+		t.Errorf("merged put = %+v, want %+v", _pool["put"], _second["put"])
+	}
+	if _first["get"].Handled != 90 {
+		t.Error("operations of the first instance were changed")
+	}
+}
@@ -0,0 +1,398 @@
+package agniapp
+
+import (
+	autypes "github.com/agnione/libs/v1/src/aau/types"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SUPERVISE_INTERVAL interval of the check of the supervised unit states
+const SUPERVISE_INTERVAL = time.Second
+
+// restart_state supervision of a unit with a restart policy
+type restart_state struct {
+	restarts []time.Time /// restarts within the restart period
+	total    int
+	last     time.Time
+	reason   string
+	next     time.Time
+	timer    *time.Timer /// scheduled restart
+	gave_up  bool
+}
+
+// unit_failure failure reported by Unit_Failed
+type unit_failure struct {
+	unit   string
+	reason error
+}
+
+// Unit_Failed reports that the given unit instance failed (iappfw.IAgniAppSupervisor).
+// The failure is handled by the supervision routine.
+func (app *AgniApp) Unit_Failed(pUnit_Name string, pInstance_ID int, pReason error) {
+
+	if _, _err := app.unit_config(pUnit_Name); _err != nil {
+		return
+	}
+
+	app.sup_lock.Lock()
+	app.failures[pInstance_ID] = unit_failure{unit: pUnit_Name, reason: pReason}
+	app.sup_lock.Unlock()
+
+	select {
+	case app.sup_wake <- struct{}{}:
+	default:
+	}
+}
+
+// restart_policy returns the restart policy of the unit config, RESTART_NEVER if not set or not valid
+func restart_policy(pConfig atypes.Appunit) string {
+	switch _policy := strings.ToLower(pConfig.Restart); _policy {
+	case atypes.RESTART_ON_FAILURE, atypes.RESTART_ALWAYS:
+		return _policy
+	}
+	return atypes.RESTART_NEVER
+}
+
+// supervise checks the states of the supervised units until the application context is cancelled
+func (app *AgniApp) supervise() {
+
+	_ticker := time.NewTicker(SUPERVISE_INTERVAL)
+	defer _ticker.Stop()
+
+	for {
+		select {
+		case <-app.ctx.Done():
+			return
+		case <-_ticker.C:
+		case <-app.sup_wake:
+		}
+		app.check_units()
+	}
+}
+
+// check_units stops the running instances which failed, or stopped by themselves when the restart policy is always,
+// and schedules their restart. The restart starts the instances missing from the pool of the unit.
+// The failed instances of the units with the never policy are stopped too, so their dependents do not keep running.
+func (app *AgniApp) check_units() {
+
+	app.units_lock.RLock()
+	_entries := make([]*unit_entry, 0, len(app.units))
+	for _, _pool := range app.units {
+		_entries = append(_entries, _pool.instances...)
+	}
+	app.units_lock.RUnlock()
+
+	/// failures of the instances which are not running any more (stopped by a resize)
+	_running := make(map[int]bool, len(_entries))
+	for _, _entry := range _entries {
+		_running[_entry.id] = true
+	}
+	app.sup_lock.Lock()
+	for _id := range app.failures {
+		if !_running[_id] {
+			delete(app.failures, _id)
+		}
+	}
+	app.sup_lock.Unlock()
+
+	for _, _entry := range _entries {
+
+		_name := _entry.config.Uname
+		app.sup_lock.Lock()
+		_failure, _reported := app.failures[_entry.id]
+		delete(app.failures, _entry.id)
+		app.sup_lock.Unlock()
+
+		var _reason error
+		switch _state := unit_state(_entry); {
+		case _state == atypes.UNIT_FAILED:
+			_reason = errors.New("unit failed")
+			if _reported && _failure.reason != nil {
+				_reason = _failure.reason
+			}
+		case _state == atypes.UNIT_STOPPED || _state == atypes.UNIT_CREATED:
+			if restart_policy(_entry.config) != atypes.RESTART_ALWAYS {
+				continue
+			}
+			_reason = errors.New("unit stopped")
+		default:
+			continue
+		}
+
+		/// the instance is stopped now, the new instance is started after the backoff
+		if !app.remove_instance(_name, _entry) {
+			continue
+		}
+		go func(pEntry *unit_entry, pReason error) {
+			/// the units depending on the unit stop before it, unless other instances of the unit are running
+			if !app.is_running(pEntry.config.Uname) {
+				if _err := app.stop_dependents(pEntry.config.Uname, false, false); _err != nil {
+					app.Write2Log(app.Name()+" - "+_err.Error(), atypes.LOG_ERROR)
+				}
+			}
+			app.stop_unit(pEntry, false)
+			if restart_policy(pEntry.config) == atypes.RESTART_NEVER {
+				app.Write2Log(app.Name()+" - Unit "+pEntry.config.Uname+" "+pReason.Error()+". Not restarted, the restart policy is never", atypes.LOG_ERROR)
+				return
+			}
+			app.schedule_restart(pEntry.config, pReason)
+		}(_entry, _reason)
+	}
+}
+
+// unit_state returns the state of the unit instance, from IsStarted if the unit does not report its state
+func unit_state(pEntry *unit_entry) atypes.UnitState {
+	if _unit, _ok := pEntry.instance.(interface{ State() atypes.UnitState }); _ok {
+		return _unit.State()
+	}
+	if pEntry.instance.IsStarted() {
+		return atypes.UNIT_RUNNING
+	}
+	return atypes.UNIT_STOPPED
+}
+
+// schedule_restart schedules the restart of the given unit after the backoff, or gives up the unit
+// when the restart intensity is exceeded
+func (app *AgniApp) schedule_restart(pConfig atypes.Appunit, pReason error) {
+
+	_policy := restart_policy(pConfig)
+	if _policy == atypes.RESTART_NEVER {
+		return
+	}
+	_name := pConfig.Uname
+	_now := time.Now()
+
+	_period := time.Duration(pConfig.RestartPeriod) * time.Second
+	if _period <= 0 {
+		_period = atypes.DEFAULT_RESTART_PERIOD * time.Second
+	}
+	_intensity := pConfig.RestartIntensity
+	if _intensity <= 0 {
+		_intensity = atypes.DEFAULT_RESTART_INTENSITY
+	}
+
+	app.sup_lock.Lock()
+	if !app.supervising {
+		app.sup_lock.Unlock()
+		return
+	}
+	_state, _ok := app.restarts[_name]
+	if !_ok {
+		_state = &restart_state{}
+		app.restarts[_name] = _state
+	}
+	if _state.timer != nil || _state.gave_up {
+		app.sup_lock.Unlock()
+		return
+	}
+	_state.reason = pReason.Error()
+
+	_recent := _state.restarts[:0]
+	for _, _time := range _state.restarts {
+		if _now.Sub(_time) < _period {
+			_recent = append(_recent, _time)
+		}
+	}
+	_state.restarts = _recent
+
+	if len(_state.restarts) >= _intensity {
+		_state.gave_up = true
+		app.sup_lock.Unlock()
+		app.give_up(pConfig, pReason, len(_state.restarts), _period)
+		return
+	}
+
+	_delay := restart_backoff(pConfig, len(_state.restarts))
+	_state.next = _now.Add(_delay)
+	_state.timer = time.AfterFunc(_delay, func() { app.restart_unit(pConfig) })
+	app.sup_lock.Unlock()
+
+	app.Write2Log(app.Name()+" - Unit "+_name+" "+pReason.Error()+". Restarting in "+_delay.String(), atypes.LOG_WARN)
+	app.publish_supervision(_name, "Restarting", map[string]string{
+		"reason":  pReason.Error(),
+		"policy":  _policy,
+		"backoff": _delay.String(),
+	})
+}
+
+// restart_backoff returns the delay before the restart, doubled for every restart within the period
+func restart_backoff(pConfig atypes.Appunit, pRestarts int) time.Duration {
+
+	_backoff := time.Duration(pConfig.RestartBackoff) * time.Millisecond
+	if _backoff <= 0 {
+		_backoff = atypes.DEFAULT_RESTART_BACKOFF * time.Millisecond
+	}
+	_max := time.Duration(pConfig.RestartMaxBackoff) * time.Millisecond
+	if _max <= 0 {
+		_max = atypes.DEFAULT_RESTART_MAX_BACKOFF * time.Millisecond
+	}
+	for _i := 0; _i < pRestarts && _backoff < _max; _i++ {
+		_backoff *= 2
+	}
+	if _backoff > _max {
+		_backoff = _max
+	}
+	return _backoff
+}
+
+// restart_unit starts a new instance of the unit when the backoff passes.
+// A failed start is handled by start_unit, which schedules the next restart
+func (app *AgniApp) restart_unit(pConfig atypes.Appunit) {
+
+	_name := pConfig.Uname
+	app.sup_lock.Lock()
+	_state, _ok := app.restarts[_name]
+	if !_ok || _state.timer == nil || !app.supervising {
+		app.sup_lock.Unlock()
+		return
+	}
+	_state.timer = nil
+	_state.next = time.Time{}
+	_state.last = time.Now()
+	_state.total++
+	_state.restarts = append(_state.restarts, _state.last)
+	app.sup_lock.Unlock()
+
+	if _err := app.start_unit(_name); _err != nil {
+		if errors.Is(_err, aerrors.ErrDependency) {
+			/// started again when its dependencies are running
+			app.set_waiting(_name, true)
+		}
+		app.Write2Log(app.Name()+" - Failed to restart the unit "+_name+". "+_err.Error(), atypes.LOG_ERROR)
+		return
+	}
+	app.publish_supervision(_name, "Restarted", map[string]string{"restarts": strconv.Itoa(app.restart_count(_name))})
+}
+
+// give_up stops the supervision of the unit and stops the application if the unit escalates
+func (app *AgniApp) give_up(pConfig atypes.Appunit, pReason error, pRestarts int, pPeriod time.Duration) {
+
+	_err := &aerrors.UnitError{Unit: pConfig.Uname, Op: "restart",
+		Err: fmt.Errorf("%d restarts within %s, last failure : %v. %w", pRestarts, pPeriod, pReason, aerrors.ErrRestartLimit)}
+
+	app.Write2Log(app.Name()+" - Unit "+pConfig.Uname+" is given up. "+_err.Error(), atypes.LOG_ERROR)
+	app.publish_supervision(pConfig.Uname, "GaveUp", map[string]string{
+		"reason":   pReason.Error(),
+		"restarts": strconv.Itoa(pRestarts),
+		"period":   pPeriod.String(),
+	})
+
+	if pConfig.RestartEscalate != 1 {
+		return
+	}
+
+	app.sup_lock.Lock()
+	if app.escalation == nil {
+		app.escalation = _err
+	}
+	app.sup_lock.Unlock()
+
+	app.Write2Log(app.Name()+" - Unit "+pConfig.Uname+" escalates the failure. Stopping the application", atypes.LOG_ERROR)
+	app.Interrupt()
+}
+
+// cancel_restart cancels the scheduled restart of the unit and clears its supervision (manual start/stop)
+func (app *AgniApp) cancel_restart(pUnit_Name string) {
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+
+	if _state, _ok := app.restarts[pUnit_Name]; _ok {
+		if _state.timer != nil {
+			_state.timer.Stop()
+			_state.timer = nil
+			_state.next = time.Time{}
+		}
+		_state.gave_up = false
+		_state.restarts = nil
+	}
+	for _id, _failure := range app.failures {
+		if _failure.unit == pUnit_Name {
+			delete(app.failures, _id)
+		}
+	}
+}
+
+// stop_supervision stops the restarts, the units are being stopped by the application
+func (app *AgniApp) stop_supervision() {
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+
+	app.supervising = false
+	for _, _state := range app.restarts {
+		if _state.timer != nil {
+			_state.timer.Stop()
+			_state.timer = nil
+			_state.next = time.Time{}
+		}
+	}
+}
+
+// Escalation returns the error of the unit which stopped the application by escalating its failure, nil if none
+func (app *AgniApp) Escalation() error {
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+	return app.escalation
+}
+
+// restart_status returns the restart status of the unit, false if the unit is not supervised
+func (app *AgniApp) restart_status(pUnit_Name string) (atypes.RestartStatus, bool) {
+
+	_config, _err := app.unit_config(pUnit_Name)
+	if _err != nil || restart_policy(_config) == atypes.RESTART_NEVER {
+		return atypes.RestartStatus{}, false
+	}
+	_status := atypes.RestartStatus{Policy: restart_policy(_config)}
+
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+	if _state, _ok := app.restarts[pUnit_Name]; _ok {
+		_status.Restarts = _state.total
+		_status.Last_Reason = _state.reason
+		_status.Gave_Up = _state.gave_up
+		if !_state.last.IsZero() {
+			_status.Last_Restart = _state.last.Format(time.RFC3339)
+		}
+		if !_state.next.IsZero() {
+			_status.Next_Restart = _state.next.Format(time.RFC3339)
+		}
+	}
+	return _status, true
+}
+
+// supervised_units returns the names of the supervised units with a restart status, sorted
+func (app *AgniApp) supervised_units() []string {
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+	_names := make([]string, 0, len(app.restarts))
+	for _name := range app.restarts {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+	return _names
+}
+
+// publish_supervision sends the supervision event of the unit as a monitoring message
+func (app *AgniApp) publish_supervision(pUnit_Name string, pStatus string, pInfo map[string]string) {
+	pInfo["unit"] = pUnit_Name
+	if _msg, _err := json.Marshal(&autypes.Status{AppID: app.ID(), ID: pUnit_Name, Status: pStatus, Info: pInfo}); _err == nil {
+		app.Send_Monitor_Message(_msg)
+	}
+}
+
+// restart_count returns the number of restarts of the unit
+func (app *AgniApp) restart_count(pUnit_Name string) int {
+	app.sup_lock.Lock()
+	defer app.sup_lock.Unlock()
+	if _state, _ok := app.restarts[pUnit_Name]; _ok {
+		return _state.total
+	}
+	return 0
+}
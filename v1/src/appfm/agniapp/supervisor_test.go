@@ -0,0 +1,173 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"errors"
+	"testing"
+	"time"
+)
+
+// running_instance returns the first running instance of the unit, nil if none
+func running_instance(pApp *AgniApp, pUnit_Name string) *pool_unit {
+	pApp.units_lock.RLock()
+	defer pApp.units_lock.RUnlock()
+	if _pool, _ok := pApp.units[pUnit_Name]; _ok && len(_pool.instances) > 0 {
+		return _pool.instances[0].instance.(*pool_unit)
+	}
+	return nil
+}
+
+// wait_until polls the condition until it is true or the timeout passes
+func wait_until(pTimeout time.Duration, pCondition func() bool) bool {
+	for _deadline := time.Now().Add(pTimeout); time.Now().Before(_deadline); time.Sleep(time.Millisecond) {
+		if pCondition() {
+			return true
+		}
+	}
+	return pCondition()
+}
+
+// start_supervised starts an application running the unit with the given config
+func start_supervised(t *testing.T, pConfig atypes.Appunit) *AgniApp {
+	pConfig.Uname, pConfig.Enable = "unit", 1
+	_app := New(t.TempDir(), &atypes.AppConfig{Appunits: []atypes.Appunit{pConfig}}, "")
+	if _err := _app.Register_Unit("unit", &pool_unit{}); _err != nil {
+		t.Fatal(_err)
+	}
+	if _err := _app.Start(); _err != nil {
+		t.Fatal(_err)
+	}
+	t.Cleanup(func() { _app.Stop() })
+	return _app
+}
+
+func TestRestartBackoff(t *testing.T) {
+	_tests := []struct {
+		backoff  int
+		max      int
+		restarts int
+		want     time.Duration
+	}{
+		{restarts: 0, want: time.Second},
+		{restarts: 1, want: 2 * time.Second},
+		{restarts: 5, want: 32 * time.Second},
+		{restarts: 6, want: time.Minute},
+		{restarts: 100, want: time.Minute},
+		{backoff: 100, max: 1000, restarts: 0, want: 100 * time.Millisecond},
+		{backoff: 100, max: 1000, restarts: 3, want: 800 * time.Millisecond},
+		{backoff: 100, max: 1000, restarts: 4, want: time.Second},
+		{backoff: 2000, max: 1000, restarts: 0, want: time.Second},
+	}
+	for _, _test := range _tests {
+		_config := atypes.Appunit{RestartBackoff: _test.backoff, RestartMaxBackoff: _test.max}
+		if _got := restart_backoff(_config, _test.restarts); _got != _test.want {
+			t.Errorf("restart_backoff(%d, %d, %d) = %v, want %v", _test.backoff, _test.max, _test.restarts, _got, _test.want)
+		}
+	}
+}
+
+func TestRestartPolicies(t *testing.T) {
+	_tests := []struct {
+		policy   string
+		fail     bool /// the unit fails, stops by itself otherwise
+		restarts int
+		removed  bool /// the instance is stopped and removed from the pool
+	}{
+		{policy: atypes.RESTART_NEVER, fail: true, restarts: 0, removed: true},
+		{policy: atypes.RESTART_NEVER, fail: false, restarts: 0},
+		{policy: atypes.RESTART_ON_FAILURE, fail: true, restarts: 1, removed: true},
+		{policy: atypes.RESTART_ON_FAILURE, fail: false, restarts: 0},
+		{policy: atypes.RESTART_ALWAYS, fail: true, restarts: 1, removed: true},
+		{policy: atypes.RESTART_ALWAYS, fail: false, restarts: 1, removed: true},
+	}
+	for _, _test := range _tests {
+		_name := _test.policy + "/stopped"
+		if _test.fail {
+			_name = _test.policy + "/failed"
+		}
+		t.Run(_name, func(t *testing.T) {
+			_app := start_supervised(t, atypes.Appunit{Restart: _test.policy, RestartBackoff: 10})
+			_unit := running_instance(_app, "unit")
+			if _test.fail {
+				_unit.Set_Failed(errors.New("disk full"))
+			} else {
+				_unit.Stop()
+			}
+			_app.check_units()
+
+			if _test.restarts == 0 {
+				time.Sleep(100 * time.Millisecond)
+			} else if !wait_until(5*time.Second, func() bool { return running_instance(_app, "unit") != nil }) {
+				t.Fatal("the unit is not restarted")
+			}
+			if _got := _app.restart_count("unit"); _got != _test.restarts {
+				t.Errorf("restarts = %d, want %d", _got, _test.restarts)
+			}
+			_running := running_instance(_app, "unit")
+			if _replaced := _running != nil && _running != _unit; _replaced != (_test.restarts > 0) {
+				t.Errorf("instance replaced %v, want %v", _replaced, _test.restarts > 0)
+			}
+			if _removed := _running != _unit; _removed != _test.removed {
+				t.Errorf("instance removed %v, want %v", _removed, _test.removed)
+			}
+		})
+	}
+}
+
+// TestRestartIntensity fails the unit until the restart intensity is exceeded. The backoff doubles for every
+// restart within the period, then the unit is given up and, with restart_escalate, the application is stopped.
+func TestRestartIntensity(t *testing.T) {
+	_tests := []struct {
+		name     string
+		escalate int8
+	}{
+		{name: "give up", escalate: 0},
+		{name: "escalate", escalate: 1},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_app := start_supervised(t, atypes.Appunit{Restart: atypes.RESTART_ON_FAILURE, RestartBackoff: 20,
+				RestartMaxBackoff: 1000, RestartIntensity: 3, RestartPeriod: 60, RestartEscalate: _test.escalate})
+
+			for _i, _want := range []time.Duration{20 * time.Millisecond, 40 * time.Millisecond, 80 * time.Millisecond} {
+				_unit := running_instance(_app, "unit")
+				_failed := time.Now()
+				_unit.Set_Failed(errors.New("disk full"))
+				if !wait_until(5*time.Second, func() bool { _running := running_instance(_app, "unit"); return _running != nil && _running != _unit }) {
+					t.Fatalf("the unit is not restarted after the failure %d", _i+1)
+				}
+				if _delay := time.Since(_failed); _delay < _want {
+					t.Errorf("restart %d after %v, want the backoff %v", _i+1, _delay, _want)
+				}
+				if _got := _app.restart_count("unit"); _got != _i+1 {
+					t.Errorf("restarts = %d, want %d", _got, _i+1)
+				}
+			}
+
+			running_instance(_app, "unit").Set_Failed(errors.New("disk full"))
+			if !wait_until(5*time.Second, func() bool { _status, _ := _app.restart_status("unit"); return _status.Gave_Up }) {
+				t.Fatal("the unit is not given up after 3 restarts")
+			}
+			time.Sleep(50 * time.Millisecond)
+			if _got := _app.restart_count("unit"); _got != 3 || running_instance(_app, "unit") != nil {
+				t.Errorf("restarts = %d, running %v, want 3 restarts and no running instance", _got, running_instance(_app, "unit") != nil)
+			}
+
+			_interrupted := false
+			select {
+			case <-_app.Is_Interrupted():
+				_interrupted = true
+			default:
+			}
+			_escalation := _app.Escalation()
+			if _test.escalate == 1 && (!_interrupted || !errors.Is(_escalation, aerrors.ErrRestartLimit)) {
+				t.Errorf("interrupted %v, Escalation() = %v, want the application interrupted with aerrors.ErrRestartLimit", _interrupted, _escalation)
+			}
+			if _test.escalate == 0 && (_interrupted || _escalation != nil) {
+				t.Errorf("interrupted %v, Escalation() = %v, want the application running", _interrupted, _escalation)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package agniapp
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+)
+
+// Tracer returns the tracer of the application
+func (app *AgniApp) Tracer() *atrace.Tracer {
+	return app.tracer
+}
+
+// new_tracer creates the tracer of the tracing configuration. When the configuration is not valid
+// the error is logged and the tracer only propagates the trace context
+func (app *AgniApp) new_tracer(pConfig atypes.Tracingconfig) *atrace.Tracer {
+
+	_tracer, _err := atrace.New_From_Config(app.Name(), pConfig, app.app_path)
+	if _err != nil {
+		app.Write2Log(app.Name()+" - Tracing is disabled. "+_err.Error(), atypes.LOG_ERROR)
+		return atrace.New_Tracer(atrace.Config{Service: app.Name()}, nil)
+	}
+	return _tracer
+}
+
+// shutdown_tracer exports the remaining spans and shuts down the exporter
+func (app *AgniApp) shutdown_tracer() error {
+	_ctx, _cancel := context.WithTimeout(context.Background(), atrace.DEFAULT_EXPORT_TIMEOUT)
+	defer _cancel()
+	return app.tracer.Shutdown(_ctx)
+}
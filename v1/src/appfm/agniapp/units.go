@@ -0,0 +1,451 @@
+package agniapp
+
+import (
+	iappunit "github.com/agnione/libs/v1/src/aau/iappunit"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// FORCE_STOP_GRACE time to wait for a unit to return from a forced stop before the instance is abandoned
+const FORCE_STOP_GRACE = time.Second
+
+// DRAIN_INTERVAL interval of the check of the dispatched calls in progress of a stopping instance
+const DRAIN_INTERVAL = 10 * time.Millisecond
+
+// unit_entry holds a running application unit instance
+type unit_entry struct {
+	config     atypes.Appunit
+	instance   iappunit.IAppUnit
+	id         int
+	dispatched atomic.Uint64 /// calls dispatched to the instance
+	inflight   atomic.Int64  /// dispatched calls in progress
+	removed    atomic.Bool   /// removed from the pool, the instance is stopping or stopped
+}
+
+// status returns the status of the instance, nil once it is removed from the pool as it may be deinitialized.
+// A panic of the unit is recovered and nil returned, so one unit does not break the status and the metrics of the others.
+func (e *unit_entry) status() (info *atypes.AppUnitInfo) {
+	if e.removed.Load() {
+		return nil
+	}
+	defer func() {
+		if recover() != nil {
+			info = nil
+		}
+	}()
+	return e.instance.Status()
+}
+
+// Register_Unit registers the application unit with the given unit name (atypes.Appunit.Uname).
+// The given unit is used as the prototype, instances are created with New() when the unit is started.
+func (app *AgniApp) Register_Unit(pUnit_Name string, pUnit iappunit.IAppUnit) error {
+
+	if pUnit == nil {
+		return fmt.Errorf("application unit %w", aerrors.ErrNilArgument)
+	}
+	if pUnit_Name == "" {
+		return fmt.Errorf("application unit name %w", aerrors.ErrNilArgument)
+	}
+
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+	app.prototypes[pUnit_Name] = pUnit
+	return nil
+}
+
+// Units_List returns the units given in the application configuration
+func (app *AgniApp) Units_List() ([]atypes.Appunit, error) {
+	return app.unit_configs(), nil
+}
+
+// Unit_Start creates, initializes and starts the instances of the given unit (Appunit.PoolSize, 1 if not set).
+// A scheduled restart of the unit is cancelled and a given up unit is supervised again.
+//
+//	Returns UnitError wrapping aerrors.ErrAlreadyStarted if all the instances of the unit are running,
+//	aerrors.ErrDependency if a unit of depends_on is not running (see Unit_Start_Cascade)
+func (app *AgniApp) Unit_Start(pUnitName *string) (bool, error) {
+
+	if pUnitName == nil {
+		return false, fmt.Errorf("unit name %w", aerrors.ErrNilArgument)
+	}
+
+	app.cancel_restart(*pUnitName)
+	if _err := app.start_unit(*pUnitName); _err != nil {
+		return false, _err
+	}
+	return true, nil
+}
+
+// start_unit starts the instances missing from the pool of the given unit, then the units waiting for it.
+// The restart of the unit is scheduled by its restart policy when an instance fails to start.
+func (app *AgniApp) start_unit(pUnit_Name string) error {
+
+	_config, _err := app.unit_config(pUnit_Name)
+	if _err != nil {
+		return &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: _err}
+	}
+	if _missing := app.missing_dependencies(_config); len(_missing) > 0 {
+		return &aerrors.UnitError{Unit: pUnit_Name, Op: "start",
+			Err: fmt.Errorf("depends on %s which is not running. %w", strings.Join(_missing, ", "), aerrors.ErrDependency)}
+	}
+
+	app.units_lock.Lock()
+	_size := pool_size(_config)
+	if _pool, _ok := app.units[pUnit_Name]; _ok {
+		_size = _pool.size - len(_pool.instances)
+	}
+	if _size <= 0 || app.pending[pUnit_Name] {
+		app.units_lock.Unlock()
+		return &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: aerrors.ErrAlreadyStarted}
+	}
+
+	_proto, _ok := app.prototypes[pUnit_Name]
+	if !_ok {
+		app.units_lock.Unlock()
+		return &aerrors.UnitError{Unit: pUnit_Name, Op: "start", Err: fmt.Errorf("not registered. %w", aerrors.ErrUnitNotFound)}
+	}
+
+	/// the units are started without holding the lock, so the unit can call the framework while starting
+	app.pending[pUnit_Name] = true
+	app.units_lock.Unlock()
+
+	defer func() {
+		app.units_lock.Lock()
+		delete(app.pending, pUnit_Name)
+		app.units_lock.Unlock()
+	}()
+
+	for _i := 0; _i < _size; _i++ {
+		_entry, _err := app.new_instance(_config, _proto)
+		if _err != nil {
+			app.schedule_restart(_config, _err)
+			return _err
+		}
+		app.add_instance(_entry)
+	}
+
+	app.set_waiting(pUnit_Name, false)
+	app.Write2Log(app.Name()+" - Starting the unit "+pUnit_Name+".....DONE", atypes.LOG_INFO)
+	app.start_waiting(pUnit_Name)
+	return nil
+}
+
+// new_instance creates, initializes and starts a new instance of the unit with a new instance id
+func (app *AgniApp) new_instance(pConfig atypes.Appunit, pProto iappunit.IAppUnit) (*unit_entry, error) {
+
+	_unit, _ok := pProto.New().(iappunit.IAppUnit)
+	if !_ok || _unit == nil {
+		return nil, &aerrors.UnitError{Unit: pConfig.Uname, Op: "start", Err: errors.New("New() did not return an iappunit.IAppUnit")}
+	}
+
+	app.units_lock.Lock()
+	app.last_unit_id++
+	_id := app.last_unit_id
+	app.units_lock.Unlock()
+
+	app.Write2Log(app.Name()+" - Starting the unit "+pConfig.Uname+" instance "+strconv.Itoa(_id), atypes.LOG_INFO)
+
+	if _, _err := _unit.Initialize(app, _id, pConfig.Uname, pConfig.Path, pConfig.ConfigFile); _err != nil {
+		return nil, &aerrors.UnitError{Unit: pConfig.Uname, Op: "initialize", Err: _err}
+	}
+
+	if _, _err := start_instance(app.ctx, _unit); _err != nil {
+		_unit.Deinitialize()
+		return nil, &aerrors.UnitError{Unit: pConfig.Uname, Op: "start", Err: _err}
+	}
+	return &unit_entry{config: pConfig, instance: _unit, id: _id}, nil
+}
+
+// add_instance adds the started instance to the pool of the unit, the pool is created by the first instance
+func (app *AgniApp) add_instance(pEntry *unit_entry) {
+
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+
+	_name := pEntry.config.Uname
+	_pool, _ok := app.units[_name]
+	if !_ok {
+		_pool = &unit_pool{config: pEntry.config, size: pool_size(pEntry.config)}
+		app.units[_name] = _pool
+		app.start_order = append(app.start_order, _name)
+	}
+	_pool.instances = append(_pool.instances, pEntry)
+}
+
+// Unit_Stop stops and deinitializes the running instances of the given unit.
+// A scheduled restart of the unit is cancelled.
+//
+//	When pForce is false the unit gets the stop_timeout of the unit config to stop, then the stop is forced.
+//	A forced stop cancels the stop context and does not wait for the unit more than FORCE_STOP_GRACE.
+//	Returns UnitError wrapping aerrors.ErrDependency if a unit depending on it is running (see Unit_Stop_Cascade)
+func (app *AgniApp) Unit_Stop(pUnitName *string, pForce bool) (bool, error) {
+
+	if pUnitName == nil {
+		return false, fmt.Errorf("unit name %w", aerrors.ErrNilArgument)
+	}
+
+	if _dependents := app.running_dependents(*pUnitName); len(_dependents) > 0 {
+		return false, &aerrors.UnitError{Unit: *pUnitName, Op: "stop",
+			Err: fmt.Errorf("%s depends on it. %w", strings.Join(_dependents, ", "), aerrors.ErrDependency)}
+	}
+
+	app.cancel_restart(*pUnitName)
+	app.set_waiting(*pUnitName, false)
+	return app.stop_pool(*pUnitName, pForce)
+}
+
+// stop_pool stops and deinitializes the running instances of the given unit
+func (app *AgniApp) stop_pool(pUnit_Name string, pForce bool) (bool, error) {
+
+	_entries := app.remove_pool(pUnit_Name)
+	if len(_entries) == 0 {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "stop", Err: aerrors.ErrNotStarted}
+	}
+
+	app.Write2Log(app.Name()+" - Stopping the unit "+pUnit_Name, atypes.LOG_INFO)
+
+	if _err := app.stop_instances(_entries, pForce); _err != nil {
+		return false, &aerrors.UnitError{Unit: pUnit_Name, Op: "stop", Err: _err}
+	}
+
+	app.Write2Log(app.Name()+" - Stopping the unit "+pUnit_Name+".....DONE", atypes.LOG_INFO)
+	return true, nil
+}
+
+// Unit_Restart stops (if running) and starts the given unit.
+// The running units which depend on it are stopped before and started again after the unit.
+func (app *AgniApp) Unit_Restart(pUnitName *string, pForce bool) (bool, error) {
+
+	if pUnitName == nil {
+		return false, fmt.Errorf("unit name %w", aerrors.ErrNilArgument)
+	}
+
+	if app.is_running(*pUnitName) {
+		app.cancel_restart(*pUnitName)
+		if _err := app.stop_dependents(*pUnitName, pForce, true); _err != nil {
+			return false, &aerrors.UnitError{Unit: *pUnitName, Op: "restart", Err: _err}
+		}
+		if _, _err := app.stop_pool(*pUnitName, pForce); _err != nil {
+			return false, _err
+		}
+	}
+	return app.Unit_Start(pUnitName)
+}
+
+// Unit_Status returns the status of the running instances of the given unit, the counters are the sums
+// of the instances and Pool holds the status of every instance.
+func (app *AgniApp) Unit_Status(pUnitName *string) (*atypes.AppUnitInfo, error) {
+
+	if pUnitName == nil {
+		return nil, fmt.Errorf("unit name %w", aerrors.ErrNilArgument)
+	}
+
+	app.units_lock.RLock()
+	_pool, _ok := app.units[*pUnitName]
+	var _entries []*unit_entry
+	var _size int
+	if _ok {
+		_entries = append(_entries, _pool.instances...)
+		_size = _pool.size
+	}
+	app.units_lock.RUnlock()
+
+	if !_ok {
+		return nil, &aerrors.UnitError{Unit: *pUnitName, Op: "status", Err: aerrors.ErrNotStarted}
+	}
+	_info := app.pool_status(_pool, _entries, _size)
+	if _info == nil {
+		return nil, nil
+	}
+	_info.Restart, _ = app.restart_status(*pUnitName)
+	return _info, nil
+}
+
+// remove_pool removes all the running instances of the unit, they are counted as stopping until they are deinitialized.
+//
+//	Returns the removed instances, nil if the unit is not running
+func (app *AgniApp) remove_pool(pUnit_Name string) []*unit_entry {
+
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+
+	_pool, _ok := app.units[pUnit_Name]
+	if !_ok {
+		return nil
+	}
+	for _, _entry := range _pool.instances {
+		app.set_stopping(_entry)
+	}
+	app.delete_pool(pUnit_Name)
+	return _pool.instances
+}
+
+// remove_instance removes the given running instance of the unit, it is counted as stopping until it is deinitialized.
+// The pool of the unit is removed with its last instance.
+//
+//	Returns false if the instance is not a running instance of the unit
+func (app *AgniApp) remove_instance(pUnit_Name string, pEntry *unit_entry) bool {
+
+	app.units_lock.Lock()
+	defer app.units_lock.Unlock()
+
+	_pool, _ok := app.units[pUnit_Name]
+	if !_ok {
+		return false
+	}
+	for _i, _entry := range _pool.instances {
+		if _entry != pEntry {
+			continue
+		}
+		_pool.instances = append(_pool.instances[:_i], _pool.instances[_i+1:]...)
+		app.set_stopping(pEntry)
+		if len(_pool.instances) == 0 {
+			app.delete_pool(pUnit_Name)
+		}
+		return true
+	}
+	return false
+}
+
+// set_stopping marks the removed instance as stopping until it is deinitialized. Called holding units_lock
+func (app *AgniApp) set_stopping(pEntry *unit_entry) {
+	pEntry.removed.Store(true)
+	app.stopping[pEntry] = struct{}{}
+}
+
+// delete_pool deletes the pool of the unit and removes it from the start order. Called holding units_lock
+func (app *AgniApp) delete_pool(pUnit_Name string) {
+	delete(app.units, pUnit_Name)
+	for _i, _name := range app.start_order {
+		if _name == pUnit_Name {
+			app.start_order = append(app.start_order[:_i], app.start_order[_i+1:]...)
+			break
+		}
+	}
+}
+
+// stop_unit stops and deinitializes the given unit instance.
+// The stop is escalated to a forced stop when the stop timeout passes or pForce is set.
+func (app *AgniApp) stop_unit(pEntry *unit_entry, pForce bool) error {
+
+	_timeout := time.Duration(pEntry.config.StopTimeout) * time.Second
+	if _timeout <= 0 {
+		_timeout = atypes.DEFAULT_STOP_TIMEOUT * time.Second
+	}
+
+	_ctx, _cancel := context.WithTimeout(context.Background(), _timeout)
+	defer _cancel()
+	if pForce {
+		_cancel()
+	}
+
+	/// the dispatched calls in progress complete before the instance is stopped
+	for pEntry.inflight.Load() > 0 && _ctx.Err() == nil {
+		select {
+		case <-_ctx.Done():
+		case <-time.After(DRAIN_INTERVAL):
+		}
+	}
+
+	_done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if _r := recover(); _r != nil {
+				_done <- fmt.Errorf("panic while stopping. %v", _r)
+			}
+		}()
+		_, _err := stop_instance(_ctx, pEntry.instance)
+		_done <- _err
+	}()
+
+	select {
+	case _err := <-_done:
+		app.retire_unit(pEntry)
+		pEntry.instance.Deinitialize()
+		return _err
+	case <-_ctx.Done():
+	}
+
+	if !pForce {
+		app.Write2Log(app.Name()+" - Unit "+pEntry.config.Uname+" did not stop within "+_timeout.String()+". Forcing the stop", atypes.LOG_WARN)
+	}
+
+	select {
+	case _err := <-_done:
+		app.retire_unit(pEntry)
+		pEntry.instance.Deinitialize()
+		return _err
+	case <-time.After(FORCE_STOP_GRACE):
+	}
+
+	/// the unit does not return from stop, deinitialize it whenever the stop returns
+	go func() {
+		<-_done
+		app.retire_unit(pEntry)
+		pEntry.instance.Deinitialize()
+	}()
+	app.Write2Log(app.Name()+" - Unit "+pEntry.config.Uname+" did not return from the forced stop. Instance is abandoned", atypes.LOG_ERROR)
+	return fmt.Errorf("forced stop did not complete within %s. %w", FORCE_STOP_GRACE, aerrors.ErrStopTimeout)
+}
+
+// start_instance starts the given unit, using StartContext if the unit implements iappunit.IAppUnitContext
+func start_instance(pCtx context.Context, pUnit iappunit.IAppUnit) (bool, error) {
+	if _unit, _ok := pUnit.(iappunit.IAppUnitContext); _ok {
+		return _unit.StartContext(pCtx)
+	}
+	return pUnit.Start()
+}
+
+// stop_instance stops the given unit, using StopContext if the unit implements iappunit.IAppUnitContext
+func stop_instance(pCtx context.Context, pUnit iappunit.IAppUnit) (bool, error) {
+	if _unit, _ok := pUnit.(iappunit.IAppUnitContext); _ok {
+		return _unit.StopContext(pCtx)
+	}
+	return pUnit.Stop()
+}
+
+// unit_configs returns a copy of the configured units
+func (app *AgniApp) unit_configs() []atypes.Appunit {
+	app.config_lock.RLock()
+	defer app.config_lock.RUnlock()
+	return append([]atypes.Appunit(nil), app.config.Appunits...)
+}
+
+// unit_config returns the configuration of the given unit
+func (app *AgniApp) unit_config(pUnit_Name string) (atypes.Appunit, error) {
+	for _, _unit := range app.unit_configs() {
+		if _unit.Uname == pUnit_Name {
+			return _unit, nil
+		}
+	}
+	return atypes.Appunit{}, fmt.Errorf("not in the configuration. %w", aerrors.ErrUnitNotFound)
+}
+
+// running_units returns the names of the running units in the start order
+func (app *AgniApp) running_units() []string {
+	app.units_lock.RLock()
+	defer app.units_lock.RUnlock()
+	return append([]string(nil), app.start_order...)
+}
+
+// running_units_reverse returns the names of the running units in the reverse dependency order,
+// a unit before the units it depends on and in the reverse start order otherwise
+func (app *AgniApp) running_units_reverse() []string {
+	_configs := make([]atypes.Appunit, 0)
+	for _, _name := range app.running_units() {
+		app.units_lock.RLock()
+		if _pool, _ok := app.units[_name]; _ok {
+			_configs = append(_configs, _pool.config)
+		}
+		app.units_lock.RUnlock()
+	}
+	return reverse_names(_configs)
+}
@@ -32,6 +32,13 @@
 //   - Get_WSClient
 //   - Get_RESTClient
 //   - Logconfig
+//   - IAgniAppCounters (Sums_Unit_Counters)
+//   - IAgniAppSupervisor (Unit_Failed)
+//   - IAgniAppLogger (Logger), Write2Log_Handler
+//   - IAgniAppLogfiles (Logfile_List)
+//   - IAgniAppMetrics (Metrics)
+//   - IAgniAppTracer (Tracer)
+//   - IAgniAppBus (Bus)
 //   - WriteFileContent
 //   - Units_List
 //   - Unit_Stop
@@ -61,28 +68,20 @@
 package iappfw
 
 import (
-	ihttp "agnione/v1/src/afplugins/http/iahttpclient" /// import the httplcient interface
-	iws "agnione/v1/src/afplugins/websocket/iawsclient"
-	atypes "agnione/v1/src/appfm/types"
+	ihttp "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient" /// import the httplcient interface
+	iws "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
 	"context"
+	"log/slog"
 	"time"
 )
 
-type IAgniApp interface {
-
-	// Reload_Config reloads the configuration of the application framework
-	// 	Returns true and nil if configuration loaded successfully.
-	//	Unless returns false and error
-	Reload_Config() (bool, error)
-
-	// Start_WSMonitor starts the web socket monitoring with the pre-set configuration in config file
-	// 	Returns true and nil if it started successfully.
-	// 	Unless returns false and error
-	Start_WSMonitor() (bool, error)
-
-	// StopWSMonitor stops the web socket monitoring
-	// 	Returns true if it started successfully. Unless returns false
-	Stop_WSMonitor() bool
+// IAgniAppCore functions of the framework interface which are shared by the v1 and v2 framework interfaces.
+type IAgniAppCore interface {
 
 	// Is_Interrupted returns the application interrupt channel to the caller.
 	// 	External routines should check this channel as a terminator of routines.
@@ -97,13 +96,10 @@ type IAgniApp interface {
 	//	Parameter log_level ztypes.LogLevel - log level to use when writing the log entry
 	Write2Log(pEntry string, pLog_Level atypes.LogLevel)
 
-
 	// Set_LogLevel set/override the curren runtime log level with given level
 	// 	Parameter pLogLevel  atypes.LogLevel - valid enum of  atypes.LogLevel
 	Set_LogLevel(pLogLevel atypes.LogLevel)
 
-
-
 	// Add_Routine increments the routine count of the Application Framework and
 	//	adds 1 to the framework waitgroup
 	//	increments the internal routine count by 1
@@ -133,7 +129,7 @@ type IAgniApp interface {
 	App_Path() *string
 
 	// Routine_Count returns the number of routines currently running
-	Routine_Count() uint16
+	Routine_Count() int64
 
 	// Execute_Command executes the given OS command and returns result
 	//	command string parameter - the command to execute with arguments
@@ -146,13 +142,6 @@ type IAgniApp interface {
 	// 	Will be used in sending status messages over REST and websockets
 	Add_Request_HandleCount()
 
-	// Save_App_Config save/overwrite the given application configuration into the app.config file.
-	// 	This function is useful to modify the app units and configuration while application is running.
-	// 	Also, it should be used with CAUTION.
-	// 	Returns the true if given content is valid app.config content and saved successfully.
-	// 	Unless returns false and error
-	Save_App_Config(pAppConfigData *[]byte)(bool, error)
-	
 	// Add_Request_Failed_Count adds 1 to the request failed handle count.
 	// 	This function is useful to external modules to update his request handle count
 	// 	Will be used in sending status messages over REST and websockets
@@ -181,7 +170,7 @@ type IAgniApp interface {
 
 	// Get_Context returns the current application context object
 	Get_Context() *context.Context
-	
+
 	// Get_FileInfo returns the information of the given file in format of FileInfo struct
 	Get_FileInfo(pFileName *string) (*atypes.FileInfo, error)
 
@@ -189,7 +178,7 @@ type IAgniApp interface {
 	// 	Returns file content []bytes,nil if successful.
 	// 	Unless returns nil and error
 	Get_File_Content(pFileName *string) (*[]byte, error)
-		
+
 	// Logfile_Basepath returns the string of the log base path.
 	Logfile_Basepath() *string
 
@@ -201,33 +190,69 @@ type IAgniApp interface {
 	// 	Unless returns nil and error
 	Get_FileContent_Lines(pFileName *string) (*[]string, error)
 
-// Write_FileContent writes the given content []byte to the given filename.
-	// 	Returns true and nil if file exists. Unless returns nil and error
-	Write_FileContent(pFileName *string, pData *[]byte) (bool, error)
-	
 	// Units_List returns the units given in the app.config file.
 	// 	Returns true and nil if list successfully loaded. Unless returns nil and error
 	Units_List() ([]atypes.Appunit, error)
-	
+
+	// Unit_Status returns the status of given unit at that time.
+	// 	Returns unit info and nil if the given uint's status is successfully fetched. Unless returns nil and error
+	Unit_Status(pUnitName *string)(*atypes.AppUnitInfo,error)
+}
+
+// IAgniApp the interface of the AgniOne Application Framework passed to the application units.
+//
+// Unit control functions (Unit_Start, Unit_Stop, Unit_Restart, Unit_Status) return *aerrors.UnitError wrapping
+// aerrors.ErrUnitNotFound, aerrors.ErrNotStarted, aerrors.ErrAlreadyStarted or the error of the unit,
+// so callers can use errors.Is / errors.As instead of matching the error strings.
+// Unit_Start and Unit_Stop return aerrors.ErrDependency when a dependency of the unit (depends_on) is not running
+// or a unit depending on it is running.
+type IAgniApp interface {
+	IAgniAppCore
+
+	// Reload_Config reloads the configuration of the application framework
+	// 	Returns true and nil if configuration loaded successfully.
+	//	Unless returns false and error
+	Reload_Config() (bool, error)
+
+	// Start_WSMonitor starts the web socket monitoring with the pre-set configuration in config file
+	// 	Returns true and nil if it started successfully.
+	// 	Unless returns false and error
+	Start_WSMonitor() (bool, error)
+
+	// StopWSMonitor stops the web socket monitoring
+	// 	Returns true if it started successfully. Unless returns false
+	Stop_WSMonitor() bool
+
+	// Save_App_Config save/overwrite the given application configuration into the app.config file.
+	// 	This function is useful to modify the app units and configuration while application is running.
+	// 	Also, it should be used with CAUTION.
+	// 	Returns the true if given content is valid app.config content and saved successfully.
+	// 	Unless returns false and error
+	Save_App_Config(pAppConfigData *[]byte)(bool, error)
+
+// Write_FileContent writes the given content []byte to the given filename.
+	// 	Returns true and nil if file exists. Unless returns nil and error
+	Write_FileContent(pFileName *string, pData *[]byte) (bool, error)
+
 	// Unit_Stop stops the given unit (if unit is loaded & running).
 	//	pForce parameter determine that the unit should load in force or wait until all the current execution stops.
+	//	When pForce is false the unit gets the stop_timeout of the unit config (atypes.DEFAULT_STOP_TIMEOUT if not set)
+	//	to stop gracefully, the stop is forced when the deadline passes.
+	//	A forced stop passes an already cancelled context to StopContext (iappunit.IAppUnitContext) and
+	//	does not wait for the unit to finish the current executions.
 	// 	Returns true and nil if the given uint is successfully stopped. Unless returns nil and error
 	Unit_Stop(pUnitName *string,pForce bool)(bool,error)
-	
+
 	// Unit_Start starts the given unit (if unit is nit loaded & not running).
 	// 	Returns true and nil if the given uint is successfully loaded and running. Unless returns nil and error
 	Unit_Start(pUnitName *string)(bool,error)
-	
+
 	// Unit_Restart re-starts the given unit.
 	//	pForce parameter determine that the unit should perform the stop and start in force or wait until all the current execution stops.
+	//	The stop follows the same rules as Unit_Stop.
 	// 	Returns true and nil if the given uint is successfully restarted. Unless returns nil and error
 	Unit_Restart(pUnitName *string,pForce bool)(bool,error)
-		
-	// Unit_Status returns the status of given unit at that time.
-	// 	Returns unit info and nil if the given uint's status is successfully fetched. Unless returns nil and error
-	Unit_Status(pUnitName *string)(*atypes.AppUnitInfo,error)
-	
-	
+
 	// Get_WSClient returns the instance of the Web Socket client defined in the config file
 	// A new instance will be created and return.
 	// If failed then returns nil and error
@@ -237,4 +262,80 @@ type IAgniApp interface {
 	// A new instance will be created and return.
 	// If failed then returns nil and error
 	Get_RESTClient(pType *string) (ihttp.IAHTTPClient, error)
-}
\ No newline at end of file
+}
+
+// IAgniAppCounters optional interface for the frameworks which aggregate the request counts lazily.
+//
+// When Sums_Unit_Counters returns true, Handled_Request_Count, Failed_Request_Count and Get_App_Status include the
+// counters of the units implementing iappunit.IAppUnitCounters, read when the totals are requested.
+// Such units do not call Add_Request_HandleCount/Add_Request_Failed_Count for every request.
+type IAgniAppCounters interface {
+
+	// Sums_Unit_Counters returns true if the framework sums the counters of the units at read time
+	Sums_Unit_Counters() bool
+}
+
+// IAgniAppSupervisor optional interface for the frameworks which restart the failed units (restart policy of atypes.Appunit).
+//
+// Units built on AUBase call Unit_Failed when they move into the Failed state, so the framework reacts without
+// waiting for its next check of the unit states.
+type IAgniAppSupervisor interface {
+
+	// Unit_Failed reports that the given unit instance failed with the given reason.
+	// 	It is called from the failing routine of the unit and must return without blocking.
+	Unit_Failed(pUnit_Name string, pInstance_ID int, pReason error)
+}
+
+// IAgniAppLogger optional interface for the frameworks with a structured logger.
+//
+// Units built on AUBase use a logger writing the entries with Write2Log (Write2Log_Handler) when the framework
+// does not implement it.
+type IAgniAppLogger interface {
+
+	// Logger returns the structured logger of the framework.
+	// 	Entries are written to the application log with the key/value attributes (JSON lines when log_format is json)
+	// 	and filtered by the current runtime log level set by Set_LogLevel.
+	// 	Units should use the child logger of AUBase, which carries the unit name, instance ID and AUID.
+	Logger() *slog.Logger
+}
+
+// IAgniAppLogfiles optional interface for the frameworks which rotate the log file.
+type IAgniAppLogfiles interface {
+
+	// Logfile_List returns the names of the rotated log files under the log base path (newest first).
+	// 	The files can be read with Get_File_Content using the log base path.
+	// 	Returns empty list and nil if no file is rotated yet. Unless returns nil and error
+	Logfile_List() ([]string, error)
+}
+
+// IAgniAppMetrics optional interface for the frameworks which export metrics.
+//
+// The metric functions of AUBase return aerrors.ErrNotSupported when the framework does not implement it.
+type IAgniAppMetrics interface {
+
+	// Metrics returns the metrics registry of the application.
+	// 	Metrics registered here are exported in the Prometheus text format by the HTTP monitor (/metrics)
+	// 	together with the application and unit status. Units should use the metric functions of AUBase.
+	Metrics() *ametrics.Registry
+}
+
+// IAgniAppTracer optional interface for the frameworks which trace the units and the plugins.
+//
+// Units built on AUBase do not record the spans when the framework does not implement it.
+type IAgniAppTracer interface {
+
+	// Tracer returns the tracer of the application configured by the tracing section of the app.config.
+	// 	Spans started from it are exported by the configured exporter. When the tracing is not enabled the
+	// 	tracer only propagates the trace context. Units should use the span functions of AUBase.
+	Tracer() *atrace.Tracer
+}
+
+// IAgniAppBus optional interface for the frameworks which run a message bus for the units.
+//
+// The bus functions of AUBase return aerrors.ErrNotSupported when the framework does not implement it.
+type IAgniAppBus interface {
+
+	// Bus returns the message bus of the units of the application, configured by the bus section of the app.config.
+	// 	Units should use the bus functions of AUBase, which count the traffic of the unit.
+	Bus() *abus.Bus
+}
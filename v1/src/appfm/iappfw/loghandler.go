@@ -0,0 +1,64 @@
+package iappfw
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// write2log_handler slog handler writing the entries with Write2Log of the framework
+type write2log_handler struct {
+	app IAgniAppCore
+	ops []func(slog.Handler) slog.Handler /// WithAttrs and WithGroup calls, replayed for every entry
+}
+
+// Write2Log_Handler returns a slog handler which writes the entries as text (msg and key=value attributes) with
+// Write2Log of the given framework. The framework filters the entries by its log level.
+// It is the logger of the units when the framework does not implement IAgniAppLogger.
+func Write2Log_Handler(pApp IAgniAppCore) slog.Handler {
+	return &write2log_handler{app: pApp}
+}
+
+func (h *write2log_handler) Enabled(context.Context, slog.Level) bool {
+	return h.app != nil
+}
+
+func (h *write2log_handler) Handle(pCtx context.Context, pRecord slog.Record) error {
+
+	var _buffer bytes.Buffer
+	var _handler slog.Handler = slog.NewTextHandler(&_buffer, &slog.HandlerOptions{
+		ReplaceAttr: func(pGroups []string, pAttr slog.Attr) slog.Attr {
+			/// Write2Log adds the time and the level
+			if len(pGroups) == 0 && (pAttr.Key == slog.TimeKey || pAttr.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return pAttr
+		},
+	})
+	for _, _op := range h.ops {
+		_handler = _op(_handler)
+	}
+	if _err := _handler.Handle(pCtx, pRecord); _err != nil {
+		return _err
+	}
+	h.app.Write2Log(strings.TrimSuffix(_buffer.String(), "\n"), atypes.From_SlogLevel(pRecord.Level))
+	return nil
+}
+
+func (h *write2log_handler) WithAttrs(pAttrs []slog.Attr) slog.Handler {
+	return h.with(func(pHandler slog.Handler) slog.Handler { return pHandler.WithAttrs(pAttrs) })
+}
+
+func (h *write2log_handler) WithGroup(pName string) slog.Handler {
+	return h.with(func(pHandler slog.Handler) slog.Handler { return pHandler.WithGroup(pName) })
+}
+
+// with returns a copy of the handler with the given operation
+func (h *write2log_handler) with(pOp func(slog.Handler) slog.Handler) slog.Handler {
+	_ops := make([]func(slog.Handler) slog.Handler, len(h.ops), len(h.ops)+1)
+	copy(_ops, h.ops)
+	return &write2log_handler{app: h.app, ops: append(_ops, pOp)}
+}
@@ -11,8 +11,15 @@
 //   - AppStatus
 //   - ConvertStoI
 //   - LogLevel
+//   - UnitState
 //   - MainConfig
 //   - Httpmonitor
+//   - Tracingconfig
+//   - Busconfig
+//   - Resilienceconfig
+//   - Authconfig
+//   - TLSconfig
+//   - BreakerStatus
 //   - Wsmonitor
 //   - Mqengine
 //   - Websocket
@@ -35,7 +42,13 @@
 //     ---------------------------------------------------------------------------------------------------------------------
 package atypes
 
-import "time"
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type Info struct {
 	Name    string
@@ -62,9 +75,9 @@ type FileInfo struct {
 }
 
 type MemUsage struct{
-	Heap uint64
-	HeapAlloc uint64
-	Total uint64
+	Heap uint64	// heap memory obtained from the OS (process wide)
+	HeapAlloc uint64	// bytes of the allocated heap objects (process wide)
+	Total uint64	// cumulative bytes allocated for the heap objects (process wide)
 	
 }
 
@@ -72,10 +85,161 @@ type MemUsage struct{
 type AppUnitInfo struct {
 	Info        Info	// holds the name & version
 	Mem_Usage   MemUsage	// holds the memory usage
-	Req_Handled uint32	// number of request handled successfully
-	Req_Failed  uint32	// number of request failed to handle
-	Routines    uint16	// count of running number of routines/threads
-	Active 		uint16	// count of current active number executions
+	Req_Handled uint64	// number of request handled successfully
+	Req_Failed  uint64	// number of request failed to handle
+	Routines    int64	// count of running number of routines/threads
+	Active 		int64	// count of current active number executions
+	State		UnitState	// current lifecycle state of the unit
+	Msg_Dropped	uint64	// number of log entries/monitoring messages dropped by the unit delivery queue
+	Operations	map[string]OperationStats	// statistics of the operations tracked with AUBase.Track
+	Resources	UnitResources	// resources attributed to the unit
+	Restart		RestartStatus	// restarts of the unit by the framework supervision
+	Pool		PoolStatus	// instances of the unit, the counters above are the sums of the instances
+	Bus			BusStats	// traffic of the unit on the message bus
+	Breakers	[]BreakerStatus	// circuit breakers of the http clients of the unit
+}
+
+// BreakerStatus retries and circuit breaker of a http client (aresilience)
+type BreakerStatus struct {
+	Client   string	// type of the http client plugin
+	State    string	// closed, open or half-open
+	Failures int	// consecutive failures counted by the breaker
+	Opened   string	// time the circuit was opened (RFC3339), empty if closed
+	Rejected uint64	// requests rejected while the circuit was open
+	Retries  uint64	// requests retried by the policy
+}
+
+// BusStats traffic of a unit on the message bus
+type BusStats struct {
+	Published     uint64	// envelopes published by the unit (events, requests and replies)
+	Received      uint64	// envelopes handled by the subscriptions of the unit
+	Failed        uint64	// envelopes failed by the handlers of the unit
+	Dropped       uint64	// envelopes dropped by the full queues of the subscriptions of the unit
+	Requests      uint64	// requests sent by the unit
+	Subscriptions int	// current subscriptions of the unit
+}
+
+// UnitCounters request and execution counters of a unit, read without locking the unit
+type UnitCounters struct {
+	Req_Handled uint64	// number of request handled successfully
+	Req_Failed  uint64	// number of request failed to handle
+	Routines    int64	// count of running number of routines/threads
+	Active      int64	// count of current active number executions
+}
+
+// UnitResources resources attributed to a unit. The memory of the units is not separated by the Go runtime,
+// see AppStatus.Process for the process wide usage.
+type UnitResources struct {
+	Routines       int    // routines started with AUBase.Go which are running
+	Pool_Gets      uint64 // objects taken from the unit pools
+	Pool_News      uint64 // objects allocated by the unit pools
+	Pool_Puts      uint64 // objects returned to the unit pools
+	Bytes_Sent     uint64 // bytes sent through the plugins (request bodies, web socket messages)
+	Bytes_Received uint64 // bytes received through the plugins (response bodies, web socket messages)
+}
+
+// ProcessStats resource usage of the process
+type ProcessStats struct {
+	Heap_Alloc        uint64  // bytes of the allocated heap objects
+	Heap_Inuse        uint64  // bytes in the in-use heap spans
+	Heap_Sys          uint64  // bytes of the heap memory obtained from the OS
+	Heap_Objects      uint64  // number of the allocated heap objects
+	Sys               uint64  // total bytes of memory obtained from the OS
+	Total_Alloc       uint64  // cumulative bytes allocated for the heap objects
+	Mallocs           uint64  // cumulative count of the heap objects allocated
+	Frees             uint64  // cumulative count of the heap objects freed
+	GC_Count          uint32  // number of completed GC cycles
+	GC_Pause_Total_Ns uint64  // cumulative GC pause time (nanoseconds)
+	GC_Last_Pause_Ns  uint64  // pause time of the last GC (nanoseconds)
+	GC_CPU_Fraction   float64 // fraction of the CPU time used by the GC
+	Goroutines        int     // number of goroutines
+	RSS               int64   // resident set size (bytes), -1 if not available
+	Open_FDs          int     // number of open file descriptors, -1 if not available
+}
+
+// OperationStats statistics of an operation of a unit.
+// Rates and percentiles are calculated over the sliding window, Handled and Failed are the totals.
+type OperationStats struct {
+	Handled        uint64  // total executions completed without error
+	Failed         uint64  // total executions completed with error
+	Rate           float64 // executions per second in the window
+	Error_Rate     float64 // failed / total executions in the window (0 - 1)
+	P50_Ms         float64 // 50th percentile duration (milliseconds) in the window
+	P95_Ms         float64 // 95th percentile duration (milliseconds) in the window
+	P99_Ms         float64 // 99th percentile duration (milliseconds) in the window
+	Window_Seconds float64 // length of the sliding window
+}
+
+// UnitState lifecycle state of an application unit
+type UnitState int32
+
+// constants for the unit states
+const (
+	UNIT_CREATED     UnitState = 0
+	UNIT_INITIALIZED UnitState = 1
+	UNIT_STARTING    UnitState = 2
+	UNIT_RUNNING     UnitState = 3
+	UNIT_STOPPING    UnitState = 4
+	UNIT_STOPPED     UnitState = 5
+	UNIT_FAILED      UnitState = 6
+)
+
+// unit_transitions holds the valid next states of each state
+var unit_transitions = map[UnitState][]UnitState{
+	UNIT_CREATED:     {UNIT_INITIALIZED, UNIT_FAILED},
+	UNIT_INITIALIZED: {UNIT_STARTING, UNIT_CREATED, UNIT_FAILED},
+	UNIT_STARTING:    {UNIT_RUNNING, UNIT_FAILED},
+	UNIT_RUNNING:     {UNIT_STOPPING, UNIT_FAILED},
+	UNIT_STOPPING:    {UNIT_STOPPED, UNIT_FAILED},
+	UNIT_STOPPED:     {UNIT_STARTING, UNIT_CREATED, UNIT_FAILED},
+	UNIT_FAILED:      {UNIT_STARTING, UNIT_STOPPING, UNIT_CREATED},
+}
+
+// Can_Transition returns true if the unit is allowed to move from the given state to the other
+func Can_Transition(pFrom UnitState, pTo UnitState) bool {
+	for _, _next := range unit_transitions[pFrom] {
+		if _next == pTo {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the name of the unit state (Created, Initialized, Starting, Running, Stopping, Stopped, Failed)
+func (s UnitState) String() string {
+	switch s {
+	case UNIT_CREATED:
+		return "Created"
+	case UNIT_INITIALIZED:
+		return "Initialized"
+	case UNIT_STARTING:
+		return "Starting"
+	case UNIT_RUNNING:
+		return "Running"
+	case UNIT_STOPPING:
+		return "Stopping"
+	case UNIT_STOPPED:
+		return "Stopped"
+	case UNIT_FAILED:
+		return "Failed"
+	}
+	return "UnitState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// MarshalText encodes the state as its name, so the monitoring messages carry readable states
+func (s UnitState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the state from its name
+func (s *UnitState) UnmarshalText(pText []byte) error {
+	for _state := UNIT_CREATED; _state <= UNIT_FAILED; _state++ {
+		if strings.EqualFold(_state.String(), string(pText)) {
+			*s = _state
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid unit state %q", string(pText))
 }
 
 // / application status
@@ -83,9 +247,10 @@ type AppStatus struct {
 	Mem_Usage      MemUsage	// holds the memory usage	// memory usage	
 	Req_Handled    uint64	// total request handled
 	Req_Failed     uint64	// total request failed
-	Routines       uint16		// count of running routines/thread
+	Routines       int64		// count of running routines/thread
 	MonitorClients uint8	// number of web socket monitor clients
 	StatusClients  uint8	// number of REST monitor client
+	Process        ProcessStats	// resource usage of the process
 }
 
 // ConvertStoI converts given structure to given interface
@@ -107,19 +272,136 @@ const (
 	LOG_DEBUG LogLevel = 6
 )
 
+// String returns the name of the log level (FATAL, PANIC, ERROR, WARN, INFO, DEBUG)
+func (l LogLevel) String() string {
+	switch l {
+	case LOG_FATAL:
+		return "FATAL"
+	case LOG_PANIC:
+		return "PANIC"
+	case LOG_ERROR:
+		return "ERROR"
+	case LOG_WARN:
+		return "WARN"
+	case LOG_INFO:
+		return "INFO"
+	case LOG_DEBUG:
+		return "DEBUG"
+	}
+	return "LEVEL(" + strconv.Itoa(int(l)) + ")"
+}
+
+// Parse_LogLevel converts the log level name used in the config files (fatal, panic, error, warn, info, debug)
+// into LogLevel.
+//
+//	Returns LOG_INFO if the given name is empty or unknown.
+func Parse_LogLevel(pLevel string) LogLevel {
+	switch strings.ToLower(strings.TrimSpace(pLevel)) {
+	case "fatal":
+		return LOG_FATAL
+	case "panic":
+		return LOG_PANIC
+	case "error":
+		return LOG_ERROR
+	case "warn", "warning":
+		return LOG_WARN
+	case "debug":
+		return LOG_DEBUG
+	}
+	return LOG_INFO
+}
+
+// Slog returns the log/slog level of the log level.
+//
+//	FATAL and PANIC are mapped above slog.LevelError (LevelError+8 and LevelError+4)
+func (l LogLevel) Slog() slog.Level {
+	switch l {
+	case LOG_FATAL:
+		return slog.LevelError + 8
+	case LOG_PANIC:
+		return slog.LevelError + 4
+	case LOG_ERROR:
+		return slog.LevelError
+	case LOG_WARN:
+		return slog.LevelWarn
+	case LOG_DEBUG:
+		return slog.LevelDebug
+	}
+	return slog.LevelInfo
+}
+
+// From_SlogLevel converts the log/slog level into LogLevel. Levels between two LogLevels are rounded down
+// to the less severe one (e.g. slog.LevelInfo+2 is LOG_INFO)
+func From_SlogLevel(pLevel slog.Level) LogLevel {
+	switch {
+	case pLevel >= slog.LevelError+8:
+		return LOG_FATAL
+	case pLevel >= slog.LevelError+4:
+		return LOG_PANIC
+	case pLevel >= slog.LevelError:
+		return LOG_ERROR
+	case pLevel >= slog.LevelWarn:
+		return LOG_WARN
+	case pLevel >= slog.LevelInfo:
+		return LOG_INFO
+	}
+	return LOG_DEBUG
+}
+
+// log formats of the Logconfig
+const (
+	LOG_FORMAT_TEXT = "text" // default, "<time> <LEVEL> <entry>" lines
+	LOG_FORMAT_JSON = "json" // one JSON object per line
+)
+
 type Logconfig struct {
 	LogLevel        string `json:"log_level"`
 	LogFileBasePath string `json:"log_file_base_path"`
-	LogFileMaxSize  int    `json:"log_file_max_size"`
+	LogFileMaxSize  int    `json:"log_file_max_size"`	/// MB, rotate when the log file reaches the size, 0 = no size based rotation
+	LogFormat       string `json:"log_format"` /// text (default) or json
+	LogFileRotate     string `json:"log_file_rotate"`      /// hourly, daily, weekly or a duration (30m), empty = size only
+	LogFileMaxBackups int    `json:"log_file_max_backups"` /// number of rotated files kept, 0 = keep all
+	LogFileMaxAge     int    `json:"log_file_max_age"`     /// days to keep the rotated files, 0 = keep all
+	LogFileCompress   bool   `json:"log_file_compress"`    /// gzip the rotated files
 }
 
 // Struture to hold application configuration
 type AppConfig struct {
 	App      App       `json:"app"`
 	Log      Logconfig `json:"log"`
+	HTTPMonitor Httpmonitor `json:"http_monitor"`
+	Tracing  Tracingconfig `json:"tracing"`
+	Bus      Busconfig `json:"bus"`
 	Appunits []Appunit `json:"appunits"`
 }
 
+// Busconfig default settings of the subscriptions of the message bus of the application (abus)
+type Busconfig struct {
+	QueueSize    int    `json:"queue_size"`	/// envelopes queued per subscription, 0 = abus.DEFAULT_QUEUE_SIZE
+	Policy       string `json:"policy"`	/// drop-oldest (default), drop-newest or block when a queue is full
+	BlockTimeout int    `json:"block_timeout"`	/// milliseconds a publisher waits for room with the block policy, 0 = abus.DEFAULT_BLOCK_TIMEOUT
+}
+
+// Tracingconfig settings of the tracing of the application (atrace)
+type Tracingconfig struct {
+	Enable       int8              `json:"enable"`	/// 1 = record and export the spans, unless only the trace context is propagated
+	Exporter     string            `json:"exporter"`	/// otlp (default), stdout or file
+	Endpoint     string            `json:"endpoint"`	/// OTLP/HTTP traces endpoint, http://localhost:4318/v1/traces if empty
+	Headers      map[string]string `json:"headers"`	/// extra headers of the OTLP requests
+	File         string            `json:"file"`	/// span file of the file exporter, relative to the application path
+	Service      string            `json:"service"`	/// service name of the spans, the application name if empty
+	Sample_Ratio float64           `json:"sample_ratio"`	/// ratio of the sampled traces (0..1), 0 = all
+}
+
+// Httpmonitor settings of the HTTP monitor (/metrics, /healthz, /readyz) of the application
+type Httpmonitor struct {
+	Host   string `json:"host"`
+	Port   int    `json:"port"`
+	Enable int8   `json:"enable"`	/// 1 = start with the application
+	HealthTimeout int `json:"health_timeout"`	/// milliseconds allowed for a health check, 0 = ahealth.DEFAULT_CHECK_TIMEOUT
+	HealthCache   int `json:"health_cache"`	/// milliseconds the health check results are reused, 0 = ahealth.DEFAULT_CACHE_TTL, -1 = not cached
+}
+
 type App struct {
 	Name    string `json:"name"`
 	ID      string `json:"id"`
@@ -131,15 +413,132 @@ type Appunit struct {
 	Path     string `json:"path"`
 	ConfigFile   string `json:"config"`
 	Enable   int8   `json:"enable"`
-	PoolSize int8   `json:"pool_size"`
+	PoolSize int8   `json:"pool_size"`	// number of instances of the unit (0 = 1)
+	PoolStrategy string `json:"pool_strategy"`	// dispatch of the work to the instances: round-robin (default) or least-active
+	StopTimeout int `json:"stop_timeout"`	// seconds allowed for a graceful stop before it is forced (0 = DEFAULT_STOP_TIMEOUT)
+	Restart     string `json:"restart"`	// restart policy: never (default), on-failure or always
+	RestartBackoff    int `json:"restart_backoff"`	// milliseconds before the first restart, doubled for every restart in the period (0 = DEFAULT_RESTART_BACKOFF)
+	RestartMaxBackoff int `json:"restart_max_backoff"`	// max milliseconds between the restarts (0 = DEFAULT_RESTART_MAX_BACKOFF)
+	RestartIntensity  int `json:"restart_intensity"`	// max restarts within the restart period, then the unit is given up (0 = DEFAULT_RESTART_INTENSITY)
+	RestartPeriod     int `json:"restart_period"`	// seconds of the restart intensity window (0 = DEFAULT_RESTART_PERIOD)
+	RestartEscalate   int8 `json:"restart_escalate"`	// 1 = stop the application when the unit is given up
+	DependsOn   []string `json:"depends_on"`	// units which must be running before the unit starts, stopped after the unit
+	OnDependencyStop string `json:"on_dependency_stop"`	// when a dependency stops the unit is stopped: stop (default) or restart, started again with the dependency
+}
+
+// actions of a unit when its dependency stops
+const (
+	DEPENDENCY_STOP    = "stop"
+	DEPENDENCY_RESTART = "restart"
+)
+
+// restart policies of the units
+const (
+	RESTART_NEVER      = "never"
+	RESTART_ON_FAILURE = "on-failure"
+	RESTART_ALWAYS     = "always"
+)
+
+// default restart settings of the units
+const (
+	DEFAULT_RESTART_BACKOFF     = 1000
+	DEFAULT_RESTART_MAX_BACKOFF = 60000
+	DEFAULT_RESTART_INTENSITY   = 5
+	DEFAULT_RESTART_PERIOD      = 60
+)
+
+// RestartStatus restarts of a supervised unit
+type RestartStatus struct {
+	Policy       string	// restart policy of the unit
+	Restarts     int	// number of restarts since the application started
+	Last_Restart string	// time of the last restart (RFC3339), empty if not restarted
+	Last_Reason  string	// failure which caused the last restart
+	Next_Restart string	// time of the scheduled restart (RFC3339), empty if none
+	Gave_Up      bool	// the restart intensity is exceeded, the unit is not restarted
+}
+
+// dispatch strategies of the unit pools
+const (
+	POOL_ROUND_ROBIN  = "round-robin"
+	POOL_LEAST_ACTIVE = "least-active"
+)
+
+// PoolStatus instances of a unit
+type PoolStatus struct {
+	Size      int	// number of instances the pool keeps running
+	Strategy  string	// dispatch strategy of the pool
+	Instances []InstanceStatus	// running instances in the start order
+}
+
+// InstanceStatus status of an instance of a unit
+type InstanceStatus struct {
+	ID          int	// instance id given to Initialize
+	State       UnitState	// lifecycle state of the instance
+	Active      int64	// current active executions of the instance
+	Req_Handled uint64	// number of request handled successfully
+	Req_Failed  uint64	// number of request failed to handle
+	Dispatched  uint64	// number of calls dispatched to the instance by the pool
 }
 
+// DEFAULT_STOP_TIMEOUT default seconds allowed for a graceful unit stop
+const DEFAULT_STOP_TIMEOUT = 10
+
 type PlugIn struct {
 	Type   string `json:"type"`
 	Ifname string `json:"ifname"`
 	Path   string `json:"path"`
 	Name   string `json:"name"`
 	Enable int8    `json:"enable"`
+	Resilience Resilienceconfig `json:"resilience"`	// retries and circuit breaker of the http client plugin
+	Auth       Authconfig       `json:"auth"`	// authentication of the requests of the http client plugin
+	TLS        TLSconfig        `json:"tls"`	// tls and mutual tls of the http and websocket client plugins
+}
+
+// TLSconfig tls settings of a http or websocket client plugin (atls).
+// The files are reloaded when they change on the disk.
+type TLSconfig struct {
+	CAFile             string   `json:"ca_file"`	// PEM bundle of the trusted CAs, the system CAs if empty
+	CertFile           string   `json:"cert_file"`	// PEM client certificate (chain) of the mutual tls
+	KeyFile            string   `json:"key_file"`	// PEM private key of the client certificate
+	ServerName         string   `json:"server_name"`	// SNI and verified name of the server, the host of the url if empty
+	MinVersion         string   `json:"min_version"`	// 1.0, 1.1, 1.2 or 1.3 (empty = 1.2)
+	Pins               []string `json:"pins"`	// base64 SHA-256 of the public key (SPKI) of a certificate of the server chain
+	InsecureSkipVerify int8     `json:"insecure_skip_verify"`	// 1 = the server chain is not verified (the pins are)
+	ReloadInterval     int      `json:"reload_interval"`	// seconds between the checks of the files (0 = atls.DEFAULT_RELOAD_INTERVAL, -1 = no reload)
+}
+
+// Authconfig authentication of the requests of a http client plugin (aauth).
+// A credential "env:NAME" is read from the environment variable NAME.
+type Authconfig struct {
+	Type          string   `json:"type"`	// basic, bearer, oauth2 or hmac, empty = none
+	Username      string   `json:"username"`	// basic
+	Password      string   `json:"password"`	// basic
+	Token         string   `json:"token"`	// bearer
+	TokenURL      string   `json:"token_url"`	// oauth2 token endpoint of the client credentials grant
+	ClientID      string   `json:"client_id"`	// oauth2
+	ClientSecret  string   `json:"client_secret"`	// oauth2
+	Scopes        []string `json:"scopes"`	// oauth2
+	RefreshBefore int      `json:"refresh_before"`	// oauth2 seconds before the expiry the token is refreshed (0 = aauth.DEFAULT_REFRESH_BEFORE)
+	AccessKey     string   `json:"access_key"`	// hmac
+	SecretKey     string   `json:"secret_key"`	// hmac
+	SessionToken  string   `json:"session_token"`	// hmac, optional
+	Region        string   `json:"region"`	// hmac
+	Service       string   `json:"service"`	// hmac
+	SingleEncode  int      `json:"single_encode"`	// hmac, 1 = encode the path once like s3 (S3 compatible services)
+}
+
+// Resilienceconfig retry and circuit breaker policy of a http client plugin (aresilience)
+type Resilienceconfig struct {
+	MaxAttempts        int      `json:"max_attempts"`	// attempts of a request including the first (0 or 1 = no retry)
+	Backoff            int      `json:"backoff"`	// milliseconds before the first retry, multiplied for every retry (0 = aresilience.DEFAULT_BACKOFF)
+	MaxBackoff         int      `json:"max_backoff"`	// max milliseconds between the attempts (0 = aresilience.DEFAULT_MAX_BACKOFF)
+	BackoffMultiplier  float64  `json:"backoff_multiplier"`	// growth of the backoff for every retry (0 = 2)
+	Jitter             float64  `json:"jitter"`	// random part of the backoff 0..1 (0 = aresilience.DEFAULT_JITTER, negative = none)
+	RetryOn            []string `json:"retry_on"`	// network, status codes (503) or classes (5xx). Empty = network, 408, 429, 502, 503, 504
+	RetryNonIdempotent int8     `json:"retry_non_idempotent"`	// 1 = retry POST and PATCH without an Idempotency-Key header
+	BreakerThreshold   int      `json:"breaker_threshold"`	// consecutive failures which open the circuit (0 = no circuit breaker)
+	BreakerOpenTime    int      `json:"breaker_open_time"`	// milliseconds the circuit stays open before the half-open probes (0 = aresilience.DEFAULT_OPEN_TIME)
+	BreakerProbes      int      `json:"breaker_probes"`	// successful half-open probes which close the circuit (0 = 1)
 }
 
 type FMConfig struct {
@@ -148,6 +547,10 @@ type FMConfig struct {
 			Level        string `json:"leg_level"`
 			File_MaxSize  int    `json:"log_file_max_size"`
 			File_Base_Path string `json:"log_file_base_path"`
+			File_Rotate      string `json:"log_file_rotate"`
+			File_Max_Backups int    `json:"log_file_max_backups"`
+			File_Max_Age     int    `json:"log_file_max_age"`
+			File_Compress    bool   `json:"log_file_compress"`
 		} `json:"log"`
 		HTTPMonitor struct {
 			Host         string `json:"host"`
@@ -167,3 +570,36 @@ type FMConfig struct {
 		Mailer []PlugIn `json:"mailer"`
 	} `json:"plugins"`
 }
+
+// HTTP_Plugin returns the config of the http client plugin of the given type, false if not configured
+func (c *FMConfig) HTTP_Plugin(pType string) (PlugIn, bool) {
+	for _, _plugin := range c.Plugins.HTTP {
+		if _plugin.Type == pType {
+			return _plugin, true
+		}
+	}
+	return PlugIn{}, false
+}
+
+// WS_Plugin returns the config of the websocket client plugin of the given type, false if not configured
+func (c *FMConfig) WS_Plugin(pType string) (PlugIn, bool) {
+	for _, _plugin := range c.Plugins.Websocket {
+		if _plugin.Type == pType {
+			return _plugin, true
+		}
+	}
+	return PlugIn{}, false
+}
+
+// Log_Config returns the log settings of the framework config as Logconfig
+func (c *FMConfig) Log_Config() Logconfig {
+	return Logconfig{
+		LogLevel:          c.Core.Log.Level,
+		LogFileBasePath:   c.Core.Log.File_Base_Path,
+		LogFileMaxSize:    c.Core.Log.File_MaxSize,
+		LogFileRotate:     c.Core.Log.File_Rotate,
+		LogFileMaxBackups: c.Core.Log.File_Max_Backups,
+		LogFileMaxAge:     c.Core.Log.File_Max_Age,
+		LogFileCompress:   c.Core.Log.File_Compress,
+	}
+}
@@ -0,0 +1,382 @@
+// abus package provides the in-process message bus of the units of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Bus, New_Bus, Config
+//
+//   - Envelope, Payload, Type_Of, Dead_Letter
+//
+//   - Subscription, Sub_Options, Handler, DropPolicy, Parse_Policy
+//
+//   - Stats, Sub_Stats
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   abus - AgniOne Application Framework
+//     Objective	:   Let the units of an application talk to each other without shared globals or external systems
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Units publish envelopes on dot separated topics (orders.created) and subscribe with patterns, where * matches
+//     one token and > matches the remaining tokens (orders.*, orders.>). Every subscription has a bounded queue and
+//     a routine which calls its handler in the order of the queue. When the queue is full the drop policy decides
+//     which envelope is dropped. Dropped envelopes and the envelopes failed by the handlers are published on
+//     DEAD_LETTER_TOPIC. Request publishes an envelope with a reply topic and waits for the first Reply.
+//     ---------------------------------------------------------------------------------------------------------------------
+package abus
+
+import (
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// default settings of the bus
+const (
+	DEFAULT_QUEUE_SIZE      = 256
+	DEFAULT_BLOCK_TIMEOUT   = time.Second
+	DEFAULT_REQUEST_TIMEOUT = 5 * time.Second
+)
+
+// system topics, not matched by the wildcards of the patterns which do not start with $
+const (
+	DEAD_LETTER_TOPIC = "$bus.dead_letter"
+	REPLY_PREFIX      = "$bus.reply."
+)
+
+// Envelope message of the bus. The payload is passed by reference, the subscribers must not modify it.
+type Envelope struct {
+	ID             string            // unique id of the envelope, set by Publish
+	Topic          string            // topic of the envelope
+	Type           string            // type of the payload, Type_Of of the payload if not set
+	Source         string            // publisher, unit#instance for the units
+	Reply_To       string            // topic of the reply of a request, empty for the events
+	Correlation_ID string            // id of the request of a reply
+	Headers        map[string]string // headers of the envelope (traceparent, ...)
+	Time           time.Time         // publish time, set by Publish
+	Payload        any               // content of the envelope
+}
+
+// Dead_Letter payload of the envelopes published on DEAD_LETTER_TOPIC
+type Dead_Letter struct {
+	Envelope *Envelope // envelope which was not delivered
+	Pattern  string    // pattern of the subscription which did not handle the envelope
+	Reason   string    // queue full or the error of the handler
+}
+
+// Payload returns the payload of the envelope as T.
+//
+//	Returns aerrors.ErrInvalidArgument if the payload is not a T
+func Payload[T any](pMsg *Envelope) (T, error) {
+	var _zero T
+	if pMsg == nil {
+		return _zero, fmt.Errorf("envelope %w", aerrors.ErrNilArgument)
+	}
+	_value, _ok := pMsg.Payload.(T)
+	if !_ok {
+		return _zero, fmt.Errorf("payload %s of %s is not %T. %w", pMsg.Type, pMsg.Topic, _zero, aerrors.ErrInvalidArgument)
+	}
+	return _value, nil
+}
+
+// Type_Of returns the type name of the payload (e.g. *orders.Order), empty for nil
+func Type_Of(pPayload any) string {
+	if pPayload == nil {
+		return ""
+	}
+	return reflect.TypeOf(pPayload).String()
+}
+
+// Config settings of the bus
+type Config struct {
+	Queue_Size    int           // default queue size of the subscriptions (0 = DEFAULT_QUEUE_SIZE)
+	Policy        DropPolicy    // default drop policy of the subscriptions (DROP_DEFAULT = DROP_OLDEST)
+	Block_Timeout time.Duration // default wait of DROP_BLOCK (0 = DEFAULT_BLOCK_TIMEOUT)
+}
+
+// Stats traffic of the bus
+type Stats struct {
+	Published     uint64 // envelopes published
+	Delivered     uint64 // envelopes handled by the subscribers
+	Dropped       uint64 // envelopes dropped by the full queues
+	Failed        uint64 // envelopes failed by the handlers
+	Subscriptions int    // current subscriptions
+}
+
+// Bus in-process publish/subscribe and request/reply bus
+type Bus struct {
+	config Config
+	prefix string /// prefix of the envelope ids
+
+	lock    sync.RWMutex
+	subs    map[uint64]*Subscription
+	replies map[string]chan *Envelope /// requests waiting for the reply, by reply topic
+	closed  bool
+
+	last_id   atomic.Uint64
+	published atomic.Uint64
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// New_Bus creates a bus with the given default settings of the subscriptions
+func New_Bus(pConfig Config) *Bus {
+	if pConfig.Queue_Size <= 0 {
+		pConfig.Queue_Size = DEFAULT_QUEUE_SIZE
+	}
+	if pConfig.Policy == DROP_DEFAULT {
+		pConfig.Policy = DROP_OLDEST
+	}
+	if pConfig.Block_Timeout <= 0 {
+		pConfig.Block_Timeout = DEFAULT_BLOCK_TIMEOUT
+	}
+
+	_random := make([]byte, 4)
+	rand.Read(_random)
+	return &Bus{
+		config:  pConfig,
+		prefix:  hex.EncodeToString(_random) + "-",
+		subs:    make(map[uint64]*Subscription),
+		replies: make(map[string]chan *Envelope),
+	}
+}
+
+// Publish delivers the envelope to the queues of the subscriptions matching its topic.
+// The ID and Time of the envelope are set, the Type is set from the payload if empty.
+//
+//	Returns aerrors.ErrInvalidArgument if the topic is empty or has a wildcard, aerrors.ErrBusClosed if the bus is closed
+func (b *Bus) Publish(pMsg *Envelope) error {
+
+	if pMsg == nil {
+		return fmt.Errorf("envelope %w", aerrors.ErrNilArgument)
+	}
+	if !valid_topic(pMsg.Topic) {
+		return fmt.Errorf("topic %q. %w", pMsg.Topic, aerrors.ErrInvalidArgument)
+	}
+	b.stamp(pMsg)
+
+	b.lock.RLock()
+	if b.closed {
+		b.lock.RUnlock()
+		return aerrors.ErrBusClosed
+	}
+	b.published.Add(1)
+
+	if strings.HasPrefix(pMsg.Topic, REPLY_PREFIX) {
+		defer b.lock.RUnlock()
+		if _waiter, _ok := b.replies[pMsg.Topic]; _ok {
+			select {
+			case _waiter <- pMsg:
+				b.delivered.Add(1)
+				return nil
+			default:
+			}
+		}
+		/// the request timed out or was already replied
+		b.dropped.Add(1)
+		return nil
+	}
+
+	var _matched []*Subscription
+	for _, _sub := range b.subs {
+		if match(_sub.pattern, pMsg.Topic) {
+			_matched = append(_matched, _sub)
+		}
+	}
+	b.lock.RUnlock()
+
+	/// queued without holding the lock, DROP_BLOCK may wait for room
+	for _, _sub := range _matched {
+		_sub.push(pMsg)
+	}
+	return nil
+}
+
+// stamp sets the id, time and type of the envelope
+func (b *Bus) stamp(pMsg *Envelope) {
+	if pMsg.ID == "" {
+		pMsg.ID = b.prefix + strconv.FormatUint(b.last_id.Add(1), 10)
+	}
+	if pMsg.Time.IsZero() {
+		pMsg.Time = time.Now()
+	}
+	if pMsg.Type == "" {
+		pMsg.Type = Type_Of(pMsg.Payload)
+	}
+}
+
+// Request publishes the envelope with a reply topic and waits for the first reply.
+// DEFAULT_REQUEST_TIMEOUT is applied if pCtx has no deadline.
+//
+//	Returns the reply. Unless returns aerrors.ErrNoResponders if no subscription matches the topic,
+//	or the error of pCtx when no reply is received in time
+func (b *Bus) Request(pCtx context.Context, pMsg *Envelope) (*Envelope, error) {
+
+	if pMsg == nil {
+		return nil, fmt.Errorf("envelope %w", aerrors.ErrNilArgument)
+	}
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	if _, _ok := pCtx.Deadline(); !_ok {
+		var _cancel context.CancelFunc
+		pCtx, _cancel = context.WithTimeout(pCtx, DEFAULT_REQUEST_TIMEOUT)
+		defer _cancel()
+	}
+	if !b.has_subscribers(pMsg.Topic) {
+		return nil, fmt.Errorf("topic %s. %w", pMsg.Topic, aerrors.ErrNoResponders)
+	}
+
+	b.stamp(pMsg)
+	pMsg.Reply_To = REPLY_PREFIX + pMsg.ID
+	_waiter := make(chan *Envelope, 1)
+
+	b.lock.Lock()
+	b.replies[pMsg.Reply_To] = _waiter
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		delete(b.replies, pMsg.Reply_To)
+		b.lock.Unlock()
+	}()
+
+	if _err := b.Publish(pMsg); _err != nil {
+		return nil, _err
+	}
+
+	select {
+	case _reply := <-_waiter:
+		return _reply, nil
+	case <-pCtx.Done():
+		return nil, fmt.Errorf("no reply to %s. %w", pMsg.Topic, pCtx.Err())
+	}
+}
+
+// Reply publishes the reply envelope of the request on its reply topic.
+//
+//	Returns aerrors.ErrInvalidArgument if the envelope is not a request
+func (b *Bus) Reply(pRequest *Envelope, pReply *Envelope) error {
+
+	if pRequest == nil || pReply == nil {
+		return fmt.Errorf("envelope %w", aerrors.ErrNilArgument)
+	}
+	if pRequest.Reply_To == "" {
+		return fmt.Errorf("envelope %s of %s is not a request. %w", pRequest.ID, pRequest.Topic, aerrors.ErrInvalidArgument)
+	}
+	pReply.Topic = pRequest.Reply_To
+	pReply.Correlation_ID = pRequest.ID
+	return b.Publish(pReply)
+}
+
+// has_subscribers returns true if a subscription matches the topic
+func (b *Bus) has_subscribers(pTopic string) bool {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, _sub := range b.subs {
+		if match(_sub.pattern, pTopic) {
+			return true
+		}
+	}
+	return false
+}
+
+// Stats returns the traffic of the bus
+func (b *Bus) Stats() Stats {
+	b.lock.RLock()
+	_subs := len(b.subs)
+	b.lock.RUnlock()
+	return Stats{
+		Published:     b.published.Load(),
+		Delivered:     b.delivered.Load(),
+		Dropped:       b.dropped.Load(),
+		Failed:        b.failed.Load(),
+		Subscriptions: _subs,
+	}
+}
+
+// Close closes all the subscriptions, the queued envelopes are discarded. Publish returns aerrors.ErrBusClosed.
+func (b *Bus) Close() {
+	b.lock.Lock()
+	b.closed = true
+	_subs := b.subs
+	b.subs = make(map[uint64]*Subscription)
+	b.lock.Unlock()
+
+	for _, _sub := range _subs {
+		_sub.cancel()
+	}
+}
+
+// dead_letter publishes the envelope which was not delivered on DEAD_LETTER_TOPIC
+func (b *Bus) dead_letter(pMsg *Envelope, pPattern string, pReason string) {
+	if pMsg.Topic == DEAD_LETTER_TOPIC || !b.has_subscribers(DEAD_LETTER_TOPIC) {
+		return
+	}
+	b.Publish(&Envelope{
+		Topic:   DEAD_LETTER_TOPIC,
+		Source:  pMsg.Source,
+		Headers: pMsg.Headers,
+		Payload: &Dead_Letter{Envelope: pMsg, Pattern: pPattern, Reason: pReason},
+	})
+}
+
+// valid_topic returns true if the topic has no empty token and no wildcard
+func valid_topic(pTopic string) bool {
+	if pTopic == "" {
+		return false
+	}
+	for _, _token := range strings.Split(pTopic, ".") {
+		if _token == "" || _token == "*" || _token == ">" {
+			return false
+		}
+	}
+	return true
+}
+
+// valid_pattern returns true if the pattern has no empty token and > only as the last token
+func valid_pattern(pPattern string) bool {
+	if pPattern == "" {
+		return false
+	}
+	_tokens := strings.Split(pPattern, ".")
+	for _i, _token := range _tokens {
+		if _token == "" || (_token == ">" && _i != len(_tokens)-1) {
+			return false
+		}
+	}
+	return true
+}
+
+// match returns true if the topic matches the pattern. * matches one token, > matches one or more tokens.
+// The wildcards do not match the system topics ($...) unless the pattern starts with $.
+func match(pPattern string, pTopic string) bool {
+
+	if pPattern == pTopic {
+		return true
+	}
+	if strings.HasPrefix(pTopic, "$") && !strings.HasPrefix(pPattern, "$") {
+		return false
+	}
+
+	for {
+		_pattern, _pattern_rest, _more_pattern := strings.Cut(pPattern, ".")
+		_topic, _topic_rest, _more_topic := strings.Cut(pTopic, ".")
+		switch {
+		case _pattern == ">":
+			return true
+		case _pattern != "*" && _pattern != _topic:
+			return false
+		case !_more_pattern || !_more_topic:
+			return _more_pattern == _more_topic
+		}
+		pPattern, pTopic = _pattern_rest, _topic_rest
+	}
+}
@@ -0,0 +1,264 @@
+package abus
+
+import (
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	_tests := []struct {
+		pattern string
+		topic   string
+		want    bool
+	}{
+		{pattern: "orders.created", topic: "orders.created", want: true},
+		{pattern: "orders.created", topic: "orders.deleted", want: false},
+		{pattern: "orders.*", topic: "orders.created", want: true},
+		{pattern: "orders.*", topic: "orders", want: false},
+		{pattern: "orders.*", topic: "orders.created.eu", want: false},
+		{pattern: "*.created", topic: "orders.created", want: true},
+		{pattern: "orders.>", topic: "orders.created", want: true},
+		{pattern: "orders.>", topic: "orders.created.eu", want: true},
+		{pattern: "orders.>", topic: "orders", want: false},
+		{pattern: ">", topic: "orders.created", want: true},
+		{pattern: "orders.*.eu", topic: "orders.created.eu", want: true},
+		{pattern: "orders.*.eu", topic: "orders.created.us", want: false},
+		{pattern: ">", topic: DEAD_LETTER_TOPIC, want: false},
+		{pattern: "*.*", topic: DEAD_LETTER_TOPIC[:len("$bus")] + ".x", want: false},
+		{pattern: "$bus.>", topic: DEAD_LETTER_TOPIC, want: true},
+		{pattern: "$bus.reply.*", topic: REPLY_PREFIX + "1", want: true},
+	}
+	for _, _test := range _tests {
+		if _got := match(_test.pattern, _test.topic); _got != _test.want {
+			t.Errorf("match(%q, %q) = %v, want %v", _test.pattern, _test.topic, _got, _test.want)
+		}
+	}
+}
+
+func TestValidTopic(t *testing.T) {
+	_tests := []struct {
+		topic      string
+		topic_ok   bool
+		pattern_ok bool
+	}{
+		{topic: "orders.created", topic_ok: true, pattern_ok: true},
+		{topic: "orders", topic_ok: true, pattern_ok: true},
+		{topic: "", topic_ok: false, pattern_ok: false},
+		{topic: "orders..created", topic_ok: false, pattern_ok: false},
+		{topic: "orders.", topic_ok: false, pattern_ok: false},
+		{topic: "orders.*", topic_ok: false, pattern_ok: true},
+		{topic: "orders.>", topic_ok: false, pattern_ok: true},
+		{topic: ">.created", topic_ok: false, pattern_ok: false},
+	}
+	for _, _test := range _tests {
+		if _got := valid_topic(_test.topic); _got != _test.topic_ok {
+			t.Errorf("valid_topic(%q) = %v, want %v", _test.topic, _got, _test.topic_ok)
+		}
+		if _got := valid_pattern(_test.topic); _got != _test.pattern_ok {
+			t.Errorf("valid_pattern(%q) = %v, want %v", _test.topic, _got, _test.pattern_ok)
+		}
+	}
+}
+
+// blocked_subscriber subscription whose handler blocks on the first envelope until it is released
+type blocked_subscriber struct {
+	sub      *Subscription
+	started  chan struct{}
+	release  chan struct{}
+	lock     sync.Mutex
+	handled  []string
+	dropped  []string
+	finished chan struct{}
+}
+
+// subscribe_blocked subscribes a blocked subscriber with a queue of 2, its handler is called with the first envelope
+func subscribe_blocked(t *testing.T, pBus *Bus, pPolicy DropPolicy, pExpected int) *blocked_subscriber {
+	_subscriber := &blocked_subscriber{started: make(chan struct{}), release: make(chan struct{}), finished: make(chan struct{})}
+	_options := Sub_Options{Queue_Size: 2, Policy: pPolicy, Block_Timeout: 20 * time.Millisecond,
+		On_Drop: func(pMsg *Envelope) {
+			_subscriber.lock.Lock()
+			_subscriber.dropped = append(_subscriber.dropped, pMsg.Payload.(string))
+			_subscriber.lock.Unlock()
+		}}
+	_sub, _err := pBus.Subscribe("orders.>", _options, func(pCtx context.Context, pMsg *Envelope) error {
+		_subscriber.lock.Lock()
+		_subscriber.handled = append(_subscriber.handled, pMsg.Payload.(string))
+		_count := len(_subscriber.handled)
+		_subscriber.lock.Unlock()
+		if _count == 1 {
+			close(_subscriber.started)
+			<-_subscriber.release
+		}
+		if _count == pExpected {
+			close(_subscriber.finished)
+		}
+		return nil
+	})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_subscriber.sub = _sub
+	return _subscriber
+}
+
+func TestDropPolicies(t *testing.T) {
+	_tests := []struct {
+		policy  DropPolicy
+		handled []string
+		dropped []string
+	}{
+		{policy: DROP_OLDEST, handled: []string{"1", "3", "4"}, dropped: []string{"2"}},
+		{policy: DROP_NEWEST, handled: []string{"1", "2", "3"}, dropped: []string{"4"}},
+		{policy: DROP_BLOCK, handled: []string{"1", "2", "3"}, dropped: []string{"4"}},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.policy.String(), func(t *testing.T) {
+			_bus := New_Bus(Config{})
+			defer _bus.Close()
+			_letters := make(chan *Dead_Letter, 4)
+			if _, _err := _bus.Subscribe(DEAD_LETTER_TOPIC, Sub_Options{}, func(pCtx context.Context, pMsg *Envelope) error {
+				_letters <- pMsg.Payload.(*Dead_Letter)
+				return nil
+			}); _err != nil {
+				t.Fatal(_err)
+			}
+			_subscriber := subscribe_blocked(t, _bus, _test.policy, 3)
+
+			/// 1 is in the handler, 2 and 3 fill the queue, 4 does not fit
+			_bus.Publish(&Envelope{Topic: "orders.created", Payload: "1"})
+			<-_subscriber.started
+			_bus.Publish(&Envelope{Topic: "orders.created", Payload: "2"})
+			_bus.Publish(&Envelope{Topic: "orders.created", Payload: "3"})
+			_started := time.Now()
+			_bus.Publish(&Envelope{Topic: "orders.created", Payload: "4"})
+			if _elapsed := time.Since(_started); _test.policy == DROP_BLOCK && _elapsed < 20*time.Millisecond {
+				t.Errorf("Publish() with a full queue returned after %v, want the block timeout", _elapsed)
+			}
+			close(_subscriber.release)
+
+			select {
+			case <-_subscriber.finished:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the queued envelopes are not handled")
+			}
+			_subscriber.lock.Lock()
+			defer _subscriber.lock.Unlock()
+			if !slices.Equal(_subscriber.handled, _test.handled) || !slices.Equal(_subscriber.dropped, _test.dropped) {
+				t.Errorf("handled %v, dropped %v, want handled %v, dropped %v", _subscriber.handled, _subscriber.dropped, _test.handled, _test.dropped)
+			}
+			if _stats := _subscriber.sub.Stats(); _stats.Dropped != 1 || _bus.Stats().Dropped != 1 {
+				t.Errorf("dropped of the subscription %d, of the bus %d, want 1", _stats.Dropped, _bus.Stats().Dropped)
+			}
+
+			select {
+			case _letter := <-_letters:
+				if _letter.Envelope.Payload != _test.dropped[0] || _letter.Pattern != "orders.>" || _letter.Reason != "queue of the subscription is full" {
+					t.Errorf("dead letter = %+v, want the dropped envelope %s", _letter, _test.dropped[0])
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no dead letter of the dropped envelope")
+			}
+			select {
+			case _letter := <-_letters:
+				t.Errorf("dead letter %+v, want only the dropped envelope", _letter)
+			case <-time.After(20 * time.Millisecond):
+			}
+		})
+	}
+}
+
+func TestDeadLetterFailed(t *testing.T) {
+	_bus := New_Bus(Config{})
+	defer _bus.Close()
+
+	_letters := make(chan *Dead_Letter, 2)
+	if _, _err := _bus.Subscribe(DEAD_LETTER_TOPIC, Sub_Options{}, func(pCtx context.Context, pMsg *Envelope) error {
+		_letters <- pMsg.Payload.(*Dead_Letter)
+		return nil
+	}); _err != nil {
+		t.Fatal(_err)
+	}
+	_sub, _ := _bus.Subscribe("orders.*", Sub_Options{}, func(pCtx context.Context, pMsg *Envelope) error {
+		if pMsg.Payload == "panic" {
+			panic("invalid order")
+		}
+		return errors.New("order rejected")
+	})
+
+	for _, _payload := range []string{"error", "panic"} {
+		if _err := _bus.Publish(&Envelope{Topic: "orders.created", Payload: _payload}); _err != nil {
+			t.Fatal(_err)
+		}
+		select {
+		case _letter := <-_letters:
+			if _letter.Envelope.Payload != _payload || _letter.Pattern != "orders.*" {
+				t.Errorf("dead letter = %+v, want the envelope %s of orders.*", _letter, _payload)
+			}
+			if _payload == "error" && _letter.Reason != "order rejected" || _payload == "panic" && !strings.Contains(_letter.Reason, "invalid order") {
+				t.Errorf("reason of the dead letter of %s = %q", _payload, _letter.Reason)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no dead letter of the %s envelope", _payload)
+		}
+	}
+	if _stats := _sub.Stats(); _stats.Failed != 2 || _stats.Delivered != 0 {
+		t.Errorf("stats of the subscription = %+v, want 2 failed", _stats)
+	}
+}
+
+func TestRequestReply(t *testing.T) {
+	_bus := New_Bus(Config{})
+	defer _bus.Close()
+
+	if _, _err := _bus.Request(context.Background(), &Envelope{Topic: "orders.get"}); !errors.Is(_err, aerrors.ErrNoResponders) {
+		t.Errorf("Request() without subscribers = %v, want aerrors.ErrNoResponders", _err)
+	}
+
+	_late := make(chan error, 1)
+	_bus.Subscribe("orders.get", Sub_Options{}, func(pCtx context.Context, pMsg *Envelope) error {
+		if !strings.HasPrefix(pMsg.Reply_To, REPLY_PREFIX) {
+			t.Errorf("Reply_To = %q, want the prefix %s", pMsg.Reply_To, REPLY_PREFIX)
+		}
+		if pMsg.Payload == "slow" {
+			time.Sleep(50 * time.Millisecond)
+			_late <- _bus.Reply(pMsg, &Envelope{Payload: "late"})
+			return nil
+		}
+		return _bus.Reply(pMsg, &Envelope{Payload: "order " + pMsg.Payload.(string)})
+	})
+
+	_request := &Envelope{Topic: "orders.get", Payload: "42"}
+	_reply, _err := _bus.Request(context.Background(), _request)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	if _reply.Payload != "order 42" || _reply.Correlation_ID != _request.ID || _reply.Topic != _request.Reply_To {
+		t.Errorf("reply = %+v, want the reply of the request %s", _reply, _request.ID)
+	}
+
+	_ctx, _cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer _cancel()
+	if _, _err := _bus.Request(_ctx, &Envelope{Topic: "orders.get", Payload: "slow"}); !errors.Is(_err, context.DeadlineExceeded) {
+		t.Errorf("Request() without a reply in time = %v, want context.DeadlineExceeded", _err)
+	}
+
+	/// the reply after the timeout is dropped
+	_dropped := _bus.Stats().Dropped
+	if _err := <-_late; _err != nil {
+		t.Fatal(_err)
+	}
+	if _bus.Stats().Dropped != _dropped+1 {
+		t.Errorf("dropped = %d, want the late reply dropped", _bus.Stats().Dropped)
+	}
+
+	if _err := _bus.Reply(&Envelope{Topic: "orders.get"}, &Envelope{}); !errors.Is(_err, aerrors.ErrInvalidArgument) {
+		t.Errorf("Reply() to an event = %v, want aerrors.ErrInvalidArgument", _err)
+	}
+}
@@ -0,0 +1,236 @@
+package abus
+
+import (
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DropPolicy decides which envelope is dropped when the queue of a subscription is full
+type DropPolicy int32
+
+// drop policies of the subscriptions
+const (
+	DROP_DEFAULT DropPolicy = 0 // the policy of the bus
+	DROP_OLDEST  DropPolicy = 1 // the oldest queued envelope is dropped to make room
+	DROP_NEWEST  DropPolicy = 2 // the new envelope is dropped
+	DROP_BLOCK   DropPolicy = 3 // the publisher waits for room up to the block timeout, then the new envelope is dropped
+)
+
+// String returns the name of the drop policy
+func (p DropPolicy) String() string {
+	switch p {
+	case DROP_DEFAULT:
+		return "default"
+	case DROP_OLDEST:
+		return "drop-oldest"
+	case DROP_NEWEST:
+		return "drop-newest"
+	case DROP_BLOCK:
+		return "block"
+	}
+	return "unknown"
+}
+
+// Parse_Policy returns the drop policy of the given name (drop-oldest, drop-newest, block), DROP_DEFAULT if not valid
+func Parse_Policy(pName string) DropPolicy {
+	switch strings.ToLower(pName) {
+	case "drop-oldest":
+		return DROP_OLDEST
+	case "drop-newest":
+		return DROP_NEWEST
+	case "block":
+		return DROP_BLOCK
+	}
+	return DROP_DEFAULT
+}
+
+// Handler handles an envelope of a subscription. pCtx is cancelled when the subscription is closed.
+// An error (or a panic) fails the envelope, it is counted and published on DEAD_LETTER_TOPIC.
+type Handler func(pCtx context.Context, pMsg *Envelope) error
+
+// Sub_Options settings of a subscription, the zero values take the settings of the bus
+type Sub_Options struct {
+	Queue_Size    int                  // number of envelopes queued for the handler
+	Policy        DropPolicy           // drop policy when the queue is full
+	Block_Timeout time.Duration        // wait of DROP_BLOCK
+	On_Drop       func(pMsg *Envelope) // called when an envelope is dropped
+}
+
+// Sub_Stats traffic of a subscription
+type Sub_Stats struct {
+	Delivered uint64 // envelopes handled
+	Dropped   uint64 // envelopes dropped by the full queue
+	Failed    uint64 // envelopes failed by the handler
+	Queued    int    // envelopes waiting in the queue
+}
+
+// Subscription subscription of a handler to the topics matching a pattern
+type Subscription struct {
+	bus     *Bus
+	id      uint64
+	pattern string
+	handler Handler
+	options Sub_Options
+
+	queue  chan *Envelope
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	delivered atomic.Uint64
+	dropped   atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// Subscribe subscribes the handler to the topics matching the pattern. The handler is called by the routine of
+// the subscription, one envelope at a time in the order of the queue.
+//
+//	Returns aerrors.ErrInvalidArgument if the pattern is not valid, aerrors.ErrBusClosed if the bus is closed
+func (b *Bus) Subscribe(pPattern string, pOptions Sub_Options, pHandler Handler) (*Subscription, error) {
+
+	if pHandler == nil {
+		return nil, fmt.Errorf("handler %w", aerrors.ErrNilArgument)
+	}
+	if !valid_pattern(pPattern) {
+		return nil, fmt.Errorf("pattern %q. %w", pPattern, aerrors.ErrInvalidArgument)
+	}
+	if pOptions.Queue_Size <= 0 {
+		pOptions.Queue_Size = b.config.Queue_Size
+	}
+	if pOptions.Policy == DROP_DEFAULT {
+		pOptions.Policy = b.config.Policy
+	}
+	if pOptions.Block_Timeout <= 0 {
+		pOptions.Block_Timeout = b.config.Block_Timeout
+	}
+
+	_sub := &Subscription{
+		bus:     b,
+		pattern: pPattern,
+		handler: pHandler,
+		options: pOptions,
+		queue:   make(chan *Envelope, pOptions.Queue_Size),
+	}
+	_sub.ctx, _sub.cancel = context.WithCancel(context.Background())
+
+	b.lock.Lock()
+	if b.closed {
+		b.lock.Unlock()
+		return nil, aerrors.ErrBusClosed
+	}
+	_sub.id = b.last_id.Add(1)
+	b.subs[_sub.id] = _sub
+	b.lock.Unlock()
+
+	go _sub.run()
+	return _sub, nil
+}
+
+// Pattern returns the pattern of the subscription
+func (s *Subscription) Pattern() string {
+	return s.pattern
+}
+
+// Unsubscribe closes the subscription, the queued envelopes are discarded.
+// The handler in progress completes, its context is cancelled.
+func (s *Subscription) Unsubscribe() {
+	s.bus.lock.Lock()
+	delete(s.bus.subs, s.id)
+	s.bus.lock.Unlock()
+	s.cancel()
+}
+
+// Stats returns the traffic of the subscription
+func (s *Subscription) Stats() Sub_Stats {
+	return Sub_Stats{
+		Delivered: s.delivered.Load(),
+		Dropped:   s.dropped.Load(),
+		Failed:    s.failed.Load(),
+		Queued:    len(s.queue),
+	}
+}
+
+// push queues the envelope according to the drop policy
+func (s *Subscription) push(pMsg *Envelope) {
+
+	for {
+		select {
+		case s.queue <- pMsg:
+			return
+		default:
+		}
+
+		switch s.options.Policy {
+		case DROP_OLDEST:
+			select {
+			case _oldest := <-s.queue:
+				s.drop(_oldest)
+			default:
+			}
+			continue
+		case DROP_BLOCK:
+			_timer := time.NewTimer(s.options.Block_Timeout)
+			select {
+			case s.queue <- pMsg:
+				_timer.Stop()
+				return
+			case <-_timer.C:
+			case <-s.ctx.Done():
+				_timer.Stop()
+			}
+		}
+		s.drop(pMsg)
+		return
+	}
+}
+
+// drop counts the dropped envelope and publishes it on DEAD_LETTER_TOPIC
+func (s *Subscription) drop(pMsg *Envelope) {
+	s.dropped.Add(1)
+	s.bus.dropped.Add(1)
+	if s.options.On_Drop != nil {
+		s.options.On_Drop(pMsg)
+	}
+	s.bus.dead_letter(pMsg, s.pattern, "queue of the subscription is full")
+}
+
+// run calls the handler with the queued envelopes until the subscription is closed
+func (s *Subscription) run() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case _msg := <-s.queue:
+			if s.ctx.Err() != nil {
+				return
+			}
+			s.deliver(_msg)
+		}
+	}
+}
+
+// deliver calls the handler, a panic fails the envelope
+func (s *Subscription) deliver(pMsg *Envelope) {
+
+	_err := func() (_err error) {
+		defer func() {
+			if _r := recover(); _r != nil {
+				_err = fmt.Errorf("handler panic. %v", _r)
+			}
+		}()
+		return s.handler(s.ctx, pMsg)
+	}()
+
+	if _err == nil {
+		s.delivered.Add(1)
+		s.bus.delivered.Add(1)
+		return
+	}
+	s.failed.Add(1)
+	s.bus.failed.Add(1)
+	s.bus.dead_letter(pMsg, s.pattern, _err.Error())
+}
@@ -0,0 +1,201 @@
+// aerrors package provides the errors returned by the AgniOne Application Framework, units and plugins
+//
+// This package includes below errors & types:
+//
+//   - ErrNotInitialized, ErrNotStarted, ErrAlreadyStarted, ErrInvalidTransition
+//
+//   - ErrUnitNotFound, ErrPluginNotFound, ErrNilArgument, ErrInvalidArgument, ErrNotSupported, ErrStopTimeout, ErrRestartLimit
+//
+//   - ErrOperationFailed
+//
+//   - ErrDependency, ErrDependencyCycle
+//
+//   - ErrBusClosed, ErrNoResponders
+//
+//   - ErrCircuitOpen, ErrAuthentication, ErrCertificatePin
+//
+//   - ErrHTTPStatus, ErrWSClosed
+//
+//   - StateError
+//
+//   - UnitError
+//
+//   - HTTPStatusError
+//
+//   - WSClosedError
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   aerrors - AgniOne Application Framework
+//     Objective	:   Define the sentinel and typed errors to use with errors.Is / errors.As
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Framework, units and plugins wrap these errors (fmt.Errorf with %w) so the callers can
+//     branch on the error without matching the error strings.
+//     ---------------------------------------------------------------------------------------------------------------------
+package aerrors
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// sentinel errors
+var (
+	// ErrNotInitialized the unit, plugin or framework instance is not initialized
+	ErrNotInitialized = errors.New("instance is not initialized")
+
+	// ErrNotStarted the unit process is not started/running
+	ErrNotStarted = errors.New("instance process is not started")
+
+	// ErrAlreadyStarted the unit is already started
+	ErrAlreadyStarted = errors.New("instance is already started")
+
+	// ErrInvalidTransition is matched by every StateError
+	ErrInvalidTransition = errors.New("invalid unit state transition")
+
+	// ErrUnitNotFound the unit is not found in the configuration or not registered
+	ErrUnitNotFound = errors.New("unit is not found")
+
+	// ErrPluginNotFound the plugin type is not found in the configuration or not registered
+	ErrPluginNotFound = errors.New("plugin is not found")
+
+	// ErrNilArgument a required argument is nil or empty
+	ErrNilArgument = errors.New("argument is nil")
+
+	// ErrInvalidArgument an argument is out of the allowed range or not valid
+	ErrInvalidArgument = errors.New("argument is not valid")
+
+	// ErrNotSupported the operation is not supported by the implementation
+	ErrNotSupported = errors.New("operation is not supported")
+
+	// ErrOperationFailed a v1 function returned false without an error (see compat)
+	ErrOperationFailed = errors.New("operation failed")
+
+	// ErrStopTimeout the stop did not complete before the deadline
+	ErrStopTimeout = errors.New("stop did not complete before the deadline")
+
+	// ErrRestartLimit the unit failed more often than its restart intensity allows and is not restarted
+	ErrRestartLimit = errors.New("unit restart intensity exceeded")
+
+	// ErrDependency the unit can not start before its dependencies or stop before its dependents
+	ErrDependency = errors.New("unit dependency is not satisfied")
+
+	// ErrDependencyCycle the units depend on each other (depends_on)
+	ErrDependencyCycle = errors.New("unit dependency cycle")
+
+	// ErrBusClosed the message bus is closed
+	ErrBusClosed = errors.New("message bus is closed")
+
+	// ErrNoResponders no subscription matches the topic of the request
+	ErrNoResponders = errors.New("no responders for the request")
+
+	// ErrCircuitOpen the circuit breaker of the http client rejects the requests
+	ErrCircuitOpen = errors.New("circuit breaker is open")
+
+	// ErrAuthentication the credentials of the http client are missing or not accepted
+	ErrAuthentication = errors.New("http client authentication failed")
+
+	// ErrCertificatePin no certificate of the server chain matches the pins of the tls settings
+	ErrCertificatePin = errors.New("server certificate does not match the pins")
+
+	// ErrHTTPStatus is matched by every HTTPStatusError
+	ErrHTTPStatus = errors.New("unexpected http status")
+
+	// ErrWSClosed is matched by every WSClosedError
+	ErrWSClosed = errors.New("web socket connection is closed")
+)
+
+// StateError returned when the unit is asked to move into a state which is not allowed from the current state
+type StateError struct {
+	Unit string           // unit name
+	From atypes.UnitState // current state
+	To   atypes.UnitState // requested state
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("unit %s : invalid state transition %s -> %s", e.Unit, e.From, e.To)
+}
+
+// Is reports ErrInvalidTransition as the matching sentinel error
+func (e *StateError) Is(pTarget error) bool {
+	return pTarget == ErrInvalidTransition
+}
+
+// UnitError returned by the unit control functions of the framework (Unit_Start, Unit_Stop, ...)
+type UnitError struct {
+	Unit string // unit name
+	Op   string // operation (start, stop, restart, status)
+	Err  error  // cause, usually one of the sentinel errors
+}
+
+func (e *UnitError) Error() string {
+	return "unit " + e.Unit + " : " + e.Op + " failed. " + e.Err.Error()
+}
+
+// Unwrap returns the cause
+func (e *UnitError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatusError returned by the http client plugins when the response status code is not 2xx.
+// The response is returned together with the error.
+type HTTPStatusError struct {
+	Method     string // http method of the request
+	URL        string // url of the request
+	StatusCode int    // response status code
+	Body       []byte // response body (may be truncated by the plugin)
+}
+
+func (e *HTTPStatusError) Error() string {
+	return e.Method + " " + e.URL + " : " + ErrHTTPStatus.Error() + " " + strconv.Itoa(e.StatusCode)
+}
+
+// Is reports ErrHTTPStatus as the matching sentinel error
+func (e *HTTPStatusError) Is(pTarget error) bool {
+	return pTarget == ErrHTTPStatus
+}
+
+// Retryable returns true for the status codes which may succeed when retried (408, 429, 5xx except 501)
+func (e *HTTPStatusError) Retryable() bool {
+	switch {
+	case e.StatusCode == 408, e.StatusCode == 429:
+		return true
+	case e.StatusCode == 501:
+		return false
+	}
+	return e.StatusCode >= 500
+}
+
+// WSClosedError returned by the web socket client plugins when the connection is closed
+type WSClosedError struct {
+	Code   int    // close code (RFC 6455 section 7.4), 0 if not received
+	Reason string // close reason sent by the peer
+	Err    error  // underlying error (may be nil)
+}
+
+func (e *WSClosedError) Error() string {
+	_msg := ErrWSClosed.Error()
+	if e.Code != 0 {
+		_msg += " (" + strconv.Itoa(e.Code) + ")"
+	}
+	if e.Reason != "" {
+		_msg += " " + e.Reason
+	}
+	if e.Err != nil {
+		_msg += ". " + e.Err.Error()
+	}
+	return _msg
+}
+
+// Is reports ErrWSClosed as the matching sentinel error
+func (e *WSClosedError) Is(pTarget error) bool {
+	return pTarget == ErrWSClosed
+}
+
+// Unwrap returns the underlying error
+func (e *WSClosedError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,246 @@
+// ahealth package provides the health checks of the units and plugins of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - HealthChecker, Timeout_Checker
+//
+//   - Result, Report, Check_Result
+//
+//   - Checker, New_Checker
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   ahealth - AgniOne Application Framework
+//     Objective	:   Tell whether the units can serve, for the liveness and readiness probes
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Units and plugins implement HealthChecker. The Checker runs the checks concurrently, each with its own
+//     timeout, caches the results for a short time so frequent probes do not load the downstream services,
+//     and aggregates them into a Report which is served as JSON by the liveness and readiness handlers.
+//     ---------------------------------------------------------------------------------------------------------------------
+package ahealth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// default settings of the Checker
+const (
+	DEFAULT_CHECK_TIMEOUT = 2 * time.Second
+	DEFAULT_CACHE_TTL     = time.Second
+)
+
+// Result result of a health check
+type Result struct {
+	Live    bool           // false if the component is broken and must be restarted
+	Ready   bool           // false if the component can not serve the requests now
+	Details map[string]any // details of the check (connection state, queue size, ...)
+	Error   string         // reason of the failure
+}
+
+// HealthChecker optional interface for the units and plugins which report their health.
+//
+// Check_Health is called concurrently by the framework and must return before ctx is done.
+type HealthChecker interface {
+	Check_Health(ctx context.Context) Result
+}
+
+// Timeout_Checker optional interface of a HealthChecker which needs a timeout other than the Checker timeout
+type Timeout_Checker interface {
+	Health_Timeout() time.Duration
+}
+
+// Check_Result result of a check in the Report
+type Check_Result struct {
+	Live        bool           `json:"live"`
+	Ready       bool           `json:"ready"`
+	Details     map[string]any `json:"details,omitempty"`
+	Error       string         `json:"error,omitempty"`
+	Checked     time.Time      `json:"checked"`
+	Duration_Ms float64        `json:"duration_ms"`
+	Cached      bool           `json:"cached"` // returned from the cache without running the check
+}
+
+// Report aggregated result of the checks. Live/Ready are true only if all the checks are live/ready
+type Report struct {
+	Live   bool                    `json:"live"`
+	Ready  bool                    `json:"ready"`
+	Checks map[string]Check_Result `json:"checks"`
+}
+
+// Failed returns the names of the checks which are not live (pReady false) or not ready (pReady true), sorted
+func (r Report) Failed(pReady bool) []string {
+	var _names []string
+	for _name, _check := range r.Checks {
+		if (pReady && !_check.Ready) || (!pReady && !_check.Live) {
+			_names = append(_names, _name)
+		}
+	}
+	sort.Strings(_names)
+	return _names
+}
+
+// Checker runs the checks and caches the results
+type Checker struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	lock  sync.Mutex
+	cache map[string]cached
+}
+
+// cached result of a check
+type cached struct {
+	result  Check_Result
+	expires time.Time
+}
+
+// New_Checker creates a checker with the given default timeout of a check and the cache time of the results.
+// DEFAULT_CHECK_TIMEOUT and DEFAULT_CACHE_TTL are used when the values are 0, a negative pCache_TTL disables the cache
+func New_Checker(pTimeout time.Duration, pCache_TTL time.Duration) *Checker {
+	if pTimeout <= 0 {
+		pTimeout = DEFAULT_CHECK_TIMEOUT
+	}
+	if pCache_TTL == 0 {
+		pCache_TTL = DEFAULT_CACHE_TTL
+	}
+	return &Checker{timeout: pTimeout, ttl: pCache_TTL, cache: make(map[string]cached)}
+}
+
+// Check runs the given checks concurrently and returns the aggregated report.
+// The results cached within the cache time are reused, the cache of the checks not given is removed.
+// The results are not cached when pCtx is done before the checks return (the probe was cancelled).
+//
+//	The name of a check must change when the component is replaced (e.g. include the instance id of a unit)
+func (c *Checker) Check(pCtx context.Context, pChecks map[string]HealthChecker) Report {
+
+	_report := Report{Live: true, Ready: true, Checks: make(map[string]Check_Result, len(pChecks))}
+	_now := time.Now()
+
+	var _lock sync.Mutex
+	var _wg sync.WaitGroup
+	_set := func(pName string, pResult Check_Result) {
+		_lock.Lock()
+		defer _lock.Unlock()
+		_report.Checks[pName] = pResult
+		_report.Live = _report.Live && pResult.Live
+		_report.Ready = _report.Ready && pResult.Ready
+	}
+
+	c.lock.Lock()
+	for _name := range c.cache {
+		if _, _ok := pChecks[_name]; !_ok {
+			delete(c.cache, _name)
+		}
+	}
+	for _name, _check := range pChecks {
+		_live := true /// liveness of a check which does not complete, kept from its last result
+		if _entry, _ok := c.cache[_name]; _ok {
+			if _now.Before(_entry.expires) {
+				_result := _entry.result
+				_result.Cached = true
+				_set(_name, _result)
+				continue
+			}
+			_live = _entry.result.Live
+		}
+		_wg.Add(1)
+		go func(pName string, pCheck HealthChecker, pLive bool) {
+			defer _wg.Done()
+			_result := c.run(pCtx, pCheck, pLive)
+			if pCtx.Err() == nil {
+				c.store(pName, _result)
+			}
+			_set(pName, _result)
+		}(_name, _check, _live)
+	}
+	c.lock.Unlock()
+
+	_wg.Wait()
+	return _report
+}
+
+// store caches the result of the check. The expired results are kept for the liveness of the next run
+func (c *Checker) store(pName string, pResult Check_Result) {
+	if c.ttl < 0 {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.cache[pName] = cached{result: pResult, expires: pResult.Checked.Add(c.ttl)}
+}
+
+// run runs the check with its timeout. A check which panics or does not return in time is not ready,
+// its liveness is pLive (the last result) as a slow or broken check does not mean the component must be restarted
+func (c *Checker) run(pCtx context.Context, pCheck HealthChecker, pLive bool) Check_Result {
+
+	_timeout := c.timeout
+	if _custom, _ok := pCheck.(Timeout_Checker); _ok && _custom.Health_Timeout() > 0 {
+		_timeout = _custom.Health_Timeout()
+	}
+	_ctx, _cancel := context.WithTimeout(pCtx, _timeout)
+	defer _cancel()
+
+	_started := time.Now()
+	_done := make(chan Result, 1)
+	go func() {
+		defer func() {
+			if _r := recover(); _r != nil {
+				_done <- Result{Live: pLive, Error: fmt.Sprintf("health check panic. %v", _r)}
+			}
+		}()
+		_done <- pCheck.Check_Health(_ctx)
+	}()
+
+	var _result Result
+	select {
+	case _result = <-_done:
+	case <-_ctx.Done():
+		_result = Result{Live: pLive, Error: "health check did not complete. " + _ctx.Err().Error()}
+	}
+
+	return Check_Result{
+		Live:        _result.Live,
+		Ready:       _result.Ready,
+		Details:     _result.Details,
+		Error:       _result.Error,
+		Checked:     _started,
+		Duration_Ms: float64(time.Since(_started)) / float64(time.Millisecond),
+	}
+}
+
+// Handler returns the handler which serves the report of the given function as JSON.
+// The status is 200 if the report is live (pReady false) or ready (pReady true), unless 503
+func Handler(pReport func(ctx context.Context) Report, pReady bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		_report := pReport(r.Context())
+		_ok := _report.Live
+		if pReady {
+			_ok = _report.Ready
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if _ok {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		if r.Method == http.MethodHead {
+			return
+		}
+		json.NewEncoder(w).Encode(_report)
+	})
+}
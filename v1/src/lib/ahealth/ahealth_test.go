@@ -0,0 +1,88 @@
+package ahealth_test
+
+import (
+	"github.com/agnione/libs/v1/src/lib/ahealth"
+
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// check_func health check calling the function and counting the calls
+type check_func struct {
+	calls atomic.Int32
+	check func(ctx context.Context) ahealth.Result
+}
+
+func (c *check_func) Check_Health(pCtx context.Context) ahealth.Result {
+	c.calls.Add(1)
+	return c.check(pCtx)
+}
+
+// blocking check which returns only when its context is done
+func blocking(pCtx context.Context) ahealth.Result {
+	<-pCtx.Done()
+	return ahealth.Result{}
+}
+
+func TestTimeoutKeepsLive(t *testing.T) {
+	_checker := ahealth.New_Checker(10*time.Millisecond, -1)
+	_report := _checker.Check(context.Background(), map[string]ahealth.HealthChecker{"slow": &check_func{check: blocking}})
+
+	_result := _report.Checks["slow"]
+	if !_result.Live || _result.Ready || _result.Error == "" {
+		t.Fatalf("timed out check without a last result: %+v, want live, not ready, with an error", _result)
+	}
+	if !_report.Live || _report.Ready {
+		t.Fatalf("report live %v ready %v, want live and not ready", _report.Live, _report.Ready)
+	}
+}
+
+func TestPanicKeepsLive(t *testing.T) {
+	_checker := ahealth.New_Checker(time.Second, -1)
+	_check := &check_func{check: func(context.Context) ahealth.Result { panic("broken") }}
+	_result := _checker.Check(context.Background(), map[string]ahealth.HealthChecker{"panic": _check}).Checks["panic"]
+	if !_result.Live || _result.Ready || _result.Error == "" {
+		t.Fatalf("panicking check: %+v, want live, not ready, with an error", _result)
+	}
+}
+
+func TestTimeoutKeepsLastLive(t *testing.T) {
+	_checker := ahealth.New_Checker(10*time.Millisecond, 5*time.Millisecond)
+	_check := &check_func{check: func(context.Context) ahealth.Result { return ahealth.Result{Live: false, Error: "dead"} }}
+	_checks := map[string]ahealth.HealthChecker{"check": _check}
+
+	if _checker.Check(context.Background(), _checks).Live {
+		t.Fatal("report live, want not live")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	_checks["check"] = &check_func{check: blocking}
+	_result := _checker.Check(context.Background(), _checks).Checks["check"]
+	if _result.Cached || _result.Live {
+		t.Fatalf("timed out check after a not live result: %+v, want run and not live", _result)
+	}
+}
+
+func TestCancelledNotCached(t *testing.T) {
+	_checker := ahealth.New_Checker(time.Second, time.Minute)
+	_checks := map[string]ahealth.HealthChecker{"check": &check_func{check: blocking}}
+
+	_ctx, _cancel := context.WithCancel(context.Background())
+	_cancel()
+	if _result := _checker.Check(_ctx, _checks).Checks["check"]; !_result.Live || _result.Ready {
+		t.Fatalf("cancelled check: %+v, want live and not ready", _result)
+	}
+
+	_check := &check_func{check: func(context.Context) ahealth.Result { return ahealth.Result{Live: true, Ready: true} }}
+	_checks["check"] = _check
+	_result := _checker.Check(context.Background(), _checks).Checks["check"]
+	if _result.Cached || !_result.Ready || _check.calls.Load() != 1 {
+		t.Fatalf("check after a cancelled probe: %+v, calls %d, want run again and ready", _result, _check.calls.Load())
+	}
+
+	if _result := _checker.Check(context.Background(), _checks).Checks["check"]; !_result.Cached {
+		t.Fatal("completed result is not cached")
+	}
+}
@@ -0,0 +1,501 @@
+// ametrics package provides the metrics registry and the Prometheus text exporter of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Registry
+//
+//   - New_Registry
+//
+//   - Counter, Gauge, Histogram
+//
+//   - Labels, Sample
+//
+//   - App_Samples, Unit_Samples, Unit_Labels
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   ametrics - AgniOne Application Framework
+//     Objective	:   Expose the framework and unit counters in the Prometheus text format (version 0.0.4)
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Counters, gauges and histograms are registered in the Registry with their labels. Values which are
+//     already kept elsewhere (AppStatus, AppUnitInfo) are read at scrape time by the registered collectors.
+//     Registry.Handler serves all of them on /metrics.
+//     ---------------------------------------------------------------------------------------------------------------------
+package ametrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// metric types
+const (
+	TYPE_COUNTER   = "counter"
+	TYPE_GAUGE     = "gauge"
+	TYPE_HISTOGRAM = "histogram"
+)
+
+// CONTENT_TYPE content type of the Prometheus text format
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// DEFAULT_BUCKETS histogram buckets (seconds) used when no buckets are given
+var DEFAULT_BUCKETS = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+var (
+	metric_name_rx = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	label_name_rx  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Labels label names and values of a metric
+type Labels map[string]string
+
+// Sample value read by a collector at scrape time
+type Sample struct {
+	Name   string  // metric name
+	Help   string  // help text
+	Type   string  // TYPE_COUNTER or TYPE_GAUGE
+	Labels Labels  // labels of the value
+	Value  float64 // value
+}
+
+// ********* metrics *********
+
+// Counter monotonically increasing value
+type Counter struct {
+	bits atomic.Uint64
+}
+
+// Inc adds 1 to the counter
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add adds the given value to the counter. Negative values are ignored
+func (c *Counter) Add(pValue float64) {
+	if pValue < 0 {
+		return
+	}
+	add_float(&c.bits, pValue)
+}
+
+// Value returns the current value
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(c.bits.Load())
+}
+
+// Gauge value which can go up and down
+type Gauge struct {
+	bits atomic.Uint64
+}
+
+// Set sets the gauge to the given value
+func (g *Gauge) Set(pValue float64) {
+	g.bits.Store(math.Float64bits(pValue))
+}
+
+// Add adds the given value (can be negative) to the gauge
+func (g *Gauge) Add(pValue float64) {
+	add_float(&g.bits, pValue)
+}
+
+// Inc adds 1 to the gauge
+func (g *Gauge) Inc() {
+	g.Add(1)
+}
+
+// Dec subtracts 1 from the gauge
+func (g *Gauge) Dec() {
+	g.Add(-1)
+}
+
+// Value returns the current value
+func (g *Gauge) Value() float64 {
+	return math.Float64frombits(g.bits.Load())
+}
+
+// Histogram counts the observed values in buckets
+type Histogram struct {
+	lock   sync.Mutex
+	upper  []float64 /// upper bounds of the buckets, sorted
+	counts []uint64  /// not cumulative
+	sum    float64
+	count  uint64
+}
+
+// Observe adds the given value to the histogram
+func (h *Histogram) Observe(pValue float64) {
+	_index := sort.SearchFloat64s(h.upper, pValue)
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	if _index < len(h.counts) {
+		h.counts[_index]++
+	}
+	h.sum += pValue
+	h.count++
+}
+
+// snapshot returns the cumulative bucket counts, the sum and the count
+func (h *Histogram) snapshot() ([]uint64, float64, uint64) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	_cumulative := make([]uint64, len(h.counts))
+	var _total uint64
+	for _i, _count := range h.counts {
+		_total += _count
+		_cumulative[_i] = _total
+	}
+	return _cumulative, h.sum, h.count
+}
+
+// add_float adds the value to the float64 stored in the given bits
+func add_float(pBits *atomic.Uint64, pValue float64) {
+	for {
+		_old := pBits.Load()
+		if pBits.CompareAndSwap(_old, math.Float64bits(math.Float64frombits(_old)+pValue)) {
+			return
+		}
+	}
+}
+
+// ********* registry *********
+
+// series metric of a family with its labels
+type series struct {
+	labels Labels
+	metric any /// *Counter, *Gauge or *Histogram
+}
+
+// family metrics with the same name
+type family struct {
+	name    string
+	help    string
+	typ     string
+	buckets []float64
+	series  map[string]*series /// key is the formatted labels
+}
+
+// Registry holds the registered metrics and collectors. Safe for the concurrent use
+type Registry struct {
+	lock           sync.RWMutex
+	families       map[string]*family
+	collectors     map[uint64]func() []Sample
+	collectors_seq uint64
+}
+
+// New_Registry creates an empty registry
+func New_Registry() *Registry {
+	return &Registry{
+		families:   make(map[string]*family),
+		collectors: make(map[uint64]func() []Sample),
+	}
+}
+
+// Counter returns the counter with the given name and labels, it is created if not registered.
+//
+//	Returns error if the name or the labels are not valid or the name is registered with another type
+func (r *Registry) Counter(pName string, pHelp string, pLabels Labels) (*Counter, error) {
+	_metric, _err := r.get(pName, pHelp, TYPE_COUNTER, nil, pLabels, func() any { return &Counter{} })
+	if _err != nil {
+		return nil, _err
+	}
+	return _metric.(*Counter), nil
+}
+
+// Gauge returns the gauge with the given name and labels, it is created if not registered.
+//
+//	Returns error if the name or the labels are not valid or the name is registered with another type
+func (r *Registry) Gauge(pName string, pHelp string, pLabels Labels) (*Gauge, error) {
+	_metric, _err := r.get(pName, pHelp, TYPE_GAUGE, nil, pLabels, func() any { return &Gauge{} })
+	if _err != nil {
+		return nil, _err
+	}
+	return _metric.(*Gauge), nil
+}
+
+// Histogram returns the histogram with the given name and labels, it is created if not registered.
+// DEFAULT_BUCKETS are used when pBuckets is empty. The buckets of the first registration are used for the name.
+//
+//	Returns error if the name or the labels are not valid or the name is registered with another type
+func (r *Registry) Histogram(pName string, pHelp string, pBuckets []float64, pLabels Labels) (*Histogram, error) {
+	if len(pBuckets) == 0 {
+		pBuckets = DEFAULT_BUCKETS
+	}
+	_buckets := append([]float64(nil), pBuckets...)
+	sort.Float64s(_buckets)
+
+	_metric, _err := r.get(pName, pHelp, TYPE_HISTOGRAM, _buckets, pLabels, nil)
+	if _err != nil {
+		return nil, _err
+	}
+	return _metric.(*Histogram), nil
+}
+
+// get returns the registered metric or registers a new one
+func (r *Registry) get(pName string, pHelp string, pType string, pBuckets []float64, pLabels Labels, pNew func() any) (any, error) {
+
+	if !metric_name_rx.MatchString(pName) {
+		return nil, fmt.Errorf("invalid metric name %q", pName)
+	}
+	for _name := range pLabels {
+		if !label_name_rx.MatchString(_name) || strings.HasPrefix(_name, "__") || (pType == TYPE_HISTOGRAM && _name == "le") {
+			return nil, fmt.Errorf("metric %s : invalid label name %q", pName, _name)
+		}
+	}
+
+	_key := format_labels(pLabels, "", "")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_family, _ok := r.families[pName]
+	if !_ok {
+		_family = &family{name: pName, help: pHelp, typ: pType, buckets: pBuckets, series: make(map[string]*series)}
+		r.families[pName] = _family
+	} else if _family.typ != pType {
+		return nil, fmt.Errorf("metric %s is already registered as %s", pName, _family.typ)
+	}
+
+	if _series, _ok := _family.series[_key]; _ok {
+		return _series.metric, nil
+	}
+
+	var _metric any
+	if pType == TYPE_HISTOGRAM {
+		_metric = &Histogram{upper: _family.buckets, counts: make([]uint64, len(_family.buckets))}
+	} else {
+		_metric = pNew()
+	}
+	_labels := make(Labels, len(pLabels))
+	for _name, _value := range pLabels {
+		_labels[_name] = _value
+	}
+	_family.series[_key] = &series{labels: _labels, metric: _metric}
+	return _metric, nil
+}
+
+// Unregister removes the metrics which have all the given labels (e.g. the metrics of a unit instance).
+//
+//	Returns the number of removed metrics
+func (r *Registry) Unregister(pLabels Labels) int {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	_removed := 0
+	for _name, _family := range r.families {
+		for _key, _series := range _family.series {
+			if has_labels(_series.labels, pLabels) {
+				delete(_family.series, _key)
+				_removed++
+			}
+		}
+		if len(_family.series) == 0 {
+			delete(r.families, _name)
+		}
+	}
+	return _removed
+}
+
+// Register_Collector adds a function which is called on every scrape to read the values kept elsewhere.
+//
+//	Returns the function which removes the collector
+func (r *Registry) Register_Collector(pCollect func() []Sample) func() {
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	r.collectors_seq++
+	_id := r.collectors_seq
+	r.collectors[_id] = pCollect
+
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		delete(r.collectors, _id)
+	}
+}
+
+// Handler returns the http handler which writes the metrics in the Prometheus text format
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(pWriter http.ResponseWriter, pRequest *http.Request) {
+		pWriter.Header().Set("Content-Type", CONTENT_TYPE)
+		if _err := r.Write(pWriter); _err != nil {
+			http.Error(pWriter, _err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// output family of the exposition
+type output struct {
+	help  string
+	typ   string
+	lines []string
+}
+
+// Write writes all the metrics and the collected samples in the Prometheus text format, sorted by the name
+func (r *Registry) Write(pWriter io.Writer) error {
+
+	_outputs := make(map[string]*output)
+	_get := func(pName string, pHelp string, pType string) *output {
+		_out, _ok := _outputs[pName]
+		if !_ok {
+			_out = &output{help: pHelp, typ: pType}
+			_outputs[pName] = _out
+		}
+		return _out
+	}
+
+	r.lock.RLock()
+	for _name, _family := range r.families {
+		_out := _get(_name, _family.help, _family.typ)
+		_keys := make([]string, 0, len(_family.series))
+		for _key := range _family.series {
+			_keys = append(_keys, _key)
+		}
+		sort.Strings(_keys)
+		for _, _key := range _keys {
+			_series := _family.series[_key]
+			switch _metric := _series.metric.(type) {
+			case *Counter:
+				_out.lines = append(_out.lines, _name+format_labels(_series.labels, "", "")+" "+format_value(_metric.Value()))
+			case *Gauge:
+				_out.lines = append(_out.lines, _name+format_labels(_series.labels, "", "")+" "+format_value(_metric.Value()))
+			case *Histogram:
+				_cumulative, _sum, _count := _metric.snapshot()
+				for _i, _upper := range _metric.upper {
+					_out.lines = append(_out.lines, _name+"_bucket"+format_labels(_series.labels, "le", format_value(_upper))+" "+strconv.FormatUint(_cumulative[_i], 10))
+				}
+				_out.lines = append(_out.lines,
+					_name+"_bucket"+format_labels(_series.labels, "le", "+Inf")+" "+strconv.FormatUint(_count, 10),
+					_name+"_sum"+format_labels(_series.labels, "", "")+" "+format_value(_sum),
+					_name+"_count"+format_labels(_series.labels, "", "")+" "+strconv.FormatUint(_count, 10))
+			}
+		}
+	}
+	_collectors := make([]func() []Sample, 0, len(r.collectors))
+	for _, _collect := range r.collectors {
+		_collectors = append(_collectors, _collect)
+	}
+	r.lock.RUnlock()
+
+	for _, _collect := range _collectors {
+		for _, _sample := range collect(_collect) {
+			if !metric_name_rx.MatchString(_sample.Name) {
+				continue
+			}
+			_out := _get(_sample.Name, _sample.Help, _sample.Type)
+			_out.lines = append(_out.lines, _sample.Name+format_labels(_sample.Labels, "", "")+" "+format_value(_sample.Value))
+		}
+	}
+
+	_names := make([]string, 0, len(_outputs))
+	for _name := range _outputs {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+
+	_buffer := bufio.NewWriter(pWriter)
+	for _, _name := range _names {
+		_out := _outputs[_name]
+		if _out.help != "" {
+			fmt.Fprintf(_buffer, "# HELP %s %s\n", _name, escape_help(_out.help))
+		}
+		if _out.typ != "" {
+			fmt.Fprintf(_buffer, "# TYPE %s %s\n", _name, _out.typ)
+		}
+		if _out.typ != TYPE_HISTOGRAM {
+			sort.Strings(_out.lines)
+		}
+		for _, _line := range _out.lines {
+			_buffer.WriteString(_line)
+			_buffer.WriteByte('\n')
+		}
+	}
+	return _buffer.Flush()
+}
+
+// collect calls the collector, a panic is ignored
+func collect(pCollect func() []Sample) (samples []Sample) {
+	defer func() {
+		if recover() != nil {
+			samples = nil
+		}
+	}()
+	return pCollect()
+}
+
+// has_labels returns true if pLabels has all the pMatch labels
+func has_labels(pLabels Labels, pMatch Labels) bool {
+	for _name, _value := range pMatch {
+		if _current, _ok := pLabels[_name]; !_ok || _current != _value {
+			return false
+		}
+	}
+	return true
+}
+
+// format_labels formats the labels sorted by the name, pExtra (e.g. le) is added at the end if given
+func format_labels(pLabels Labels, pExtra string, pExtra_Value string) string {
+
+	if len(pLabels) == 0 && pExtra == "" {
+		return ""
+	}
+
+	_names := make([]string, 0, len(pLabels))
+	for _name := range pLabels {
+		_names = append(_names, _name)
+	}
+	sort.Strings(_names)
+
+	var _builder strings.Builder
+	_builder.WriteByte('{')
+	for _i, _name := range _names {
+		if _i > 0 {
+			_builder.WriteByte(',')
+		}
+		_builder.WriteString(_name + `="` + escape_label(pLabels[_name]) + `"`)
+	}
+	if pExtra != "" {
+		if len(_names) > 0 {
+			_builder.WriteByte(',')
+		}
+		_builder.WriteString(pExtra + `="` + escape_label(pExtra_Value) + `"`)
+	}
+	_builder.WriteByte('}')
+	return _builder.String()
+}
+
+// format_value formats the value as expected by Prometheus (+Inf, -Inf, NaN)
+func format_value(pValue float64) string {
+	switch {
+	case math.IsInf(pValue, 1):
+		return "+Inf"
+	case math.IsInf(pValue, -1):
+		return "-Inf"
+	case math.IsNaN(pValue):
+		return "NaN"
+	}
+	return strconv.FormatFloat(pValue, 'g', -1, 64)
+}
+
+var (
+	label_escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	help_escaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escape_label(pValue string) string {
+	return label_escaper.Replace(pValue)
+}
+
+func escape_help(pValue string) string {
+	return help_escaper.Replace(pValue)
+}
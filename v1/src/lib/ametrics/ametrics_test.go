@@ -0,0 +1,146 @@
+package ametrics_test
+
+import (
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// GOLDEN exposition of the metrics registered by new_registry
+const GOLDEN = `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{unit="api",le="0.1"} 0
+latency_seconds_bucket{unit="api",le="1"} 2
+latency_seconds_bucket{unit="api",le="+Inf"} 3
+latency_seconds_sum{unit="api"} 4.75
+latency_seconds_count{unit="api"} 3
+# HELP requests_total Total requests.\nSecond \\ line
+# TYPE requests_total counter
+requests_total{path="a\"b\\c\n"} 2
+requests_total{path="plain"} 1
+# TYPE temperature gauge
+temperature -1.5
+# HELP up 1 if the unit is up.
+# TYPE up gauge
+up{unit="a"} 0
+up{unit="b"} 1
+`
+
+// new_registry returns a registry with a metric of every type, label values and help texts which need escaping
+// and a collector
+func new_registry(t *testing.T) *ametrics.Registry {
+	_registry := ametrics.New_Registry()
+
+	_counter, _err := _registry.Counter("requests_total", "Total requests.\nSecond \\ line", ametrics.Labels{"path": "a\"b\\c\n"})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_counter.Inc()
+	_counter.Add(1)
+	_counter.Add(-5)
+	_plain, _ := _registry.Counter("requests_total", "Total requests.\nSecond \\ line", ametrics.Labels{"path": "plain"})
+	_plain.Inc()
+
+	_gauge, _err := _registry.Gauge("temperature", "", nil)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_gauge.Set(-1.5)
+
+	_histogram, _err := _registry.Histogram("latency_seconds", "Latency.", []float64{1, 0.1}, ametrics.Labels{"unit": "api"})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	for _, _value := range []float64{0.25, 0.5, 4} {
+		_histogram.Observe(_value)
+	}
+
+	_registry.Register_Collector(func() []ametrics.Sample {
+		return []ametrics.Sample{
+			{Name: "up", Help: "1 if the unit is up.", Type: ametrics.TYPE_GAUGE, Labels: ametrics.Labels{"unit": "b"}, Value: 1},
+			{Name: "up", Help: "1 if the unit is up.", Type: ametrics.TYPE_GAUGE, Labels: ametrics.Labels{"unit": "a"}, Value: 0},
+			{Name: "invalid name", Value: 1},
+		}
+	})
+	return _registry
+}
+
+func TestWriteGolden(t *testing.T) {
+	var _out strings.Builder
+	if _err := new_registry(t).Write(&_out); _err != nil {
+		t.Fatal(_err)
+	}
+	if _out.String() != GOLDEN {
+		t.Errorf("Write() =\n%s\nwant\n%s", _out.String(), GOLDEN)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	_recorder := httptest.NewRecorder()
+	new_registry(t).Handler().ServeHTTP(_recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	if _type := _recorder.Header().Get("Content-Type"); _type != ametrics.CONTENT_TYPE {
+		t.Errorf("Content-Type = %q, want %q", _type, ametrics.CONTENT_TYPE)
+	}
+	if _body, _ := io.ReadAll(_recorder.Body); string(_body) != GOLDEN {
+		t.Errorf("body =\n%s\nwant\n%s", _body, GOLDEN)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	_registry := ametrics.New_Registry()
+	if _, _err := _registry.Counter("1invalid", "", nil); _err == nil {
+		t.Error("Counter() with an invalid name = nil, want an error")
+	}
+	if _, _err := _registry.Counter("valid", "", ametrics.Labels{"__reserved": "x"}); _err == nil {
+		t.Error("Counter() with a reserved label name = nil, want an error")
+	}
+	if _, _err := _registry.Histogram("latency", "", nil, ametrics.Labels{"le": "1"}); _err == nil {
+		t.Error("Histogram() with the le label = nil, want an error")
+	}
+	if _, _err := _registry.Counter("requests", "", nil); _err != nil {
+		t.Fatal(_err)
+	}
+	if _, _err := _registry.Gauge("requests", "", nil); _err == nil {
+		t.Error("Gauge() of a counter name = nil, want an error")
+	}
+}
+
+// TestCollectorPanic registers a panicking collector, the scrape still has the other metrics and collectors
+func TestCollectorPanic(t *testing.T) {
+	_registry := new_registry(t)
+	_registry.Register_Collector(func() []ametrics.Sample { panic("collector failed") })
+
+	var _out strings.Builder
+	if _err := _registry.Write(&_out); _err != nil {
+		t.Fatal(_err)
+	}
+	if _out.String() != GOLDEN {
+		t.Errorf("Write() with a panicking collector =\n%s\nwant\n%s", _out.String(), GOLDEN)
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	_registry := ametrics.New_Registry()
+	for _, _unit := range []string{"api", "db"} {
+		_counter, _ := _registry.Counter("requests_total", "", ametrics.Labels{"unit": _unit})
+		_counter.Inc()
+	}
+	_remove := _registry.Register_Collector(func() []ametrics.Sample {
+		return []ametrics.Sample{{Name: "up", Type: ametrics.TYPE_GAUGE, Value: 1}}
+	})
+
+	if _removed := _registry.Unregister(ametrics.Labels{"unit": "api"}); _removed != 1 {
+		t.Errorf("Unregister() = %d, want 1", _removed)
+	}
+	_remove()
+
+	var _out strings.Builder
+	_registry.Write(&_out)
+	if _want := "# TYPE requests_total counter\nrequests_total{unit=\"db\"} 1\n"; _out.String() != _want {
+		t.Errorf("Write() after Unregister() =\n%s\nwant\n%s", _out.String(), _want)
+	}
+}
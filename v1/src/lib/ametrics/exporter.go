@@ -0,0 +1,77 @@
+package ametrics
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"strconv"
+)
+
+// label names of the framework metrics
+const (
+	LABEL_APP_ID   = "app_id"
+	LABEL_UNIT     = "unit"
+	LABEL_INSTANCE = "instance"
+)
+
+// App_Samples returns the samples of the application status with the app_id label
+func App_Samples(pAppID string, pStatus atypes.AppStatus, pUptime_Seconds float64) []Sample {
+
+	_labels := Labels{LABEL_APP_ID: pAppID}
+	_samples := []Sample{
+		{Name: "agnione_app_requests_handled_total", Help: "Total requests handled by the application.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pStatus.Req_Handled)},
+		{Name: "agnione_app_requests_failed_total", Help: "Total requests failed by the application.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pStatus.Req_Failed)},
+		{Name: "agnione_app_routines", Help: "Number of running routines of the application.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Routines)},
+		{Name: "agnione_app_memory_heap_bytes", Help: "Bytes of the heap memory obtained from the OS.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Mem_Usage.Heap)},
+		{Name: "agnione_app_memory_heap_alloc_bytes", Help: "Bytes of the allocated heap objects.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Mem_Usage.HeapAlloc)},
+		{Name: "agnione_app_memory_alloc_bytes_total", Help: "Cumulative bytes allocated for the heap objects.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pStatus.Mem_Usage.Total)},
+		{Name: "agnione_app_memory_sys_bytes", Help: "Total bytes of memory obtained from the OS.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Process.Sys)},
+		{Name: "agnione_app_gc_cycles_total", Help: "Number of completed GC cycles.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pStatus.Process.GC_Count)},
+		{Name: "agnione_app_gc_pause_seconds_total", Help: "Cumulative GC pause time.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pStatus.Process.GC_Pause_Total_Ns) / 1e9},
+		{Name: "agnione_app_goroutines", Help: "Number of goroutines of the process.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Process.Goroutines)},
+		{Name: "agnione_app_monitor_clients", Help: "Number of the connected monitor clients.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.MonitorClients) + float64(pStatus.StatusClients)},
+		{Name: "agnione_app_uptime_seconds", Help: "Seconds since the application started.", Type: TYPE_GAUGE, Labels: _labels, Value: pUptime_Seconds},
+	}
+	if pStatus.Process.RSS >= 0 {
+		_samples = append(_samples, Sample{Name: "agnione_app_resident_memory_bytes", Help: "Resident set size of the process.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Process.RSS)})
+	}
+	if pStatus.Process.Open_FDs >= 0 {
+		_samples = append(_samples, Sample{Name: "agnione_app_open_fds", Help: "Number of open file descriptors of the process.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pStatus.Process.Open_FDs)})
+	}
+	return _samples
+}
+
+// Unit_Samples returns the samples of the unit status with the app_id, unit and instance labels
+func Unit_Samples(pAppID string, pInstance_ID int, pInfo *atypes.AppUnitInfo) []Sample {
+
+	if pInfo == nil {
+		return nil
+	}
+
+	_labels := Unit_Labels(pAppID, pInfo.Info.Name, pInstance_ID)
+	_up := 0.0
+	if pInfo.State == atypes.UNIT_RUNNING {
+		_up = 1
+	}
+
+	return []Sample{
+		{Name: "agnione_unit_up", Help: "1 if the unit is running.", Type: TYPE_GAUGE, Labels: _labels, Value: _up},
+		{Name: "agnione_unit_state", Help: "Lifecycle state of the unit (0 created, 1 initialized, 2 starting, 3 running, 4 stopping, 5 stopped, 6 failed).", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pInfo.State)},
+		{Name: "agnione_unit_requests_handled_total", Help: "Total requests handled by the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Req_Handled)},
+		{Name: "agnione_unit_requests_failed_total", Help: "Total requests failed by the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Req_Failed)},
+		{Name: "agnione_unit_routines", Help: "Number of running routines of the unit.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pInfo.Routines)},
+		{Name: "agnione_unit_active", Help: "Number of the active executions of the unit.", Type: TYPE_GAUGE, Labels: _labels, Value: float64(pInfo.Active)},
+		{Name: "agnione_unit_bytes_sent_total", Help: "Bytes sent by the unit through the plugins.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Resources.Bytes_Sent)},
+		{Name: "agnione_unit_bytes_received_total", Help: "Bytes received by the unit through the plugins.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Resources.Bytes_Received)},
+		{Name: "agnione_unit_pool_allocations_total", Help: "Objects allocated by the unit pools.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Resources.Pool_News)},
+		{Name: "agnione_unit_messages_dropped_total", Help: "Log entries and monitoring messages dropped by the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Msg_Dropped)},
+		{Name: "agnione_unit_bus_published_total", Help: "Envelopes published by the unit on the message bus.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Bus.Published)},
+		{Name: "agnione_unit_bus_received_total", Help: "Envelopes handled by the subscriptions of the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Bus.Received)},
+		{Name: "agnione_unit_bus_failed_total", Help: "Envelopes failed by the handlers of the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Bus.Failed)},
+		{Name: "agnione_unit_bus_dropped_total", Help: "Envelopes dropped by the full queues of the subscriptions of the unit.", Type: TYPE_COUNTER, Labels: _labels, Value: float64(pInfo.Bus.Dropped)},
+	}
+}
+
+// Unit_Labels returns the labels which identify a unit instance
+func Unit_Labels(pAppID string, pUnit string, pInstance_ID int) Labels {
+	return Labels{LABEL_APP_ID: pAppID, LABEL_UNIT: pUnit, LABEL_INSTANCE: strconv.Itoa(pInstance_ID)}
+}
@@ -0,0 +1,125 @@
+// aresource package provides the resource accounting of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Process_Stats
+//
+//   - Mem_Usage
+//
+//   - Pool
+//
+//   - New_Pool
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   aresource - AgniOne Application Framework
+//     Objective	:   Report the process resource usage and count the resources used by the units
+//     ---------------------------------------------------------------------------------------------------------------------
+//     The Go runtime does not separate the memory of the units running in the same process, so the memory,
+//     GC, RSS and open files are reported process wide. Units get the attribution of the resources they
+//     allocate through the framework (routines, pools, plugin traffic) from AUBase.
+//     runtime.ReadMemStats stops the world, the stats are read at most once in STATS_MAX_AGE.
+//     ---------------------------------------------------------------------------------------------------------------------
+package aresource
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// STATS_MAX_AGE the process stats are reused for this duration
+const STATS_MAX_AGE = time.Second
+
+var (
+	stats_lock sync.Mutex
+	stats      atypes.ProcessStats
+	stats_read time.Time
+)
+
+// Process_Stats returns the resource usage of the process. The stats are cached for STATS_MAX_AGE.
+//
+//	RSS and Open_FDs are -1 if not available on the platform.
+func Process_Stats() atypes.ProcessStats {
+
+	stats_lock.Lock()
+	defer stats_lock.Unlock()
+
+	if !stats_read.IsZero() && time.Since(stats_read) < STATS_MAX_AGE {
+		return stats
+	}
+
+	var _mem runtime.MemStats
+	runtime.ReadMemStats(&_mem)
+
+	stats = atypes.ProcessStats{
+		Heap_Alloc:        _mem.HeapAlloc,
+		Heap_Inuse:        _mem.HeapInuse,
+		Heap_Sys:          _mem.HeapSys,
+		Heap_Objects:      _mem.HeapObjects,
+		Sys:               _mem.Sys,
+		Total_Alloc:       _mem.TotalAlloc,
+		Mallocs:           _mem.Mallocs,
+		Frees:             _mem.Frees,
+		GC_Count:          _mem.NumGC,
+		GC_Pause_Total_Ns: _mem.PauseTotalNs,
+		GC_CPU_Fraction:   _mem.GCCPUFraction,
+		Goroutines:        runtime.NumGoroutine(),
+		RSS:               read_rss(),
+		Open_FDs:          count_fds(),
+	}
+	if _mem.NumGC > 0 {
+		stats.GC_Last_Pause_Ns = _mem.PauseNs[(_mem.NumGC+255)%256]
+	}
+	stats_read = time.Now()
+	return stats
+}
+
+// Mem_Usage returns the process memory usage as atypes.MemUsage
+// (Heap = heap memory obtained from the OS, HeapAlloc = bytes of the allocated heap objects,
+// Total = cumulative bytes allocated for the heap objects).
+func Mem_Usage() atypes.MemUsage {
+	_stats := Process_Stats()
+	return atypes.MemUsage{Heap: _stats.Heap_Sys, HeapAlloc: _stats.Heap_Alloc, Total: _stats.Total_Alloc}
+}
+
+// Pool sync.Pool which counts the gets, the new allocations and the puts
+type Pool struct {
+	pool sync.Pool
+	gets atomic.Uint64
+	news atomic.Uint64
+	puts atomic.Uint64
+}
+
+// New_Pool creates a pool which calls pNew when there is no free object
+func New_Pool(pNew func() any) *Pool {
+	_pool := &Pool{}
+	_pool.pool.New = func() any {
+		_pool.news.Add(1)
+		return pNew()
+	}
+	return _pool
+}
+
+// Get returns a free object from the pool or a new one
+func (p *Pool) Get() any {
+	p.gets.Add(1)
+	return p.pool.Get()
+}
+
+// Put returns the object to the pool
+func (p *Pool) Put(pObject any) {
+	if pObject == nil {
+		return
+	}
+	p.puts.Add(1)
+	p.pool.Put(pObject)
+}
+
+// Stats returns the number of gets, new allocations and puts
+func (p *Pool) Stats() (uint64, uint64, uint64) {
+	return p.gets.Load(), p.news.Load(), p.puts.Load()
+}
@@ -0,0 +1,33 @@
+package aresource
+
+import (
+	"bytes"
+	"os"
+	"strconv"
+)
+
+// read_rss returns the resident set size of the process from /proc/self/statm, -1 if not available
+func read_rss() int64 {
+	_data, _err := os.ReadFile("/proc/self/statm")
+	if _err != nil {
+		return -1
+	}
+	_fields := bytes.Fields(_data)
+	if len(_fields) < 2 {
+		return -1
+	}
+	_pages, _err := strconv.ParseInt(string(_fields[1]), 10, 64)
+	if _err != nil {
+		return -1
+	}
+	return _pages * int64(os.Getpagesize())
+}
+
+// count_fds returns the number of open file descriptors of the process, -1 if not available
+func count_fds() int {
+	_entries, _err := os.ReadDir("/proc/self/fd")
+	if _err != nil {
+		return -1
+	}
+	return len(_entries)
+}
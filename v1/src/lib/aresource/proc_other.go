@@ -0,0 +1,13 @@
+//go:build !linux
+
+package aresource
+
+// read_rss is not available on this platform
+func read_rss() int64 {
+	return -1
+}
+
+// count_fds is not available on this platform
+func count_fds() int {
+	return -1
+}
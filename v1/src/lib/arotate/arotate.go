@@ -0,0 +1,416 @@
+// arotate package provides the rotating log file writer of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - Config
+//
+//   - Config_From
+//
+//   - Parse_Interval
+//
+//   - Writer
+//
+//   - New
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   arotate - AgniOne Application Framework
+//     Objective	:   Rotate, compress and remove the log files according to the log configuration
+//     ---------------------------------------------------------------------------------------------------------------------
+//     The log file is rotated when it reaches the max size, when the rotate interval elapses (checked on write)
+//     or when Rotate is called (SIGHUP). The rotated file is renamed to <name>-<UTC time>.<ext>, or
+//     <name>-<UTC time>-<n>.<ext> when it is rotated again in the same millisecond, compressed with gzip
+//     (optional) and removed when there are more than max backups or it is older than max age.
+//     ---------------------------------------------------------------------------------------------------------------------
+package arotate
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// time format of the rotated file names, sorts in the rotation order
+const backup_time_format = "2006-01-02T15-04-05.000"
+
+// compressed file extension
+const gzip_ext = ".gz"
+
+// Config settings of the rotating log file
+type Config struct {
+	Dir         string        // directory of the log file
+	Name        string        // name of the log file (e.g. app.log)
+	Max_Size    int64         // rotate when the file reaches the size in bytes, 0 = no size based rotation
+	Interval    time.Duration // rotate at every interval (aligned to UTC), 0 = no time based rotation
+	Max_Backups int           // number of rotated files kept, 0 = keep all
+	Max_Age     time.Duration // rotated files older than this are removed, 0 = keep all
+	Compress    bool          // compress the rotated files with gzip
+}
+
+// Config_From creates the rotation settings of the given log file from the log configuration.
+//
+//	log_file_max_size is in MB and log_file_max_age is in days.
+//	Returns error if log_file_rotate is not valid.
+func Config_From(pDir string, pName string, pLog atypes.Logconfig) (Config, error) {
+
+	_interval, _err := Parse_Interval(pLog.LogFileRotate)
+	if _err != nil {
+		return Config{}, _err
+	}
+
+	return Config{
+		Dir:         pDir,
+		Name:        pName,
+		Max_Size:    int64(pLog.LogFileMaxSize) * 1024 * 1024,
+		Interval:    _interval,
+		Max_Backups: pLog.LogFileMaxBackups,
+		Max_Age:     time.Duration(pLog.LogFileMaxAge) * 24 * time.Hour,
+		Compress:    pLog.LogFileCompress,
+	}, nil
+}
+
+// Parse_Interval converts the log_file_rotate value (hourly, daily, weekly or a duration like 30m) into
+// the rotate interval.
+//
+//	Returns 0 for an empty value.
+func Parse_Interval(pValue string) (time.Duration, error) {
+
+	switch strings.ToLower(strings.TrimSpace(pValue)) {
+	case "", "none":
+		return 0, nil
+	case "hourly":
+		return time.Hour, nil
+	case "daily":
+		return 24 * time.Hour, nil
+	case "weekly":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	_interval, _err := time.ParseDuration(strings.TrimSpace(pValue))
+	if _err != nil || _interval < time.Minute {
+		return 0, fmt.Errorf("invalid log_file_rotate %q, use hourly, daily, weekly or a duration of 1m or more", pValue)
+	}
+	return _interval, nil
+}
+
+// Writer rotating log file writer, safe for the concurrent use
+type Writer struct {
+	lock   sync.Mutex
+	config Config
+	file   *os.File
+	size   int64
+	next   time.Time /// next time based rotation
+
+	mill      chan struct{} /// signals the compress/cleanup routine
+	mill_done chan struct{}
+	closed    bool
+}
+
+// New opens (appends) the log file and starts the routine which compresses and removes the rotated files.
+//
+//	Returns error if the directory or the file can not be created.
+func New(pConfig Config) (*Writer, error) {
+
+	if pConfig.Name == "" {
+		return nil, fmt.Errorf("log file name %w", aerrors.ErrNilArgument)
+	}
+	if _err := os.MkdirAll(pConfig.Dir, 0o755); _err != nil {
+		return nil, fmt.Errorf("failed to create the log directory %s. %w", pConfig.Dir, _err)
+	}
+
+	_writer := &Writer{
+		config:    pConfig,
+		mill:      make(chan struct{}, 1),
+		mill_done: make(chan struct{}),
+	}
+	if _err := _writer.open(); _err != nil {
+		return nil, _err
+	}
+
+	go _writer.run_mill()
+	_writer.mill <- struct{}{} /// apply the retention to the existing backups
+	return _writer, nil
+}
+
+// Name returns the name of the current log file
+func (w *Writer) Name() string {
+	return w.config.Name
+}
+
+// Write writes the data to the current log file, the file is rotated first when the size or the interval is reached
+func (w *Writer) Write(pData []byte) (int, error) {
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	if (w.config.Max_Size > 0 && w.size > 0 && w.size+int64(len(pData)) > w.config.Max_Size) ||
+		(!w.next.IsZero() && !time.Now().Before(w.next)) {
+		if _err := w.rotate(); _err != nil {
+			return 0, _err
+		}
+	}
+
+	_n, _err := w.file.Write(pData)
+	w.size += int64(_n)
+	return _n, _err
+}
+
+// Rotate closes the current log file, renames it as a backup and opens a new log file
+func (w *Writer) Rotate() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Close closes the log file and waits for the compress/cleanup routine
+func (w *Writer) Close() error {
+
+	w.lock.Lock()
+	if w.closed {
+		w.lock.Unlock()
+		return nil
+	}
+	w.closed = true
+	_err := w.file.Close()
+	close(w.mill)
+	w.lock.Unlock()
+
+	<-w.mill_done
+	return _err
+}
+
+// Backups returns the names of the rotated log files (newest first)
+func (w *Writer) Backups() ([]string, error) {
+
+	_files, _err := w.backups()
+	if _err != nil {
+		return nil, _err
+	}
+
+	_names := make([]string, len(_files))
+	for _i, _file := range _files {
+		_names[_i] = _file.name
+	}
+	return _names, nil
+}
+
+// open opens the log file for append. Caller must hold the lock
+func (w *Writer) open() error {
+
+	_file, _err := os.OpenFile(filepath.Join(w.config.Dir, w.config.Name), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if _err != nil {
+		return fmt.Errorf("failed to open the log file %s. %w", w.config.Name, _err)
+	}
+
+	_info, _err := _file.Stat()
+	if _err != nil {
+		_file.Close()
+		return fmt.Errorf("failed to read the log file %s. %w", w.config.Name, _err)
+	}
+
+	w.file = _file
+	w.size = _info.Size()
+	if w.config.Interval > 0 {
+		w.next = time.Now().UTC().Truncate(w.config.Interval).Add(w.config.Interval)
+	}
+	return nil
+}
+
+// rotate renames the current log file and opens a new one. Caller must hold the lock
+func (w *Writer) rotate() error {
+
+	if _err := w.file.Close(); _err != nil {
+		return fmt.Errorf("failed to close the log file %s. %w", w.config.Name, _err)
+	}
+
+	_path := filepath.Join(w.config.Dir, w.config.Name)
+	_backup := filepath.Join(w.config.Dir, w.unused_backup_name(time.Now()))
+	if _err := os.Rename(_path, _backup); _err != nil && !os.IsNotExist(_err) {
+		/// keep writing to the current file
+		if _open_err := w.open(); _open_err != nil {
+			return _open_err
+		}
+		return fmt.Errorf("failed to rotate the log file %s. %w", w.config.Name, _err)
+	}
+
+	if _err := w.open(); _err != nil {
+		return _err
+	}
+
+	select {
+	case w.mill <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// backup_name returns the name of the rotated file for the given time and sequence (0 = none)
+func (w *Writer) backup_name(pTime time.Time, pSeq int) string {
+	_ext := filepath.Ext(w.config.Name)
+	_prefix := strings.TrimSuffix(w.config.Name, _ext)
+	_name := _prefix + "-" + pTime.UTC().Format(backup_time_format)
+	if pSeq > 0 {
+		_name += "-" + strconv.Itoa(pSeq)
+	}
+	return _name + _ext
+}
+
+// unused_backup_name returns the name of the rotated file for the given time, with a sequence suffix when
+// a file was already rotated in the same millisecond, so the rotated files are not overwritten
+func (w *Writer) unused_backup_name(pTime time.Time) string {
+	for _seq := 0; ; _seq++ {
+		_name := w.backup_name(pTime, _seq)
+		_path := filepath.Join(w.config.Dir, _name)
+		if !file_exists(_path) && !file_exists(_path+gzip_ext) {
+			return _name
+		}
+	}
+}
+
+func file_exists(pPath string) bool {
+	_, _err := os.Lstat(pPath)
+	return _err == nil
+}
+
+// backup rotated log file
+type backup struct {
+	name string
+	time time.Time
+	seq  int /// files rotated in the same millisecond
+}
+
+// parse_stamp returns the time and the sequence of the stamp of a rotated file name
+func parse_stamp(pStamp string) (time.Time, int, bool) {
+	if _time, _err := time.Parse(backup_time_format, pStamp); _err == nil {
+		return _time, 0, true
+	}
+	_at := strings.LastIndex(pStamp, "-")
+	if _at < 0 {
+		return time.Time{}, 0, false
+	}
+	_seq, _err := strconv.Atoi(pStamp[_at+1:])
+	if _err != nil || _seq <= 0 {
+		return time.Time{}, 0, false
+	}
+	_time, _err := time.Parse(backup_time_format, pStamp[:_at])
+	if _err != nil {
+		return time.Time{}, 0, false
+	}
+	return _time, _seq, true
+}
+
+// backups returns the rotated files of the log file (newest first)
+func (w *Writer) backups() ([]backup, error) {
+
+	_entries, _err := os.ReadDir(w.config.Dir)
+	if _err != nil {
+		return nil, fmt.Errorf("failed to read the log directory %s. %w", w.config.Dir, _err)
+	}
+
+	_ext := filepath.Ext(w.config.Name)
+	_prefix := strings.TrimSuffix(w.config.Name, _ext) + "-"
+
+	var _backups []backup
+	for _, _entry := range _entries {
+		_name := _entry.Name()
+		if _entry.IsDir() || !strings.HasPrefix(_name, _prefix) {
+			continue
+		}
+		_stamp := strings.TrimSuffix(strings.TrimSuffix(_name, gzip_ext), _ext)
+		_time, _seq, _ok := parse_stamp(strings.TrimPrefix(_stamp, _prefix))
+		if !_ok {
+			continue
+		}
+		_backups = append(_backups, backup{name: _name, time: _time, seq: _seq})
+	}
+
+	sort.Slice(_backups, func(i, j int) bool {
+		if !_backups[i].time.Equal(_backups[j].time) {
+			return _backups[i].time.After(_backups[j].time)
+		}
+		return _backups[i].seq > _backups[j].seq
+	})
+	return _backups, nil
+}
+
+// run_mill compresses and removes the rotated files when signalled, exits when the writer is closed
+func (w *Writer) run_mill() {
+	defer close(w.mill_done)
+	for range w.mill {
+		w.mill_backups()
+	}
+}
+
+// mill_backups removes the rotated files over max backups/max age and compresses the remaining ones
+func (w *Writer) mill_backups() {
+
+	_backups, _err := w.backups()
+	if _err != nil {
+		return
+	}
+
+	_cutoff := time.Time{}
+	if w.config.Max_Age > 0 {
+		_cutoff = time.Now().Add(-w.config.Max_Age)
+	}
+
+	for _i, _backup := range _backups {
+		_path := filepath.Join(w.config.Dir, _backup.name)
+		if (w.config.Max_Backups > 0 && _i >= w.config.Max_Backups) || _backup.time.Before(_cutoff) {
+			os.Remove(_path)
+			continue
+		}
+		if w.config.Compress && !strings.HasSuffix(_backup.name, gzip_ext) {
+			compress(_path)
+		}
+	}
+}
+
+// compress writes the gzip of the given file and removes the file
+func compress(pPath string) error {
+
+	_src, _err := os.Open(pPath)
+	if _err != nil {
+		return _err
+	}
+	defer _src.Close()
+
+	_dst, _err := os.OpenFile(pPath+gzip_ext, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if _err != nil {
+		return _err
+	}
+
+	_zip := gzip.NewWriter(_dst)
+	_, _err = io.Copy(_zip, _src)
+	if _err == nil {
+		_err = _zip.Close()
+	}
+	if _close_err := _dst.Close(); _err == nil {
+		_err = _close_err
+	}
+	if _err != nil {
+		os.Remove(pPath + gzip_ext)
+		return _err
+	}
+
+	_src.Close()
+	return os.Remove(pPath)
+}
@@ -0,0 +1,69 @@
+package arotate
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRotateSameMillisecond rotates faster than the time stamp of the rotated files changes
+func TestRotateSameMillisecond(t *testing.T) {
+
+	_dir := t.TempDir()
+	_writer, _err := New(Config{Dir: _dir, Name: "app.log"})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	defer _writer.Close()
+
+	const _count = 20
+	for _i := 0; _i < _count; _i++ {
+		fmt.Fprintf(_writer, "entry %d\n", _i)
+		if _err := _writer.Rotate(); _err != nil {
+			t.Fatal(_err)
+		}
+	}
+
+	_backups, _err := _writer.Backups()
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	if len(_backups) != _count {
+		t.Fatalf("Backups() = %d files, want %d: %v", len(_backups), _count, _backups)
+	}
+	/// newest first, every entry is kept
+	for _i, _name := range _backups {
+		_data, _err := os.ReadFile(filepath.Join(_dir, _name))
+		if _err != nil {
+			t.Fatal(_err)
+		}
+		if _want := fmt.Sprintf("entry %d\n", _count-1-_i); string(_data) != _want {
+			t.Errorf("%s = %q, want %q", _name, _data, _want)
+		}
+	}
+}
+
+func TestUnusedBackupName(t *testing.T) {
+
+	_writer := &Writer{config: Config{Dir: t.TempDir(), Name: "app.log"}}
+	_time := time.Date(2026, 10, 18, 11, 10, 48, 123e6, time.UTC)
+
+	_first := _writer.unused_backup_name(_time)
+	if _first != "app-2026-10-18T11-10-48.123.log" {
+		t.Fatalf("unused_backup_name() = %s", _first)
+	}
+	os.WriteFile(filepath.Join(_writer.config.Dir, _first+gzip_ext), nil, 0o644)
+
+	_second := _writer.unused_backup_name(_time)
+	if _second != "app-2026-10-18T11-10-48.123-1.log" {
+		t.Fatalf("unused_backup_name() with a compressed backup = %s", _second)
+	}
+	os.WriteFile(filepath.Join(_writer.config.Dir, _second), nil, 0o644)
+
+	_backups, _ := _writer.backups()
+	if len(_backups) != 2 || _backups[0].name != _second || _backups[1].name != _first+gzip_ext {
+		t.Errorf("backups() = %+v, want the sequence first", _backups)
+	}
+}
@@ -0,0 +1,330 @@
+// atls package provides the tls and mutual tls settings of the client plugins of the AgniOne Application Framework
+//
+// This package includes below types & functions:
+//
+//   - TLS, New_TLS, Is_Enabled
+//
+//   - Client_Config, Transport, Reload, Watch, Close
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   atls - AgniOne Application Framework
+//     Objective	:   Share one tls setting between the http and websocket client plugins and rotate the certificates
+//     ---------------------------------------------------------------------------------------------------------------------
+//     TLS is created from the tls section (atypes.TLSconfig) of a plugin in plugins.http or plugins.websocket of the
+//     FMConfig: the CA bundle, the client certificate of the mutual tls, the SNI override, the minimum version and the
+//     pins of the server public keys. Watch reloads the files when they change on the disk: the client certificate
+//     is read on every handshake, the CAs by every Client_Config, and Transport creates the config of every new
+//     connection, so the clients are not created again. A reload which fails keeps the certificates loaded before.
+//     ---------------------------------------------------------------------------------------------------------------------
+package atls
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DEFAULT_RELOAD_INTERVAL time between the checks of the files when the config has no reload_interval
+const DEFAULT_RELOAD_INTERVAL = 30 * time.Second
+
+// PIN_PREFIX optional prefix of the pins (sha256/base64)
+const PIN_PREFIX = "sha256/"
+
+// tls_versions min_version values of the config
+var tls_versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLS tls settings of a client plugin with the certificates loaded from the files of the config
+type TLS struct {
+	config      atypes.TLSconfig
+	min_version uint16
+	pins        [][]byte /// SHA-256 of the SPKI
+	interval    time.Duration
+
+	reload_lock sync.Mutex
+	lock        sync.RWMutex
+	material    *material
+	files       map[string]file_state
+
+	watch_once sync.Once
+	close_once sync.Once
+	stop       chan struct{}
+}
+
+// material certificates loaded from the files
+type material struct {
+	roots *x509.CertPool   /// nil = the system CAs
+	cert  *tls.Certificate /// nil = no client certificate
+}
+
+// file_state state of a file when it was loaded
+type file_state struct {
+	mod_time time.Time
+	size     int64
+}
+
+// Is_Enabled returns true if the config has a tls setting
+func Is_Enabled(pConfig atypes.TLSconfig) bool {
+	return pConfig.CAFile != "" || pConfig.CertFile != "" || pConfig.KeyFile != "" || pConfig.ServerName != "" ||
+		pConfig.MinVersion != "" || len(pConfig.Pins) > 0 || pConfig.InsecureSkipVerify == 1
+}
+
+// New_TLS returns the tls settings of the config with the files loaded, nil if the config has no tls setting.
+//
+//	Returns aerrors.ErrInvalidArgument if a setting is not valid, or the error of loading the files
+func New_TLS(pConfig atypes.TLSconfig) (*TLS, error) {
+
+	if !Is_Enabled(pConfig) {
+		return nil, nil
+	}
+
+	_tls := &TLS{config: pConfig, min_version: tls.VersionTLS12, stop: make(chan struct{})}
+	if pConfig.MinVersion != "" {
+		_version, _ok := tls_versions[strings.TrimSpace(pConfig.MinVersion)]
+		if !_ok {
+			return nil, fmt.Errorf("tls min_version %q. %w", pConfig.MinVersion, aerrors.ErrInvalidArgument)
+		}
+		_tls.min_version = _version
+	}
+	if (pConfig.CertFile == "") != (pConfig.KeyFile == "") {
+		return nil, fmt.Errorf("tls cert_file and key_file must be set together. %w", aerrors.ErrInvalidArgument)
+	}
+	for _, _pin := range pConfig.Pins {
+		_hash, _err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(_pin), PIN_PREFIX))
+		if _err != nil || len(_hash) != sha256.Size {
+			return nil, fmt.Errorf("tls pin %q is not a base64 SHA-256. %w", _pin, aerrors.ErrInvalidArgument)
+		}
+		_tls.pins = append(_tls.pins, _hash)
+	}
+
+	switch {
+	case pConfig.ReloadInterval > 0:
+		_tls.interval = time.Duration(pConfig.ReloadInterval) * time.Second
+	case pConfig.ReloadInterval == 0:
+		_tls.interval = DEFAULT_RELOAD_INTERVAL
+	}
+
+	if _err := _tls.Reload(); _err != nil {
+		return nil, _err
+	}
+	return _tls, nil
+}
+
+// Config returns the config of the tls settings
+func (t *TLS) Config() atypes.TLSconfig {
+	return t.config
+}
+
+// Client_Config returns a new client tls config with the CAs loaded last, a connection should get its own config.
+// The handshakes send the client certificate loaded last, the chain of the server is verified with the ServerName
+// of the config (the host of the url if empty) and the pins.
+func (t *TLS) Client_Config() *tls.Config {
+
+	t.lock.RLock()
+	_roots := t.material.roots
+	t.lock.RUnlock()
+
+	_config := &tls.Config{
+		MinVersion:           t.min_version,
+		ServerName:           t.config.ServerName,
+		RootCAs:              _roots,
+		InsecureSkipVerify:   t.config.InsecureSkipVerify == 1,
+		GetClientCertificate: t.client_certificate,
+	}
+	if len(t.pins) > 0 {
+		_config.VerifyConnection = t.verify_pins
+	}
+	return _config
+}
+
+// Transport returns a new http transport with the settings of http.DefaultTransport, which dials every new
+// connection with a new Client_Config. The connections through a proxy use the config of the creation.
+func (t *TLS) Transport() *http.Transport {
+
+	_transport := http.DefaultTransport.(*http.Transport).Clone()
+	_transport.TLSClientConfig = t.Client_Config()
+
+	_dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	_transport.DialTLSContext = func(pCtx context.Context, pNetwork string, pAddress string) (net.Conn, error) {
+		_config := t.Client_Config()
+		if _config.ServerName == "" {
+			_config.ServerName, _, _ = net.SplitHostPort(pAddress)
+		}
+		_config.NextProtos = []string{"h2", "http/1.1"}
+		_tls_dialer := &tls.Dialer{NetDialer: _dialer, Config: _config}
+		return _tls_dialer.DialContext(pCtx, pNetwork, pAddress)
+	}
+	return _transport
+}
+
+// Reload loads the files of the config. The certificates loaded before are kept if the files are not valid.
+func (t *TLS) Reload() error {
+
+	t.reload_lock.Lock()
+	defer t.reload_lock.Unlock()
+
+	/// the state is read before the files, a change during the load is loaded by the next check
+	_files := t.file_states()
+	_material, _err := t.load()
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	t.files = _files
+	if _err != nil {
+		return _err
+	}
+	t.material = _material
+	return nil
+}
+
+// Watch checks the files every reload_interval of the config and reloads them when they change.
+// pOn_Reload is called with the result of every reload. It does nothing if the reload is disabled, or after the first call.
+func (t *TLS) Watch(pOn_Reload func(pErr error)) {
+	if t.interval <= 0 || len(t.file_names()) == 0 {
+		return
+	}
+	t.watch_once.Do(func() {
+		go t.watch(pOn_Reload)
+	})
+}
+
+// Close stops watching the files. The clients keep the certificates loaded last.
+func (t *TLS) Close() {
+	t.close_once.Do(func() {
+		close(t.stop)
+	})
+}
+
+// watch routine of Watch
+func (t *TLS) watch(pOn_Reload func(pErr error)) {
+
+	_ticker := time.NewTicker(t.interval)
+	defer _ticker.Stop()
+
+	for {
+		select {
+		case <-t.stop:
+			return
+		case <-_ticker.C:
+			if !t.changed() {
+				continue
+			}
+			_err := t.Reload()
+			if pOn_Reload != nil {
+				pOn_Reload(_err)
+			}
+		}
+	}
+}
+
+// file_names returns the files of the config
+func (t *TLS) file_names() []string {
+	_names := make([]string, 0, 3)
+	for _, _name := range []string{t.config.CAFile, t.config.CertFile, t.config.KeyFile} {
+		if _name != "" {
+			_names = append(_names, _name)
+		}
+	}
+	return _names
+}
+
+// file_states returns the current state of the files, a missing file has a zero state
+func (t *TLS) file_states() map[string]file_state {
+	_states := make(map[string]file_state, 3)
+	for _, _name := range t.file_names() {
+		if _stat, _err := os.Stat(_name); _err == nil {
+			_states[_name] = file_state{mod_time: _stat.ModTime(), size: _stat.Size()}
+		} else {
+			_states[_name] = file_state{}
+		}
+	}
+	return _states
+}
+
+// changed returns true if a file changed since it was loaded
+func (t *TLS) changed() bool {
+	_states := t.file_states()
+
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	for _name, _state := range _states {
+		if _loaded := t.files[_name]; !_loaded.mod_time.Equal(_state.mod_time) || _loaded.size != _state.size {
+			return true
+		}
+	}
+	return false
+}
+
+// load loads the CAs and the client certificate of the config
+func (t *TLS) load() (*material, error) {
+
+	_material := &material{}
+	if t.config.CAFile != "" {
+		_pem, _err := os.ReadFile(t.config.CAFile)
+		if _err != nil {
+			return nil, fmt.Errorf("tls ca_file. %w", _err)
+		}
+		_material.roots = x509.NewCertPool()
+		if !_material.roots.AppendCertsFromPEM(_pem) {
+			return nil, fmt.Errorf("tls ca_file %s has no PEM certificate. %w", t.config.CAFile, aerrors.ErrInvalidArgument)
+		}
+	}
+
+	if t.config.CertFile != "" {
+		_cert, _err := tls.LoadX509KeyPair(t.config.CertFile, t.config.KeyFile)
+		if _err != nil {
+			return nil, fmt.Errorf("tls cert_file and key_file. %w", _err)
+		}
+		_material.cert = &_cert
+	}
+	return _material, nil
+}
+
+// client_certificate returns the client certificate loaded last, an empty certificate (none is sent) if not configured
+func (t *TLS) client_certificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+	if t.material.cert == nil {
+		return &tls.Certificate{}, nil
+	}
+	return t.material.cert, nil
+}
+
+// verify_pins returns an error if no certificate of the verified chains (the certificates sent by the server
+// when the chain is not verified) matches the pins
+func (t *TLS) verify_pins(pState tls.ConnectionState) error {
+
+	_chains := pState.VerifiedChains
+	if t.config.InsecureSkipVerify == 1 {
+		_chains = [][]*x509.Certificate{pState.PeerCertificates}
+	}
+	for _, _chain := range _chains {
+		for _, _cert := range _chain {
+			_hash := sha256.Sum256(_cert.RawSubjectPublicKeyInfo)
+			for _, _pin := range t.pins {
+				if bytes.Equal(_hash[:], _pin) {
+					return nil
+				}
+			}
+		}
+	}
+	return fmt.Errorf("tls server %s. %w", pState.ServerName, aerrors.ErrCertificatePin)
+}
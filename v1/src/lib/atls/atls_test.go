@@ -0,0 +1,233 @@
+package atls_test
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/atls"
+
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// write_certificate writes a self-signed client certificate of the given common name and its key into the directory
+func write_certificate(t *testing.T, pDir string, pName string) (string, string) {
+	_key, _err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: pName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	_der, _err := x509.CreateCertificate(rand.Reader, _template, _template, &_key.PublicKey, _key)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	_key_der, _err := x509.MarshalECPrivateKey(_key)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+
+	_cert_file, _key_file := filepath.Join(pDir, "client.pem"), filepath.Join(pDir, "client.key")
+	write_file(t, _cert_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: _der}))
+	write_file(t, _key_file, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: _key_der}))
+	return _cert_file, _key_file
+}
+
+func write_file(t *testing.T, pName string, pContent []byte) {
+	if _err := os.WriteFile(pName, pContent, 0600); _err != nil {
+		t.Fatal(_err)
+	}
+}
+
+// client_name returns the common name of the client certificate sent by the handshakes of the settings
+func client_name(t *testing.T, pTLS *atls.TLS) string {
+	_cert, _err := pTLS.Client_Config().GetClientCertificate(&tls.CertificateRequestInfo{})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	if len(_cert.Certificate) == 0 {
+		return ""
+	}
+	_parsed, _err := x509.ParseCertificate(_cert.Certificate[0])
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	return _parsed.Subject.CommonName
+}
+
+// pin returns the pin of the public key of the certificate
+func pin(pCert *x509.Certificate) string {
+	_hash := sha256.Sum256(pCert.RawSubjectPublicKeyInfo)
+	return atls.PIN_PREFIX + base64.StdEncoding.EncodeToString(_hash[:])
+}
+
+func TestNewTLS(t *testing.T) {
+	_dir := t.TempDir()
+	_cert_file, _key_file := write_certificate(t, _dir, "client")
+	_valid_pin := base64.StdEncoding.EncodeToString(make([]byte, sha256.Size))
+
+	_tests := []struct {
+		name    string
+		config  atypes.TLSconfig
+		invalid bool
+	}{
+		{name: "min version", config: atypes.TLSconfig{MinVersion: " 1.3"}},
+		{name: "unknown min version", config: atypes.TLSconfig{MinVersion: "1.4"}, invalid: true},
+		{name: "cert and key", config: atypes.TLSconfig{CertFile: _cert_file, KeyFile: _key_file}},
+		{name: "cert without key", config: atypes.TLSconfig{CertFile: _cert_file}, invalid: true},
+		{name: "key without cert", config: atypes.TLSconfig{KeyFile: _key_file}, invalid: true},
+		{name: "pin", config: atypes.TLSconfig{Pins: []string{_valid_pin}}},
+		{name: "prefixed pin", config: atypes.TLSconfig{Pins: []string{atls.PIN_PREFIX + _valid_pin}}},
+		{name: "pin not base64", config: atypes.TLSconfig{Pins: []string{"not base64!"}}, invalid: true},
+		{name: "pin not SHA-256", config: atypes.TLSconfig{Pins: []string{base64.StdEncoding.EncodeToString([]byte("short"))}}, invalid: true},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_tls, _err := atls.New_TLS(_test.config)
+			if _test.invalid {
+				if !errors.Is(_err, aerrors.ErrInvalidArgument) {
+					t.Errorf("New_TLS() = %v, want aerrors.ErrInvalidArgument", _err)
+				}
+				return
+			}
+			if _err != nil || _tls == nil {
+				t.Fatalf("New_TLS() = %v, %v", _tls, _err)
+			}
+			_tls.Close()
+		})
+	}
+
+	if _tls, _err := atls.New_TLS(atypes.TLSconfig{}); _tls != nil || _err != nil {
+		t.Errorf("New_TLS() without a tls setting = %v, %v, want nil, nil", _tls, _err)
+	}
+	if _, _err := atls.New_TLS(atypes.TLSconfig{CAFile: filepath.Join(_dir, "missing.pem")}); !errors.Is(_err, os.ErrNotExist) {
+		t.Errorf("New_TLS() with a missing ca_file = %v, want os.ErrNotExist", _err)
+	}
+}
+
+func TestReloadKeepsCertificate(t *testing.T) {
+	_dir := t.TempDir()
+	_cert_file, _key_file := write_certificate(t, _dir, "first")
+	_tls, _err := atls.New_TLS(atypes.TLSconfig{CertFile: _cert_file, KeyFile: _key_file, ReloadInterval: -1})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	defer _tls.Close()
+
+	write_file(t, _cert_file, []byte("not a certificate"))
+	if _err := _tls.Reload(); _err == nil {
+		t.Fatal("Reload() of a bad cert_file = nil, want an error")
+	}
+	if _name := client_name(t, _tls); _name != "first" {
+		t.Errorf("client certificate after the bad reload = %q, want first", _name)
+	}
+
+	write_certificate(t, _dir, "second")
+	if _err := _tls.Reload(); _err != nil {
+		t.Fatal(_err)
+	}
+	if _name := client_name(t, _tls); _name != "second" {
+		t.Errorf("client certificate after the reload = %q, want second", _name)
+	}
+}
+
+func TestWatchRotation(t *testing.T) {
+	_dir := t.TempDir()
+	_cert_file, _key_file := write_certificate(t, _dir, "first")
+	_tls, _err := atls.New_TLS(atypes.TLSconfig{CertFile: _cert_file, KeyFile: _key_file, ReloadInterval: 1})
+	if _err != nil {
+		t.Fatal(_err)
+	}
+	defer _tls.Close()
+
+	_reloaded := make(chan error, 1)
+	_tls.Watch(func(pErr error) {
+		select {
+		case _reloaded <- pErr:
+		default:
+		}
+	})
+
+	/// the name has another length, so the size of the file changes whatever the precision of the modification time
+	write_certificate(t, _dir, "rotated certificate")
+	select {
+	case _err := <-_reloaded:
+		if _err != nil {
+			t.Fatal(_err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the rotated certificate is not reloaded")
+	}
+	if _name := client_name(t, _tls); _name != "rotated certificate" {
+		t.Errorf("client certificate after the rotation = %q, want rotated certificate", _name)
+	}
+}
+
+func TestVerifyPins(t *testing.T) {
+	_server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer _server.Close()
+
+	_ca_file := filepath.Join(t.TempDir(), "ca.pem")
+	write_file(t, _ca_file, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: _server.Certificate().Raw}))
+	_other, _ := write_certificate(t, t.TempDir(), "other")
+	_other_pem, _ := os.ReadFile(_other)
+	_block, _ := pem.Decode(_other_pem)
+	_other_cert, _err := x509.ParseCertificate(_block.Bytes)
+	if _err != nil {
+		t.Fatal(_err)
+	}
+
+	_tests := []struct {
+		name     string
+		pins     []string
+		insecure int8
+		pinned   bool
+	}{
+		{name: "pinned", pins: []string{pin(_server.Certificate())}, pinned: true},
+		{name: "pinned among others", pins: []string{pin(_other_cert), pin(_server.Certificate())}, pinned: true},
+		{name: "not pinned", pins: []string{pin(_other_cert)}},
+		{name: "not pinned without verify", pins: []string{pin(_other_cert)}, insecure: 1},
+		{name: "pinned without verify", pins: []string{pin(_server.Certificate())}, insecure: 1, pinned: true},
+	}
+	for _, _test := range _tests {
+		t.Run(_test.name, func(t *testing.T) {
+			_tls, _err := atls.New_TLS(atypes.TLSconfig{CAFile: _ca_file, Pins: _test.pins, InsecureSkipVerify: _test.insecure, ReloadInterval: -1})
+			if _err != nil {
+				t.Fatal(_err)
+			}
+			defer _tls.Close()
+
+			_transport := _tls.Transport()
+			defer _transport.CloseIdleConnections()
+			_response, _err := (&http.Client{Transport: _transport}).Get(_server.URL)
+			if _err == nil {
+				_response.Body.Close()
+			}
+			if _test.pinned && _err != nil {
+				t.Errorf("Get() = %v, want the pinned server", _err)
+			}
+			if !_test.pinned && !errors.Is(_err, aerrors.ErrCertificatePin) {
+				t.Errorf("Get() = %v, want aerrors.ErrCertificatePin", _err)
+			}
+		})
+	}
+}
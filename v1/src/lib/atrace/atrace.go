@@ -0,0 +1,534 @@
+// atrace package provides the tracing of the AgniOne Application Framework with the W3C trace context propagation
+//
+// This package includes below types & functions:
+//
+//   - Tracer, New_Tracer, New_From_Config
+//
+//   - Span, Span_Data, Span_Context
+//
+//   - Context_With_Span, Span_From_Context, Span_Context_From
+//
+//   - Inject, Inject_Values, Extract, Parse_Traceparent
+//
+//   - Exporter, Writer_Exporter, Memory_Exporter, OTLP_Exporter
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright   :	MIT License
+//     package		:   atrace - AgniOne Application Framework
+//     Objective	:   Follow a request from a unit through the plugins to the downstream services
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Spans are started from the context of the parent span and carried in the context.Context.
+//     The trace context crosses the process boundaries in the W3C traceparent header. The ended spans are
+//     exported in batches by the exporter of the tracer (OTLP/HTTP JSON, JSON lines to stdout/file or memory).
+//     ---------------------------------------------------------------------------------------------------------------------
+package atrace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// default settings of the tracer
+const (
+	DEFAULT_QUEUE_SIZE     = 2048
+	DEFAULT_BATCH_SIZE     = 512
+	DEFAULT_FLUSH_INTERVAL = 5 * time.Second
+	DEFAULT_EXPORT_TIMEOUT = 10 * time.Second
+)
+
+// SpanKind role of the span in the trace
+type SpanKind int
+
+const (
+	KIND_INTERNAL SpanKind = iota
+	KIND_SERVER
+	KIND_CLIENT
+	KIND_PRODUCER
+	KIND_CONSUMER
+)
+
+// String returns the name of the span kind
+func (k SpanKind) String() string {
+	switch k {
+	case KIND_SERVER:
+		return "server"
+	case KIND_CLIENT:
+		return "client"
+	case KIND_PRODUCER:
+		return "producer"
+	case KIND_CONSUMER:
+		return "consumer"
+	}
+	return "internal"
+}
+
+// StatusCode status of the span
+type StatusCode int
+
+const (
+	STATUS_UNSET StatusCode = iota
+	STATUS_OK
+	STATUS_ERROR
+)
+
+// String returns the name of the status code
+func (c StatusCode) String() string {
+	switch c {
+	case STATUS_OK:
+		return "ok"
+	case STATUS_ERROR:
+		return "error"
+	}
+	return "unset"
+}
+
+// TraceID identifier of a trace
+type TraceID [16]byte
+
+// SpanID identifier of a span
+type SpanID [8]byte
+
+// String returns the lower case hex of the trace id
+func (t TraceID) String() string { return hex.EncodeToString(t[:]) }
+
+// Is_Valid returns false for the all zero trace id
+func (t TraceID) Is_Valid() bool { return t != TraceID{} }
+
+// String returns the lower case hex of the span id
+func (s SpanID) String() string { return hex.EncodeToString(s[:]) }
+
+// Is_Valid returns false for the all zero span id
+func (s SpanID) Is_Valid() bool { return s != SpanID{} }
+
+// Span_Context identifies a span and is propagated to the child spans and downstream services
+type Span_Context struct {
+	Trace_ID TraceID
+	Span_ID  SpanID
+	Sampled  bool // the span is recorded and exported
+	Remote   bool // extracted from an incoming traceparent header
+}
+
+// Is_Valid returns true if both the trace id and the span id are set
+func (sc Span_Context) Is_Valid() bool {
+	return sc.Trace_ID.Is_Valid() && sc.Span_ID.Is_Valid()
+}
+
+// Span_Data the recorded data of an ended span, passed to the exporter
+type Span_Data struct {
+	Name           string
+	Kind           SpanKind
+	Context        Span_Context
+	Parent_ID      SpanID // zero for the root spans
+	Start          time.Time
+	End            time.Time
+	Attributes     map[string]any
+	Status         StatusCode
+	Status_Message string
+	Service        string // service name of the tracer
+}
+
+// Span an operation of the trace. A nil span is valid and records nothing
+type Span struct {
+	tracer *Tracer
+	lock   sync.Mutex
+	data   Span_Data
+	ended  bool
+}
+
+// Context returns the span context, zero for a nil span
+func (s *Span) Context() Span_Context {
+	if s == nil {
+		return Span_Context{}
+	}
+	return s.data.Context
+}
+
+// Is_Recording returns true if the span is sampled and not ended
+func (s *Span) Is_Recording() bool {
+	if s == nil || !s.data.Context.Sampled {
+		return false
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return !s.ended
+}
+
+// Set_Attribute sets the attribute of the span. Ignored after End
+func (s *Span) Set_Attribute(pKey string, pValue any) {
+	if s == nil || !s.data.Context.Sampled {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ended {
+		return
+	}
+	if s.data.Attributes == nil {
+		s.data.Attributes = make(map[string]any)
+	}
+	s.data.Attributes[pKey] = pValue
+}
+
+// Set_Status sets the status of the span. Ignored after End
+func (s *Span) Set_Status(pCode StatusCode, pMessage string) {
+	if s == nil || !s.data.Context.Sampled {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.ended {
+		return
+	}
+	s.data.Status = pCode
+	s.data.Status_Message = pMessage
+}
+
+// Record_Error sets the error status with the message of the given error. Ignored if pErr is nil
+func (s *Span) Record_Error(pErr error) {
+	if pErr == nil {
+		return
+	}
+	s.Set_Status(STATUS_ERROR, pErr.Error())
+}
+
+// End ends the span and passes it to the exporter. Calls after the first one are ignored
+func (s *Span) End() {
+	if s == nil || !s.data.Context.Sampled {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	_data := s.data
+	s.lock.Unlock()
+
+	s.tracer.enqueue(_data)
+}
+
+// Config settings of the tracer
+type Config struct {
+	Service        string        // service name reported with the spans
+	Sample_Ratio   float64       // ratio of the root spans sampled (0..1), the child spans follow the parent
+	Queue_Size     int           // ended spans waiting for the export, DEFAULT_QUEUE_SIZE if 0
+	Batch_Size     int           // max spans per export, DEFAULT_BATCH_SIZE if 0
+	Flush_Interval time.Duration // max time an ended span waits for the export, DEFAULT_FLUSH_INTERVAL if 0
+}
+
+// Tracer starts the spans and exports the ended spans in batches. A nil tracer is valid and records nothing
+type Tracer struct {
+	config   Config
+	exporter Exporter
+	limit    uint64 /// root spans are sampled when the random part of the trace id is below the limit
+
+	lock    sync.Mutex
+	queue   []Span_Data
+	flush   chan chan struct{}
+	wake    chan struct{}
+	closed  bool
+	done    chan struct{}
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	last    atomic.Value /// last export error
+}
+
+// New_Tracer creates a tracer which exports the spans with the given exporter.
+// A nil exporter creates the span contexts for the propagation but records nothing.
+func New_Tracer(pConfig Config, pExporter Exporter) *Tracer {
+
+	if pConfig.Queue_Size <= 0 {
+		pConfig.Queue_Size = DEFAULT_QUEUE_SIZE
+	}
+	if pConfig.Batch_Size <= 0 {
+		pConfig.Batch_Size = DEFAULT_BATCH_SIZE
+	}
+	if pConfig.Flush_Interval <= 0 {
+		pConfig.Flush_Interval = DEFAULT_FLUSH_INTERVAL
+	}
+
+	_tracer := &Tracer{
+		config:   pConfig,
+		exporter: pExporter,
+		flush:    make(chan chan struct{}),
+		wake:     make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	switch {
+	case pExporter == nil || pConfig.Sample_Ratio <= 0:
+		_tracer.limit = 0
+	case pConfig.Sample_Ratio >= 1:
+		_tracer.limit = math.MaxUint64
+	default:
+		_tracer.limit = uint64(pConfig.Sample_Ratio * math.MaxUint64)
+	}
+
+	if pExporter == nil {
+		close(_tracer.done)
+	} else {
+		go _tracer.run()
+	}
+	return _tracer
+}
+
+// Service returns the service name of the tracer
+func (t *Tracer) Service() string {
+	if t == nil {
+		return ""
+	}
+	return t.config.Service
+}
+
+// Start starts a span which is the child of the span (or remote span context) of pCtx.
+//
+//	Returns the context carrying the new span and the span. The span must be ended with End
+func (t *Tracer) Start(pCtx context.Context, pName string, pKind SpanKind) (context.Context, *Span) {
+
+	if pCtx == nil {
+		pCtx = context.Background()
+	}
+	if t == nil {
+		return pCtx, nil
+	}
+
+	_parent := Span_Context_From(pCtx)
+	_span := &Span{tracer: t}
+	_span.data.Name = pName
+	_span.data.Kind = pKind
+	_span.data.Service = t.config.Service
+	_span.data.Start = time.Now()
+	_span.data.Context.Span_ID = new_span_id()
+
+	if _parent.Is_Valid() {
+		_span.data.Context.Trace_ID = _parent.Trace_ID
+		_span.data.Context.Sampled = _parent.Sampled && t.exporter != nil
+		_span.data.Parent_ID = _parent.Span_ID
+	} else {
+		_span.data.Context.Trace_ID = new_trace_id()
+		_span.data.Context.Sampled = t.limit > 0 && binary.BigEndian.Uint64(_span.data.Context.Trace_ID[8:]) <= t.limit
+	}
+
+	return Context_With_Span(pCtx, _span), _span
+}
+
+// Dropped returns the number of ended spans dropped because the export queue was full
+func (t *Tracer) Dropped() uint64 {
+	if t == nil {
+		return 0
+	}
+	return t.dropped.Load()
+}
+
+// Export_Errors returns the number of failed exports and the last export error
+func (t *Tracer) Export_Errors() (uint64, error) {
+	if t == nil {
+		return 0, nil
+	}
+	_err, _ := t.last.Load().(error)
+	return t.failed.Load(), _err
+}
+
+// Flush exports the ended spans waiting in the queue.
+//
+//	Returns ctx.Err() if the context is done before the export completes
+func (t *Tracer) Flush(pCtx context.Context) error {
+
+	if t == nil || t.exporter == nil {
+		return nil
+	}
+	_done := make(chan struct{})
+	select {
+	case t.flush <- _done:
+	case <-t.done:
+		return nil
+	case <-pCtx.Done():
+		return pCtx.Err()
+	}
+	select {
+	case <-_done:
+		return nil
+	case <-pCtx.Done():
+		return pCtx.Err()
+	}
+}
+
+// Shutdown exports the remaining spans and shuts down the exporter. The spans ended after Shutdown are dropped
+func (t *Tracer) Shutdown(pCtx context.Context) error {
+
+	if t == nil || t.exporter == nil {
+		return nil
+	}
+	t.lock.Lock()
+	if t.closed {
+		t.lock.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.lock.Unlock()
+
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+	select {
+	case <-t.done:
+	case <-pCtx.Done():
+		return pCtx.Err()
+	}
+	return t.exporter.Shutdown(pCtx)
+}
+
+// enqueue adds the ended span to the export queue, the span is dropped when the queue is full
+func (t *Tracer) enqueue(pData Span_Data) {
+
+	t.lock.Lock()
+	if t.closed || len(t.queue) >= t.config.Queue_Size {
+		t.lock.Unlock()
+		t.dropped.Add(1)
+		return
+	}
+	t.queue = append(t.queue, pData)
+	_full := len(t.queue) >= t.config.Batch_Size
+	t.lock.Unlock()
+
+	if _full {
+		select {
+		case t.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the queued spans when a batch is full, the flush interval passes or a flush is requested
+func (t *Tracer) run() {
+
+	defer close(t.done)
+	_ticker := time.NewTicker(t.config.Flush_Interval)
+	defer _ticker.Stop()
+
+	for {
+		var _flushed chan struct{}
+		select {
+		case <-_ticker.C:
+		case <-t.wake:
+		case _flushed = <-t.flush:
+		}
+
+		t.export_all()
+		if _flushed != nil {
+			close(_flushed)
+		}
+
+		t.lock.Lock()
+		_closed := t.closed
+		t.lock.Unlock()
+		if _closed {
+			t.export_all()
+			return
+		}
+	}
+}
+
+// export_all exports the queued spans in batches
+func (t *Tracer) export_all() {
+	for {
+		t.lock.Lock()
+		_count := len(t.queue)
+		if _count == 0 {
+			t.lock.Unlock()
+			return
+		}
+		if _count > t.config.Batch_Size {
+			_count = t.config.Batch_Size
+		}
+		_batch := make([]Span_Data, _count)
+		copy(_batch, t.queue)
+		t.queue = append(t.queue[:0], t.queue[_count:]...)
+		t.lock.Unlock()
+
+		_ctx, _cancel := context.WithTimeout(context.Background(), DEFAULT_EXPORT_TIMEOUT)
+		_err := t.export(_ctx, _batch)
+		_cancel()
+		if _err != nil {
+			t.failed.Add(1)
+			t.last.Store(_err)
+		}
+	}
+}
+
+// export passes the batch to the exporter, a panic of the exporter is returned as an error
+func (t *Tracer) export(pCtx context.Context, pBatch []Span_Data) (err error) {
+	defer func() {
+		if _r := recover(); _r != nil {
+			err = fmt.Errorf("exporter panic. %v", _r)
+		}
+	}()
+	return t.exporter.Export(pCtx, pBatch)
+}
+
+// ********* context *********
+
+type span_key struct{}
+type remote_key struct{}
+
+// Context_With_Span returns a copy of the context carrying the given span
+func Context_With_Span(pCtx context.Context, pSpan *Span) context.Context {
+	return context.WithValue(pCtx, span_key{}, pSpan)
+}
+
+// Span_From_Context returns the span carried by the context, nil if none
+func Span_From_Context(pCtx context.Context) *Span {
+	if pCtx == nil {
+		return nil
+	}
+	_span, _ := pCtx.Value(span_key{}).(*Span)
+	return _span
+}
+
+// Span_Context_From returns the span context of the span carried by the context,
+// or the remote span context extracted into it. Zero if none
+func Span_Context_From(pCtx context.Context) Span_Context {
+	if pCtx == nil {
+		return Span_Context{}
+	}
+	if _span := Span_From_Context(pCtx); _span != nil {
+		return _span.Context()
+	}
+	_remote, _ := pCtx.Value(remote_key{}).(Span_Context)
+	return _remote
+}
+
+// Context_With_Remote returns a copy of the context carrying the given remote span context
+// as the parent of the spans started from it
+func Context_With_Remote(pCtx context.Context, pRemote Span_Context) context.Context {
+	pRemote.Remote = true
+	return context.WithValue(context.WithValue(pCtx, span_key{}, (*Span)(nil)), remote_key{}, pRemote)
+}
+
+// new_trace_id returns a random trace id. The ids are read from crypto/rand, they can not be predicted
+// from the ids seen in the headers and the sampling of the root spans is uniform over the random part
+func new_trace_id() TraceID {
+	var _id TraceID
+	for !_id.Is_Valid() {
+		rand.Read(_id[:])
+	}
+	return _id
+}
+
+// new_span_id returns a random span id read from crypto/rand
+func new_span_id() SpanID {
+	var _id SpanID
+	for !_id.Is_Valid() {
+		rand.Read(_id[:])
+	}
+	return _id
+}
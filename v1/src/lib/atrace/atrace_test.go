@@ -0,0 +1,200 @@
+package atrace_test
+
+import (
+	"github.com/agnione/libs/v1/src/lib/atrace"
+
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	_tests := []struct {
+		value   string
+		valid   bool
+		sampled bool
+	}{
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", valid: true, sampled: true},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", valid: true},
+		{value: " 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-03 ", valid: true, sampled: true},
+		{value: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{value: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00F067AA0BA902B7-01"},
+		{value: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{value: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra", valid: true, sampled: true},
+		{value: "0g-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01"},
+		{value: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{value: ""},
+	}
+	for _, _test := range _tests {
+		_sc, _err := atrace.Parse_Traceparent(_test.value)
+		if !_test.valid {
+			if !errors.Is(_err, atrace.ErrInvalidTraceparent) {
+				t.Errorf("Parse_Traceparent(%q) = %v, want ErrInvalidTraceparent", _test.value, _err)
+			}
+			continue
+		}
+		if _err != nil {
+			t.Errorf("Parse_Traceparent(%q) = %v", _test.value, _err)
+			continue
+		}
+		if _sc.Trace_ID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || _sc.Span_ID.String() != "00f067aa0ba902b7" ||
+			_sc.Sampled != _test.sampled || !_sc.Remote {
+			t.Errorf("Parse_Traceparent(%q) = %+v, want sampled %v", _test.value, _sc, _test.sampled)
+		}
+	}
+}
+
+func TestSampleRatio(t *testing.T) {
+	_tests := []struct {
+		ratio   float64
+		sampled bool
+	}{
+		{ratio: 0, sampled: false},
+		{ratio: -1, sampled: false},
+		{ratio: 1, sampled: true},
+		{ratio: 2, sampled: true},
+	}
+	for _, _test := range _tests {
+		_tracer := atrace.New_Tracer(atrace.Config{Sample_Ratio: _test.ratio}, atrace.New_Memory_Exporter())
+		for _i := 0; _i < 100; _i++ {
+			_, _span := _tracer.Start(context.Background(), "root", atrace.KIND_INTERNAL)
+			if !_span.Context().Is_Valid() {
+				t.Fatalf("span context %+v is not valid", _span.Context())
+			}
+			if _span.Is_Recording() != _test.sampled {
+				t.Fatalf("ratio %v: Is_Recording() = %v, want %v", _test.ratio, _span.Is_Recording(), _test.sampled)
+			}
+		}
+		_tracer.Shutdown(context.Background())
+	}
+}
+
+func TestParentSampled(t *testing.T) {
+	_tests := []struct {
+		ratio   float64
+		parent  bool
+		sampled bool
+	}{
+		{ratio: 0, parent: true, sampled: true},
+		{ratio: 1, parent: false, sampled: false},
+	}
+	for _, _test := range _tests {
+		_tracer := atrace.New_Tracer(atrace.Config{Sample_Ratio: _test.ratio}, atrace.New_Memory_Exporter())
+		_remote, _ := atrace.Parse_Traceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+		_remote.Sampled = _test.parent
+
+		_ctx, _parent := _tracer.Start(atrace.Context_With_Remote(context.Background(), _remote), "server", atrace.KIND_SERVER)
+		_, _child := _tracer.Start(_ctx, "client", atrace.KIND_CLIENT)
+		for _, _span := range []*atrace.Span{_parent, _child} {
+			if _span.Context().Sampled != _test.sampled || _span.Context().Trace_ID != _remote.Trace_ID {
+				t.Errorf("ratio %v, parent sampled %v: span %+v, want sampled %v in the remote trace", _test.ratio, _test.parent, _span.Context(), _test.sampled)
+			}
+		}
+		_tracer.Shutdown(context.Background())
+	}
+}
+
+// blocking_exporter exporter which records the batch sizes, the first export waits until it is released
+type blocking_exporter struct {
+	lock     sync.Mutex
+	batches  []int
+	started  chan struct{}
+	release  chan struct{}
+	blocking bool
+}
+
+func (e *blocking_exporter) Export(pCtx context.Context, pSpans []atrace.Span_Data) error {
+	e.lock.Lock()
+	e.batches = append(e.batches, len(pSpans))
+	_block := e.blocking
+	e.blocking = false
+	e.lock.Unlock()
+	if _block {
+		close(e.started)
+		<-e.release
+	}
+	return nil
+}
+
+func (e *blocking_exporter) Shutdown(pCtx context.Context) error {
+	return nil
+}
+
+func (e *blocking_exporter) Batches() []int {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]int(nil), e.batches...)
+}
+
+// end_spans starts and ends the given number of root spans
+func end_spans(pTracer *atrace.Tracer, pCount int) {
+	for _i := 0; _i < pCount; _i++ {
+		_, _span := pTracer.Start(context.Background(), "work", atrace.KIND_INTERNAL)
+		_span.End()
+	}
+}
+
+func TestExportBatches(t *testing.T) {
+	_exporter := &blocking_exporter{started: make(chan struct{}), release: make(chan struct{}), blocking: true}
+	_tracer := atrace.New_Tracer(atrace.Config{Sample_Ratio: 1, Batch_Size: 2, Queue_Size: 3, Flush_Interval: time.Hour}, _exporter)
+
+	/// the full batch is exported without waiting for the flush interval, the export blocks
+	end_spans(_tracer, 2)
+	select {
+	case <-_exporter.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("a full batch is not exported")
+	}
+
+	/// 3 spans fill the queue while the export is blocked, the 4th one is dropped
+	end_spans(_tracer, 4)
+	if _tracer.Dropped() != 1 {
+		t.Errorf("Dropped() = %d, want 1", _tracer.Dropped())
+	}
+	close(_exporter.release)
+
+	_ctx, _cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer _cancel()
+	if _err := _tracer.Flush(_ctx); _err != nil {
+		t.Fatal(_err)
+	}
+	if _batches := _exporter.Batches(); len(_batches) != 3 || _batches[0] != 2 || _batches[1] != 2 || _batches[2] != 1 {
+		t.Errorf("exported batches %v, want [2 2 1]", _batches)
+	}
+
+	if _err := _tracer.Shutdown(_ctx); _err != nil {
+		t.Fatal(_err)
+	}
+	end_spans(_tracer, 1)
+	if _tracer.Dropped() != 2 {
+		t.Errorf("Dropped() after Shutdown = %d, want 2", _tracer.Dropped())
+	}
+}
+
+func TestFlush(t *testing.T) {
+	_exporter := atrace.New_Memory_Exporter()
+	_tracer := atrace.New_Tracer(atrace.Config{Sample_Ratio: 1, Flush_Interval: time.Hour}, _exporter)
+	defer _tracer.Shutdown(context.Background())
+
+	_ctx, _parent := _tracer.Start(context.Background(), "parent", atrace.KIND_SERVER)
+	_, _child := _tracer.Start(_ctx, "child", atrace.KIND_CLIENT)
+	_child.End()
+	_parent.End()
+	if len(_exporter.Spans()) != 0 {
+		t.Fatal("spans exported before the batch is full or the flush interval")
+	}
+
+	if _err := _tracer.Flush(context.Background()); _err != nil {
+		t.Fatal(_err)
+	}
+	_spans := _exporter.Spans()
+	if len(_spans) != 2 || _spans[0].Name != "child" || _spans[0].Parent_ID != _parent.Context().Span_ID || _spans[1].Parent_ID.Is_Valid() {
+		t.Errorf("exported spans %+v, want the child then the root span", _spans)
+	}
+}
@@ -0,0 +1,338 @@
+package atrace
+
+import (
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// exporter names of the tracing configuration
+const (
+	EXPORTER_OTLP   = "otlp"
+	EXPORTER_STDOUT = "stdout"
+	EXPORTER_FILE   = "file"
+)
+
+// DEFAULT_OTLP_ENDPOINT traces endpoint of a local OpenTelemetry collector (OTLP/HTTP)
+const DEFAULT_OTLP_ENDPOINT = "http://localhost:4318/v1/traces"
+
+// Exporter exports the ended spans. Export is called from one routine of the tracer
+type Exporter interface {
+
+	// Export exports the batch of the ended spans
+	Export(ctx context.Context, spans []Span_Data) error
+
+	// Shutdown flushes and releases the exporter. Export is not called after Shutdown
+	Shutdown(ctx context.Context) error
+}
+
+// New_From_Config creates the tracer of the given tracing configuration.
+// A tracer which only propagates the trace context is returned when the tracing is not enabled.
+//
+//	pBase_Path is used to resolve a relative file of the file exporter
+func New_From_Config(pService string, pConfig atypes.Tracingconfig, pBase_Path string) (*Tracer, error) {
+
+	_config := Config{Service: pService, Sample_Ratio: pConfig.Sample_Ratio}
+	if pConfig.Service != "" {
+		_config.Service = pConfig.Service
+	}
+	if pConfig.Enable != 1 {
+		return New_Tracer(_config, nil), nil
+	}
+	if _config.Sample_Ratio <= 0 {
+		_config.Sample_Ratio = 1
+	}
+
+	var _exporter Exporter
+	switch strings.ToLower(pConfig.Exporter) {
+	case EXPORTER_OTLP, "":
+		_exporter = New_OTLP_Exporter(pConfig.Endpoint, pConfig.Headers)
+	case EXPORTER_STDOUT:
+		_exporter = New_Writer_Exporter(os.Stdout)
+	case EXPORTER_FILE:
+		_file := pConfig.File
+		if _file == "" {
+			return nil, fmt.Errorf("tracing file %w", aerrors.ErrNilArgument)
+		}
+		if !filepath.IsAbs(_file) && pBase_Path != "" {
+			_file = filepath.Join(pBase_Path, _file)
+		}
+		_writer, _err := New_File_Exporter(_file)
+		if _err != nil {
+			return nil, _err
+		}
+		_exporter = _writer
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", pConfig.Exporter)
+	}
+	return New_Tracer(_config, _exporter), nil
+}
+
+// ********* writer exporter *********
+
+// Writer_Exporter writes the spans as JSON lines, to stdout or a file for the tests and the local runs
+type Writer_Exporter struct {
+	lock   sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// New_Writer_Exporter creates an exporter writing to the given writer
+func New_Writer_Exporter(pOut io.Writer) *Writer_Exporter {
+	return &Writer_Exporter{out: pOut}
+}
+
+// New_File_Exporter creates an exporter appending to the given file, the file is closed by Shutdown
+func New_File_Exporter(pFile string) (*Writer_Exporter, error) {
+	_file, _err := os.OpenFile(pFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if _err != nil {
+		return nil, _err
+	}
+	return &Writer_Exporter{out: _file, closer: _file}, nil
+}
+
+// span_line JSON line of a span
+type span_line struct {
+	Service        string         `json:"service,omitempty"`
+	Name           string         `json:"name"`
+	Kind           string         `json:"kind"`
+	Trace_ID       string         `json:"trace_id"`
+	Span_ID        string         `json:"span_id"`
+	Parent_ID      string         `json:"parent_id,omitempty"`
+	Start          time.Time      `json:"start"`
+	End            time.Time      `json:"end"`
+	Duration_Ms    float64        `json:"duration_ms"`
+	Attributes     map[string]any `json:"attributes,omitempty"`
+	Status         string         `json:"status"`
+	Status_Message string         `json:"status_message,omitempty"`
+}
+
+func (e *Writer_Exporter) Export(pCtx context.Context, pSpans []Span_Data) error {
+
+	var _buffer bytes.Buffer
+	_encoder := json.NewEncoder(&_buffer)
+	for _i := range pSpans {
+		_span := &pSpans[_i]
+		_line := span_line{
+			Service:        _span.Service,
+			Name:           _span.Name,
+			Kind:           _span.Kind.String(),
+			Trace_ID:       _span.Context.Trace_ID.String(),
+			Span_ID:        _span.Context.Span_ID.String(),
+			Start:          _span.Start,
+			End:            _span.End,
+			Duration_Ms:    float64(_span.End.Sub(_span.Start)) / float64(time.Millisecond),
+			Attributes:     _span.Attributes,
+			Status:         _span.Status.String(),
+			Status_Message: _span.Status_Message,
+		}
+		if _span.Parent_ID.Is_Valid() {
+			_line.Parent_ID = _span.Parent_ID.String()
+		}
+		if _err := _encoder.Encode(&_line); _err != nil {
+			return _err
+		}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.out == nil {
+		return aerrors.ErrNotInitialized
+	}
+	_, _err := e.out.Write(_buffer.Bytes())
+	return _err
+}
+
+func (e *Writer_Exporter) Shutdown(pCtx context.Context) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.out = nil
+	if e.closer != nil {
+		_err := e.closer.Close()
+		e.closer = nil
+		return _err
+	}
+	return nil
+}
+
+// ********* memory exporter *********
+
+// Memory_Exporter keeps the exported spans in memory for the tests
+type Memory_Exporter struct {
+	lock  sync.Mutex
+	spans []Span_Data
+}
+
+// New_Memory_Exporter creates an empty memory exporter
+func New_Memory_Exporter() *Memory_Exporter {
+	return &Memory_Exporter{}
+}
+
+func (e *Memory_Exporter) Export(pCtx context.Context, pSpans []Span_Data) error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = append(e.spans, pSpans...)
+	return nil
+}
+
+func (e *Memory_Exporter) Shutdown(pCtx context.Context) error {
+	return nil
+}
+
+// Spans returns a copy of the exported spans in the export order
+func (e *Memory_Exporter) Spans() []Span_Data {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return append([]Span_Data(nil), e.spans...)
+}
+
+// Reset removes the exported spans
+func (e *Memory_Exporter) Reset() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.spans = nil
+}
+
+// ********* OTLP exporter *********
+
+// OTLP_Exporter posts the spans to an OpenTelemetry collector with OTLP/HTTP in the JSON encoding
+type OTLP_Exporter struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+}
+
+// New_OTLP_Exporter creates an exporter posting to the given traces endpoint (DEFAULT_OTLP_ENDPOINT if empty)
+// with the given extra headers (e.g. authorization of the collector)
+func New_OTLP_Exporter(pEndpoint string, pHeaders map[string]string) *OTLP_Exporter {
+	if pEndpoint == "" {
+		pEndpoint = DEFAULT_OTLP_ENDPOINT
+	}
+	_headers := make(map[string]string, len(pHeaders))
+	for _name, _value := range pHeaders {
+		_headers[_name] = _value
+	}
+	return &OTLP_Exporter{endpoint: pEndpoint, headers: _headers, client: &http.Client{Timeout: DEFAULT_EXPORT_TIMEOUT}}
+}
+
+func (e *OTLP_Exporter) Export(pCtx context.Context, pSpans []Span_Data) error {
+
+	if len(pSpans) == 0 {
+		return nil
+	}
+	_body, _err := json.Marshal(otlp_request(pSpans))
+	if _err != nil {
+		return _err
+	}
+
+	_request, _err := http.NewRequestWithContext(pCtx, http.MethodPost, e.endpoint, bytes.NewReader(_body))
+	if _err != nil {
+		return _err
+	}
+	_request.Header.Set("Content-Type", "application/json")
+	for _name, _value := range e.headers {
+		_request.Header.Set(_name, _value)
+	}
+
+	_response, _err := e.client.Do(_request)
+	if _err != nil {
+		return _err
+	}
+	defer _response.Body.Close()
+	_message, _ := io.ReadAll(io.LimitReader(_response.Body, 1024))
+	if _response.StatusCode < 200 || _response.StatusCode > 299 {
+		return &aerrors.HTTPStatusError{StatusCode: _response.StatusCode, Body: _message}
+	}
+	return nil
+}
+
+func (e *OTLP_Exporter) Shutdown(pCtx context.Context) error {
+	e.client.CloseIdleConnections()
+	return nil
+}
+
+// otlp_request returns the OTLP JSON export request of the spans, grouped by the service
+func otlp_request(pSpans []Span_Data) map[string]any {
+
+	_services := make(map[string][]any)
+	var _order []string
+	for _i := range pSpans {
+		_span := &pSpans[_i]
+		if _, _ok := _services[_span.Service]; !_ok {
+			_order = append(_order, _span.Service)
+		}
+		_services[_span.Service] = append(_services[_span.Service], otlp_span(_span))
+	}
+
+	_resources := make([]any, 0, len(_order))
+	for _, _service := range _order {
+		_resources = append(_resources, map[string]any{
+			"resource": map[string]any{"attributes": otlp_attributes(map[string]any{"service.name": _service})},
+			"scopeSpans": []any{map[string]any{
+				"scope": map[string]any{"name": "agnione"},
+				"spans": _services[_service],
+			}},
+		})
+	}
+	return map[string]any{"resourceSpans": _resources}
+}
+
+// otlp_span returns the OTLP JSON span
+func otlp_span(pSpan *Span_Data) map[string]any {
+	_span := map[string]any{
+		"traceId":           pSpan.Context.Trace_ID.String(),
+		"spanId":            pSpan.Context.Span_ID.String(),
+		"name":              pSpan.Name,
+		"kind":              int(pSpan.Kind) + 1,
+		"startTimeUnixNano": strconv.FormatInt(pSpan.Start.UnixNano(), 10),
+		"endTimeUnixNano":   strconv.FormatInt(pSpan.End.UnixNano(), 10),
+		"attributes":        otlp_attributes(pSpan.Attributes),
+		"status":            map[string]any{"code": int(pSpan.Status), "message": pSpan.Status_Message},
+	}
+	if pSpan.Parent_ID.Is_Valid() {
+		_span["parentSpanId"] = pSpan.Parent_ID.String()
+	}
+	return _span
+}
+
+// otlp_attributes returns the OTLP JSON key values of the attributes
+func otlp_attributes(pAttributes map[string]any) []any {
+	_attributes := make([]any, 0, len(pAttributes))
+	for _key, _value := range pAttributes {
+		var _any map[string]any
+		switch _v := _value.(type) {
+		case string:
+			_any = map[string]any{"stringValue": _v}
+		case bool:
+			_any = map[string]any{"boolValue": _v}
+		case int:
+			_any = map[string]any{"intValue": strconv.FormatInt(int64(_v), 10)}
+		case int32:
+			_any = map[string]any{"intValue": strconv.FormatInt(int64(_v), 10)}
+		case int64:
+			_any = map[string]any{"intValue": strconv.FormatInt(_v, 10)}
+		case uint64:
+			_any = map[string]any{"intValue": strconv.FormatUint(_v, 10)}
+		case float32:
+			_any = map[string]any{"doubleValue": float64(_v)}
+		case float64:
+			_any = map[string]any{"doubleValue": _v}
+		default:
+			_any = map[string]any{"stringValue": fmt.Sprint(_v)}
+		}
+		_attributes = append(_attributes, map[string]any{"key": _key, "value": _any})
+	}
+	return _attributes
+}
@@ -0,0 +1,85 @@
+package atrace
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"strings"
+)
+
+// TRACEPARENT_HEADER name of the W3C trace context header
+const TRACEPARENT_HEADER = "traceparent"
+
+// ErrInvalidTraceparent the traceparent header is not valid
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// Traceparent returns the W3C traceparent header value of the span context, empty if it is not valid
+func (sc Span_Context) Traceparent() string {
+	if !sc.Is_Valid() {
+		return ""
+	}
+	_flags := "00"
+	if sc.Sampled {
+		_flags = "01"
+	}
+	return "00-" + sc.Trace_ID.String() + "-" + sc.Span_ID.String() + "-" + _flags
+}
+
+// Parse_Traceparent parses the W3C traceparent header value (version-trace_id-parent_id-flags).
+//
+//	Returns the remote span context, unless ErrInvalidTraceparent
+func Parse_Traceparent(pValue string) (Span_Context, error) {
+
+	_parts := strings.Split(strings.TrimSpace(pValue), "-")
+	if len(_parts) < 4 || len(_parts[0]) != 2 || _parts[0] == "ff" || (_parts[0] == "00" && len(_parts) != 4) {
+		return Span_Context{}, ErrInvalidTraceparent
+	}
+
+	var _sc Span_Context
+	var _flags [1]byte
+	if !decode_hex(_parts[1], _sc.Trace_ID[:]) || !decode_hex(_parts[2], _sc.Span_ID[:]) ||
+		!decode_hex(_parts[3], _flags[:]) || !decode_hex(_parts[0], make([]byte, 1)) || !_sc.Is_Valid() {
+		return Span_Context{}, ErrInvalidTraceparent
+	}
+	_sc.Sampled = _flags[0]&0x01 == 0x01
+	_sc.Remote = true
+	return _sc, nil
+}
+
+// Inject sets the traceparent header of the span carried by the context into the given headers.
+// Nothing is set if the context has no valid span context
+func Inject(pCtx context.Context, pHeaders map[string]string) {
+	if _value := Span_Context_From(pCtx).Traceparent(); _value != "" && pHeaders != nil {
+		pHeaders[TRACEPARENT_HEADER] = _value
+	}
+}
+
+// Inject_Values sets the traceparent header into the given multi value headers (web socket handshake)
+func Inject_Values(pCtx context.Context, pHeaders map[string][]string) {
+	if _value := Span_Context_From(pCtx).Traceparent(); _value != "" && pHeaders != nil {
+		pHeaders[TRACEPARENT_HEADER] = []string{_value}
+	}
+}
+
+// Extract returns a copy of the context carrying the remote span context of the traceparent header
+// of the given headers (the header name is matched case insensitive). pCtx is returned if there is no valid header
+func Extract(pCtx context.Context, pHeaders map[string]string) context.Context {
+	for _name, _value := range pHeaders {
+		if !strings.EqualFold(_name, TRACEPARENT_HEADER) {
+			continue
+		}
+		if _sc, _err := Parse_Traceparent(_value); _err == nil {
+			return Context_With_Remote(pCtx, _sc)
+		}
+	}
+	return pCtx
+}
+
+// decode_hex decodes the lower case hex into the given bytes, false if the length or the characters are not valid
+func decode_hex(pValue string, pOut []byte) bool {
+	if len(pValue) != 2*len(pOut) || strings.ToLower(pValue) != pValue {
+		return false
+	}
+	_, _err := hex.Decode(pOut, []byte(pValue))
+	return _err == nil
+}
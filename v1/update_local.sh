@@ -1,13 +1,21 @@
 #! /bin/bash
 
+### DEPRECATED : packages are available as the Go module "github.com/agnione/libs" (see README.md).
+###		  This script is kept only for the AU plugins that still build from GOROOT.
+
 ### copy/update package into GOROOT
 ### set you GOROOT path here.
 #AGNI_PATH=/usr/local/go/src/agnione/v1
 
 AGNI_PATH=$GOROOT/src/agnione/v1
 
+echo "update_local.sh is deprecated. Use the github.com/agnione/libs Go module instead (see README.md)" >&2
+
 rm -r $AGNI_PATH 
 
 mkdir -p $AGNI_PATH  ### create the folder for AgniOne packages
 
-cp -r ./src $AGNI_PATH  ### copy/update packages
\ No newline at end of file
+cp -r ./src $AGNI_PATH  ### copy/update packages
+
+### the sources import the module path, the GOROOT copy keeps the old agnione/ import paths
+grep -rl '"github.com/agnione/libs/' --include=*.go $AGNI_PATH | xargs -r sed -i 's#"github.com/agnione/libs/#"agnione/#'
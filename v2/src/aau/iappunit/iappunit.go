@@ -0,0 +1,56 @@
+// iappunit package provides the v2 interface for the AgniOne Application Unit
+//
+// This package includes below functions:
+//   - New
+//   - Initialize
+//   - IsInitialized
+//   - GetID
+//   - Deinitialize
+//   - Start
+//   - IsStarted
+//   - Stop
+//   - Status
+//   - Info
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright     :	Open source MIT License
+//     Class/module  : IAppUnit v2 - AgniOne Application Framework
+//     Objective     : Define the v2 interface for AgniOne Application Unit
+//     ---------------------------------------------------------------------------------------------------------------------
+//     v2 drops the redundant bool results of the v1 interface, the functions return only the error
+//     (wrapping the aerrors sentinel errors). Start and Stop take the context (same as v1 IAppUnitContext).
+//     compat.Unit_V1 wraps a v2 unit so it can be loaded by the v1 framework.
+//     ---------------------------------------------------------------------------------------------------------------------
+package iappunit
+
+import (
+	v1 "github.com/agnione/libs/v1/src/aau/iappunit"
+	iappfw "github.com/agnione/libs/v2/src/appfm/iappfw"
+
+	"context"
+)
+
+// IAppUnit the v2 interface for the AgniOne Application Unit
+type IAppUnit interface {
+	v1.IAppUnitCore
+
+	// Initialize initializes the application unit
+	//
+	// Parameter frm_instance - instance of application framework (v2 iappfw.IAgniApp)
+	// Parameter instance_id - instance id
+	// Parameter appunit_name - unit name
+	// Parameter appunit_path - unit file name
+	// Parameter config_file - unit configuration file name
+	// Returns nil if suceess, unless error
+	Initialize(frm_instance iappfw.IAgniApp, instance_id int,
+		appunit_name string, appunit_path string, config_file string) error
+
+	// Start starts the process of the application unit.
+	//	Parameter ctx - limits the time allowed for the start.
+	// Returns nil if suceess, unless error
+	Start(ctx context.Context) error
+
+	// Stop stops the process of the application unit.
+	//	Parameter ctx - deadline for the stop, the unit must return without waiting when ctx is done (forced stop).
+	// Returns nil if suceess, unless error
+	Stop(ctx context.Context) error
+}
@@ -0,0 +1,43 @@
+// package provides the v2 Interface to implement the HTTP client plugin for AgniOne Application Framework
+//
+// This interface defines functions that needs to implement when building client plugin
+// (in addition to v1 iahttpclient.IAHTTPClientCore)
+//
+//   - Initialize
+//
+//   - Initialize_TLS (optional IAHTTPClientTLS)
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright     :  Open source MIT License
+//     Class/module  :	IAHTTPClient v2 - AgniOne Application Framework
+//     Objective     :  Define the v2 http client interface plugin
+//     ---------------------------------------------------------------------------------------------------------------------
+//     v2 drops the redundant bool result of Initialize. The request functions are shared with v1.
+//     compat.HTTPClient_V2 wraps a v1 plugin into this interface.
+//     ---------------------------------------------------------------------------------------------------------------------
+package iahttpclient
+
+import (
+	v1 "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	"github.com/agnione/libs/v1/src/lib/atls"
+)
+
+// IAHTTPClient v2 interface of the http client plugin
+type IAHTTPClient interface {
+	v1.IAHTTPClientCore
+
+	// Initialize the instance.
+	//
+	// Returns nil if initialized scussessfully. Unless error
+	Initialize(pInstance_ID int) error
+}
+
+// IAHTTPClientTLS optional interface for the http client plugins which support the tls settings of the plugin config.
+// See v1 iahttpclient.IAHTTPClientTLS.
+type IAHTTPClientTLS interface {
+
+	// Initialize_TLS initializes the instance with the given id and tls settings.
+	//
+	// Returns nil if initialized scussessfully. Unless error
+	Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) error
+}
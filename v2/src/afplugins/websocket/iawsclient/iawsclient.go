@@ -0,0 +1,71 @@
+// package provides the v2 Interface to implement web socket client plugin for AgniOne Application Framework
+//
+// This package includes below functions (in addition to v1 iawsclient.IAWSClientCore):
+//
+//   - Initialize
+//
+//   - IsConnected
+//
+//   - Connect
+//
+//   - Disconnect
+//
+//   - Write
+//
+//   - Initialize_TLS (optional IAWSClientTLS)
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright     :   Open source MIT License
+//     Class/module  :   iawsclient v2 - AgniOne Application Framework
+//     Objective     :   Define the v2 interface to build web socket client library
+//
+//     v2 drops the redundant bool results of the v1 interface, the functions return only the error.
+//     A closed connection is reported with *aerrors.WSClosedError.
+//     compat.WSClient_V2 wraps a v1 plugin into this interface.
+//     ---------------------------------------------------------------------------------------------------------------------
+package iawsclient
+
+import (
+	v1 "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	"github.com/agnione/libs/v1/src/lib/atls"
+)
+
+// IAWSClient v2 interface of the web socket client plugin
+type IAWSClient interface {
+	v1.IAWSClientCore
+
+	// Initialize initializes the given id to the instance. Used to identify the instance
+	//
+	// Returns nil if success. Unless error
+	Initialize(pInstance_ID int) error
+
+	// IsConnected checks the connection is live by writing PING message.
+	//
+	// Returns nil if the connection is live. Unless error (*aerrors.WSClosedError when closed)
+	IsConnected() error
+
+	// Connect establishes the connection with the given url, request headers and sub protocols.
+	//
+	// Returns the HTTP status code of the handshake and nil if success. Unless returns -1 and error
+	Connect(pWS_URL string, pRequest_Headers *map[string][]string, pSub_protocols *[]string, pCompression bool) (int, error)
+
+	// Disconnect disconnects the connection.
+	//
+	// Returns nil if disconnection is success. Unless error
+	Disconnect() error
+
+	// Write writes the message to the connection.
+	//
+	// Returns nil if write is success. Unless error (*aerrors.WSClosedError when closed)
+	Write(pMessage_Type int, pMessage *[]byte) error
+}
+
+// IAWSClientTLS optional interface for the web socket client plugins which support the tls settings of the plugin config.
+// See v1 iawsclient.IAWSClientTLS.
+type IAWSClientTLS interface {
+
+	// Initialize_TLS initializes the given id and tls settings to the instance.
+	//
+	// Returns nil if success. Unless error
+	Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) error
+}
@@ -0,0 +1,82 @@
+// iappfw package provides the v2 interface of the AgniOne Application Framework
+//
+// This package includes below functions (in addition to v1 iappfw.IAgniAppCore):
+//
+//   - Reload_Config
+//   - Start_WSMonitor
+//   - Stop_WSMonitor
+//   - Save_App_Config
+//   - Write_FileContent
+//   - Unit_Stop
+//   - Unit_Start
+//   - Unit_Restart
+//   - Get_WSClient
+//   - Get_RESTClient
+//
+// ---------------------------------------------------------------------------------------------------------------------
+// Copyright		:	Open source MIT License
+// Class/module		:	IAgniApp v2 - AgniOne Application Framework
+// Objective		:	Define the v2 interface for AgniOne Application Framework
+// ---------------------------------------------------------------------------------------------------------------------
+// v2 drops the redundant bool results of the v1 interface, the functions return only the error.
+// Errors wrap the aerrors sentinel errors, unit control functions return *aerrors.UnitError.
+// compat.App_V2 wraps a v1 framework instance into this interface.
+// ---------------------------------------------------------------------------------------------------------------------
+package iappfw
+
+import (
+	v1 "github.com/agnione/libs/v1/src/appfm/iappfw"
+	ihttp "github.com/agnione/libs/v2/src/afplugins/http/iahttpclient"
+	iws "github.com/agnione/libs/v2/src/afplugins/websocket/iawsclient"
+)
+
+// IAgniApp the v2 interface of the AgniOne Application Framework
+type IAgniApp interface {
+	v1.IAgniAppCore
+	v1.IAgniAppLogger
+	v1.IAgniAppLogfiles
+	v1.IAgniAppMetrics
+	v1.IAgniAppTracer
+	v1.IAgniAppBus
+
+	// Reload_Config reloads the configuration of the application framework
+	// 	Returns nil if configuration loaded successfully. Unless returns error
+	Reload_Config() error
+
+	// Start_WSMonitor starts the web socket monitoring with the pre-set configuration in config file
+	// 	Returns nil if it started successfully. Unless returns error
+	Start_WSMonitor() error
+
+	// Stop_WSMonitor stops the web socket monitoring
+	// 	Returns nil if it stopped successfully. Unless returns error
+	Stop_WSMonitor() error
+
+	// Save_App_Config save/overwrite the given application configuration into the app.config file.
+	// 	Should be used with CAUTION.
+	// 	Returns nil if given content is valid app.config content and saved successfully. Unless returns error
+	Save_App_Config(pAppConfigData *[]byte) error
+
+	// Write_FileContent writes the given content []byte to the given filename.
+	// 	Returns nil if successful. Unless returns error
+	Write_FileContent(pFileName *string, pData *[]byte) error
+
+	// Unit_Stop stops the given unit (if unit is loaded & running). pForce has the same meaning as v1 Unit_Stop.
+	// 	Returns nil if the given uint is successfully stopped. Unless returns *aerrors.UnitError
+	Unit_Stop(pUnitName *string, pForce bool) error
+
+	// Unit_Start starts the given unit (if unit is nit loaded & not running).
+	// 	Returns nil if the given uint is successfully loaded and running. Unless returns *aerrors.UnitError
+	Unit_Start(pUnitName *string) error
+
+	// Unit_Restart re-starts the given unit. pForce has the same meaning as v1 Unit_Restart.
+	// 	Returns nil if the given uint is successfully restarted. Unless returns *aerrors.UnitError
+	Unit_Restart(pUnitName *string, pForce bool) error
+
+	// Get_WSClient returns a new instance of the Web Socket client defined in the config file
+	// If failed then returns nil and error
+	Get_WSClient(pType *string) (iws.IAWSClient, error)
+
+	// Get_RESTClient returns a new instance of the REST client defined in the config file
+	// If failed then returns nil and error
+	Get_RESTClient(pType *string) (ihttp.IAHTTPClient, error)
+}
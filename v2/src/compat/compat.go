@@ -0,0 +1,334 @@
+// compat package provides the adapters between the v1 and v2 interfaces of the AgniOne Application Framework
+//
+// This package includes below functions:
+//
+//   - Unit_V1
+//
+//   - App_V2
+//
+//   - HTTPClient_V2
+//
+//   - WSClient_V2
+//
+//     ---------------------------------------------------------------------------------------------------------------------
+//     Copyright     :   Open source MIT License
+//     Class/module  :   compat - AgniOne Application Framework
+//     Objective     :   Allow v2 units to run in the v1 framework and to use the v1 plugins
+//     ---------------------------------------------------------------------------------------------------------------------
+//     A v2 unit is exported to the v1 framework with Unit_V1. The v1 framework instance given to Initialize is
+//     passed to the unit as v2 iappfw.IAgniApp (App_V2), plugins returned by it are wrapped with HTTPClient_V2/WSClient_V2.
+//     v1 functions which return false with a nil error are reported with an error wrapping aerrors.ErrOperationFailed.
+//     ---------------------------------------------------------------------------------------------------------------------
+package compat
+
+import (
+	iappunit1 "github.com/agnione/libs/v1/src/aau/iappunit"
+	ihttp1 "github.com/agnione/libs/v1/src/afplugins/http/iahttpclient"
+	htypes "github.com/agnione/libs/v1/src/afplugins/http/types"
+	iws1 "github.com/agnione/libs/v1/src/afplugins/websocket/iawsclient"
+	iappfw1 "github.com/agnione/libs/v1/src/appfm/iappfw"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	"github.com/agnione/libs/v1/src/lib/abus"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	"github.com/agnione/libs/v1/src/lib/ametrics"
+	"github.com/agnione/libs/v1/src/lib/atls"
+	"github.com/agnione/libs/v1/src/lib/atrace"
+	iappunit2 "github.com/agnione/libs/v2/src/aau/iappunit"
+	ihttp2 "github.com/agnione/libs/v2/src/afplugins/http/iahttpclient"
+	iws2 "github.com/agnione/libs/v2/src/afplugins/websocket/iawsclient"
+	iappfw2 "github.com/agnione/libs/v2/src/appfm/iappfw"
+
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// result converts the v1 (bool, error) result into error, false with a nil error wraps aerrors.ErrOperationFailed
+func result(pOperation string, pOk bool, pErr error) error {
+	if pErr != nil {
+		return pErr
+	}
+	if !pOk {
+		return fmt.Errorf("%s. %w", pOperation, aerrors.ErrOperationFailed)
+	}
+	return nil
+}
+
+// ********* units *********
+
+// unit_v1 exports a v2 unit as v1 iappunit.IAppUnit (and iappunit.IAppUnitContext)
+type unit_v1 struct {
+	iappunit2.IAppUnit
+}
+
+// Unit_V1 wraps the given v2 unit so it can be loaded by the v1 framework.
+// The returned unit also implements iappunit.IAppUnitContext.
+func Unit_V1(pUnit iappunit2.IAppUnit) iappunit1.IAppUnit {
+	if pUnit == nil {
+		return nil
+	}
+	return &unit_v1{IAppUnit: pUnit}
+}
+
+func (u *unit_v1) New() interface{} {
+	_unit, _ok := u.IAppUnit.New().(iappunit2.IAppUnit)
+	if !_ok {
+		return nil
+	}
+	return Unit_V1(_unit)
+}
+
+func (u *unit_v1) Initialize(pFrm_Instance iappfw1.IAgniApp, pInstance_ID int,
+	pUnit_Name string, pUnit_Path string, pConfig_File string) (bool, error) {
+	_err := u.IAppUnit.Initialize(App_V2(pFrm_Instance), pInstance_ID, pUnit_Name, pUnit_Path, pConfig_File)
+	return _err == nil, _err
+}
+
+func (u *unit_v1) Start() (bool, error) {
+	return u.StartContext(context.Background())
+}
+
+// Stop stops the unit with a deadline of atypes.DEFAULT_STOP_TIMEOUT seconds, same as AUBase.Stop
+func (u *unit_v1) Stop() (bool, error) {
+	_ctx, _cancel := context.WithTimeout(context.Background(), atypes.DEFAULT_STOP_TIMEOUT*time.Second)
+	defer _cancel()
+	return u.StopContext(_ctx)
+}
+
+func (u *unit_v1) StartContext(pCtx context.Context) (bool, error) {
+	_err := u.IAppUnit.Start(pCtx)
+	return _err == nil, _err
+}
+
+func (u *unit_v1) StopContext(pCtx context.Context) (bool, error) {
+	_err := u.IAppUnit.Stop(pCtx)
+	return _err == nil, _err
+}
+
+// ********* framework *********
+
+// app_v2 exposes a v1 framework instance as v2 iappfw.IAgniApp
+type app_v2 struct {
+	iappfw1.IAgniApp
+}
+
+// App_V2 wraps the given v1 framework instance into the v2 framework interface
+func App_V2(pApp iappfw1.IAgniApp) iappfw2.IAgniApp {
+	if pApp == nil {
+		return nil
+	}
+	return &app_v2{IAgniApp: pApp}
+}
+
+// Logger returns the logger of the v1 framework, a logger writing with Write2Log if it has none
+func (a *app_v2) Logger() *slog.Logger {
+	if _app, _ok := a.IAgniApp.(iappfw1.IAgniAppLogger); _ok {
+		return _app.Logger()
+	}
+	return slog.New(iappfw1.Write2Log_Handler(a.IAgniApp))
+}
+
+// Logfile_List returns the rotated log files of the v1 framework.
+//
+//	Returns aerrors.ErrNotSupported if the v1 framework does not rotate the log file (iappfw.IAgniAppLogfiles)
+func (a *app_v2) Logfile_List() ([]string, error) {
+	if _app, _ok := a.IAgniApp.(iappfw1.IAgniAppLogfiles); _ok {
+		return _app.Logfile_List()
+	}
+	return nil, fmt.Errorf("log file list %w", aerrors.ErrNotSupported)
+}
+
+// Metrics returns the metrics registry of the v1 framework, nil if it has none (iappfw.IAgniAppMetrics)
+func (a *app_v2) Metrics() *ametrics.Registry {
+	if _app, _ok := a.IAgniApp.(iappfw1.IAgniAppMetrics); _ok {
+		return _app.Metrics()
+	}
+	return nil
+}
+
+// Tracer returns the tracer of the v1 framework, nil if it has none (iappfw.IAgniAppTracer).
+// A nil tracer does not record the spans.
+func (a *app_v2) Tracer() *atrace.Tracer {
+	if _app, _ok := a.IAgniApp.(iappfw1.IAgniAppTracer); _ok {
+		return _app.Tracer()
+	}
+	return nil
+}
+
+// Bus returns the message bus of the v1 framework, nil if it has none (iappfw.IAgniAppBus)
+func (a *app_v2) Bus() *abus.Bus {
+	if _app, _ok := a.IAgniApp.(iappfw1.IAgniAppBus); _ok {
+		return _app.Bus()
+	}
+	return nil
+}
+
+func (a *app_v2) Reload_Config() error {
+	_ok, _err := a.IAgniApp.Reload_Config()
+	return result("reload config", _ok, _err)
+}
+
+func (a *app_v2) Start_WSMonitor() error {
+	_ok, _err := a.IAgniApp.Start_WSMonitor()
+	return result("start web socket monitor", _ok, _err)
+}
+
+func (a *app_v2) Stop_WSMonitor() error {
+	return result("stop web socket monitor", a.IAgniApp.Stop_WSMonitor(), nil)
+}
+
+func (a *app_v2) Save_App_Config(pAppConfigData *[]byte) error {
+	_ok, _err := a.IAgniApp.Save_App_Config(pAppConfigData)
+	return result("save app config", _ok, _err)
+}
+
+func (a *app_v2) Write_FileContent(pFileName *string, pData *[]byte) error {
+	_ok, _err := a.IAgniApp.Write_FileContent(pFileName, pData)
+	return result("write file content", _ok, _err)
+}
+
+func (a *app_v2) Unit_Stop(pUnitName *string, pForce bool) error {
+	_ok, _err := a.IAgniApp.Unit_Stop(pUnitName, pForce)
+	return result("unit stop", _ok, _err)
+}
+
+func (a *app_v2) Unit_Start(pUnitName *string) error {
+	_ok, _err := a.IAgniApp.Unit_Start(pUnitName)
+	return result("unit start", _ok, _err)
+}
+
+func (a *app_v2) Unit_Restart(pUnitName *string, pForce bool) error {
+	_ok, _err := a.IAgniApp.Unit_Restart(pUnitName, pForce)
+	return result("unit restart", _ok, _err)
+}
+
+func (a *app_v2) Get_WSClient(pType *string) (iws2.IAWSClient, error) {
+	_client, _err := a.IAgniApp.Get_WSClient(pType)
+	if _err != nil {
+		return nil, _err
+	}
+	return WSClient_V2(_client), nil
+}
+
+func (a *app_v2) Get_RESTClient(pType *string) (ihttp2.IAHTTPClient, error) {
+	_client, _err := a.IAgniApp.Get_RESTClient(pType)
+	if _err != nil {
+		return nil, _err
+	}
+	return HTTPClient_V2(_client), nil
+}
+
+// ********* plugins *********
+
+// http_client_v2 exposes a v1 http client plugin as v2 iahttpclient.IAHTTPClient
+type http_client_v2 struct {
+	ihttp1.IAHTTPClient
+}
+
+// HTTPClient_V2 wraps the given v1 http client plugin into the v2 plugin interface
+func HTTPClient_V2(pClient ihttp1.IAHTTPClient) ihttp2.IAHTTPClient {
+	if pClient == nil {
+		return nil
+	}
+	return &http_client_v2{IAHTTPClient: pClient}
+}
+
+func (c *http_client_v2) New() interface{} {
+	_client, _ok := c.IAHTTPClient.New().(ihttp1.IAHTTPClient)
+	if !_ok {
+		return nil
+	}
+	return HTTPClient_V2(_client)
+}
+
+func (c *http_client_v2) Initialize(pInstance_ID int) error {
+	return result("http client initialize", c.IAHTTPClient.Initialize(pInstance_ID), nil)
+}
+
+func (c *http_client_v2) Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) error {
+	_client, _ok := c.IAHTTPClient.(ihttp1.IAHTTPClientTLS)
+	if !_ok {
+		return fmt.Errorf("http client tls. %w", aerrors.ErrNotSupported)
+	}
+	return result("http client initialize", _client.Initialize_TLS(pInstance_ID, pTLS), nil)
+}
+
+func (c *http_client_v2) Patch(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return ihttp1.Method_Func(c.IAHTTPClient, http.MethodPatch)(pHTTP_Request)
+}
+
+func (c *http_client_v2) Head(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return ihttp1.Method_Func(c.IAHTTPClient, http.MethodHead)(pHTTP_Request)
+}
+
+func (c *http_client_v2) Options(pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return ihttp1.Method_Func(c.IAHTTPClient, http.MethodOptions)(pHTTP_Request)
+}
+
+func (c *http_client_v2) Do(pCtx context.Context, pHTTP_Request *htypes.AHTTPRequest) (*htypes.AHTTPResponse, error) {
+	return ihttp1.Do(pCtx, c.IAHTTPClient, pHTTP_Request)
+}
+
+// ws_client_v2 exposes a v1 web socket client plugin as v2 iawsclient.IAWSClient
+type ws_client_v2 struct {
+	iws1.IAWSClient
+}
+
+// WSClient_V2 wraps the given v1 web socket client plugin into the v2 plugin interface
+func WSClient_V2(pClient iws1.IAWSClient) iws2.IAWSClient {
+	if pClient == nil {
+		return nil
+	}
+	return &ws_client_v2{IAWSClient: pClient}
+}
+
+func (c *ws_client_v2) New() interface{} {
+	_client, _ok := c.IAWSClient.New().(iws1.IAWSClient)
+	if !_ok {
+		return nil
+	}
+	return WSClient_V2(_client)
+}
+
+func (c *ws_client_v2) Initialize(pInstance_ID int) error {
+	return result("web socket client initialize", c.IAWSClient.Initialize(pInstance_ID), nil)
+}
+
+func (c *ws_client_v2) Initialize_TLS(pInstance_ID int, pTLS *atls.TLS) error {
+	_client, _ok := c.IAWSClient.(iws1.IAWSClientTLS)
+	if !_ok {
+		return fmt.Errorf("web socket client tls. %w", aerrors.ErrNotSupported)
+	}
+	return result("web socket client initialize", _client.Initialize_TLS(pInstance_ID, pTLS), nil)
+}
+
+func (c *ws_client_v2) IsConnected() error {
+	_ok, _err := c.IAWSClient.IsConnected()
+	return result("web socket is connected", _ok, _err)
+}
+
+func (c *ws_client_v2) Connect(pWS_URL string, pRequest_Headers *map[string][]string, pSub_protocols *[]string, pCompression bool) (int, error) {
+	_ok, _status, _err := c.IAWSClient.Connect(pWS_URL, pRequest_Headers, pSub_protocols, pCompression)
+	return _status, result("web socket connect", _ok, _err)
+}
+
+func (c *ws_client_v2) Disconnect() error {
+	_ok, _err := c.IAWSClient.Disconnect()
+	return result("web socket disconnect", _ok, _err)
+}
+
+func (c *ws_client_v2) Write(pMessage_Type int, pMessage *[]byte) error {
+	_ok, _err := c.IAWSClient.Write(pMessage_Type, pMessage)
+	return result("web socket write", _ok, _err)
+}
+
+// make sure the adapters satisfy the interfaces
+var (
+	_ iappunit1.IAppUnitContext = (*unit_v1)(nil)
+	_ iappfw2.IAgniApp          = (*app_v2)(nil)
+	_ ihttp2.IAHTTPClientTLS    = (*http_client_v2)(nil)
+	_ ihttp1.IAHTTPClientDo     = (*http_client_v2)(nil)
+	_ iws2.IAWSClientTLS        = (*ws_client_v2)(nil)
+)
@@ -0,0 +1,66 @@
+package compat
+
+import (
+	"github.com/agnione/libs/v1/src/aau/aautest"
+	atypes "github.com/agnione/libs/v1/src/appfm/types"
+	build "github.com/agnione/libs/v1/src/lib"
+	"github.com/agnione/libs/v1/src/lib/aerrors"
+	iappfw2 "github.com/agnione/libs/v2/src/appfm/iappfw"
+
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stop_unit v2 unit recording the context of Stop
+type stop_unit struct {
+	stop_ctx context.Context
+}
+
+func (u *stop_unit) New() interface{}            { return &stop_unit{} }
+func (u *stop_unit) IsInitialized() bool         { return true }
+func (u *stop_unit) GetID() int                  { return 1 }
+func (u *stop_unit) Deinitialize()               {}
+func (u *stop_unit) IsStarted() bool             { return false }
+func (u *stop_unit) Status() *atypes.AppUnitInfo { return nil }
+func (u *stop_unit) Info() build.BuildInfo       { return build.BuildInfo{} }
+func (u *stop_unit) Start(context.Context) error { return nil }
+func (u *stop_unit) Stop(pCtx context.Context) error {
+	u.stop_ctx = pCtx
+	return nil
+}
+func (u *stop_unit) Initialize(iappfw2.IAgniApp, int, string, string, string) error { return nil }
+
+func TestResult(t *testing.T) {
+	if _err := result("unit stop", false, nil); !errors.Is(_err, aerrors.ErrOperationFailed) {
+		t.Errorf("result(false, nil) = %v, want aerrors.ErrOperationFailed", _err)
+	}
+	if _err := result("unit stop", false, aerrors.ErrUnitNotFound); !errors.Is(_err, aerrors.ErrUnitNotFound) {
+		t.Errorf("result(false, err) = %v, want the error", _err)
+	}
+	if _err := result("unit stop", true, nil); _err != nil {
+		t.Errorf("result(true, nil) = %v, want nil", _err)
+	}
+}
+
+func TestStopDeadline(t *testing.T) {
+	_unit := &stop_unit{}
+	if _ok, _err := Unit_V1(_unit).Stop(); !_ok || _err != nil {
+		t.Fatalf("Stop() = %v, %v", _ok, _err)
+	}
+	_deadline, _ok := _unit.stop_ctx.Deadline()
+	if !_ok {
+		t.Fatal("Stop() must pass a context with a deadline")
+	}
+	if _left := time.Until(_deadline); _left <= 0 || _left > atypes.DEFAULT_STOP_TIMEOUT*time.Second {
+		t.Errorf("deadline in %v, want at most %d seconds", _left, atypes.DEFAULT_STOP_TIMEOUT)
+	}
+}
+
+func TestLogfileListNotSupported(t *testing.T) {
+	_app := App_V2(aautest.Plain(aautest.New_FakeApp()))
+	if _, _err := _app.Logfile_List(); !errors.Is(_err, aerrors.ErrNotSupported) {
+		t.Errorf("Logfile_List() = %v, want aerrors.ErrNotSupported", _err)
+	}
+}